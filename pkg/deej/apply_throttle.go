@@ -0,0 +1,80 @@
+package deej
+
+import (
+	"time"
+)
+
+// a fast drag moves a slider a hundred times a second, and every one of those moves sets the volume of each of
+// its targets - which some audio drivers are slow to take. with volume_apply_max_rate set, each target's volume
+// is set at most that many times per second, using the latest value. moves that come in too soon after the last
+// one are held back, and applied once it's been long enough, so the volume always ends up where the slider stopped.
+// unlike the noise gate, this doesn't care how far a slider moved, only how often
+
+// pendingApply is the latest volume held back for a resolved target
+type pendingApply struct {
+	volume   float32
+	position float32
+}
+
+// throttleApply returns whether a slider's volume for a resolved target has to wait, in which case it's kept to be
+// applied later (replacing anything already waiting). must only be called from the slider move goroutine
+func (m *sessionMap) throttleApply(resolvedTarget string, volume float32, position float32) bool {
+	interval := m.deej.config.VolumeApplyInterval
+	if interval <= 0 {
+		return false
+	}
+
+	if time.Since(m.lastApply[resolvedTarget]) < interval {
+		m.pendingApplies[resolvedTarget] = pendingApply{volume: volume, position: position}
+		return true
+	}
+
+	m.lastApply[resolvedTarget] = time.Now()
+	delete(m.pendingApplies, resolvedTarget)
+
+	return false
+}
+
+// untilNextPendingApply returns how long until the first held back volume is due, and false if there aren't any
+func (m *sessionMap) untilNextPendingApply() (time.Duration, bool) {
+	if len(m.pendingApplies) == 0 {
+		return 0, false
+	}
+
+	next := time.Duration(-1)
+
+	for resolvedTarget := range m.pendingApplies {
+		due := time.Until(m.lastApply[resolvedTarget].Add(m.deej.config.VolumeApplyInterval))
+		if next < 0 || due < next {
+			next = due
+		}
+	}
+
+	if next < 0 {
+		next = 0
+	}
+
+	return next, true
+}
+
+// applyPendingVolumes applies every held back volume that's due. must only be called from the slider move goroutine
+func (m *sessionMap) applyPendingVolumes() {
+	adjustmentFailed := false
+
+	for resolvedTarget, pending := range m.pendingApplies {
+		if time.Since(m.lastApply[resolvedTarget]) < m.deej.config.VolumeApplyInterval {
+			continue
+		}
+
+		m.lastApply[resolvedTarget] = time.Now()
+		delete(m.pendingApplies, resolvedTarget)
+
+		_, failed := m.applyVolume(resolvedTarget, pending.volume, pending.position)
+		adjustmentFailed = adjustmentFailed || failed
+	}
+
+	// performance: same as with slider moves, this only happens when a SetVolume call errored
+	if adjustmentFailed {
+		m.refreshSessions(true)
+	}
+}
@@ -0,0 +1,93 @@
+package deej
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestVolumeApplyThrottle(t *testing.T) {
+	d := newTestDeej(t, zap.NewNop().Sugar(), `
+com_port: x
+volume_apply_max_rate: 10
+slider_mapping:
+  0: spotify.exe
+`)
+
+	if d.config.VolumeApplyInterval != 100*time.Millisecond {
+		t.Fatalf("applying at most 10 times a second waits %v, want 100ms", d.config.VolumeApplyInterval)
+	}
+
+	tests := []struct {
+		name string
+		move float32
+
+		// as if that long had passed since the last volume was applied, before applying what's due
+		elapsed time.Duration
+
+		want        float32
+		wantPending bool
+	}{
+		{"first move applies", 0.2, 0, 0.2, false},
+		{"next move waits", 0.4, 0, 0.2, true},
+		{"latest move replaces the waiting one", 0.6, 0, 0.2, true},
+		{"not due yet", -1, 50 * time.Millisecond, 0.2, true},
+		{"due", -1, 100 * time.Millisecond, 0.6, false},
+		{"move right after waits again", 0.8, 0, 0.6, true},
+		{"move long after applies", 0.9, 200 * time.Millisecond, 0.9, false},
+	}
+
+	for _, test := range tests {
+		if test.elapsed > 0 {
+			d.sessions.lastApply["spotify.exe"] = time.Now().Add(-test.elapsed)
+		}
+
+		if test.move >= 0 {
+			d.sessions.handleSliderMoveEvent(SliderMoveEvent{SliderID: 0, PercentValue: test.move})
+		} else {
+			d.sessions.applyPendingVolumes()
+		}
+
+		if got := mockVolume(t, d, "spotify.exe"); !floatsClose(got, test.want) {
+			t.Errorf("%s: spotify.exe is at %.2f, want %.2f", test.name, got, test.want)
+		}
+
+		until, pending := d.sessions.untilNextPendingApply()
+		if pending != test.wantPending {
+			t.Errorf("%s: a volume is waiting: %v, want %v", test.name, pending, test.wantPending)
+		}
+
+		if pending && (until < 0 || until > d.config.VolumeApplyInterval) {
+			t.Errorf("%s: the waiting volume is due in %v, want within %v", test.name, until, d.config.VolumeApplyInterval)
+		}
+	}
+}
+
+func TestVolumeApplyMaxRate(t *testing.T) {
+	tests := []struct {
+		configYAML string
+		want       time.Duration
+	}{
+		{"com_port: x\n", 0},
+		{"com_port: x\nvolume_apply_max_rate: 0\n", 0},
+		{"com_port: x\nvolume_apply_max_rate: -5\n", 0},
+		{"com_port: x\nvolume_apply_max_rate: 20\n", 50 * time.Millisecond},
+		{"com_port: x\nvolume_apply_max_rate: 2.5\n", 400 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		d := newTestDeej(t, zap.NewNop().Sugar(), test.configYAML)
+
+		if got := d.config.VolumeApplyInterval; got != test.want {
+			t.Errorf("config %q applies volumes every %v, want %v", test.configYAML, got, test.want)
+		}
+
+		// without a rate, nothing ever waits
+		if test.want == 0 {
+			if d.sessions.throttleApply("spotify.exe", 0.5, 0.5) || d.sessions.throttleApply("spotify.exe", 0.6, 0.6) {
+				t.Errorf("config %q held a volume back", test.configYAML)
+			}
+		}
+	}
+}
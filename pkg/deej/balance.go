@@ -0,0 +1,140 @@
+package deej
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// balances pan targets towards one of their channels, from -1 (fully left) to 1 (fully right):
+//
+//	balance:
+//	  discord.exe: -0.3
+//
+// only the quieter side is turned down, so a balanced target is never louder than its volume says. this is
+// separate from the volume, so sliders keep working as usual, and it's only applied where the platform has
+// per-channel volumes for the target's sessions
+
+var errBalanceUnsupported = errors.New("session doesn't support balance")
+
+// balanceChannelFactors returns how much of the volume the left and right channels get at a given balance
+func balanceChannelFactors(balance float32) (float32, float32) {
+	if balance < 0 {
+		return 1, 1 + balance
+	}
+
+	return 1 - balance, 1
+}
+
+// validBalance returns whether a balance is within -1 and 1
+func validBalance(balance float64) bool {
+	return balance >= -1 && balance <= 1
+}
+
+func balancesFromConfig(
+	userConfig *viper.Viper,
+	warnInvalidValue func(message string, key string, keysAndValues ...interface{}),
+) map[string]float32 {
+	result := make(map[string]float32)
+
+	for target, rawBalance := range userConfig.GetStringMapString(configKeyBalance) {
+		balance, err := strconv.ParseFloat(rawBalance, 32)
+		if err != nil || !validBalance(balance) {
+			warnInvalidValue("Invalid balance specified (must be between -1 and 1), ignoring",
+				configKeyBalance,
+				"target", target,
+				"invalidValue", rawBalance)
+
+			continue
+		}
+
+		// a centered target is no different from one that isn't listed
+		if balance != 0 {
+			result[strings.ToLower(target)] = float32(balance)
+		}
+	}
+
+	return result
+}
+
+// balancesToConfigValue returns the balances in the shape they're written to config.yaml in
+func balancesToConfigValue(balances map[string]float32) map[string]interface{} {
+	value := make(map[string]interface{}, len(balances))
+
+	for target, balance := range balances {
+		value[target] = balance
+	}
+
+	return value
+}
+
+// applyBalances pans every session of each target with a balance, and re-centers sessions deej panned before
+// whose target no longer has one. it's safe to repeat, so it runs whenever sessions are (re-)acquired as well
+func (m *sessionMap) applyBalances() {
+	m.balanceLock.Lock()
+	defer m.balanceLock.Unlock()
+
+	balances := m.deej.config.Balances
+	balanced := make(map[string]bool)
+
+	apply := func(key string, balance float32) {
+		sessions, ok := m.get(key)
+		if !ok {
+			return
+		}
+
+		for _, session := range sessions {
+			balancedSession, ok := session.(balancedSession)
+			if !ok || !session.Controllable() || !balancedSession.CanBalance() {
+				continue
+			}
+
+			if err := m.setSessionBalance(session, balancedSession, balance); err != nil {
+				m.logger.Warnw("Failed to set session balance", "key", key, "balance", balance, "error", err)
+			}
+		}
+	}
+
+	for target, balance := range balances {
+		for _, key := range m.resolveTarget(target) {
+			apply(key, balance)
+			balanced[key] = true
+		}
+	}
+
+	for key := range m.balancedKeys {
+		if !balanced[key] {
+			apply(key, 0)
+		}
+	}
+
+	m.balancedKeys = balanced
+}
+
+// keyCanBalance returns whether any of a key's sessions can be balanced. assumes the lock is held
+func (m *sessionMap) keyCanBalance(key string) bool {
+	for _, session := range m.m[key] {
+		if balancedSession, ok := session.(balancedSession); ok && session.Controllable() && balancedSession.CanBalance() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// TargetCanBalance returns whether any of a (possibly special) target's current sessions can be balanced
+func (m *sessionMap) TargetCanBalance(target string) bool {
+	for _, key := range m.resolveTarget(target) {
+		m.lock.Lock()
+		canBalance := m.keyCanBalance(key)
+		m.lock.Unlock()
+
+		if canBalance {
+			return true
+		}
+	}
+
+	return false
+}
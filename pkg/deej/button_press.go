@@ -0,0 +1,164 @@
+package deej
+
+import (
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// a button's state is sent with every line, so holding one down sends a stream of "pressed" frames. only the edge
+// from released to pressed counts as a press, and neither edge is taken within buttons.debounce of the last one,
+// so a bouncing contact doesn't press it twice. with buttons.long_press set, a press is only told apart once it's
+// over: released before then it's a short press (which toggles mute, same as without it), but held for that long
+// it's a long press instead, which sets the button's targets back to buttons.long_press_volume and unmutes them
+
+const (
+
+	// in seconds
+	defaultButtonDebounce = 0.15
+
+	// long presses are off by default, so presses are taken as soon as the button goes down
+	defaultButtonLongPress       = 0
+	defaultButtonLongPressVolume = 1.0
+
+	// anything longer would start eating deliberate double presses
+	maxButtonDebounce = 1 * time.Second
+)
+
+// ButtonSettings decides how button states turn into presses
+type ButtonSettings struct {
+	Debounce time.Duration
+
+	// 0 means there's no long press
+	LongPress       time.Duration
+	LongPressVolume float32
+}
+
+// buttonPress is what a single button frame turned out to be
+type buttonPress int
+
+const (
+	buttonPressNone buttonPress = iota
+	buttonPressShort
+	buttonPressLong
+)
+
+// buttonTracker follows the debounced state of a single button
+type buttonTracker struct {
+	pressed bool
+
+	// when the state last changed, and when the current press began
+	changedAt time.Time
+	pressedAt time.Time
+
+	// a long press fires while the button is still held, and only once per press
+	longPressFired bool
+}
+
+// newButtonTracker starts from a button's first seen state, which doesn't count as a press either way
+func newButtonTracker(pressed bool, now time.Time) *buttonTracker {
+	return &buttonTracker{
+		pressed:   pressed,
+		changedAt: now,
+		pressedAt: now,
+
+		// a button that's already held when deej connects shouldn't long press once it's been held for long enough
+		longPressFired: pressed,
+	}
+}
+
+// update takes the button's state from the latest frame, and returns the press it completes (if any)
+func (b *buttonTracker) update(pressed bool, now time.Time, settings ButtonSettings) buttonPress {
+	if pressed != b.pressed {
+
+		// too soon after the last edge, so it's the contact bouncing. frames keep coming, so a real change is
+		// still picked up once the debounce is over
+		if now.Sub(b.changedAt) < settings.Debounce {
+			return buttonPressNone
+		}
+
+		b.pressed = pressed
+		b.changedAt = now
+
+		if pressed {
+			b.pressedAt = now
+			b.longPressFired = false
+
+			if settings.LongPress <= 0 {
+				return buttonPressShort
+			}
+
+			return buttonPressNone
+		}
+
+		// released before it became a long press
+		if settings.LongPress > 0 && !b.longPressFired {
+			return buttonPressShort
+		}
+
+		return buttonPressNone
+	}
+
+	if b.pressed && settings.LongPress > 0 && !b.longPressFired && now.Sub(b.pressedAt) >= settings.LongPress {
+		b.longPressFired = true
+		return buttonPressLong
+	}
+
+	return buttonPressNone
+}
+
+func buttonSettingsFromConfig(
+	userConfig *viper.Viper,
+	warnInvalidValue func(message string, key string, keysAndValues ...interface{}),
+) ButtonSettings {
+	settings := ButtonSettings{}
+
+	debounce := userConfig.GetFloat64(configKeyButtonDebounce)
+	if debounce < 0 || debounce > maxButtonDebounce.Seconds() {
+		warnInvalidValue("Invalid button debounce specified, using default value",
+			configKeyButtonDebounce,
+			"invalidValue", debounce,
+			"maximumValue", maxButtonDebounce.Seconds(),
+			"defaultValue", defaultButtonDebounce)
+
+		debounce = defaultButtonDebounce
+	}
+
+	settings.Debounce = time.Duration(debounce * float64(time.Second))
+
+	longPress := userConfig.GetFloat64(configKeyButtonLongPress)
+	if longPress < 0 {
+		warnInvalidValue("Invalid button long press duration specified, using default value",
+			configKeyButtonLongPress,
+			"invalidValue", longPress,
+			"defaultValue", defaultButtonLongPress)
+
+		longPress = defaultButtonLongPress
+	}
+
+	// a long press has to be told apart from presses that are still bouncing
+	if longPress > 0 && longPress < debounce {
+		warnInvalidValue("Button long press duration is shorter than the debounce, using the debounce instead",
+			configKeyButtonLongPress,
+			"invalidValue", longPress,
+			"minimumValue", debounce)
+
+		longPress = debounce
+	}
+
+	settings.LongPress = time.Duration(longPress * float64(time.Second))
+
+	longPressVolume := userConfig.GetFloat64(configKeyButtonLongVolume)
+	if longPressVolume < 0 || longPressVolume > 1 {
+		warnInvalidValue("Invalid button long press volume specified, it must be between 0 and 1 - using default value",
+			configKeyButtonLongVolume,
+			"invalidValue", longPressVolume,
+			"defaultValue", defaultButtonLongPressVolume)
+
+		longPressVolume = defaultButtonLongPressVolume
+	}
+
+	settings.LongPressVolume = float32(longPressVolume)
+
+	return settings
+}
@@ -0,0 +1,128 @@
+package deej
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestButtonTracker(t *testing.T) {
+	shortOnly := ButtonSettings{Debounce: 50 * time.Millisecond}
+	withLongPress := ButtonSettings{Debounce: 50 * time.Millisecond, LongPress: 500 * time.Millisecond}
+
+	type frame struct {
+		at      time.Duration
+		pressed bool
+		want    buttonPress
+	}
+
+	tests := []struct {
+		name     string
+		settings ButtonSettings
+		initial  bool
+		frames   []frame
+	}{
+		{"press counts on the way down", shortOnly, false, []frame{
+			{10 * time.Millisecond, false, buttonPressNone},
+			{100 * time.Millisecond, true, buttonPressShort},
+			{110 * time.Millisecond, true, buttonPressNone},
+			{200 * time.Millisecond, false, buttonPressNone},
+		}},
+		{"held when first seen isn't a press", shortOnly, true, []frame{
+			{100 * time.Millisecond, true, buttonPressNone},
+			{2 * time.Second, true, buttonPressNone},
+			{2100 * time.Millisecond, false, buttonPressNone},
+		}},
+		{"bounces are ignored on both edges", shortOnly, false, []frame{
+			{100 * time.Millisecond, true, buttonPressShort},
+			{110 * time.Millisecond, false, buttonPressNone},
+			{120 * time.Millisecond, true, buttonPressNone},
+			{200 * time.Millisecond, false, buttonPressNone},
+			{210 * time.Millisecond, true, buttonPressNone},
+			{220 * time.Millisecond, false, buttonPressNone},
+			{300 * time.Millisecond, true, buttonPressShort},
+		}},
+		{"a change held through the bounce is picked up after it", shortOnly, false, []frame{
+			{100 * time.Millisecond, true, buttonPressShort},
+			{120 * time.Millisecond, false, buttonPressNone},
+			{140 * time.Millisecond, false, buttonPressNone},
+			{160 * time.Millisecond, false, buttonPressNone},
+			{200 * time.Millisecond, true, buttonPressNone},
+			{210 * time.Millisecond, true, buttonPressShort},
+		}},
+
+		{"short press counts on the way up", withLongPress, false, []frame{
+			{100 * time.Millisecond, true, buttonPressNone},
+			{300 * time.Millisecond, true, buttonPressNone},
+			{400 * time.Millisecond, false, buttonPressShort},
+		}},
+		{"long press fires while held, and only once", withLongPress, false, []frame{
+			{100 * time.Millisecond, true, buttonPressNone},
+			{599 * time.Millisecond, true, buttonPressNone},
+			{600 * time.Millisecond, true, buttonPressLong},
+			{900 * time.Millisecond, true, buttonPressNone},
+			{1 * time.Second, false, buttonPressNone},
+		}},
+		{"held when first seen doesn't long press", withLongPress, true, []frame{
+			{1 * time.Second, true, buttonPressNone},
+			{1100 * time.Millisecond, false, buttonPressNone},
+			{1200 * time.Millisecond, true, buttonPressNone},
+			{1300 * time.Millisecond, false, buttonPressShort},
+		}},
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, test := range tests {
+		tracker := newButtonTracker(test.initial, start)
+
+		for _, frame := range test.frames {
+			if got := tracker.update(frame.pressed, start.Add(frame.at), test.settings); got != frame.want {
+				t.Errorf("%s: at %v (pressed: %v) got press %d, want %d", test.name, frame.at, frame.pressed, got, frame.want)
+			}
+		}
+	}
+}
+
+func TestButtonSettingsFromConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		configYAML string
+		want       ButtonSettings
+		wantWarned []string
+	}{
+		{"valid", "buttons:\n  debounce: 0.1\n  long_press: 0.8\n  long_press_volume: 0.5\n",
+			ButtonSettings{Debounce: 100 * time.Millisecond, LongPress: 800 * time.Millisecond, LongPressVolume: 0.5}, nil},
+		{"no long press", "buttons:\n  debounce: 0\n  long_press: 0\n  long_press_volume: 1\n",
+			ButtonSettings{LongPressVolume: 1}, nil},
+		{"negative debounce", "buttons:\n  debounce: -1\n  long_press: 0\n  long_press_volume: 1\n",
+			ButtonSettings{Debounce: 150 * time.Millisecond, LongPressVolume: 1}, []string{configKeyButtonDebounce}},
+		{"debounce too long", "buttons:\n  debounce: 2\n  long_press: 0\n  long_press_volume: 1\n",
+			ButtonSettings{Debounce: 150 * time.Millisecond, LongPressVolume: 1}, []string{configKeyButtonDebounce}},
+		{"negative long press", "buttons:\n  debounce: 0.1\n  long_press: -1\n  long_press_volume: 1\n",
+			ButtonSettings{Debounce: 100 * time.Millisecond, LongPressVolume: 1}, []string{configKeyButtonLongPress}},
+		{"long press within the debounce", "buttons:\n  debounce: 0.2\n  long_press: 0.1\n  long_press_volume: 1\n",
+			ButtonSettings{Debounce: 200 * time.Millisecond, LongPress: 200 * time.Millisecond, LongPressVolume: 1},
+			[]string{configKeyButtonLongPress}},
+		{"long press volume out of range", "buttons:\n  debounce: 0.1\n  long_press: 1\n  long_press_volume: 1.5\n",
+			ButtonSettings{Debounce: 100 * time.Millisecond, LongPress: time.Second, LongPressVolume: 1},
+			[]string{configKeyButtonLongVolume}},
+	}
+
+	for _, test := range tests {
+		var warned []string
+
+		settings := buttonSettingsFromConfig(userConfigFromYAML(t, test.configYAML),
+			func(message string, key string, keysAndValues ...interface{}) {
+				warned = append(warned, key)
+			})
+
+		if settings != test.want {
+			t.Errorf("%s: got %+v, want %+v", test.name, settings, test.want)
+		}
+
+		if !reflect.DeepEqual(warned, test.wantWarned) {
+			t.Errorf("%s: warned about %v, want %v", test.name, warned, test.wantWarned)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Calibration is the raw range a slider's potentiometer actually covers, which is stretched over the full 0.0 - 1.0
+// before anything else (i.e. inverting or the volume curve) happens. worn or cheap potentiometers often stop short of
+// the full 0 - 1023, which would otherwise leave 0% or 100% out of reach
+type Calibration struct {
+	Min int `json:"min" schema:"minimum=0,maximum=frameMaxSliderValue"`
+	Max int `json:"max" schema:"minimum=0,maximum=frameMaxSliderValue"`
+}
+
+// calibrationRecorder tracks the raw values a slider reaches while it's being calibrated
+type calibrationRecorder struct {
+	min int
+	max int
+
+	// until something was read from the slider, min and max don't mean anything
+	seen bool
+}
+
+const (
+
+	// a calibrated range narrower than this is more likely a slider that wasn't moved through its
+	// travel than a potentiometer that really covers so little
+	minCalibrationRange = 100
+)
+
+var (
+	errNotCalibrating       = errors.New("slider isn't being calibrated")
+	errNotCalibrated        = errors.New("slider isn't calibrated")
+	errCalibrationTooNarrow = fmt.Errorf("slider must be moved through at least %d of its raw range", minCalibrationRange)
+)
+
+// defaultCalibration is the full range of the board's analog reads, which leaves values untouched
+var defaultCalibration = Calibration{Min: 0, Max: frameMaxSliderValue}
+
+func (c Calibration) validate() error {
+	if c.Min < 0 || c.Max > frameMaxSliderValue {
+		return fmt.Errorf("min and max must be between 0 and %d, got %d and %d", frameMaxSliderValue, c.Min, c.Max)
+	}
+
+	if c.Max-c.Min < minCalibrationRange {
+		return fmt.Errorf("max must be at least %d more than min, got %d and %d", minCalibrationRange, c.Min, c.Max)
+	}
+
+	return nil
+}
+
+// apply maps a raw value from the slider's calibrated range to 0.0 - 1.0. values past either end are clamped,
+// since a potentiometer can still read a bit beyond what it reached during calibration
+func (c Calibration) apply(raw int) float32 {
+	if raw <= c.Min {
+		return 0
+	}
+
+	if raw >= c.Max {
+		return 1
+	}
+
+	return float32(raw-c.Min) / float32(c.Max-c.Min)
+}
+
+func (r *calibrationRecorder) observe(raw int) {
+	if !r.seen || raw < r.min {
+		r.min = raw
+	}
+
+	if !r.seen || raw > r.max {
+		r.max = raw
+	}
+
+	r.seen = true
+}
+
+// StartCalibration starts recording the raw values the given slider reaches, replacing any recording
+// that was already in progress for it. the slider's current calibration stays in effect until it's finished
+func (sio *SerialIO) StartCalibration(sliderIdx int) {
+	sio.sliderLock.Lock()
+	defer sio.sliderLock.Unlock()
+
+	if sio.calibrating == nil {
+		sio.calibrating = make(map[int]*calibrationRecorder)
+	}
+
+	sio.calibrating[sliderIdx] = &calibrationRecorder{}
+}
+
+// CalibrationProgress returns the raw range the given slider reached since its calibration started. ok is false
+// if it isn't being calibrated, and the range is only meaningful once seen is true
+func (sio *SerialIO) CalibrationProgress(sliderIdx int) (observed Calibration, seen bool, ok bool) {
+	sio.sliderLock.Lock()
+	defer sio.sliderLock.Unlock()
+
+	recorder, ok := sio.calibrating[sliderIdx]
+	if !ok {
+		return Calibration{}, false, false
+	}
+
+	return Calibration{Min: recorder.min, Max: recorder.max}, recorder.seen, true
+}
+
+// FinishCalibration stops recording the given slider and returns the range it reached. the recording is
+// dropped either way, so a slider that wasn't moved far enough has to be calibrated from the start again
+func (sio *SerialIO) FinishCalibration(sliderIdx int) (Calibration, error) {
+	sio.sliderLock.Lock()
+	defer sio.sliderLock.Unlock()
+
+	recorder, ok := sio.calibrating[sliderIdx]
+	if !ok {
+		return Calibration{}, errNotCalibrating
+	}
+
+	delete(sio.calibrating, sliderIdx)
+
+	observed := Calibration{Min: recorder.min, Max: recorder.max}
+	if !recorder.seen || observed.Max-observed.Min < minCalibrationRange {
+		return Calibration{}, errCalibrationTooNarrow
+	}
+
+	return observed, nil
+}
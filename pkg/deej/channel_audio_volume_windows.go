@@ -0,0 +1,67 @@
+package deej
+
+import (
+	"math"
+	"syscall"
+	"unsafe"
+
+	ole "github.com/go-ole/go-ole"
+)
+
+// IChannelAudioVolume sets a session's per-channel volumes, which Windows applies on top of its (ISimpleAudioVolume)
+// volume. go-wca doesn't have it, so it's declared here
+var iidChannelAudioVolume = ole.NewGUID("{1c158861-b533-4b30-b1cf-e853e51c59b8}")
+
+type iChannelAudioVolume struct {
+	ole.IUnknown
+}
+
+type iChannelAudioVolumeVtbl struct {
+	ole.IUnknownVtbl
+	GetChannelCount  uintptr
+	SetChannelVolume uintptr
+	GetChannelVolume uintptr
+	SetAllVolumes    uintptr
+	GetAllVolumes    uintptr
+}
+
+func (v *iChannelAudioVolume) vTable() *iChannelAudioVolumeVtbl {
+	return (*iChannelAudioVolumeVtbl)(unsafe.Pointer(v.RawVTable))
+}
+
+func (v *iChannelAudioVolume) getChannelCount() (uint32, error) {
+	var count uint32
+
+	hr, _, _ := syscall.Syscall(
+		v.vTable().GetChannelCount,
+		2,
+		uintptr(unsafe.Pointer(v)),
+		uintptr(unsafe.Pointer(&count)),
+		0)
+
+	if hr != 0 {
+		return 0, ole.NewError(hr)
+	}
+
+	return count, nil
+}
+
+// setChannelVolume takes a level from 0.0 to 1.0. like with go-wca's float arguments, the level is passed in an
+// integer register, which the syscall package mirrors into the matching floating point register on amd64
+func (v *iChannelAudioVolume) setChannelVolume(channel uint32, level float32, eventCtx *ole.GUID) error {
+	hr, _, _ := syscall.Syscall6(
+		v.vTable().SetChannelVolume,
+		4,
+		uintptr(unsafe.Pointer(v)),
+		uintptr(channel),
+		uintptr(math.Float32bits(level)),
+		uintptr(unsafe.Pointer(eventCtx)),
+		0,
+		0)
+
+	if hr != 0 {
+		return ole.NewError(hr)
+	}
+
+	return nil
+}
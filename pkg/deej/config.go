@@ -6,11 +6,13 @@ import (
 	"path"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"gopkg.in/yaml.v3"
 
 	"github.com/omriharel/deej/pkg/deej/util"
@@ -21,21 +23,198 @@ import (
 type CanonicalConfig struct {
 	SliderMapping *sliderMap
 
+	// button index -> targets whose mute state each press toggles. same shape as the slider mapping
+	ButtonMapping *sliderMap
+
+	// how button states turn into presses, see button_press.go
+	Buttons ButtonSettings
+
 	ConnectionInfo struct {
 		COMPort  string
 		BaudRate int
 	}
 
+	// every board deej reads from, the one in ConnectionInfo first (see serial_connection.go)
+	SerialSources []SerialSource
+
+	// what's written back to the boards, if anything. see serial_feedback.go
+	SerialFeedback SerialFeedbackSettings
+
+	WebServer struct {
+		Host             string
+		Port             int
+		StrictPort       bool
+		Token            string
+		WebSocketMaxRate int
+
+		// how many POST, PUT and DELETE API requests are let through per second. 0 means no limit
+		WriteRateLimit float64
+
+		// in bytes, the largest request body the API reads
+		MaxBodySize int64
+
+		// origins (i.e. "http://192.168.1.10:8080") whose pages may call the API, normalized to lowercase.
+		// empty means only the UI deej serves itself, and "*" means any origin
+		CORSOrigins []string
+		CORSMethods []string
+		CORSHeaders []string
+
+		// when a certificate and key are configured, the UI is served over HTTPS with them. otherwise,
+		// TLSSelfSigned serves it over HTTPS with a certificate deej generates itself
+		TLSCert       string
+		TLSKey        string
+		TLSSelfSigned bool
+
+		// the path the UI and API are served under, normalized to "/path" (empty when served at the root)
+		BasePath string
+
+		// how long the serial connection may be down before /api/health reports deej as unhealthy
+		HealthGracePeriod time.Duration
+
+		// how long clients get to send requests and read responses, see server_timeouts.go
+		Timeouts ServerTimeouts
+
+		// the lowest level of log lines sent to /api/logs/stream
+		LogStreamLevel zapcore.Level
+
+		// paths (relative to BasePath) left out of the debug access log, of which only every AccessLogSample-th
+		// request is logged. 0 means none of them are. see server_accesslog.go
+		AccessLogExclude []string
+		AccessLogSample  int
+	}
+
+	MQTT MQTTSettings
+	OSC  OSCSettings
+	MIDI MIDISettings
+
+	// MIDI CC numbers, keyed by the index of the slider they move
+	MIDIMapping map[int]int
+
+	Webhooks []WebhookRule
+
+	// commands to run when sliders move past a threshold, see slider_actions.go
+	SliderActions SliderActionSettings
+
+	// sliders that jointly control the same targets
+	SliderGroups []SliderGroup
+
+	// ranges that targets' volumes are kept within
+	VolumeLimits []VolumeLimit
+
+	// how far each target is panned, see balance.go. centered targets aren't listed
+	Balances map[string]float32
+
+	// the volumes POST /api/reset sets targets to, see volume_reset.go
+	Reset ResetSettings
+
+	// the web UI's own preferences by their API name, with defaults filled in. see ui_preferences.go
+	UIPreferences map[string]interface{}
+
 	InvertSliders bool
 
+	// keyed by slider index, only contains sliders that have any settings of their own
+	SliderSettings map[int]SliderSettings
+
 	NoiseReductionLevel string
 
+	// when set, takes precedence over NoiseReductionLevel. 0 means it isn't set
+	NoiseThreshold float64
+
+	VolumeCurve VolumeCurve
+
+	MuteAtZero bool
+
+	// whether sessions go back to the volume (and mute state) they had before deej first changed them, on exit
+	RestoreVolumesOnExit bool
+
+	// whether slider values are saved to disk and restored on startup, see slider_positions.go
+	PersistSliderPositions bool
+
+	// the least amount of time between two volume changes a slider makes to the same target, 0 for no limit.
+	// see apply_throttle.go
+	VolumeApplyInterval time.Duration
+
+	// whether newly detected sessions are set to their slider's volume right away, see launch_volume.go
+	ApplyVolumeOnLaunch bool
+
+	// what happens to volumes changed from outside of deej, see external_volume.go
+	ExternalVolume ExternalVolumeSettings
+
+	// how often audio sessions are re-acquired, to pick up apps that started (or stopped) playing audio
+	SessionRefreshInterval time.Duration
+
+	// how long a session's volume, mute or balance change may take before it's abandoned, see session_timeout.go
+	SessionCallTimeout time.Duration
+
+	// what deej.current does when the focused window has no audio session
+	CurrentWindowFallback string
+
+	// how apps' session names become keys, see session_names.go
+	SessionNames SessionNameSettings
+
+	// which kind of target controls a session that more than one reaches, first to last. see target_precedence.go
+	TargetPrecedence []string
+
+	// named slider mappings that can be swapped in, and the one that's in use (empty if none is)
+	Profiles      map[string]*sliderMap
+	ActiveProfile string
+
 	logger             *zap.SugaredLogger
 	notifier           Notifier
 	stopWatcherChannel chan bool
 
 	reloadConsumers []chan bool
 
+	// bumped on every successful load, see config_version.go. all guarded by versionLock
+	version       uint64
+	loadedAt      time.Time
+	loadConsumers []chan ConfigLoadEvent
+	versionLock   sync.Mutex
+
+	// serializes reloads triggered by the file watcher and by explicit requests
+	reloadLock sync.Mutex
+
+	// why the last reload failed, nil if it didn't. guarded by reloadLock
+	lastReloadErr error
+
+	// the first edit from the API in a while is written right away, so a failed write reaches whoever made it.
+	// the ones right behind it are queued, and written together once edits have been quiet for a moment, so a
+	// burst of them comes down to a single write (and a single reload). anything else written in the meantime
+	// takes them along. each edited section is kept (under its config key) until a load that started after it
+	// was written picks it up, so edits made before that build on it rather than on what was loaded last. the
+	// lock also keeps writes to config.yaml from interleaving
+	pendingWriteLock   sync.Mutex
+	pendingEdits       map[string]interface{}
+	pendingWriteValues map[string]interface{}
+	pendingWriteTimer  *time.Timer
+	lastEditAt         time.Time
+
+	// whether the queued edits failed to be written, in which case the next edit is written right away instead
+	queuedWriteFailed bool
+
+	// how many times edits have been written, and how many of those the load in progress can see
+	editWrites        uint64
+	loadingEditWrites uint64
+
+	// slider mappings from before each edit, for undo and redo
+	mappingHistory mappingHistory
+
+	// a reload replaces SliderMapping while the API and the session map read it, so it's swapped under this lock
+	sliderMappingLock sync.RWMutex
+
+	// every slider mapping edit reads the mapping and writes back a changed copy of it. these happen one at a
+	// time, so two API requests at once can't both start from the same mapping and lose one of the edits
+	mappingEditLock sync.Mutex
+
+	// the same goes for edits to every other section the API changes a part of (i.e. one slider's settings)
+	sectionEditLock sync.Mutex
+
+	// whether the config file didn't exist and was created on this launch, see first_run.go
+	firstRun bool
+
+	// human-readable notes about values from the last load that were invalid and replaced
+	validationIssues []string
+
 	userConfig     *viper.Viper
 	internalConfig *viper.Viper
 }
@@ -52,13 +231,125 @@ const (
 	configType = "yaml"
 
 	configKeySliderMapping       = "slider_mapping"
+	configKeyButtonMapping       = "button_mapping"
+	configKeyButtonDebounce      = "buttons.debounce"
+	configKeyButtonLongPress     = "buttons.long_press"
+	configKeyButtonLongVolume    = "buttons.long_press_volume"
 	configKeyInvertSliders       = "invert_sliders"
+	configKeySliderSettings      = "slider_settings"
 	configKeyCOMPort             = "com_port"
 	configKeyBaudRate            = "baud_rate"
+	configKeySerialSources       = "serial_sources"
+	configKeyFeedbackEnabled     = "serial_feedback.enabled"
+	configKeyFeedbackInterval    = "serial_feedback.interval"
+	configKeyFeedbackMapping     = "serial_feedback.mapping"
+	configKeyFeedbackVolumes     = "serial_feedback.volumes"
 	configKeyNoiseReductionLevel = "noise_reduction"
+	configKeyNoiseThreshold      = "noise_threshold"
+	configKeyVolumeCurve         = "volume_curve"
+	configKeyVolumeCurveExponent = "volume_curve_exponent"
+	configKeyMuteAtZero          = "mute_at_zero"
+	configKeyRestoreVolumes      = "restore_volumes_on_exit"
+	configKeyPersistSliders      = "persist_slider_positions"
+	configKeyApplyOnLaunch       = "apply_volume_on_launch"
+	configKeyApplyMaxRate        = "volume_apply_max_rate"
+	configKeyExternalPolicy      = "external_volume_changes.policy"
+	configKeyExternalInterval    = "external_volume_changes.interval"
+	configKeyExternalRelease     = "external_volume_changes.release_threshold"
+	configKeySessionRefresh      = "session_refresh_interval"
+	configKeySessionTimeout      = "session_call_timeout"
+	configKeyCurrentFallback     = "current_window_fallback"
+	configKeySessionStripPath    = "session_names.strip_path"
+	configKeySessionStripExt     = "session_names.strip_extension"
+	configKeyTargetPrecedence    = "target_precedence"
+	configKeyProfiles            = "profiles"
+	configKeyActiveProfile       = "active_profile"
+	configKeyWebServerHost       = "web_server.host"
+	configKeyWebServerPort       = "web_server.port"
+	configKeyWebServerToken      = "web_server.token"
+	configKeyWebSocketMaxRate    = "web_server.websocket_max_rate"
+	configKeyHealthGracePeriod   = "web_server.health_grace_period"
+	configKeyServerReadTimeout   = "web_server.read_timeout"
+	configKeyServerWriteTimeout  = "web_server.write_timeout"
+	configKeyServerIdleTimeout   = "web_server.idle_timeout"
+	configKeyWriteRateLimit      = "web_server.write_rate_limit"
+	configKeyMaxBodySize         = "web_server.max_body_size"
+	configKeyCORSOrigins         = "web_server.cors_origins"
+	configKeyCORSMethods         = "web_server.cors_methods"
+	configKeyCORSHeaders         = "web_server.cors_headers"
+	configKeyTLSCert             = "web_server.tls_cert"
+	configKeyTLSKey              = "web_server.tls_key"
+	configKeyTLSSelfSigned       = "web_server.tls_self_signed"
+	configKeyBasePath            = "web_server.base_path"
+	configKeyLogStreamLevel      = "web_server.log_stream_level"
+	configKeyAccessLogExclude    = "web_server.access_log_exclude"
+	configKeyAccessLogSample     = "web_server.access_log_sample"
+	configKeyMQTTEnabled         = "mqtt.enabled"
+	configKeyMQTTBroker          = "mqtt.broker"
+	configKeyMQTTClientID        = "mqtt.client_id"
+	configKeyMQTTUsername        = "mqtt.username"
+	configKeyMQTTPassword        = "mqtt.password"
+	configKeyMQTTTopicPrefix     = "mqtt.topic_prefix"
+	configKeyMQTTSliderLockout   = "mqtt.slider_lockout"
+	configKeyOSCEnabled          = "osc.enabled"
+	configKeyOSCHost             = "osc.host"
+	configKeyOSCPort             = "osc.port"
+	configKeyMIDIEnabled         = "midi.enabled"
+	configKeyMIDIDevice          = "midi.device"
+	configKeyMIDIMapping         = "midi_mapping"
+	configKeyWebhooks            = "webhooks"
+	configKeyActionsSection      = "slider_actions"
+	configKeyActionsEnabled      = "slider_actions.enabled"
+	configKeyActionsAPIEdit      = "slider_actions.allow_api_edit"
+	configKeySliderActions       = "slider_actions.actions"
+	configKeySliderGroups        = "slider_groups"
+	configKeyVolumeLimits        = "volume_limits"
+	configKeyBalance             = "balance"
+	configKeyResetVolume         = "reset.volume"
+	configKeyResetTargets        = "reset.targets"
+	configKeyUIPreferences       = "ui_preferences"
+
+	// do nothing, or keep controlling the last focused window that had an audio session
+	currentWindowFallbackNone = "none"
+	currentWindowFallbackLast = "last"
 
 	defaultCOMPort  = "COM4"
 	defaultBaudRate = 9600
+
+	// only reachable from this machine unless the user explicitly opts into something like 0.0.0.0
+	defaultWebServerHost    = "127.0.0.1"
+	defaultWebServerPort    = 9123
+	defaultWebSocketMaxRate = 30
+
+	// how long changes to config.yaml have to be quiet for before it's reloaded
+	configReloadDelay = 300 * time.Millisecond
+
+	// how long edits from the API have to be quiet for before the queued ones are written to config.yaml
+	editWriteDelay = 300 * time.Millisecond
+
+	// plenty for clicking around the web UI, but not for a PUT on every drag event
+	defaultWriteRateLimit = 5
+
+	// in seconds, long enough to ride out a board being replugged or reconnected to
+	defaultHealthGracePeriod = 30
+
+	// debug lines are only logged by dev builds, and then there's a lot of them
+	defaultLogStreamLevel = "info"
+
+	// in seconds. re-acquiring all sessions is a kind of expensive operation, so this shouldn't be too frequent
+	defaultSessionRefreshInterval = 45
+
+	defaultMQTTBroker      = "tcp://127.0.0.1:1883"
+	defaultMQTTClientID    = "deej"
+	defaultMQTTTopicPrefix = "deej"
+
+	// in seconds, long enough for a hand to still be on the slider
+	defaultMQTTSliderLockout = 2
+
+	// OSC controllers are other devices (i.e. a tablet), so the listener is only useful when exposed.
+	// the port matches TouchOSC's default
+	defaultOSCHost = "0.0.0.0"
+	defaultOSCPort = 8000
 )
 
 // has to be defined as a non-constant because we're using path.Join
@@ -89,9 +380,63 @@ func NewConfig(logger *zap.SugaredLogger, notifier Notifier) (*CanonicalConfig,
 	userConfig.AddConfigPath(userConfigPath)
 
 	userConfig.SetDefault(configKeySliderMapping, map[string][]string{})
+	userConfig.SetDefault(configKeyButtonMapping, map[string][]string{})
+	userConfig.SetDefault(configKeyButtonDebounce, defaultButtonDebounce)
+	userConfig.SetDefault(configKeyButtonLongPress, defaultButtonLongPress)
+	userConfig.SetDefault(configKeyButtonLongVolume, defaultButtonLongPressVolume)
+	userConfig.SetDefault(configKeyFeedbackEnabled, false)
+	userConfig.SetDefault(configKeyFeedbackInterval, defaultSerialFeedbackInterval)
+	userConfig.SetDefault(configKeyFeedbackMapping, true)
+	userConfig.SetDefault(configKeyFeedbackVolumes, true)
 	userConfig.SetDefault(configKeyInvertSliders, false)
+	userConfig.SetDefault(configKeyVolumeCurve, defaultVolumeCurve)
+	userConfig.SetDefault(configKeyMuteAtZero, false)
+	userConfig.SetDefault(configKeyRestoreVolumes, true)
+	userConfig.SetDefault(configKeyPersistSliders, false)
+	userConfig.SetDefault(configKeyApplyOnLaunch, false)
+	userConfig.SetDefault(configKeyApplyMaxRate, 0)
+	userConfig.SetDefault(configKeyExternalPolicy, externalVolumePolicyIgnore)
+	userConfig.SetDefault(configKeyExternalInterval, defaultExternalVolumeInterval)
+	userConfig.SetDefault(configKeyExternalRelease, defaultExternalVolumeRelease)
+	userConfig.SetDefault(configKeySessionRefresh, defaultSessionRefreshInterval)
+	userConfig.SetDefault(configKeySessionTimeout, defaultSessionCallTimeout)
+	userConfig.SetDefault(configKeyCurrentFallback, currentWindowFallbackNone)
+	userConfig.SetDefault(configKeySessionStripPath, false)
+	userConfig.SetDefault(configKeySessionStripExt, false)
+	userConfig.SetDefault(configKeyTargetPrecedence, defaultTargetPrecedence)
 	userConfig.SetDefault(configKeyCOMPort, defaultCOMPort)
 	userConfig.SetDefault(configKeyBaudRate, defaultBaudRate)
+	userConfig.SetDefault(configKeyWebServerHost, defaultWebServerHost)
+	userConfig.SetDefault(configKeyWebServerPort, defaultWebServerPort)
+	userConfig.SetDefault(configKeyWebSocketMaxRate, defaultWebSocketMaxRate)
+	userConfig.SetDefault(configKeyHealthGracePeriod, defaultHealthGracePeriod)
+	userConfig.SetDefault(configKeyServerReadTimeout, defaultServerReadTimeout)
+	userConfig.SetDefault(configKeyServerWriteTimeout, defaultServerWriteTimeout)
+	userConfig.SetDefault(configKeyServerIdleTimeout, defaultServerIdleTimeout)
+	userConfig.SetDefault(configKeyWriteRateLimit, defaultWriteRateLimit)
+	userConfig.SetDefault(configKeyMaxBodySize, defaultMaxBodySize)
+	userConfig.SetDefault(configKeyCORSOrigins, []string{})
+	userConfig.SetDefault(configKeyCORSMethods, defaultCORSMethods)
+	userConfig.SetDefault(configKeyCORSHeaders, defaultCORSHeaders)
+	userConfig.SetDefault(configKeyAccessLogExclude, []string{})
+	userConfig.SetDefault(configKeyAccessLogSample, 0)
+	userConfig.SetDefault(configKeyTLSSelfSigned, false)
+	userConfig.SetDefault(configKeyLogStreamLevel, defaultLogStreamLevel)
+	userConfig.SetDefault(configKeyMQTTEnabled, false)
+	userConfig.SetDefault(configKeyMQTTBroker, defaultMQTTBroker)
+	userConfig.SetDefault(configKeyMQTTClientID, defaultMQTTClientID)
+	userConfig.SetDefault(configKeyMQTTTopicPrefix, defaultMQTTTopicPrefix)
+	userConfig.SetDefault(configKeyMQTTSliderLockout, defaultMQTTSliderLockout)
+	userConfig.SetDefault(configKeyOSCEnabled, false)
+	userConfig.SetDefault(configKeyOSCHost, defaultOSCHost)
+	userConfig.SetDefault(configKeyOSCPort, defaultOSCPort)
+	userConfig.SetDefault(configKeyMIDIEnabled, false)
+	userConfig.SetDefault(configKeyMIDIMapping, map[string]int{})
+	userConfig.SetDefault(configKeyActionsEnabled, false)
+	userConfig.SetDefault(configKeyActionsAPIEdit, false)
+	userConfig.SetDefault(configKeyBalance, map[string]float64{})
+	userConfig.SetDefault(configKeyResetVolume, defaultResetVolume)
+	userConfig.SetDefault(configKeyResetTargets, map[string]float64{})
 
 	internalConfig := viper.New()
 	internalConfig.SetConfigName(internalConfigName)
@@ -119,6 +464,11 @@ func (cc *CanonicalConfig) Load() error {
 		return fmt.Errorf("config file doesn't exist: %s", userConfigFilepath)
 	}
 
+	// edits written after this point are newer than what's about to be read
+	cc.pendingWriteLock.Lock()
+	cc.loadingEditWrites = cc.editWrites
+	cc.pendingWriteLock.Unlock()
+
 	// load the user config
 	if err := cc.userConfig.ReadInConfig(); err != nil {
 		cc.logger.Warnw("Viper failed to read user config", "error", err)
@@ -139,17 +489,27 @@ func (cc *CanonicalConfig) Load() error {
 		cc.logger.Debugw("Viper failed to read internal config", "error", err, "reminder", "this is fine")
 	}
 
+	// what the load is compared against, once it's done
+	previousSliderMapping := cc.loadedSliderMapping()
+
 	// canonize the configuration with viper's helpers
 	if err := cc.populateFromVipers(); err != nil {
 		cc.logger.Warnw("Failed to populate config fields", "error", err)
+		cc.notifier.Notify("Invalid configuration!", err.Error())
+
 		return fmt.Errorf("populate config fields: %w", err)
 	}
 
+	cc.recordLoad(previousSliderMapping)
+
 	cc.logger.Info("Loaded config successfully")
 	cc.logger.Infow("Config values",
 		"sliderMapping", cc.SliderMapping,
+		"buttonMapping", cc.ButtonMapping,
 		"connectionInfo", cc.ConnectionInfo,
-		"invertSliders", cc.InvertSliders)
+		"invertSliders", cc.InvertSliders,
+		"sliderSettings", cc.SliderSettings,
+		"activeProfile", cc.ActiveProfile)
 
 	return nil
 }
@@ -167,43 +527,36 @@ func (cc *CanonicalConfig) SubscribeToChanges() chan bool {
 func (cc *CanonicalConfig) WatchConfigFileChanges() {
 	cc.logger.Debugw("Starting to watch user config file for changes", "path", userConfigFilepath)
 
-	const (
-		minTimeBetweenReloadAttempts = time.Millisecond * 500
-		delayBetweenEventAndReload   = time.Millisecond * 50
+	var (
+		reloadTimer     *time.Timer
+		reloadTimerLock sync.Mutex
 	)
 
-	lastAttemptedReload := time.Now()
+	reload := func() {
+		if _, err := cc.Reload(); err != nil {
+			cc.logger.Warnw("Failed to reload config file", "error", err)
+		} else {
+			cc.notifier.Notify("Configuration reloaded!", "Your changes have been applied.")
+		}
+	}
 
-	// establish watch using viper as opposed to doing it ourselves, though our internal cooldown is still required
+	// establish watch using viper as opposed to doing it ourselves, though our internal debounce is still required
 	cc.userConfig.WatchConfig()
 	cc.userConfig.OnConfigChange(func(event fsnotify.Event) {
 
-		// when we get a write event...
-		if event.Op&fsnotify.Write == fsnotify.Write {
-
-			now := time.Now()
-
-			// ... check if it's not a duplicate (many editors will write to a file twice)
-			if lastAttemptedReload.Add(minTimeBetweenReloadAttempts).Before(now) {
-
-				// and attempt reload if appropriate
-				cc.logger.Debugw("Config file modified, attempting reload", "event", event)
+		// when we get a write event (or a create event, which is what an atomic save via rename looks like)...
+		if event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+			cc.logger.Debugw("Config file modified, reloading once it's quiet", "event", event)
 
-				// wait a bit to let the editor actually flush the new file contents to disk
-				<-time.After(delayBetweenEventAndReload)
-
-				if err := cc.Load(); err != nil {
-					cc.logger.Warnw("Failed to reload config file", "error", err)
-				} else {
-					cc.logger.Info("Reloaded config successfully")
-					cc.notifier.Notify("Configuration reloaded!", "Your changes have been applied.")
-
-					cc.onConfigReloaded()
-				}
-
-				// don't forget to update the time
-				lastAttemptedReload = now
+			// ... wait for it to be quiet for a moment. many editors write to a file twice, and a burst of edits
+			// from the API shouldn't cause a reload each. that also lets the editor flush the new contents to disk
+			reloadTimerLock.Lock()
+			if reloadTimer != nil {
+				reloadTimer.Stop()
 			}
+
+			reloadTimer = time.AfterFunc(configReloadDelay, reload)
+			reloadTimerLock.Unlock()
 		}
 	})
 
@@ -213,6 +566,35 @@ func (cc *CanonicalConfig) WatchConfigFileChanges() {
 	cc.userConfig.OnConfigChange(nil)
 }
 
+// Reload re-reads the config files from disk and lets all consumers know, just like a change to the file would.
+// on success, it returns a note for every value that was invalid and had to be replaced
+func (cc *CanonicalConfig) Reload() ([]string, error) {
+	cc.reloadLock.Lock()
+	defer cc.reloadLock.Unlock()
+
+	if err := cc.Load(); err != nil {
+		cc.lastReloadErr = err
+		return nil, err
+	}
+
+	cc.lastReloadErr = nil
+	cc.logger.Info("Reloaded config successfully")
+
+	issues := append([]string{}, cc.validationIssues...)
+	cc.onConfigReloaded()
+
+	return issues, nil
+}
+
+// LoadStatus returns the notes about invalid values from the last successful load, and why the last reload
+// failed if it did (in which case that load is still the one in effect)
+func (cc *CanonicalConfig) LoadStatus() ([]string, error) {
+	cc.reloadLock.Lock()
+	defer cc.reloadLock.Unlock()
+
+	return append([]string{}, cc.validationIssues...), cc.lastReloadErr
+}
+
 // StopWatchingConfigFile signals our filesystem watcher to stop
 func (cc *CanonicalConfig) StopWatchingConfigFile() {
 	cc.stopWatcherChannel <- true
@@ -220,33 +602,376 @@ func (cc *CanonicalConfig) StopWatchingConfigFile() {
 
 func (cc *CanonicalConfig) populateFromVipers() error {
 
+	// unlike most values, a bad baud rate has no sensible stand-in - opening the port at the default instead would
+	// only have deej read garbage. it's checked before anything else, so a reload that fails on it leaves the
+	// previous config fully in effect
+	baudRate := cc.userConfig.GetInt(configKeyBaudRate)
+	if !validBaudRate(baudRate) {
+		return fmt.Errorf("%s: %w", configKeyBaudRate, invalidBaudRateError(baudRate))
+	}
+
+	cc.validationIssues = nil
+
+	warnInvalidSliderIndices(cc.userConfig.GetStringMapStringSlice(configKeySliderMapping), configKeySliderMapping, cc.warnInvalidValue)
+
 	// merge the slider mappings from the user and internal configs
-	cc.SliderMapping = sliderMapFromConfigs(
+	sliderMapping := sliderMapFromConfigs(
 		cc.userConfig.GetStringMapStringSlice(configKeySliderMapping),
 		cc.internalConfig.GetStringMapStringSlice(configKeySliderMapping),
 	)
 
+	// a reload picks up the slider mapping edits that were written before it started, but not the ones written
+	// while it was reading. both change together, so GetSliderMappingRaw never sees the edits gone before the
+	// mapping they were written to
+	cc.pendingWriteLock.Lock()
+	cc.dropLoadedEdit(configKeySliderMapping)
+
+	cc.sliderMappingLock.Lock()
+	cc.SliderMapping = sliderMapping
+	cc.sliderMappingLock.Unlock()
+	cc.pendingWriteLock.Unlock()
+
+	warnInvalidTargetWeights(cc.SliderMapping, configKeySliderMapping, cc.warnInvalidValue)
+
+	warnInvalidSliderIndices(cc.userConfig.GetStringMapStringSlice(configKeyButtonMapping), configKeyButtonMapping, cc.warnInvalidValue)
+
+	// buttons only come from the user config
+	cc.ButtonMapping = sliderMapFromConfigs(
+		cc.userConfig.GetStringMapStringSlice(configKeyButtonMapping),
+		map[string][]string{},
+	)
+
+	cc.Buttons = buttonSettingsFromConfig(cc.userConfig, cc.warnInvalidValue)
+
 	// get the rest of the config fields - viper saves us a lot of effort here
 	cc.ConnectionInfo.COMPort = cc.userConfig.GetString(configKeyCOMPort)
 
-	cc.ConnectionInfo.BaudRate = cc.userConfig.GetInt(configKeyBaudRate)
-	if cc.ConnectionInfo.BaudRate <= 0 {
-		cc.logger.Warnw("Invalid baud rate specified, using default value",
-			"key", configKeyBaudRate,
-			"invalidValue", cc.ConnectionInfo.BaudRate,
-			"defaultValue", defaultBaudRate)
+	cc.ConnectionInfo.BaudRate = baudRate
+
+	cc.SerialSources = serialSourcesFromConfig(cc.userConfig, SerialSource{
+		COMPort:  cc.ConnectionInfo.COMPort,
+		BaudRate: cc.ConnectionInfo.BaudRate,
+	}, cc.warnInvalidValue)
+
+	cc.SerialFeedback = serialFeedbackFromConfig(cc.userConfig, cc.warnInvalidValue)
+
+	cc.WebServer.Host = cc.userConfig.GetString(configKeyWebServerHost)
+
+	cc.WebServer.Port = cc.userConfig.GetInt(configKeyWebServerPort)
+	if cc.WebServer.Port <= 0 || cc.WebServer.Port > 65535 {
+		cc.warnInvalidValue("Invalid web server port specified, using default value",
+			configKeyWebServerPort,
+			"invalidValue", cc.WebServer.Port,
+			"defaultValue", defaultWebServerPort)
+
+		cc.WebServer.Port = defaultWebServerPort
+	}
+
+	// a port the user explicitly asked for is never swapped for another one
+	cc.WebServer.StrictPort = cc.userConfig.IsSet(configKeyWebServerPort)
 
-		cc.ConnectionInfo.BaudRate = defaultBaudRate
+	cc.WebServer.Token = cc.userConfig.GetString(configKeyWebServerToken)
+
+	cc.WebServer.WebSocketMaxRate = cc.userConfig.GetInt(configKeyWebSocketMaxRate)
+	if cc.WebServer.WebSocketMaxRate <= 0 {
+		cc.warnInvalidValue("Invalid websocket message rate specified, using default value",
+			configKeyWebSocketMaxRate,
+			"invalidValue", cc.WebServer.WebSocketMaxRate,
+			"defaultValue", defaultWebSocketMaxRate)
+
+		cc.WebServer.WebSocketMaxRate = defaultWebSocketMaxRate
+	}
+
+	healthGracePeriod := cc.userConfig.GetFloat64(configKeyHealthGracePeriod)
+	if healthGracePeriod < 0 {
+		cc.warnInvalidValue("Invalid health check grace period specified, using default value",
+			configKeyHealthGracePeriod,
+			"invalidValue", healthGracePeriod,
+			"defaultValue", defaultHealthGracePeriod)
+
+		healthGracePeriod = defaultHealthGracePeriod
 	}
 
+	cc.WebServer.HealthGracePeriod = time.Duration(healthGracePeriod * float64(time.Second))
+	cc.WebServer.Timeouts = serverTimeoutsFromConfig(cc.userConfig, cc.warnInvalidValue)
+
+	cc.WebServer.WriteRateLimit = cc.userConfig.GetFloat64(configKeyWriteRateLimit)
+	if cc.WebServer.WriteRateLimit < 0 {
+		cc.warnInvalidValue("Invalid API write rate limit specified, using default value",
+			configKeyWriteRateLimit,
+			"invalidValue", cc.WebServer.WriteRateLimit,
+			"defaultValue", defaultWriteRateLimit)
+
+		cc.WebServer.WriteRateLimit = defaultWriteRateLimit
+	}
+
+	maxBodySize := cc.userConfig.GetInt64(configKeyMaxBodySize)
+	if maxBodySize <= 0 {
+		cc.warnInvalidValue("Invalid API max body size specified, using default value",
+			configKeyMaxBodySize,
+			"invalidValue", maxBodySize,
+			"defaultValue", defaultMaxBodySize)
+
+		maxBodySize = defaultMaxBodySize
+	}
+
+	cc.WebServer.MaxBodySize = maxBodySize * 1024
+
+	cc.WebServer.CORSOrigins = []string{}
+	for _, origin := range cc.userConfig.GetStringSlice(configKeyCORSOrigins) {
+		normalized, err := normalizeCORSOrigin(origin)
+		if err != nil {
+			cc.warnInvalidValue("Invalid CORS origin specified, ignoring it",
+				configKeyCORSOrigins,
+				"invalidValue", origin,
+				"error", err)
+
+			continue
+		}
+
+		cc.WebServer.CORSOrigins = append(cc.WebServer.CORSOrigins, normalized)
+	}
+
+	cc.WebServer.CORSMethods = corsListFromConfig(cc.userConfig.GetStringSlice(configKeyCORSMethods), strings.ToUpper)
+	if len(cc.WebServer.CORSMethods) == 0 {
+		cc.warnInvalidValue("No CORS methods specified, using default value",
+			configKeyCORSMethods,
+			"defaultValue", defaultCORSMethods)
+
+		cc.WebServer.CORSMethods = defaultCORSMethods
+	}
+
+	cc.WebServer.CORSHeaders = corsListFromConfig(cc.userConfig.GetStringSlice(configKeyCORSHeaders), strings.TrimSpace)
+
+	cc.WebServer.TLSCert = cc.userConfig.GetString(configKeyTLSCert)
+	cc.WebServer.TLSKey = cc.userConfig.GetString(configKeyTLSKey)
+	cc.WebServer.TLSSelfSigned = cc.userConfig.GetBool(configKeyTLSSelfSigned)
+
+	// neither works without the other, and quietly falling back to a self-signed certificate would be surprising
+	if (cc.WebServer.TLSCert == "") != (cc.WebServer.TLSKey == "") {
+		cc.warnInvalidValue("TLS certificate and key must be specified together, serving the UI over plain HTTP",
+			configKeyTLSCert,
+			"cert", cc.WebServer.TLSCert,
+			"key", cc.WebServer.TLSKey)
+
+		cc.WebServer.TLSCert = ""
+		cc.WebServer.TLSKey = ""
+		cc.WebServer.TLSSelfSigned = false
+	}
+
+	basePath, err := normalizeBasePath(cc.userConfig.GetString(configKeyBasePath))
+	if err != nil {
+		cc.warnInvalidValue("Invalid base path specified, serving the UI at the root instead",
+			configKeyBasePath,
+			"invalidValue", cc.userConfig.GetString(configKeyBasePath),
+			"error", err)
+	}
+
+	cc.WebServer.BasePath = basePath
+
+	if err := cc.WebServer.LogStreamLevel.UnmarshalText([]byte(cc.userConfig.GetString(configKeyLogStreamLevel))); err != nil {
+		cc.warnInvalidValue("Invalid log stream level specified, using default value",
+			configKeyLogStreamLevel,
+			"invalidValue", cc.userConfig.GetString(configKeyLogStreamLevel),
+			"defaultValue", defaultLogStreamLevel)
+
+		cc.WebServer.LogStreamLevel = zapcore.InfoLevel
+	}
+
+	cc.WebServer.AccessLogExclude = accessLogExcludeFromConfig(
+		cc.userConfig.GetStringSlice(configKeyAccessLogExclude), cc.warnInvalidValue)
+
+	cc.WebServer.AccessLogSample = cc.userConfig.GetInt(configKeyAccessLogSample)
+	if cc.WebServer.AccessLogSample < 0 {
+		cc.warnInvalidValue("Invalid access log sample rate specified, not logging excluded paths at all",
+			configKeyAccessLogSample,
+			"invalidValue", cc.WebServer.AccessLogSample)
+
+		cc.WebServer.AccessLogSample = 0
+	}
+
+	cc.MQTT = MQTTSettings{
+		Enabled:     cc.userConfig.GetBool(configKeyMQTTEnabled),
+		Broker:      cc.userConfig.GetString(configKeyMQTTBroker),
+		ClientID:    cc.userConfig.GetString(configKeyMQTTClientID),
+		Username:    cc.userConfig.GetString(configKeyMQTTUsername),
+		Password:    cc.userConfig.GetString(configKeyMQTTPassword),
+		TopicPrefix: strings.Trim(cc.userConfig.GetString(configKeyMQTTTopicPrefix), "/"),
+	}
+
+	// a bare host:port is the most common way to write a broker address, and it's always plain tcp
+	if cc.MQTT.Broker != "" && !strings.Contains(cc.MQTT.Broker, "://") {
+		cc.MQTT.Broker = "tcp://" + cc.MQTT.Broker
+	}
+
+	if cc.MQTT.Broker == "" {
+		cc.warnInvalidValue("Invalid MQTT broker specified, using default value",
+			configKeyMQTTBroker,
+			"defaultValue", defaultMQTTBroker)
+
+		cc.MQTT.Broker = defaultMQTTBroker
+	}
+
+	if cc.MQTT.ClientID == "" {
+		cc.MQTT.ClientID = defaultMQTTClientID
+	}
+
+	if cc.MQTT.TopicPrefix == "" || strings.ContainsAny(cc.MQTT.TopicPrefix, "+#") {
+		cc.warnInvalidValue("Invalid MQTT topic prefix specified, using default value",
+			configKeyMQTTTopicPrefix,
+			"invalidValue", cc.MQTT.TopicPrefix,
+			"defaultValue", defaultMQTTTopicPrefix)
+
+		cc.MQTT.TopicPrefix = defaultMQTTTopicPrefix
+	}
+
+	sliderLockout := cc.userConfig.GetFloat64(configKeyMQTTSliderLockout)
+	if sliderLockout < 0 {
+		cc.warnInvalidValue("Invalid MQTT slider lockout specified, using default value",
+			configKeyMQTTSliderLockout,
+			"invalidValue", sliderLockout,
+			"defaultValue", defaultMQTTSliderLockout)
+
+		sliderLockout = defaultMQTTSliderLockout
+	}
+
+	cc.MQTT.SliderLockout = time.Duration(sliderLockout * float64(time.Second))
+
+	cc.OSC = OSCSettings{
+		Enabled: cc.userConfig.GetBool(configKeyOSCEnabled),
+		Host:    cc.userConfig.GetString(configKeyOSCHost),
+		Port:    cc.userConfig.GetInt(configKeyOSCPort),
+	}
+
+	if cc.OSC.Port <= 0 || cc.OSC.Port > 65535 {
+		cc.warnInvalidValue("Invalid OSC port specified, using default value",
+			configKeyOSCPort,
+			"invalidValue", cc.OSC.Port,
+			"defaultValue", defaultOSCPort)
+
+		cc.OSC.Port = defaultOSCPort
+	}
+
+	cc.MIDI = MIDISettings{
+		Enabled: cc.userConfig.GetBool(configKeyMIDIEnabled),
+		Device:  cc.userConfig.GetString(configKeyMIDIDevice),
+	}
+
+	cc.MIDIMapping = midiMappingFromConfig(cc.userConfig, cc.warnInvalidValue)
+
+	cc.Webhooks = webhookRulesFromConfig(cc.userConfig, cc.warnInvalidValue)
+
+	cc.SliderActions.Enabled = cc.userConfig.GetBool(configKeyActionsEnabled)
+	cc.SliderActions.AllowAPIEdit = cc.userConfig.GetBool(configKeyActionsAPIEdit)
+	cc.SliderActions.Actions = sliderActionsFromConfig(cc.userConfig, cc.warnInvalidValue)
+
+	cc.SliderGroups = sliderGroupsFromConfig(cc.userConfig, cc.SliderMapping, cc.warnInvalidValue)
+
+	cc.VolumeLimits = volumeLimitsFromConfig(cc.userConfig, cc.warnInvalidValue)
+
+	cc.Balances = balancesFromConfig(cc.userConfig, cc.warnInvalidValue)
+	cc.Reset = resetSettingsFromConfig(cc.userConfig, cc.warnInvalidValue)
+	cc.UIPreferences = uiPreferencesFromConfig(cc.userConfig, cc.warnInvalidValue)
+
 	cc.InvertSliders = cc.userConfig.GetBool(configKeyInvertSliders)
+	cc.VolumeCurve = VolumeCurve{
+		Type:     cc.userConfig.GetString(configKeyVolumeCurve),
+		Exponent: cc.userConfig.GetFloat64(configKeyVolumeCurveExponent),
+	}
+
+	if err := cc.VolumeCurve.validate(); err != nil {
+		cc.warnInvalidValue("Invalid volume curve specified, using default value",
+			configKeyVolumeCurve,
+			"error", err,
+			"defaultValue", defaultVolumeCurve)
+
+		cc.VolumeCurve = VolumeCurve{Type: defaultVolumeCurve}
+	}
+
+	cc.MuteAtZero = cc.userConfig.GetBool(configKeyMuteAtZero)
+	cc.RestoreVolumesOnExit = cc.userConfig.GetBool(configKeyRestoreVolumes)
+	cc.PersistSliderPositions = cc.userConfig.GetBool(configKeyPersistSliders)
+	cc.ApplyVolumeOnLaunch = cc.userConfig.GetBool(configKeyApplyOnLaunch)
+
+	cc.VolumeApplyInterval = 0
+	if applyMaxRate := cc.userConfig.GetFloat64(configKeyApplyMaxRate); applyMaxRate > 0 {
+		cc.VolumeApplyInterval = time.Duration(float64(time.Second) / applyMaxRate)
+	} else if applyMaxRate < 0 {
+		cc.warnInvalidValue("Invalid volume apply rate specified, applying every slider move instead",
+			configKeyApplyMaxRate,
+			"invalidValue", applyMaxRate)
+	}
+	cc.ExternalVolume = externalVolumeSettingsFromConfig(cc.userConfig, cc.warnInvalidValue)
+
+	// anything more frequent than the refresh cooldown would just be skipped
+	sessionRefreshInterval := time.Duration(cc.userConfig.GetFloat64(configKeySessionRefresh) * float64(time.Second))
+	if sessionRefreshInterval < minTimeBetweenSessionRefreshes {
+		cc.warnInvalidValue("Session refresh interval is too short, using the minimum instead",
+			configKeySessionRefresh,
+			"invalidValue", cc.userConfig.GetFloat64(configKeySessionRefresh),
+			"minimumValue", minTimeBetweenSessionRefreshes.Seconds())
+
+		sessionRefreshInterval = minTimeBetweenSessionRefreshes
+	}
+
+	cc.SessionRefreshInterval = sessionRefreshInterval
+	cc.SessionCallTimeout = sessionCallTimeoutFromConfig(cc.userConfig, cc.warnInvalidValue)
+
+	cc.CurrentWindowFallback = strings.ToLower(cc.userConfig.GetString(configKeyCurrentFallback))
+	if cc.CurrentWindowFallback != currentWindowFallbackNone && cc.CurrentWindowFallback != currentWindowFallbackLast {
+		cc.warnInvalidValue("Invalid current window fallback specified, using default value",
+			configKeyCurrentFallback,
+			"invalidValue", cc.CurrentWindowFallback,
+			"defaultValue", currentWindowFallbackNone)
+
+		cc.CurrentWindowFallback = currentWindowFallbackNone
+	}
+
+	cc.TargetPrecedence = targetPrecedenceFromConfig(cc.userConfig, cc.warnInvalidValue)
+	cc.SessionNames = sessionNameSettingsFromConfig(cc.userConfig)
+
+	cc.Profiles = profilesFromConfig(cc.userConfig, cc.warnInvalidValue)
+
+	cc.ActiveProfile = strings.ToLower(cc.userConfig.GetString(configKeyActiveProfile))
+	if _, ok := cc.Profiles[cc.ActiveProfile]; cc.ActiveProfile != "" && !ok {
+		cc.warnInvalidValue("Active profile doesn't exist, using slider_mapping as-is",
+			configKeyActiveProfile,
+			"invalidValue", cc.ActiveProfile)
+
+		cc.ActiveProfile = ""
+	}
+
+	cc.SliderSettings = sliderSettingsFromConfig(cc.userConfig, cc.warnInvalidValue)
 	cc.NoiseReductionLevel = cc.userConfig.GetString(configKeyNoiseReductionLevel)
 
+	cc.NoiseThreshold = cc.userConfig.GetFloat64(configKeyNoiseThreshold)
+	if !validNoiseThreshold(cc.NoiseThreshold) {
+		cc.warnInvalidValue("Invalid noise threshold specified, using noise reduction level instead",
+			configKeyNoiseThreshold,
+			"invalidValue", cc.NoiseThreshold,
+			"noiseReductionLevel", cc.NoiseReductionLevel)
+
+		cc.NoiseThreshold = 0
+	}
+
+	// everything else is in place by now, so the other sections' pending edits can go too
+	cc.pendingWriteLock.Lock()
+	for key := range cc.pendingEdits {
+		cc.dropLoadedEdit(key)
+	}
+	cc.pendingWriteLock.Unlock()
+
 	cc.logger.Debug("Populated config fields from vipers")
 
 	return nil
 }
 
+// warnInvalidValue logs a config value that couldn't be used as it is, and remembers it for Reload to report
+func (cc *CanonicalConfig) warnInvalidValue(message string, key string, keysAndValues ...interface{}) {
+	cc.logger.Warnw(message, append([]interface{}{"key", key}, keysAndValues...)...)
+	cc.validationIssues = append(cc.validationIssues, fmt.Sprintf("%s: %s", key, message))
+}
+
 func (cc *CanonicalConfig) onConfigReloaded() {
 	cc.logger.Debug("Notifying consumers about configuration reload")
 
@@ -257,35 +982,477 @@ func (cc *CanonicalConfig) onConfigReloaded() {
 
 // GetSliderMappingRaw returns the raw slider mapping for API use
 func (cc *CanonicalConfig) GetSliderMappingRaw() map[int][]string {
-	result := make(map[int][]string)
+	cc.pendingWriteLock.Lock()
+	defer cc.pendingWriteLock.Unlock()
 
-	cc.SliderMapping.iterate(func(sliderIdx int, targets []string) {
-		targetsCopy := make([]string, len(targets))
-		copy(targetsCopy, targets)
-		result[sliderIdx] = targetsCopy
-	})
+	// edits that haven't been loaded back yet are the ones the next edit should build on
+	if pending, ok := cc.pendingEdits[configKeySliderMapping].(map[int][]string); ok {
+		return sliderMapFromRaw(pending).raw()
+	}
 
-	return result
+	return cc.sliderMapping().raw()
+}
+
+// sliderMapping returns the slider mapping as it was last loaded. it's safe to use while a reload replaces it
+func (cc *CanonicalConfig) sliderMapping() *sliderMap {
+	cc.sliderMappingLock.RLock()
+	defer cc.sliderMappingLock.RUnlock()
+
+	return cc.SliderMapping
+}
+
+// SliderInverted reports whether the given slider's direction is flipped, taking its own settings into account
+func (cc *CanonicalConfig) SliderInverted(sliderIdx int) bool {
+	if settings, ok := cc.SliderSettings[sliderIdx]; ok && settings.Invert != nil {
+		return *settings.Invert
+	}
+
+	return cc.InvertSliders
+}
+
+// SliderNoiseThreshold returns the smallest value change that counts as the given slider actually moving
+func (cc *CanonicalConfig) SliderNoiseThreshold(sliderIdx int) float64 {
+	if settings, ok := cc.SliderSettings[sliderIdx]; ok && settings.NoiseThreshold != nil {
+		return *settings.NoiseThreshold
+	}
+
+	return cc.noiseGate()
+}
+
+// noiseGate returns the smallest value change that counts as a change at all, unless a slider has its own
+func (cc *CanonicalConfig) noiseGate() float64 {
+	if cc.NoiseThreshold > 0 {
+		return cc.NoiseThreshold
+	}
+
+	return util.NoiseReductionThreshold(cc.NoiseReductionLevel)
+}
+
+// SliderVolumeCurve returns the curve used to translate the given slider's position to a volume
+func (cc *CanonicalConfig) SliderVolumeCurve(sliderIdx int) VolumeCurve {
+	if settings, ok := cc.SliderSettings[sliderIdx]; ok && settings.VolumeCurve != nil {
+		return *settings.VolumeCurve
+	}
+
+	return cc.VolumeCurve
+}
+
+// DefaultVolumeCurve returns the curve used by every slider that doesn't have one of its own
+func (cc *CanonicalConfig) DefaultVolumeCurve() VolumeCurve {
+	return cc.VolumeCurve
+}
+
+// SessionRefreshPeriod returns how often sessions are refreshed on their own
+func (cc *CanonicalConfig) SessionRefreshPeriod() time.Duration {
+	return cc.SessionRefreshInterval
+}
+
+// ExternalVolumePolicy returns what's done about volumes changed from outside of deej
+func (cc *CanonicalConfig) ExternalVolumePolicy() string {
+	return cc.ExternalVolume.Policy
+}
+
+// SliderSmoothing returns how the given slider's movements are smoothed. sliders aren't smoothed unless they ask for it
+func (cc *CanonicalConfig) SliderSmoothing(sliderIdx int) Smoothing {
+	if settings, ok := cc.SliderSettings[sliderIdx]; ok && settings.Smoothing != nil {
+		return *settings.Smoothing
+	}
+
+	return Smoothing{Type: smoothingNone}
+}
+
+// SliderCalibration returns the raw range the given slider covers. uncalibrated sliders cover the board's full range
+func (cc *CanonicalConfig) SliderCalibration(sliderIdx int) Calibration {
+	if settings, ok := cc.SliderSettings[sliderIdx]; ok && settings.Calibration != nil {
+		return *settings.Calibration
+	}
+
+	return defaultCalibration
+}
+
+// SliderDeadZone returns how much of either end of the given slider's travel snaps to that end, 0 (none) by default
+func (cc *CanonicalConfig) SliderDeadZone(sliderIdx int) float64 {
+	if settings, ok := cc.SliderSettings[sliderIdx]; ok && settings.DeadZone != nil {
+		return *settings.DeadZone
+	}
+
+	return 0
 }
 
-// WriteSliderMapping updates the slider_mapping section of config.yaml
+// SliderLabels returns the label of every slider that has one
+func (cc *CanonicalConfig) SliderLabels() map[int]string {
+	labels := make(map[int]string)
+
+	for sliderIdx, settings := range cc.SliderSettings {
+		if settings.Label != nil {
+			labels[sliderIdx] = *settings.Label
+		}
+	}
+
+	return labels
+}
+
+// GetSliderSettingsRaw returns a copy of the per-slider settings for API use
+func (cc *CanonicalConfig) GetSliderSettingsRaw() map[int]SliderSettings {
+	cc.pendingWriteLock.Lock()
+	defer cc.pendingWriteLock.Unlock()
+
+	// same as with the slider mapping, an edit that wasn't loaded back yet is what the next one builds on
+	if pending, ok := cc.pendingEdits[configKeySliderSettings].(map[int]SliderSettings); ok {
+		return copySliderSettings(pending)
+	}
+
+	return copySliderSettings(cc.SliderSettings)
+}
+
+// GetButtonMappingRaw returns the raw button mapping for API use
+func (cc *CanonicalConfig) GetButtonMappingRaw() map[int][]string {
+	return cc.ButtonMapping.raw()
+}
+
+// WriteSliderMapping updates the slider_mapping section of config.yaml. an edit right behind another one is
+// written together with it once no other edits have come in for a moment, see saveEdit
 func (cc *CanonicalConfig) WriteSliderMapping(mapping map[int][]string) error {
+	cc.mappingEditLock.Lock()
+	defer cc.mappingEditLock.Unlock()
+
+	return cc.writeSliderMapping(mapping)
+}
+
+// UpdateSliderMapping changes the current slider mapping and writes it, without any other edit getting in
+// between. update gets a copy to change, and nothing is written if it returns an error. it returns the mapping
+// from before and after the edit
+func (cc *CanonicalConfig) UpdateSliderMapping(
+	update func(mapping map[int][]string) error,
+) (map[int][]string, map[int][]string, error) {
+	cc.mappingEditLock.Lock()
+	defer cc.mappingEditLock.Unlock()
+
+	oldMapping := cc.GetSliderMappingRaw()
+	newMapping := cc.GetSliderMappingRaw()
+
+	if err := update(newMapping); err != nil {
+		return nil, nil, err
+	}
+
+	if err := cc.writeSliderMapping(newMapping); err != nil {
+		return nil, nil, err
+	}
+
+	return oldMapping, newMapping, nil
+}
+
+// UpdateSlider sets a single slider's apps, and returns the ones it had before along with the resulting mapping
+func (cc *CanonicalConfig) UpdateSlider(sliderID int, apps []string) ([]string, map[int][]string, error) {
+	oldMapping, newMapping, err := cc.UpdateSliderMapping(func(mapping map[int][]string) error {
+		mapping[sliderID] = apps
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return oldMapping[sliderID], newMapping, nil
+}
+
+// RemoveSlider unmaps a single slider, and returns the apps it had. errSliderNotMapped means there was nothing to remove
+func (cc *CanonicalConfig) RemoveSlider(sliderID int) ([]string, error) {
+	oldMapping, _, err := cc.UpdateSliderMapping(func(mapping map[int][]string) error {
+		if _, ok := mapping[sliderID]; !ok {
+			return errSliderNotMapped
+		}
+
+		delete(mapping, sliderID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return oldMapping[sliderID], nil
+}
+
+// writeSliderMapping expects the caller to hold mappingEditLock. only an edit that was saved can be undone
+func (cc *CanonicalConfig) writeSliderMapping(mapping map[int][]string) error {
+	previous := cc.GetSliderMappingRaw()
+
+	if err := cc.saveSliderMapping(mapping); err != nil {
+		return err
+	}
+
+	cc.mappingHistory.record(previous, mapping)
+
+	return nil
+}
+
+// UndoSliderMapping restores the slider mapping from before the last edit, and returns it
+func (cc *CanonicalConfig) UndoSliderMapping() (map[int][]string, error) {
+	cc.mappingEditLock.Lock()
+	defer cc.mappingEditLock.Unlock()
+
+	cc.logger.Debug("Undoing slider mapping edit")
+
+	return cc.mappingHistory.stepBack(cc.GetSliderMappingRaw(), cc.saveSliderMapping)
+}
+
+// RedoSliderMapping restores the slider mapping from before the last undo, and returns it
+func (cc *CanonicalConfig) RedoSliderMapping() (map[int][]string, error) {
+	cc.mappingEditLock.Lock()
+	defer cc.mappingEditLock.Unlock()
+
+	cc.logger.Debug("Redoing slider mapping edit")
+
+	return cc.mappingHistory.stepForward(cc.GetSliderMappingRaw(), cc.saveSliderMapping)
+}
+
+// saveSliderMapping writes a slider mapping to config.yaml, and keeps it around until it's loaded back
+func (cc *CanonicalConfig) saveSliderMapping(mapping map[int][]string) error {
 	cc.logger.Debug("Writing slider mapping to config file")
 
-	// Read existing config
-	data, err := os.ReadFile(userConfigFilepath)
+	values := map[string]interface{}{
+		configKeySliderMapping: mappingToConfigValue(mapping),
+	}
+
+	// edits made while a profile is active belong to that profile, so switching away and back keeps them
+	if cc.ActiveProfile != "" {
+		profiles := make(map[string]*sliderMap, len(cc.Profiles))
+		for name, profileMapping := range cc.Profiles {
+			profiles[name] = profileMapping
+		}
+
+		profiles[cc.ActiveProfile] = sliderMapFromRaw(mapping)
+		values[configKeyProfiles] = profilesToConfigValue(profiles)
+	}
+
+	if err := cc.saveEdit(values, configKeySliderMapping, sliderMapFromRaw(mapping).raw()); err != nil {
+		return err
+	}
+
+	cc.logger.Debug("Wrote updated slider mapping to config file")
+	return nil
+}
+
+// WriteButtonMapping updates the button_mapping section of config.yaml
+func (cc *CanonicalConfig) WriteButtonMapping(mapping map[int][]string) error {
+	cc.logger.Debug("Writing button mapping to config file")
+
+	if err := cc.writeUserConfigValue(configKeyButtonMapping, mappingToConfigValue(mapping)); err != nil {
+		return err
+	}
+
+	cc.logger.Debug("Wrote updated button mapping to config file")
+	return nil
+}
+
+// GetMIDIMappingRaw returns a copy of the MIDI mapping for API use
+func (cc *CanonicalConfig) GetMIDIMappingRaw() map[int]int {
+	cc.pendingWriteLock.Lock()
+	defer cc.pendingWriteLock.Unlock()
+
+	mapping := cc.MIDIMapping
+	if pending, ok := cc.pendingEdits[configKeyMIDIMapping].(map[int]int); ok {
+		mapping = pending
+	}
+
+	result := make(map[int]int, len(mapping))
+
+	for sliderIdx, controller := range mapping {
+		result[sliderIdx] = controller
+	}
+
+	return result
+}
+
+// UpdateMIDIMapping changes the current MIDI mapping and writes it, without any other edit getting in
+// between (see UpdateSliderMapping). update gets a copy to change, and nothing is written if it returns an error
+func (cc *CanonicalConfig) UpdateMIDIMapping(update func(mapping map[int]int) error) error {
+	cc.sectionEditLock.Lock()
+	defer cc.sectionEditLock.Unlock()
+
+	mapping := cc.GetMIDIMappingRaw()
+	if err := update(mapping); err != nil {
+		return err
+	}
+
+	cc.logger.Debug("Writing MIDI mapping to config file")
+
+	edited := make(map[int]int, len(mapping))
+	for sliderIdx, controller := range mapping {
+		edited[sliderIdx] = controller
+	}
+
+	values := map[string]interface{}{configKeyMIDIMapping: midiMappingToConfigValue(mapping)}
+	if err := cc.saveEdit(values, configKeyMIDIMapping, edited); err != nil {
+		return err
+	}
+
+	cc.logger.Debug("Wrote updated MIDI mapping to config file")
+	return nil
+}
+
+// GetBalancesRaw returns a copy of the balances for API use
+func (cc *CanonicalConfig) GetBalancesRaw() map[string]float32 {
+	cc.pendingWriteLock.Lock()
+	defer cc.pendingWriteLock.Unlock()
+
+	balances := cc.Balances
+	if pending, ok := cc.pendingEdits[configKeyBalance].(map[string]float32); ok {
+		balances = pending
+	}
+
+	result := make(map[string]float32, len(balances))
+
+	for target, balance := range balances {
+		result[target] = balance
+	}
+
+	return result
+}
+
+// UpdateBalances changes the current balances and writes them, without any other edit getting in
+// between (see UpdateSliderMapping). update gets a copy to change, and nothing is written if it returns an error
+func (cc *CanonicalConfig) UpdateBalances(update func(balances map[string]float32) error) error {
+	cc.sectionEditLock.Lock()
+	defer cc.sectionEditLock.Unlock()
+
+	balances := cc.GetBalancesRaw()
+	if err := update(balances); err != nil {
+		return err
+	}
+
+	cc.logger.Debug("Writing balances to config file")
+
+	edited := make(map[string]float32, len(balances))
+	for target, balance := range balances {
+		edited[target] = balance
+	}
+
+	values := map[string]interface{}{configKeyBalance: balancesToConfigValue(balances)}
+	if err := cc.saveEdit(values, configKeyBalance, edited); err != nil {
+		return err
+	}
+
+	cc.logger.Debug("Wrote updated balances to config file")
+	return nil
+}
+
+// GetUIPreferencesRaw returns a copy of the UI preferences for API use
+func (cc *CanonicalConfig) GetUIPreferencesRaw() map[string]interface{} {
+	cc.pendingWriteLock.Lock()
+	defer cc.pendingWriteLock.Unlock()
+
+	preferences := cc.UIPreferences
+	if pending, ok := cc.pendingEdits[configKeyUIPreferences].(map[string]interface{}); ok {
+		preferences = pending
+	}
+
+	result := make(map[string]interface{}, len(preferences))
+
+	for name, value := range preferences {
+		result[name] = value
+	}
+
+	return result
+}
+
+// UpdateUIPreferences changes the current UI preferences and writes them, without any other edit getting in
+// between (see UpdateSliderMapping). update gets a copy, and returns the preferences to write in its place -
+// nothing is written if it returns an error. it returns the preferences as they were written
+func (cc *CanonicalConfig) UpdateUIPreferences(
+	update func(preferences map[string]interface{}) (map[string]interface{}, error),
+) (map[string]interface{}, error) {
+	cc.sectionEditLock.Lock()
+	defer cc.sectionEditLock.Unlock()
+
+	preferences, err := update(cc.GetUIPreferencesRaw())
 	if err != nil {
-		return fmt.Errorf("read config: %w", err)
+		return nil, err
 	}
 
-	// Parse into generic map to preserve other fields
-	var config map[string]interface{}
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return fmt.Errorf("parse config: %w", err)
+	cc.logger.Debug("Writing UI preferences to config file")
+
+	edited := make(map[string]interface{}, len(preferences))
+	for name, value := range preferences {
+		edited[name] = value
+	}
+
+	values := map[string]interface{}{configKeyUIPreferences: uiPreferencesToConfigValue(preferences)}
+	if err := cc.saveEdit(values, configKeyUIPreferences, edited); err != nil {
+		return nil, err
+	}
+
+	cc.logger.Debug("Wrote updated UI preferences to config file")
+	return preferences, nil
+}
+
+// GetWebhooksRaw returns a copy of the webhook rules for API use
+func (cc *CanonicalConfig) GetWebhooksRaw() []WebhookRule {
+	return append([]WebhookRule{}, cc.Webhooks...)
+}
+
+// WriteWebhooks replaces the webhooks section of config.yaml
+func (cc *CanonicalConfig) WriteWebhooks(rules []WebhookRule) error {
+	cc.logger.Debug("Writing webhooks to config file")
+
+	if err := cc.writeUserConfigValue(configKeyWebhooks, webhookRulesToConfigValue(rules)); err != nil {
+		return err
+	}
+
+	cc.logger.Debug("Wrote updated webhooks to config file")
+	return nil
+}
+
+// GetSliderActionsRaw returns a copy of the slider action settings for API use
+func (cc *CanonicalConfig) GetSliderActionsRaw() SliderActionSettings {
+	settings := cc.SliderActions
+	settings.Actions = append([]SliderAction{}, cc.SliderActions.Actions...)
+
+	return settings
+}
+
+// WriteSliderActions replaces the actions under slider_actions in config.yaml. the rest of the section is written
+// as it was loaded, since it can only be changed by editing the file
+func (cc *CanonicalConfig) WriteSliderActions(actions []SliderAction) error {
+	cc.logger.Debug("Writing slider actions to config file")
+
+	settings := cc.SliderActions
+	settings.Actions = actions
+
+	if err := cc.writeUserConfigValue(configKeyActionsSection, sliderActionsToConfigValue(settings)); err != nil {
+		return err
+	}
+
+	cc.logger.Debug("Wrote updated slider actions to config file")
+	return nil
+}
+
+// GetVolumeLimitsRaw returns a copy of the volume limits for API use
+func (cc *CanonicalConfig) GetVolumeLimitsRaw() []VolumeLimit {
+	return append([]VolumeLimit{}, cc.VolumeLimits...)
+}
+
+// WriteVolumeLimits replaces the volume_limits section of config.yaml
+func (cc *CanonicalConfig) WriteVolumeLimits(limits []VolumeLimit) error {
+	cc.logger.Debug("Writing volume limits to config file")
+
+	if err := cc.writeUserConfigValue(configKeyVolumeLimits, volumeLimitsToConfigValue(limits)); err != nil {
+		return err
 	}
 
+	cc.logger.Debug("Wrote updated volume limits to config file")
+	return nil
+}
+
+// GetSliderGroupsRaw returns a copy of the slider groups for API use
+func (cc *CanonicalConfig) GetSliderGroupsRaw() []SliderGroup {
+	return append([]SliderGroup{}, cc.SliderGroups...)
+}
+
+// mappingToConfigValue returns an index -> targets mapping in the shape it's written to config.yaml in
+func mappingToConfigValue(mapping map[int][]string) map[string]interface{} {
+
 	// Convert int keys to string keys for YAML
-	sliderMapping := make(map[string]interface{})
+	configValue := make(map[string]interface{})
 
 	// Get sorted keys for consistent output
 	keys := make([]int, 0, len(mapping))
@@ -298,15 +1465,240 @@ func (cc *CanonicalConfig) WriteSliderMapping(mapping map[int][]string) error {
 		v := mapping[k]
 		strKey := fmt.Sprintf("%d", k)
 		if len(v) == 0 {
-			sliderMapping[strKey] = nil
+			configValue[strKey] = nil
 		} else if len(v) == 1 {
-			sliderMapping[strKey] = v[0]
+			configValue[strKey] = v[0]
 		} else {
-			sliderMapping[strKey] = v
+			configValue[strKey] = v
 		}
 	}
 
-	config[configKeySliderMapping] = sliderMapping
+	return configValue
+}
+
+// UpdateSliderSettings changes the current slider settings and writes them, without any other edit getting in
+// between (see UpdateSliderMapping). update gets a copy to change, and nothing is written if it returns an error
+func (cc *CanonicalConfig) UpdateSliderSettings(update func(settings map[int]SliderSettings) error) error {
+	cc.sectionEditLock.Lock()
+	defer cc.sectionEditLock.Unlock()
+
+	settings := cc.GetSliderSettingsRaw()
+	if err := update(settings); err != nil {
+		return err
+	}
+
+	cc.logger.Debug("Writing slider settings to config file")
+
+	values := map[string]interface{}{configKeySliderSettings: sliderSettingsToConfigValue(settings)}
+	if err := cc.saveEdit(values, configKeySliderSettings, copySliderSettings(settings)); err != nil {
+		return err
+	}
+
+	cc.logger.Debug("Wrote updated slider settings to config file")
+	return nil
+}
+
+// GetProfilesRaw returns a copy of every profile's slider mapping for API use
+func (cc *CanonicalConfig) GetProfilesRaw() map[string]map[int][]string {
+	result := make(map[string]map[int][]string, len(cc.Profiles))
+
+	for name, mapping := range cc.Profiles {
+		result[name] = mapping.raw()
+	}
+
+	return result
+}
+
+// ActivateProfile makes the named profile's slider mapping the live one and remembers the choice in config.yaml.
+// the config is reloaded right away rather than waiting on the file watcher, so volumes follow immediately
+func (cc *CanonicalConfig) ActivateProfile(name string) error {
+	name = strings.ToLower(name)
+
+	profile, ok := cc.Profiles[name]
+	if !ok {
+		return errProfileNotFound
+	}
+
+	cc.logger.Debugw("Activating profile", "name", name)
+
+	// the profile's mapping replaces whatever the current one is, so it can't go in between another edit
+	cc.mappingEditLock.Lock()
+	defer cc.mappingEditLock.Unlock()
+
+	if err := cc.writeUserConfigValues(map[string]interface{}{
+		configKeySliderMapping: mappingToConfigValue(profile.raw()),
+		configKeyActiveProfile: name,
+	}); err != nil {
+		return err
+	}
+
+	if _, err := cc.Reload(); err != nil {
+		return fmt.Errorf("reload config: %w", err)
+	}
+
+	// the history was made of another profile's edits
+	cc.mappingHistory.clear()
+
+	cc.logger.Infow("Activated profile", "name", name)
+	return nil
+}
+
+// writeUserConfigValue replaces a single top-level key in config.yaml, leaving the rest of it as-is
+func (cc *CanonicalConfig) writeUserConfigValue(key string, value interface{}) error {
+	return cc.writeUserConfigValues(map[string]interface{}{key: value})
+}
+
+// writeUserConfigValues replaces several top-level keys in config.yaml in one write, leaving the rest of it as-is.
+// queued edits go out with it, unless it replaces the same keys
+func (cc *CanonicalConfig) writeUserConfigValues(values map[string]interface{}) error {
+	cc.pendingWriteLock.Lock()
+	defer cc.pendingWriteLock.Unlock()
+
+	if err := cc.writeQueuedEdits(values); err != nil {
+		return err
+	}
+
+	// whatever's written this way is always reloaded right after, so any edits it replaces don't need to be kept
+	for key := range values {
+		delete(cc.pendingEdits, key)
+	}
+
+	return nil
+}
+
+// saveEdit writes an edit to config.yaml, or queues it if it comes right behind another one (see
+// FlushPendingWrites), and keeps the edited section around under its key until it's loaded back. edited must be
+// a copy nothing else changes. only a write that's made right away can fail
+func (cc *CanonicalConfig) saveEdit(values map[string]interface{}, key string, edited interface{}) error {
+	cc.pendingWriteLock.Lock()
+	defer cc.pendingWriteLock.Unlock()
+
+	now := time.Now()
+
+	if cc.queuedWriteFailed || (cc.pendingWriteValues == nil && now.Sub(cc.lastEditAt) >= editWriteDelay) {
+		if err := cc.writeQueuedEdits(values); err != nil {
+			return err
+		}
+	} else {
+		cc.queueEdit(values)
+	}
+
+	cc.lastEditAt = now
+
+	if cc.pendingEdits == nil {
+		cc.pendingEdits = make(map[string]interface{})
+	}
+
+	cc.pendingEdits[key] = edited
+
+	return nil
+}
+
+// queueEdit adds an edit to the ones waiting to be written, and pushes their write back until edits have been
+// quiet for a moment. the pending write lock must be held
+func (cc *CanonicalConfig) queueEdit(values map[string]interface{}) {
+	cc.logger.Debug("Queueing edit for a moment before writing it")
+
+	if cc.pendingWriteValues == nil {
+		cc.pendingWriteValues = make(map[string]interface{})
+	}
+
+	for key, value := range values {
+		cc.pendingWriteValues[key] = value
+	}
+
+	if cc.pendingWriteTimer != nil {
+		cc.pendingWriteTimer.Stop()
+	}
+
+	cc.pendingWriteTimer = time.AfterFunc(editWriteDelay, func() {
+		if err := cc.FlushPendingWrites(); err != nil {
+			cc.logger.Warnw("Failed to write queued edits to config file", "error", err)
+		}
+	})
+}
+
+// FlushPendingWrites writes any queued edits to config.yaml right away, and the edit after it is written right
+// away too. if that fails they stay queued, and go out with the next write
+func (cc *CanonicalConfig) FlushPendingWrites() error {
+	cc.pendingWriteLock.Lock()
+	defer cc.pendingWriteLock.Unlock()
+
+	cc.lastEditAt = time.Time{}
+
+	if cc.pendingWriteValues == nil {
+		return nil
+	}
+
+	return cc.writeQueuedEdits(nil)
+}
+
+// writeQueuedEdits writes values to config.yaml together with the queued edits, which values override. the
+// pending write lock must be held
+func (cc *CanonicalConfig) writeQueuedEdits(values map[string]interface{}) error {
+	merged := make(map[string]interface{}, len(cc.pendingWriteValues)+len(values))
+	for key, value := range cc.pendingWriteValues {
+		merged[key] = value
+	}
+
+	for key, value := range values {
+		merged[key] = value
+	}
+
+	if err := cc.writeUserConfigFile(merged); err != nil {
+		cc.queuedWriteFailed = cc.pendingWriteValues != nil
+		return err
+	}
+
+	if cc.pendingWriteTimer != nil {
+		cc.pendingWriteTimer.Stop()
+		cc.pendingWriteTimer = nil
+	}
+
+	cc.pendingWriteValues = nil
+	cc.queuedWriteFailed = false
+
+	// a load that started before this didn't see any of it
+	cc.editWrites++
+
+	return nil
+}
+
+// dropLoadedEdit forgets a section's pending edit once the load in progress has picked it up, which it only has
+// if it isn't still queued and nothing was written since the load started. the pending write lock must be held
+func (cc *CanonicalConfig) dropLoadedEdit(key string) {
+	if _, queued := cc.pendingWriteValues[key]; !queued && cc.editWrites == cc.loadingEditWrites {
+		delete(cc.pendingEdits, key)
+	}
+}
+
+func (cc *CanonicalConfig) writeUserConfigFile(values map[string]interface{}) error {
+
+	// Read existing config
+	data, err := os.ReadFile(userConfigFilepath)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+
+	// Parse into generic map to preserve other fields
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("parse config: %w", err)
+	}
+
+	// an empty config file parses into a nil map
+	if config == nil {
+		config = make(map[string]interface{})
+	}
+
+	// a nil value removes the key altogether, so it goes back to its default
+	for key, value := range values {
+		if value == nil {
+			delete(config, key)
+		} else {
+			config[key] = value
+		}
+	}
 
 	// Marshal back to YAML
 	output, err := yaml.Marshal(config)
@@ -324,6 +1716,5 @@ func (cc *CanonicalConfig) WriteSliderMapping(mapping map[int][]string) error {
 		return fmt.Errorf("rename config: %w", err)
 	}
 
-	cc.logger.Debug("Wrote updated slider mapping to config file")
 	return nil
 }
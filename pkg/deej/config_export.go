@@ -0,0 +1,335 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// bump this whenever ConfigExport changes in a way older imports can't be read as-is,
+// and teach migrateConfigExport how to bring the previous version forward
+const configExportVersion = 1
+
+// ConfigExport is the portable form of everything that can be tuned about deej's behavior.
+// connection and web server settings are left out on purpose: they're specific to the machine
+// deej runs on, and the API token is a secret that has no business being shared
+type ConfigExport struct {
+	Version int `json:"version" schema:"required,minimum=1,maximum=configExportVersion"`
+
+	SliderMapping  map[string][]string       `json:"sliderMapping" schema:"keys=index,values.items.minLength=1,values.items.examples=targets"`
+	ButtonMapping  map[string][]string       `json:"buttonMapping" schema:"keys=index,values.items.minLength=1,values.items.examples=targets"`
+	SliderSettings map[string]SliderSettings `json:"sliderSettings" schema:"keys=index"`
+
+	InvertSliders  bool    `json:"invertSliders"`
+	NoiseReduction string  `json:"noiseReduction" schema:"enum=noiseReductionLevels"`
+	NoiseThreshold float64 `json:"noiseThreshold,omitempty" schema:"minimum=0,exclusiveMaximum=1"`
+
+	VolumeCurve VolumeCurve `json:"volumeCurve"`
+	MuteAtZero  bool        `json:"muteAtZero"`
+
+	CurrentWindowFallback string `json:"currentWindowFallback" schema:"enum=currentWindowFallbacks"`
+
+	Profiles      map[string]map[string][]string `json:"profiles" schema:"values.keys=index,values.values.items.minLength=1,values.values.items.examples=targets"`
+	ActiveProfile string                         `json:"activeProfile,omitempty"`
+}
+
+// errInvalidConfigImport wraps every reason an import gets rejected, so callers can tell them apart from I/O errors
+var errInvalidConfigImport = errors.New("invalid config import")
+
+// Export returns the config as it's currently in effect
+func (cc *CanonicalConfig) Export() ConfigExport {
+	sliderSettings := make(map[string]SliderSettings)
+	for sliderIdx, settings := range cc.GetSliderSettingsRaw() {
+		sliderSettings[strconv.Itoa(sliderIdx)] = settings
+	}
+
+	profiles := make(map[string]map[string][]string)
+	for name, mapping := range cc.GetProfilesRaw() {
+		profiles[name] = mappingToExport(mapping)
+	}
+
+	return ConfigExport{
+		Version: configExportVersion,
+
+		SliderMapping:  mappingToExport(cc.GetSliderMappingRaw()),
+		ButtonMapping:  mappingToExport(cc.GetButtonMappingRaw()),
+		SliderSettings: sliderSettings,
+
+		InvertSliders:  cc.InvertSliders,
+		NoiseReduction: cc.NoiseReductionLevel,
+		NoiseThreshold: cc.NoiseThreshold,
+
+		VolumeCurve: cc.VolumeCurve,
+		MuteAtZero:  cc.MuteAtZero,
+
+		CurrentWindowFallback: cc.CurrentWindowFallback,
+
+		Profiles:      profiles,
+		ActiveProfile: cc.ActiveProfile,
+	}
+}
+
+// Import validates an exported config in full and only then writes it to config.yaml, replacing every setting
+// it covers. the config is reloaded right away, like it is when activating a profile
+func (cc *CanonicalConfig) Import(export ConfigExport) error {
+	if err := migrateConfigExport(&export); err != nil {
+		return fmt.Errorf("%w: %s", errInvalidConfigImport, err)
+	}
+
+	values, err := export.toConfigValues()
+	if err != nil {
+		return fmt.Errorf("%w: %s", errInvalidConfigImport, err)
+	}
+
+	cc.logger.Debugw("Importing config", "version", export.Version)
+
+	// same as activating a profile, the imported mapping replaces whatever the current one is
+	cc.mappingEditLock.Lock()
+	defer cc.mappingEditLock.Unlock()
+
+	if err := cc.writeUserConfigValues(values); err != nil {
+		return err
+	}
+
+	if _, err := cc.Reload(); err != nil {
+		return fmt.Errorf("reload config: %w", err)
+	}
+
+	// undoing only the slider mapping back to before an import would leave a mix of both configs
+	cc.mappingHistory.clear()
+
+	cc.logger.Info("Imported config successfully")
+	return nil
+}
+
+// migrateConfigExport brings an export from an older version up to the current one
+func migrateConfigExport(export *ConfigExport) error {
+	switch {
+	case export.Version <= 0:
+		return errors.New("version: missing or invalid export version")
+	case export.Version > configExportVersion:
+		return fmt.Errorf("version: export version %d is newer than this version of deej supports (%d)",
+			export.Version, configExportVersion)
+	}
+
+	// nothing to migrate yet - version 1 is the only one there is
+	return nil
+}
+
+// toConfigValues validates every part of the export, returning the top-level config.yaml keys it translates to
+func (export ConfigExport) toConfigValues() (map[string]interface{}, error) {
+	sliderMapping, err := mappingFromExport("sliderMapping", "slider", export.SliderMapping)
+	if err != nil {
+		return nil, err
+	}
+
+	buttonMapping, err := mappingFromExport("buttonMapping", "button", export.ButtonMapping)
+	if err != nil {
+		return nil, err
+	}
+
+	sliderSettings := make(map[int]SliderSettings)
+	for key, settings := range export.SliderSettings {
+		sliderIdx, err := parseExportIndex("sliderSettings", "slider", key)
+		if err != nil {
+			return nil, err
+		}
+
+		if settings.NoiseThreshold != nil && !validNoiseThreshold(*settings.NoiseThreshold) {
+			return nil, fmt.Errorf("sliderSettings.%s.noiseThreshold: must be at least 0 and less than 1, got %v",
+				key, *settings.NoiseThreshold)
+		}
+
+		if settings.VolumeCurve != nil {
+			if err := settings.VolumeCurve.validate(); err != nil {
+				return nil, fmt.Errorf("sliderSettings.%s.volumeCurve: %w", key, err)
+			}
+		}
+
+		if settings.Smoothing != nil {
+			if err := settings.Smoothing.validate(); err != nil {
+				return nil, fmt.Errorf("sliderSettings.%s.smoothing: %w", key, err)
+			}
+		}
+
+		if settings.Calibration != nil {
+			if err := settings.Calibration.validate(); err != nil {
+				return nil, fmt.Errorf("sliderSettings.%s.calibration: %w", key, err)
+			}
+		}
+
+		if settings.Label != nil {
+			label, err := normalizeSliderLabel(*settings.Label)
+			if err != nil {
+				return nil, fmt.Errorf("sliderSettings.%s.label: %w", key, err)
+			}
+
+			settings.Label = &label
+			if label == "" {
+				settings.Label = nil
+			}
+		}
+
+		if settings.DeadZone != nil && !validDeadZone(*settings.DeadZone) {
+			return nil, fmt.Errorf("sliderSettings.%s.deadZone: must be at least 0 and less than 0.5, got %v",
+				key, *settings.DeadZone)
+		}
+
+		if settings.FixedValue != nil && !validFixedValue(*settings.FixedValue) {
+			return nil, fmt.Errorf("sliderSettings.%s.fixedValue: must be between 0 and 1, got %v",
+				key, *settings.FixedValue)
+		}
+
+		sliderSettings[sliderIdx] = settings
+	}
+
+	noiseReduction := strings.ToLower(export.NoiseReduction)
+	switch noiseReduction {
+	case "", "low", "default", "high":
+	default:
+		return nil, fmt.Errorf("noiseReduction: must be low, default or high, got %q", export.NoiseReduction)
+	}
+
+	if !validNoiseThreshold(export.NoiseThreshold) {
+		return nil, fmt.Errorf("noiseThreshold: must be at least 0 and less than 1, got %v", export.NoiseThreshold)
+	}
+
+	volumeCurve := export.VolumeCurve
+	if volumeCurve.Type == "" {
+		volumeCurve.Type = defaultVolumeCurve
+	}
+
+	if err := volumeCurve.validate(); err != nil {
+		return nil, fmt.Errorf("volumeCurve: %w", err)
+	}
+
+	currentWindowFallback := strings.ToLower(export.CurrentWindowFallback)
+	switch currentWindowFallback {
+	case "":
+		currentWindowFallback = currentWindowFallbackNone
+	case currentWindowFallbackNone, currentWindowFallbackLast:
+	default:
+		return nil, fmt.Errorf("currentWindowFallback: must be %s or %s, got %q",
+			currentWindowFallbackNone, currentWindowFallbackLast, export.CurrentWindowFallback)
+	}
+
+	profiles := make(map[string]*sliderMap)
+	for name, mapping := range export.Profiles {
+		lowerName := strings.ToLower(name)
+
+		if !validProfileName(lowerName) {
+			return nil, fmt.Errorf("profiles: invalid profile name %q (names can't be empty or contain dots)", name)
+		}
+
+		if _, ok := profiles[lowerName]; ok {
+			return nil, fmt.Errorf("profiles: profile %q appears more than once (names are case-insensitive)", name)
+		}
+
+		profileMapping, err := mappingFromExport(fmt.Sprintf("profiles.%s", name), "slider", mapping)
+		if err != nil {
+			return nil, err
+		}
+
+		profiles[lowerName] = sliderMapFromRaw(profileMapping)
+	}
+
+	var activeProfile interface{}
+	if export.ActiveProfile != "" {
+		lowerName := strings.ToLower(export.ActiveProfile)
+
+		if _, ok := profiles[lowerName]; !ok {
+			return nil, fmt.Errorf("activeProfile: no profile named %q", export.ActiveProfile)
+		}
+
+		activeProfile = lowerName
+	}
+
+	var noiseThreshold, volumeCurveExponent interface{}
+	if export.NoiseThreshold != 0 {
+		noiseThreshold = export.NoiseThreshold
+	}
+
+	if volumeCurve.Type == volumeCurvePower {
+		volumeCurveExponent = volumeCurve.Exponent
+	}
+
+	var noiseReductionValue interface{}
+	if noiseReduction != "" {
+		noiseReductionValue = noiseReduction
+	}
+
+	return map[string]interface{}{
+		configKeySliderMapping:       mappingToConfigValue(sliderMapping),
+		configKeyButtonMapping:       mappingToConfigValue(buttonMapping),
+		configKeySliderSettings:      sliderSettingsToConfigValue(sliderSettings),
+		configKeyInvertSliders:       export.InvertSliders,
+		configKeyNoiseReductionLevel: noiseReductionValue,
+		configKeyNoiseThreshold:      noiseThreshold,
+		configKeyVolumeCurve:         volumeCurve.Type,
+		configKeyVolumeCurveExponent: volumeCurveExponent,
+		configKeyMuteAtZero:          export.MuteAtZero,
+		configKeyCurrentFallback:     currentWindowFallback,
+		configKeyProfiles:            profilesToConfigValue(profiles),
+		configKeyActiveProfile:       activeProfile,
+	}, nil
+}
+
+func mappingToExport(mapping map[int][]string) map[string][]string {
+	result := make(map[string][]string, len(mapping))
+
+	for idx, targets := range mapping {
+		result[strconv.Itoa(idx)] = targets
+	}
+
+	return result
+}
+
+// mappingFromExport validates an exported slider or button mapping. field and kind only make errors precise
+func mappingFromExport(field string, kind string, mapping map[string][]string) (map[int][]string, error) {
+	result := make(map[int][]string, len(mapping))
+
+	// go through keys in order, so the same bad import always reports the same error
+	keys := make([]string, 0, len(mapping))
+	for key := range mapping {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		idx, err := parseExportIndex(field, kind, key)
+		if err != nil {
+			return nil, err
+		}
+
+		for targetIdx, target := range mapping[key] {
+			if strings.TrimSpace(target) == "" {
+				return nil, fmt.Errorf("%s.%s[%d]: app names can't be empty", field, key, targetIdx)
+			}
+
+			if isTargetPattern(target) {
+				if _, err := compileTargetPattern(target); err != nil {
+					return nil, fmt.Errorf("%s.%s[%d]: invalid pattern %q: %w", field, key, targetIdx, target, err)
+				}
+			}
+		}
+
+		if _, ok := result[idx]; ok {
+			return nil, fmt.Errorf("%s.%s: %s %d appears more than once", field, key, kind, idx)
+		}
+
+		result[idx] = mapping[key]
+	}
+
+	return result, nil
+}
+
+func parseExportIndex(field string, kind string, key string) (int, error) {
+	idx, err := strconv.Atoi(key)
+	if err != nil || idx < 0 {
+		return 0, fmt.Errorf("%s: invalid %s ID %q: %s IDs must be non-negative integers", field, kind, key, kind)
+	}
+
+	return idx, nil
+}
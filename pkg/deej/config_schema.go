@@ -0,0 +1,321 @@
+package deej
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GET /api/schema describes the request bodies the UI sends to change the mapping and tunables, as a JSON Schema,
+// so it can build its forms from it and validate before sending. it's generated from the Go types those requests
+// are decoded into: json tags give the properties and Go types give their types, while schema tags add what the Go
+// type can't say, i.e. ranges and allowed values. a schema tag is a comma-separated list of JSON Schema keywords
+// with their values (or just the keyword, for required and readOnly). a value can also name one of the constants
+// deej validates against (see schemaNamedValues), so the schema follows them. keywords prefixed by "values." go to
+// a map's values and ones prefixed by "items." go to a slice's items, rather than the field itself.
+//
+// the schema only covers what a value can look like on its own: the rest (i.e. a calibration's range being wide
+// enough) is still only checked by the handlers
+
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// slider and button indices, as map keys
+const schemaIndexPattern = "^[0-9]+$"
+
+// the named values schema tags can refer to, on top of the special targets that are added per request
+var schemaNamedValues = map[string]interface{}{
+	"configExportVersion":  configExportVersion,
+	"frameMaxSliderValue":  frameMaxSliderValue,
+	"maxDeadZone":          maxDeadZone,
+	"maxSliderLabelLength": maxSliderLabelLength,
+
+	"volumeCurves":           []string{volumeCurveLinear, volumeCurveLogarithmic, volumeCurvePower},
+	"smoothings":             []string{smoothingNone, smoothingSlew, smoothingEMA},
+	"noiseReductionLevels":   []string{"low", "default", "high"},
+	"currentWindowFallbacks": []string{currentWindowFallbackNone, currentWindowFallbackLast},
+}
+
+// the requests the schema describes, by method and path
+var schemaRequests = map[string]interface{}{
+	"PUT /api/sliders":               slidersResponse{},
+	"PUT /api/sliders/{id}":          updateSliderRequest{},
+	"PUT /api/sliders/{id}/settings": updateSliderSettingsRequest{},
+	"PUT /api/sliders/{id}/label":    sliderLabelRequest{},
+	"PUT /api/sliders/{id}/fixed":    sliderFixedValueRequest{},
+	"PUT /api/buttons":               buttonsResponse{},
+	"PUT /api/volume-limits":         volumeLimitsMessage{},
+	"PUT /api/balance":               balanceRequest{},
+	"POST /api/config/import":        ConfigExport{},
+}
+
+// schemaBuilder collects the exported types it comes across as definitions, so each of them is only described once
+type schemaBuilder struct {
+	named map[string]interface{}
+	defs  map[string]interface{}
+}
+
+// buildConfigSchema returns the schema for every request in schemaRequests, given the special targets there are
+// right now. they're only suggested as examples, since any app can be a target
+func buildConfigSchema(targets []string) (map[string]interface{}, error) {
+	b := &schemaBuilder{
+		named: map[string]interface{}{"targets": targets},
+		defs:  make(map[string]interface{}),
+	}
+
+	for name, value := range schemaNamedValues {
+		b.named[name] = value
+	}
+
+	requests := make(map[string]interface{}, len(schemaRequests))
+	for request, body := range schemaRequests {
+		schema, err := b.schemaOf(reflect.TypeOf(body), "")
+		if err != nil {
+			return nil, fmt.Errorf("describe %s: %w", request, err)
+		}
+
+		requests[request] = schema
+	}
+
+	return map[string]interface{}{
+		"$schema":     schemaDialect,
+		"title":       "deej",
+		"description": "The request bodies that change deej's slider mapping and settings, under requests",
+		"requests":    requests,
+		"$defs":       b.defs,
+	}, nil
+}
+
+// schemaOf describes a Go type, along with the keywords from the schema tag of the field it's for
+func (b *schemaBuilder) schemaOf(t reflect.Type, tag string) (map[string]interface{}, error) {
+
+	// fields that can be left out are pointers, which isn't something JSON has
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	own, values, items := splitSchemaTag(tag)
+	schema := make(map[string]interface{})
+
+	switch t.Kind() {
+	case reflect.Bool:
+		schema["type"] = "boolean"
+
+	case reflect.String:
+		schema["type"] = "string"
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		schema["type"] = "integer"
+
+	case reflect.Float32, reflect.Float64:
+		schema["type"] = "number"
+
+	case reflect.Slice:
+		itemSchema, err := b.schemaOf(t.Elem(), items)
+		if err != nil {
+			return nil, err
+		}
+
+		schema["type"] = "array"
+		schema["items"] = itemSchema
+
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map keys must be strings, got %s", t.Key())
+		}
+
+		valueSchema, err := b.schemaOf(t.Elem(), values)
+		if err != nil {
+			return nil, err
+		}
+
+		schema["type"] = "object"
+		schema["additionalProperties"] = valueSchema
+
+	case reflect.Struct:
+		if name := t.Name(); name != "" && unicode.IsUpper([]rune(name)[0]) {
+			if err := b.define(t); err != nil {
+				return nil, err
+			}
+
+			schema["$ref"] = "#/$defs/" + name
+		} else if err := b.describeStruct(t, schema); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+
+	if err := b.applyKeywords(schema, own); err != nil {
+		return nil, fmt.Errorf("%s: %w", t, err)
+	}
+
+	return schema, nil
+}
+
+// define adds an exported struct type to the definitions, unless it's already there
+func (b *schemaBuilder) define(t reflect.Type) error {
+	if _, ok := b.defs[t.Name()]; ok {
+		return nil
+	}
+
+	schema := make(map[string]interface{})
+
+	// before describing its fields, so a type that contains itself refers back to this definition
+	b.defs[t.Name()] = schema
+
+	return b.describeStruct(t, schema)
+}
+
+// describeStruct describes a struct's fields as the properties of an object
+func (b *schemaBuilder) describeStruct(t reflect.Type, schema map[string]interface{}) error {
+	properties := make(map[string]interface{})
+	required := []string{}
+
+	for fieldIdx := 0; fieldIdx < t.NumField(); fieldIdx++ {
+		field := t.Field(fieldIdx)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		tag := field.Tag.Get("schema")
+
+		fieldSchema, err := b.schemaOf(field.Type, tag)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", t.Name(), field.Name, err)
+		}
+
+		properties[name] = fieldSchema
+
+		for _, keyword := range strings.Split(tag, ",") {
+			if keyword == "required" {
+				required = append(required, name)
+			}
+		}
+	}
+
+	sort.Strings(required)
+
+	schema["type"] = "object"
+	schema["properties"] = properties
+
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return nil
+}
+
+// applyKeywords adds a field's own keywords to its schema
+func (b *schemaBuilder) applyKeywords(schema map[string]interface{}, keywords string) error {
+	if keywords == "" {
+		return nil
+	}
+
+	for _, keyword := range strings.Split(keywords, ",") {
+		parts := strings.SplitN(keyword, "=", 2)
+		name := parts[0]
+
+		value := ""
+		if len(parts) == 2 {
+			value = parts[1]
+		}
+
+		switch name {
+
+		// handled by the struct the field is in
+		case "required":
+
+		case "readOnly":
+			schema[name] = true
+
+		case "keys":
+			if value != "index" {
+				return fmt.Errorf("unknown keys %q", value)
+			}
+
+			schema["propertyNames"] = map[string]interface{}{"pattern": schemaIndexPattern}
+
+		case "enum", "examples":
+			list, ok := b.named[value]
+			if !ok || reflect.TypeOf(list).Kind() != reflect.Slice {
+				return fmt.Errorf("%s: no list named %q", name, value)
+			}
+
+			schema[name] = list
+
+		case "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum", "minLength", "maxLength":
+			number, err := b.number(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+
+			schema[name] = number
+
+		default:
+			return fmt.Errorf("unknown schema keyword %q", name)
+		}
+	}
+
+	return nil
+}
+
+// number parses a keyword's value, or looks it up by name
+func (b *schemaBuilder) number(value string) (interface{}, error) {
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		return number, nil
+	}
+
+	named, ok := b.named[value]
+	if !ok {
+		return nil, fmt.Errorf("%q is neither a number nor a named value", value)
+	}
+
+	switch reflect.TypeOf(named).Kind() {
+	case reflect.Int, reflect.Float64:
+		return named, nil
+	}
+
+	return nil, fmt.Errorf("%q isn't a number", value)
+}
+
+// splitSchemaTag separates a field's own keywords from those meant for its map values or slice items
+func splitSchemaTag(tag string) (own string, values string, items string) {
+	join := func(joined *string, keyword string) {
+		if *joined != "" {
+			*joined += ","
+		}
+
+		*joined += keyword
+	}
+
+	if tag == "" {
+		return
+	}
+
+	for _, keyword := range strings.Split(tag, ",") {
+		switch {
+		case strings.HasPrefix(keyword, "values."):
+			join(&values, strings.TrimPrefix(keyword, "values."))
+		case strings.HasPrefix(keyword, "items."):
+			join(&items, strings.TrimPrefix(keyword, "items."))
+		default:
+			join(&own, keyword)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,137 @@
+package deej
+
+import (
+	"strconv"
+	"time"
+)
+
+// any open UI goes stale once config.yaml is edited by hand (or by another tab), with nothing to tell it so. every
+// successful config load - on startup, after an API write, or after the file watcher noticed an edit - bumps the
+// config's version by one. GET /api/status reports it as configVersion along with configLoadedAt, and websocket
+// clients get a configReloaded message for every load, with how the slider mapping changed since the one before
+// it. the version only ever goes up while deej runs, and starts over with it
+
+// how many loads a subscriber can fall behind on before it misses some
+const configLoadConsumerBufferSize = 4
+
+// SliderMappingChange is a slider whose targets changed between two loads
+type SliderMappingChange struct {
+	Before []string `json:"before"`
+	After  []string `json:"after"`
+}
+
+// SliderMappingDiff is how the slider mapping changed between two loads, keyed by slider index
+type SliderMappingDiff struct {
+	Added   map[string][]string            `json:"added"`
+	Removed map[string][]string            `json:"removed"`
+	Changed map[string]SliderMappingChange `json:"changed"`
+}
+
+// ConfigLoadEvent is a successful config load
+type ConfigLoadEvent struct {
+	Version  uint64            `json:"version"`
+	LoadedAt time.Time         `json:"loadedAt"`
+	Sliders  SliderMappingDiff `json:"sliders"`
+}
+
+// Version returns the version of the config that's in effect, and when it was loaded
+func (cc *CanonicalConfig) Version() (uint64, time.Time) {
+	cc.versionLock.Lock()
+	defer cc.versionLock.Unlock()
+
+	return cc.version, cc.loadedAt
+}
+
+// SubscribeToLoads allows external components to receive every successful load, along with how it changed the
+// slider mapping. unlike SubscribeToChanges, it never holds up a load: a subscriber that falls behind misses loads
+func (cc *CanonicalConfig) SubscribeToLoads() chan ConfigLoadEvent {
+	cc.versionLock.Lock()
+	defer cc.versionLock.Unlock()
+
+	c := make(chan ConfigLoadEvent, configLoadConsumerBufferSize)
+	cc.loadConsumers = append(cc.loadConsumers, c)
+
+	return c
+}
+
+// recordLoad bumps the version after a successful load, and lets subscribers know how the slider mapping changed
+// from the one before it
+func (cc *CanonicalConfig) recordLoad(previous map[int][]string) {
+	cc.versionLock.Lock()
+	defer cc.versionLock.Unlock()
+
+	cc.version++
+	cc.loadedAt = time.Now()
+
+	event := ConfigLoadEvent{
+		Version:  cc.version,
+		LoadedAt: cc.loadedAt,
+		Sliders:  diffSliderMappings(previous, cc.loadedSliderMapping()),
+	}
+
+	cc.logger.Debugw("Config version bumped",
+		"version", event.Version,
+		"addedSliders", len(event.Sliders.Added),
+		"removedSliders", len(event.Sliders.Removed),
+		"changedSliders", len(event.Sliders.Changed))
+
+	for _, consumer := range cc.loadConsumers {
+		select {
+		case consumer <- event:
+		default:
+		}
+	}
+}
+
+// loadedSliderMapping returns the slider mapping from the last load, empty before the first one
+func (cc *CanonicalConfig) loadedSliderMapping() map[int][]string {
+	if mapping := cc.sliderMapping(); mapping != nil {
+		return mapping.raw()
+	}
+
+	return map[int][]string{}
+}
+
+// diffSliderMappings compares two slider mappings. a slider with the same targets in a different order counts as
+// changed, since targets are shown (and exported) in the order they're mapped in
+func diffSliderMappings(before map[int][]string, after map[int][]string) SliderMappingDiff {
+	diff := SliderMappingDiff{
+		Added:   make(map[string][]string),
+		Removed: make(map[string][]string),
+		Changed: make(map[string]SliderMappingChange),
+	}
+
+	for sliderIdx, targets := range after {
+		previous, ok := before[sliderIdx]
+		if !ok {
+			diff.Added[strconv.Itoa(sliderIdx)] = targets
+			continue
+		}
+
+		if !sameTargets(previous, targets) {
+			diff.Changed[strconv.Itoa(sliderIdx)] = SliderMappingChange{Before: previous, After: targets}
+		}
+	}
+
+	for sliderIdx, targets := range before {
+		if _, ok := after[sliderIdx]; !ok {
+			diff.Removed[strconv.Itoa(sliderIdx)] = targets
+		}
+	}
+
+	return diff
+}
+
+func sameTargets(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,340 @@
+package deej
+
+/*
+#cgo LDFLAGS: -framework CoreAudio -framework CoreFoundation
+
+#include <CoreAudio/CoreAudio.h>
+#include <CoreFoundation/CoreFoundation.h>
+#include <libproc.h>
+
+// these aren't in the headers of SDKs older than macOS 14.2, so they're spelled out here. older systems
+// don't answer them either, which only means there are no app sessions to list
+#define deejPropertyProcessObjectList 0x70727323 // 'prs#'
+#define deejProcessPropertyPID 0x70706964 // 'ppid'
+#define deejProcessPropertyIsRunningOutput 0x7069726f // 'piro'
+
+// AudioToolbox's virtual main volume. unlike the main element's volume, this one is there for devices that
+// only have per-channel volumes (most of them)
+#define deejPropertyVirtualMainVolume 0x766d7663 // 'vmvc'
+
+// and its balance, from 0 (fully left) to 1 (fully right)
+#define deejPropertyVirtualMainBalance 0x766d626c // 'vmbl'
+
+static AudioObjectPropertyAddress deejAddress(AudioObjectPropertySelector selector, int input) {
+	AudioObjectPropertyAddress address = {
+		selector,
+		input ? kAudioObjectPropertyScopeInput : kAudioObjectPropertyScopeOutput,
+		0, // kAudioObjectPropertyElementMain, which older SDKs call by another name
+	};
+
+	return address;
+}
+
+static AudioObjectPropertyAddress deejGlobalAddress(AudioObjectPropertySelector selector) {
+	AudioObjectPropertyAddress address = {
+		selector,
+		kAudioObjectPropertyScopeGlobal,
+		0, // kAudioObjectPropertyElementMain, which older SDKs call by another name
+	};
+
+	return address;
+}
+
+static OSStatus deejGetDefaultDevice(int input, AudioObjectID *device) {
+	AudioObjectPropertyAddress address = deejGlobalAddress(
+		input ? kAudioHardwarePropertyDefaultInputDevice : kAudioHardwarePropertyDefaultOutputDevice);
+	UInt32 size = sizeof(AudioObjectID);
+
+	return AudioObjectGetPropertyData(kAudioObjectSystemObject, &address, 0, NULL, &size, device);
+}
+
+static OSStatus deejGetObjectCount(AudioObjectID object, AudioObjectPropertySelector selector, UInt32 *count) {
+	AudioObjectPropertyAddress address = deejGlobalAddress(selector);
+
+	if (!AudioObjectHasProperty(object, &address)) {
+		*count = 0;
+		return noErr;
+	}
+
+	UInt32 size = 0;
+	OSStatus status = AudioObjectGetPropertyDataSize(object, &address, 0, NULL, &size);
+	*count = size / sizeof(AudioObjectID);
+
+	return status;
+}
+
+static OSStatus deejGetObjects(AudioObjectID object, AudioObjectPropertySelector selector, AudioObjectID *objects, UInt32 *count) {
+	AudioObjectPropertyAddress address = deejGlobalAddress(selector);
+	UInt32 size = *count * sizeof(AudioObjectID);
+	OSStatus status = AudioObjectGetPropertyData(object, &address, 0, NULL, &size, objects);
+	*count = size / sizeof(AudioObjectID);
+
+	return status;
+}
+
+static int deejHasStreams(AudioObjectID device, int input) {
+	AudioObjectPropertyAddress address = deejAddress(kAudioDevicePropertyStreams, input);
+	UInt32 size = 0;
+
+	if (AudioObjectGetPropertyDataSize(device, &address, 0, NULL, &size) != noErr) {
+		return 0;
+	}
+
+	return size > 0;
+}
+
+static OSStatus deejGetName(AudioObjectID object, char *buffer, UInt32 bufferSize) {
+	AudioObjectPropertyAddress address = deejGlobalAddress(kAudioObjectPropertyName);
+	CFStringRef name = NULL;
+	UInt32 size = sizeof(CFStringRef);
+
+	OSStatus status = AudioObjectGetPropertyData(object, &address, 0, NULL, &size, &name);
+	if (status != noErr) {
+		return status;
+	}
+
+	if (name == NULL) {
+		return kAudioHardwareUnspecifiedError;
+	}
+
+	Boolean ok = CFStringGetCString(name, buffer, bufferSize, kCFStringEncodingUTF8);
+	CFRelease(name);
+
+	return ok ? noErr : kAudioHardwareUnspecifiedError;
+}
+
+static int deejIsSettable(AudioObjectID device, AudioObjectPropertySelector selector, int input) {
+	AudioObjectPropertyAddress address = deejAddress(selector, input);
+	Boolean settable = false;
+
+	if (!AudioObjectHasProperty(device, &address)) {
+		return 0;
+	}
+
+	if (AudioObjectIsPropertySettable(device, &address, &settable) != noErr) {
+		return 0;
+	}
+
+	return settable;
+}
+
+static OSStatus deejGetFloat(AudioObjectID device, AudioObjectPropertySelector selector, int input, Float32 *value) {
+	AudioObjectPropertyAddress address = deejAddress(selector, input);
+	UInt32 size = sizeof(Float32);
+
+	return AudioObjectGetPropertyData(device, &address, 0, NULL, &size, value);
+}
+
+static OSStatus deejSetFloat(AudioObjectID device, AudioObjectPropertySelector selector, int input, Float32 value) {
+	AudioObjectPropertyAddress address = deejAddress(selector, input);
+
+	return AudioObjectSetPropertyData(device, &address, 0, NULL, sizeof(Float32), &value);
+}
+
+static OSStatus deejGetUInt32(AudioObjectID object, AudioObjectPropertyAddress address, UInt32 *value) {
+	UInt32 size = sizeof(UInt32);
+
+	return AudioObjectGetPropertyData(object, &address, 0, NULL, &size, value);
+}
+
+static OSStatus deejSetUInt32(AudioObjectID device, AudioObjectPropertySelector selector, int input, UInt32 value) {
+	AudioObjectPropertyAddress address = deejAddress(selector, input);
+
+	return AudioObjectSetPropertyData(device, &address, 0, NULL, sizeof(UInt32), &value);
+}
+
+static OSStatus deejGetProcessPID(AudioObjectID process, pid_t *pid) {
+	AudioObjectPropertyAddress address = deejGlobalAddress(deejProcessPropertyPID);
+	UInt32 size = sizeof(pid_t);
+
+	return AudioObjectGetPropertyData(process, &address, 0, NULL, &size, pid);
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// audioObjectID is CoreAudio's handle for devices, processes and the system itself
+type audioObjectID uint32
+
+// the longest device name we bother reading in full
+const coreAudioMaxNameLength = 256
+
+var errCoreAudio = errors.New("CoreAudio call failed")
+
+func coreAudioError(what string, status C.OSStatus) error {
+	return fmt.Errorf("%w: %s (OSStatus %d)", errCoreAudio, what, int32(status))
+}
+
+func boolToCInt(b bool) C.int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// coreAudioDefaultDevice returns the current default output (or input) device
+func coreAudioDefaultDevice(input bool) (audioObjectID, error) {
+	var device C.AudioObjectID
+
+	if status := C.deejGetDefaultDevice(boolToCInt(input), &device); status != 0 {
+		return 0, coreAudioError("get default device", status)
+	}
+
+	if device == C.kAudioObjectUnknown {
+		return 0, fmt.Errorf("%w: no default device", errCoreAudio)
+	}
+
+	return audioObjectID(device), nil
+}
+
+// coreAudioObjects returns the objects listed by one of the system object's properties
+func coreAudioObjects(selector C.AudioObjectPropertySelector) ([]audioObjectID, error) {
+	var count C.UInt32
+
+	if status := C.deejGetObjectCount(C.kAudioObjectSystemObject, selector, &count); status != 0 {
+		return nil, coreAudioError("get object count", status)
+	}
+
+	if count == 0 {
+		return nil, nil
+	}
+
+	objects := make([]C.AudioObjectID, count)
+
+	if status := C.deejGetObjects(C.kAudioObjectSystemObject, selector, &objects[0], &count); status != 0 {
+		return nil, coreAudioError("get objects", status)
+	}
+
+	ids := make([]audioObjectID, count)
+	for idx := range ids {
+		ids[idx] = audioObjectID(objects[idx])
+	}
+
+	return ids, nil
+}
+
+// coreAudioDevices returns every audio device, input and output alike
+func coreAudioDevices() ([]audioObjectID, error) {
+	return coreAudioObjects(C.kAudioHardwarePropertyDevices)
+}
+
+// coreAudioProcesses returns every process that has an audio connection. it's empty before macOS 14.2
+func coreAudioProcesses() ([]audioObjectID, error) {
+	return coreAudioObjects(C.deejPropertyProcessObjectList)
+}
+
+// coreAudioDeviceHasScope returns whether a device plays (or, for input, records) anything at all
+func coreAudioDeviceHasScope(device audioObjectID, input bool) bool {
+	return C.deejHasStreams(C.AudioObjectID(device), boolToCInt(input)) != 0
+}
+
+// coreAudioName returns a device's name, as the sound settings show it
+func coreAudioName(object audioObjectID) (string, error) {
+	buffer := make([]C.char, coreAudioMaxNameLength)
+
+	if status := C.deejGetName(C.AudioObjectID(object), &buffer[0], C.UInt32(len(buffer))); status != 0 {
+		return "", coreAudioError("get name", status)
+	}
+
+	return C.GoString(&buffer[0]), nil
+}
+
+// coreAudioVolumeSettable returns whether a device's volume can be changed. some (i.e. HDMI and most
+// USB interfaces) leave their volume to whatever they're plugged into
+func coreAudioVolumeSettable(device audioObjectID, input bool) bool {
+	return C.deejIsSettable(C.AudioObjectID(device), C.deejPropertyVirtualMainVolume, boolToCInt(input)) != 0
+}
+
+func coreAudioVolume(device audioObjectID, input bool) (float32, error) {
+	var volume C.Float32
+
+	if status := C.deejGetFloat(C.AudioObjectID(device), C.deejPropertyVirtualMainVolume, boolToCInt(input), &volume); status != 0 {
+		return 0, coreAudioError("get volume", status)
+	}
+
+	return float32(volume), nil
+}
+
+func coreAudioSetVolume(device audioObjectID, input bool, volume float32) error {
+	if status := C.deejSetFloat(C.AudioObjectID(device), C.deejPropertyVirtualMainVolume, boolToCInt(input), C.Float32(volume)); status != 0 {
+		return coreAudioError("set volume", status)
+	}
+
+	return nil
+}
+
+// coreAudioBalanceSettable returns whether a device's balance can be changed, which takes a stereo device
+func coreAudioBalanceSettable(device audioObjectID, input bool) bool {
+	return C.deejIsSettable(C.AudioObjectID(device), C.deejPropertyVirtualMainBalance, boolToCInt(input)) != 0
+}
+
+// coreAudioSetBalance takes a balance from -1 (fully left) to 1 (fully right), like the rest of deej does
+func coreAudioSetBalance(device audioObjectID, input bool, balance float32) error {
+	if status := C.deejSetFloat(C.AudioObjectID(device), C.deejPropertyVirtualMainBalance, boolToCInt(input), C.Float32((balance+1)/2)); status != 0 {
+		return coreAudioError("set balance", status)
+	}
+
+	return nil
+}
+
+func coreAudioMute(device audioObjectID, input bool) (bool, error) {
+	var mute C.UInt32
+
+	address := C.deejAddress(C.kAudioDevicePropertyMute, boolToCInt(input))
+
+	if status := C.deejGetUInt32(C.AudioObjectID(device), address, &mute); status != 0 {
+		return false, coreAudioError("get mute state", status)
+	}
+
+	return mute != 0, nil
+}
+
+func coreAudioSetMute(device audioObjectID, input bool, mute bool) error {
+	var value C.UInt32
+	if mute {
+		value = 1
+	}
+
+	if status := C.deejSetUInt32(C.AudioObjectID(device), C.kAudioDevicePropertyMute, boolToCInt(input), value); status != 0 {
+		return coreAudioError("set mute state", status)
+	}
+
+	return nil
+}
+
+// coreAudioProcessPlaying returns whether an audio process is currently playing anything
+func coreAudioProcessPlaying(process audioObjectID) bool {
+	var running C.UInt32
+
+	address := C.deejGlobalAddress(C.deejProcessPropertyIsRunningOutput)
+
+	if status := C.deejGetUInt32(C.AudioObjectID(process), address, &running); status != 0 {
+		return false
+	}
+
+	return running != 0
+}
+
+// coreAudioProcessName returns the name of an audio process's executable, i.e. "Spotify"
+func coreAudioProcessName(process audioObjectID) (string, error) {
+	var pid C.pid_t
+
+	if status := C.deejGetProcessPID(C.AudioObjectID(process), &pid); status != 0 {
+		return "", coreAudioError("get process PID", status)
+	}
+
+	buffer := make([]byte, C.PROC_PIDPATHINFO_MAXSIZE)
+
+	length := C.proc_name(C.int(pid), unsafe.Pointer(&buffer[0]), C.uint32_t(len(buffer)))
+	if length <= 0 {
+		return "", fmt.Errorf("get name of process %d: %w", pid, errNoSuchProcess)
+	}
+
+	return string(buffer[:length]), nil
+}
@@ -0,0 +1,31 @@
+package deej
+
+// a slider that's hard to push all the way can be given a dead zone: the bottom and top dead_zone of its travel
+// snap to 0.0 and 1.0, so muting or maxing out doesn't take pinning it against the end. unlike calibration, the
+// rest of the travel isn't stretched - it's applied right after calibration, and before the slider is inverted
+// or put through its volume curve
+
+// a dead zone of half the travel or more would leave no room between the ends
+const maxDeadZone = 0.5
+
+func validDeadZone(deadZone float64) bool {
+	return deadZone >= 0 && deadZone < maxDeadZone
+}
+
+// applyDeadZone snaps a calibrated value within deadZone of either end to that end
+func applyDeadZone(value float32, deadZone float64) float32 {
+	if deadZone <= 0 {
+		return value
+	}
+
+	// in float32, so an edge like 0.05 counts as within the dead zone whichever way it was rounded
+	if value <= float32(deadZone) {
+		return 0
+	}
+
+	if value >= float32(1-deadZone) {
+		return 1
+	}
+
+	return value
+}
@@ -0,0 +1,100 @@
+package deej
+
+import (
+	"testing"
+)
+
+func TestApplyDeadZone(t *testing.T) {
+	tests := []struct {
+		value    float32
+		deadZone float64
+		want     float32
+	}{
+		{0.03, 0, 0.03},
+		{0.97, 0, 0.97},
+		{0, 0.05, 0},
+		{0.03, 0.05, 0},
+		{0.05, 0.05, 0},
+		{0.06, 0.05, 0.06},
+		{0.5, 0.05, 0.5},
+		{0.94, 0.05, 0.94},
+		{0.95, 0.05, 1},
+		{0.97, 0.05, 1},
+		{1, 0.05, 1},
+		{0.45, 0.49, 0},
+		{0.52, 0.49, 1},
+	}
+
+	for _, test := range tests {
+		if got := applyDeadZone(test.value, test.deadZone); got != test.want {
+			t.Errorf("applyDeadZone(%v, %v) = %v, want %v", test.value, test.deadZone, got, test.want)
+		}
+	}
+}
+
+// raw values are calibrated first, and only then snapped, so the dead zone is a part of the calibrated travel
+func TestCalibratedDeadZone(t *testing.T) {
+	calibration := Calibration{Min: 100, Max: 900}
+
+	tests := []struct {
+		raw      int
+		deadZone float64
+		want     float32
+	}{
+		{0, 0, 0},
+		{100, 0, 0},
+		{140, 0, 0.05},
+		{500, 0, 0.5},
+		{860, 0, 0.95},
+		{900, 0, 1},
+		{1023, 0, 1},
+		{140, 0.05, 0},
+		{148, 0.05, 0.06},
+		{852, 0.05, 0.94},
+		{860, 0.05, 1},
+	}
+
+	for _, test := range tests {
+		got := applyDeadZone(calibration.apply(test.raw), test.deadZone)
+		if !floatsClose(got, test.want) {
+			t.Errorf("raw %d with a dead zone of %v = %v, want %v", test.raw, test.deadZone, got, test.want)
+		}
+	}
+}
+
+func TestSliderDeadZone(t *testing.T) {
+	settings, warned := sliderSettingsFromYAML(t, `
+slider_settings:
+  0:
+    dead_zone: 0.05
+  1:
+    dead_zone: 0.5
+  2:
+    dead_zone: -0.1
+  3:
+    dead_zone: 0
+`)
+
+	if len(warned) != 2 {
+		t.Errorf("warned about %v, want sliders 1 and 2", warned)
+	}
+
+	cc := &CanonicalConfig{SliderSettings: settings}
+
+	tests := []struct {
+		sliderIdx int
+		want      float64
+	}{
+		{0, 0.05},
+		{1, 0},
+		{2, 0},
+		{3, 0},
+		{4, 0},
+	}
+
+	for _, test := range tests {
+		if got := cc.SliderDeadZone(test.sliderIdx); got != test.want {
+			t.Errorf("SliderDeadZone(%d) = %v, want %v", test.sliderIdx, got, test.want)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -16,24 +17,59 @@ const (
 
 	// when this is set to anything, deej won't use a tray icon
 	envNoTray = "DEEJ_NO_TRAY_ICON"
+
+	// when this is set to anything, deej controls made-up audio sessions instead of real ones
+	envMockAudio = "DEEJ_MOCK_AUDIO"
+
+	// a comma-separated list of made-up sessions whose volume, mute and balance changes hang for a while
+	envMockAudioHang = "DEEJ_MOCK_AUDIO_HANG"
 )
 
 // Deej is the main entity managing access to all sub-components
 type Deej struct {
-	logger   *zap.SugaredLogger
-	notifier Notifier
-	config   *CanonicalConfig
-	serial   *SerialIO
-	sessions *sessionMap
-	server   *Server
+	logger    *zap.SugaredLogger
+	notifier  Notifier
+	config    *CanonicalConfig
+	serial    *SerialIO
+	sessions  *sessionMap
+	server    *Server
+	mqtt      *MQTTPublisher
+	osc       *OSCListener
+	midi      *MIDIInput
+	webhooks  *WebhookNotifier
+	actions   *SliderActionRunner
+	positions *SliderPositionStore
 
 	stopChannel chan bool
 	version     string
 	verbose     bool
+	startedAt   time.Time
 }
 
 // NewDeej creates a Deej instance
 func NewDeej(logger *zap.SugaredLogger, verbose bool) (*Deej, error) {
+	finderLogger := logger.Named("deej")
+
+	var sessionFinder SessionFinder
+
+	if _, mockAudioSet := os.LookupEnv(envMockAudio); mockAudioSet {
+		finderLogger.Infow("Using mock audio sessions", "reason", "envvar set")
+		sessionFinder = newMockSessionFinder(finderLogger)
+	} else {
+		var err error
+
+		sessionFinder, err = newSessionFinder(finderLogger)
+		if err != nil {
+			finderLogger.Errorw("Failed to create SessionFinder", "error", err)
+			return nil, fmt.Errorf("create new SessionFinder: %w", err)
+		}
+	}
+
+	return newDeej(logger, verbose, sessionFinder)
+}
+
+// newDeej creates a Deej instance on top of the given audio backend, which is how tests get the mock one
+func newDeej(logger *zap.SugaredLogger, verbose bool, sessionFinder SessionFinder) (*Deej, error) {
 	logger = logger.Named("deej")
 
 	notifier, err := NewToastNotifier(logger)
@@ -54,6 +90,7 @@ func NewDeej(logger *zap.SugaredLogger, verbose bool) (*Deej, error) {
 		config:      config,
 		stopChannel: make(chan bool),
 		verbose:     verbose,
+		startedAt:   time.Now(),
 	}
 
 	serial, err := NewSerialIO(d, logger)
@@ -64,12 +101,6 @@ func NewDeej(logger *zap.SugaredLogger, verbose bool) (*Deej, error) {
 
 	d.serial = serial
 
-	sessionFinder, err := newSessionFinder(logger)
-	if err != nil {
-		logger.Errorw("Failed to create SessionFinder", "error", err)
-		return nil, fmt.Errorf("create new SessionFinder: %w", err)
-	}
-
 	sessions, err := newSessionMap(d, logger, sessionFinder)
 	if err != nil {
 		logger.Errorw("Failed to create sessionMap", "error", err)
@@ -81,6 +112,13 @@ func NewDeej(logger *zap.SugaredLogger, verbose bool) (*Deej, error) {
 	// Create web server
 	d.server = NewServer(logger, d)
 
+	d.mqtt = NewMQTTPublisher(d, logger)
+	d.osc = NewOSCListener(d, logger)
+	d.midi = NewMIDIInput(d, logger)
+	d.webhooks = NewWebhookNotifier(d, logger)
+	d.actions = NewSliderActionRunner(d, logger)
+	d.positions = NewSliderPositionStore(d, logger)
+
 	logger.Debug("Created deej instance")
 
 	return d, nil
@@ -90,6 +128,11 @@ func NewDeej(logger *zap.SugaredLogger, verbose bool) (*Deej, error) {
 func (d *Deej) Initialize() error {
 	d.logger.Debug("Initializing")
 
+	// on a fresh install, start out with the default config rather than none
+	if err := d.config.CreateDefaultIfMissing(); err != nil {
+		d.logger.Warnw("Failed to create default config", "error", err)
+	}
+
 	// load the config for the first time
 	if err := d.config.Load(); err != nil {
 		d.logger.Errorw("Failed to load config during initialization", "error", err)
@@ -129,6 +172,11 @@ func (d *Deej) Verbose() bool {
 	return d.verbose
 }
 
+// Uptime returns how long deej has been running
+func (d *Deej) Uptime() time.Duration {
+	return time.Since(d.startedAt)
+}
+
 // GetServerURL returns the URL of the web configuration server
 func (d *Deej) GetServerURL() string {
 	if d.server != nil {
@@ -155,9 +203,24 @@ func (d *Deej) run() {
 		d.logger.Warnw("Failed to start web server", "error", err)
 	}
 
+	// connects in the background, if enabled
+	d.mqtt.Start()
+
+	if err := d.osc.Start(); err != nil {
+		d.logger.Warnw("Failed to start OSC listener", "error", err)
+	}
+
+	if err := d.midi.Start(); err != nil {
+		d.logger.Warnw("Failed to open MIDI input", "error", err)
+	}
+
 	// watch the config file for changes
 	go d.config.WatchConfigFileChanges()
 
+	// the saved slider values go first, so the board's first line overrides them
+	d.positions.Restore()
+	d.positions.Start()
+
 	// connect to the arduino for the first time
 	go func() {
 		if err := d.serial.Start(); err != nil {
@@ -173,15 +236,15 @@ func (d *Deej) run() {
 
 				d.signalStop()
 
-				// also notify if the COM port they gave isn't found, maybe their config is wrong
+				// also notify if the COM port they gave isn't found - the board might just not be plugged in yet,
+				// so deej keeps trying to reach it, but their config could also be wrong
 			} else if errors.Is(err, os.ErrNotExist) {
-				d.logger.Warnw("Provided COM port seems wrong, notifying user and closing",
+				d.logger.Warnw("Provided COM port doesn't exist (yet), notifying user and retrying in the background",
 					"comPort", d.config.ConnectionInfo.COMPort)
 
 				d.notifier.Notify(fmt.Sprintf("Can't connect to %s!", d.config.ConnectionInfo.COMPort),
-					"This serial port doesn't exist, check your configuration and make sure it's set correctly.")
-
-				d.signalStop()
+					"This serial port doesn't exist. deej will connect once the board is plugged in, "+
+						"otherwise check your configuration and make sure it's set correctly.")
 			}
 		}
 	}()
@@ -212,9 +275,23 @@ func (d *Deej) stop() error {
 		d.logger.Warnw("Failed to stop web server", "error", err)
 	}
 
+	// the server is down, so no more edits can come in - get the ones still waiting on disk
+	if err := d.config.FlushPendingWrites(); err != nil {
+		d.logger.Warnw("Failed to write pending config changes", "error", err)
+	}
+
 	d.config.StopWatchingConfigFile()
+	d.mqtt.Stop()
+	d.osc.Stop()
+	d.midi.Stop()
 	d.serial.Stop()
 
+	// the board is gone, so these are the values it left the sliders at
+	d.positions.Stop()
+
+	// nothing can move a slider anymore, so this is the final say on every session's volume
+	d.sessions.restoreVolumes(volumeRestoreTimeout)
+
 	// release the session map
 	if err := d.sessions.release(); err != nil {
 		d.logger.Errorw("Failed to release session map", "error", err)
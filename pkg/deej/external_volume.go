@@ -0,0 +1,260 @@
+package deej
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// deej only ever sets volumes, so it can't tell when an app's volume is changed from somewhere else (i.e. the
+// windows mixer) - and the next bit of slider jitter snaps it right back. with external_volume_changes.policy set
+// to log or respect, every session a slider has set is read back every interval. one that drifted from what deej
+// set by more than the noise gate is logged and listed by /api/status, and with respect, deej also leaves it at
+// its new volume until the slider moves by more than release_threshold from where it was
+
+const (
+	externalVolumePolicyIgnore  = "ignore"
+	externalVolumePolicyLog     = "log"
+	externalVolumePolicyRespect = "respect"
+
+	// in seconds. every check asks the OS for the volume of every session sliders have set
+	defaultExternalVolumeInterval = 2
+
+	// a slider has to move by 5% of its travel to take a session back
+	defaultExternalVolumeRelease = 0.05
+
+	minExternalVolumeInterval = 500 * time.Millisecond
+)
+
+// ExternalVolumeSettings decides what happens to volumes that are changed from outside of deej
+type ExternalVolumeSettings struct {
+	Policy string
+
+	// how often sessions are read back, unused with the ignore policy
+	Interval time.Duration
+
+	// only used by respect: how far a slider has to move before it takes a session back
+	ReleaseThreshold float64
+}
+
+// ExternalVolumeChange is a session key whose volume isn't what deej last set it to
+type ExternalVolumeChange struct {
+	Key        string
+	Set        float32
+	Observed   float32
+	DetectedAt time.Time
+
+	// whether deej is leaving it at the observed volume, which only happens with the respect policy
+	Respected bool
+
+	// the slider position the key was last set from, which the slider has to move away from to take it back
+	position float32
+}
+
+// ExternalVolumeChanges lists every session key whose volume was changed from outside of deej, as of the last check
+func (m *sessionMap) ExternalVolumeChanges() []ExternalVolumeChange {
+	m.externalLock.Lock()
+	defer m.externalLock.Unlock()
+
+	changes := make([]ExternalVolumeChange, 0, len(m.externalVolumes))
+	for _, change := range m.externalVolumes {
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+// checkExternalVolumes reads back the volume of every session key a slider set, and notes the ones that moved
+// since. must only be called from the slider move goroutine
+func (m *sessionMap) checkExternalVolumes() {
+	settings := m.deej.config.ExternalVolume
+
+	if settings.Policy == externalVolumePolicyIgnore {
+		m.externalLock.Lock()
+		m.externalVolumes = make(map[string]ExternalVolumeChange)
+		m.externalLock.Unlock()
+
+		return
+	}
+
+	gate := float32(m.deej.config.noiseGate())
+
+	for key, position := range m.appliedPositions {
+		observed, ok := m.readVolume(key)
+		if !ok {
+			m.forgetExternalVolume(key)
+			continue
+		}
+
+		m.externalLock.Lock()
+		change, tracked := m.externalVolumes[key]
+		m.externalLock.Unlock()
+
+		if tracked {
+			m.updateExternalVolume(change, observed, gate)
+			continue
+		}
+
+		state, ok := m.getState(key)
+		if !ok || float32(math.Abs(float64(observed-state.volume))) <= gate {
+			continue
+		}
+
+		change = ExternalVolumeChange{
+			Key:        key,
+			Set:        state.volume,
+			Observed:   observed,
+			DetectedAt: time.Now(),
+			Respected:  settings.Policy == externalVolumePolicyRespect,
+			position:   position,
+		}
+
+		m.logger.Infow("Session volume was changed outside of deej",
+			"key", key,
+			"set", state.volume,
+			"observed", observed,
+			"respected", change.Respected)
+
+		m.externalLock.Lock()
+		m.externalVolumes[key] = change
+		m.externalLock.Unlock()
+
+		// deej isn't about to put it back, so what it knows about the session should say so too
+		if change.Respected {
+			state.volume = observed
+			m.setState(key, state)
+		}
+	}
+}
+
+// updateExternalVolume follows a key that was already changed from outside of deej, forgetting about it once
+// it's back at the volume deej set
+func (m *sessionMap) updateExternalVolume(change ExternalVolumeChange, observed float32, gate float32) {
+	if float32(math.Abs(float64(observed-change.Set))) <= gate {
+		m.logger.Debugw("Session volume is back to what deej set", "key", change.Key)
+		m.forgetExternalVolume(change.Key)
+
+		if change.Respected {
+			if state, ok := m.getState(change.Key); ok {
+				state.volume = change.Set
+				m.setState(change.Key, state)
+			}
+		}
+
+		return
+	}
+
+	if float32(math.Abs(float64(observed-change.Observed))) <= gate {
+		return
+	}
+
+	m.logger.Infow("Session volume was changed outside of deej again", "key", change.Key, "observed", observed)
+
+	change.Observed = observed
+
+	m.externalLock.Lock()
+	m.externalVolumes[change.Key] = change
+	m.externalLock.Unlock()
+
+	if change.Respected {
+		if state, ok := m.getState(change.Key); ok {
+			state.volume = observed
+			m.setState(change.Key, state)
+		}
+	}
+}
+
+// readVolume asks the OS for a session key's volume, going by its first controllable session
+func (m *sessionMap) readVolume(key string) (float32, bool) {
+	sessions, ok := m.get(key)
+	if !ok {
+		return 0, false
+	}
+
+	for _, session := range sessions {
+		if session.Controllable() {
+			return m.backend.GetVolume(session), true
+		}
+	}
+
+	return 0, false
+}
+
+// externalVolumeHeld returns whether a slider at the given position should leave a resolved target alone, since
+// its volume was changed from outside of deej and the slider hasn't moved far enough since. once it has, the
+// target is the slider's again
+func (m *sessionMap) externalVolumeHeld(resolvedTarget string, position float32) bool {
+	if m.deej.config.ExternalVolume.Policy != externalVolumePolicyRespect {
+		return false
+	}
+
+	m.externalLock.Lock()
+	change, ok := m.externalVolumes[resolvedTarget]
+	m.externalLock.Unlock()
+
+	if !ok || !change.Respected {
+		return false
+	}
+
+	if math.Abs(float64(position-change.position)) <= m.deej.config.ExternalVolume.ReleaseThreshold {
+		return true
+	}
+
+	m.logger.Debugw("Slider moved far enough, taking back externally changed session", "key", resolvedTarget)
+	m.forgetExternalVolume(resolvedTarget)
+
+	return false
+}
+
+// forgetExternalVolume is called whenever deej sets a key's volume itself, which overrides any outside change
+func (m *sessionMap) forgetExternalVolume(key string) {
+	m.externalLock.Lock()
+	delete(m.externalVolumes, key)
+	m.externalLock.Unlock()
+}
+
+func externalVolumeSettingsFromConfig(
+	userConfig *viper.Viper,
+	warnInvalidValue func(message string, key string, keysAndValues ...interface{}),
+) ExternalVolumeSettings {
+	settings := ExternalVolumeSettings{
+		Policy:           strings.ToLower(userConfig.GetString(configKeyExternalPolicy)),
+		ReleaseThreshold: userConfig.GetFloat64(configKeyExternalRelease),
+	}
+
+	switch settings.Policy {
+	case externalVolumePolicyIgnore, externalVolumePolicyLog, externalVolumePolicyRespect:
+	default:
+		warnInvalidValue("Invalid external volume change policy specified, using default value",
+			configKeyExternalPolicy,
+			"invalidValue", settings.Policy,
+			"defaultValue", externalVolumePolicyIgnore)
+
+		settings.Policy = externalVolumePolicyIgnore
+	}
+
+	interval := time.Duration(userConfig.GetFloat64(configKeyExternalInterval) * float64(time.Second))
+	if interval < minExternalVolumeInterval {
+		warnInvalidValue("External volume check interval is too short, using the minimum instead",
+			configKeyExternalInterval,
+			"invalidValue", userConfig.GetFloat64(configKeyExternalInterval),
+			"minimumValue", minExternalVolumeInterval.Seconds())
+
+		interval = minExternalVolumeInterval
+	}
+
+	settings.Interval = interval
+
+	if settings.ReleaseThreshold < 0 || settings.ReleaseThreshold > 1 {
+		warnInvalidValue("Invalid external volume release threshold specified, it must be between 0 and 1 - using default value",
+			configKeyExternalRelease,
+			"invalidValue", settings.ReleaseThreshold,
+			"defaultValue", defaultExternalVolumeRelease)
+
+		settings.ReleaseThreshold = defaultExternalVolumeRelease
+	}
+
+	return settings
+}
@@ -0,0 +1,57 @@
+package deej
+
+import (
+	_ "embed" // for the default config
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// the same config that ships with release builds, master on the first slider and every other option documented
+//
+//go:embed scripts/misc/default-config.yaml
+var defaultUserConfig []byte
+
+// CreateDefaultIfMissing writes the default config on a fresh install that has none, so deej starts out usable
+// instead of refusing to start. a config that does exist is never touched, even if it's empty
+func (cc *CanonicalConfig) CreateDefaultIfMissing() error {
+
+	// O_EXCL makes sure the file isn't written over if it shows up in the meantime
+	file, err := os.OpenFile(userConfigFilepath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("create default config: %w", err)
+	}
+
+	if _, err := file.Write(defaultUserConfig); err != nil {
+		file.Close()
+		os.Remove(userConfigFilepath)
+
+		return fmt.Errorf("write default config: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("close default config: %w", err)
+	}
+
+	cc.firstRun = true
+
+	path, err := filepath.Abs(userConfigFilepath)
+	if err != nil {
+		path = userConfigFilepath
+	}
+
+	cc.logger.Infow("No config file found, created a default one", "path", path)
+	cc.notifier.Notify("Welcome to deej!", fmt.Sprintf("A default configuration was created at %s", path))
+
+	return nil
+}
+
+// FirstRun returns whether deej created its config on this launch, for onboarding
+func (cc *CanonicalConfig) FirstRun() bool {
+	return cc.firstRun
+}
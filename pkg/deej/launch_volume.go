@@ -0,0 +1,72 @@
+package deej
+
+import (
+	"sort"
+)
+
+// with apply_volume_on_launch enabled, sessions that show up in a refresh (an app that just started playing audio)
+// are set to their slider's volume right away, rather than playing at whatever the OS gave them until the slider
+// is moved. only sliders whose position is known are applied, and a session reachable from more than one slider
+// gets the lowest-numbered one's volume - slider groups go along with each of their sliders
+
+// queueLaunchVolumes remembers session keys that just appeared, for the slider move goroutine to set their volume.
+// refreshes also happen on that goroutine, so this only leaves a signal for it instead of waiting on it
+func (m *sessionMap) queueLaunchVolumes(keys []string) {
+	if !m.deej.config.ApplyVolumeOnLaunch || len(keys) == 0 {
+		return
+	}
+
+	m.launchLock.Lock()
+	for _, key := range keys {
+		m.launchedKeys[key] = true
+	}
+	m.launchLock.Unlock()
+
+	select {
+	case m.launchedSignal <- true:
+	default:
+	}
+}
+
+// applyLaunchVolumes sets every queued session key to its slider's volume, leaving all other sessions alone.
+// must only be called from the slider move goroutine
+func (m *sessionMap) applyLaunchVolumes() {
+	m.launchLock.Lock()
+	launched := m.launchedKeys
+	m.launchedKeys = make(map[string]bool)
+	m.launchLock.Unlock()
+
+	if !m.deej.config.ApplyVolumeOnLaunch || len(launched) == 0 {
+		return
+	}
+
+	// sessions that were already around count as adjusted, so applying a slider only touches the new ones
+	adjustedTargets := make(map[string]bool)
+
+	m.lock.Lock()
+	for key := range m.m {
+		if !launched[key] {
+			adjustedTargets[key] = true
+		}
+	}
+	m.lock.Unlock()
+
+	// sliders the board (or anything else) hasn't reported a position for yet have nothing to apply
+	sliderIDs := make([]int, 0, len(m.smoothers))
+	for sliderID := range m.smoothers {
+		sliderIDs = append(sliderIDs, sliderID)
+	}
+
+	sort.Ints(sliderIDs)
+
+	for _, sliderID := range sliderIDs {
+		if !m.sliderMapped(sliderID) {
+			continue
+		}
+
+		// a session that fails to take its volume is left to the next slider move, which refreshes them
+		m.applySliderTargets(sliderID, m.smoothers[sliderID].position, adjustedTargets)
+	}
+
+	m.logger.Debugw("Applied slider volumes to new sessions", "keys", len(launched))
+}
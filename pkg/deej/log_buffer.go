@@ -0,0 +1,159 @@
+package deej
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+
+	// how many of the most recent log lines are kept, for log streams that connect later on
+	logBufferSize = 500
+
+	// how far behind a log stream can fall before it starts missing lines
+	logSubscriberBufferSize = 64
+)
+
+// LogLine is a single line logged by some part of deej
+type LogLine struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Logger  string    `json:"logger"`
+	Message string    `json:"message"`
+
+	// the whole line as it appears in the log file, fields and all
+	Text string `json:"text"`
+
+	level zapcore.Level
+}
+
+// logBuffer keeps the most recent log lines in a ring, and hands each new one to everyone streaming them
+type logBuffer struct {
+	lock sync.Mutex
+
+	lines []LogLine
+	next  int
+
+	subscribers map[chan LogLine]bool
+}
+
+// logBufferCore is a zap core that writes into a logBuffer, at the same level as the rest of the logger
+type logBufferCore struct {
+	zapcore.LevelEnabler
+
+	encoder zapcore.Encoder
+	buffer  *logBuffer
+}
+
+// the one every logger made by NewLogger writes to
+var recentLogs = newLogBuffer(logBufferSize)
+
+func newLogBuffer(size int) *logBuffer {
+	return &logBuffer{
+		lines:       make([]LogLine, 0, size),
+		subscribers: make(map[chan LogLine]bool),
+	}
+}
+
+// add never blocks - a stream that's too slow to keep up just misses the line
+func (b *logBuffer) add(line LogLine) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if len(b.lines) < cap(b.lines) {
+		b.lines = append(b.lines, line)
+	} else {
+		b.lines[b.next] = line
+		b.next = (b.next + 1) % len(b.lines)
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+// subscribe returns the lines logged so far (oldest first), and a channel for the ones logged after them
+func (b *logBuffer) subscribe() ([]LogLine, chan LogLine) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	backlog := make([]LogLine, 0, len(b.lines))
+	backlog = append(backlog, b.lines[b.next:]...)
+	backlog = append(backlog, b.lines[:b.next]...)
+
+	ch := make(chan LogLine, logSubscriberBufferSize)
+	b.subscribers[ch] = true
+
+	return backlog, ch
+}
+
+func (b *logBuffer) unsubscribe(ch chan LogLine) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	delete(b.subscribers, ch)
+}
+
+func newLogBufferCore(level zapcore.LevelEnabler, encoderConfig zapcore.EncoderConfig, buffer *logBuffer) *logBufferCore {
+
+	// the browser shows these as plain text, so the terminal colors have to go
+	encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+
+	return &logBufferCore{
+		LevelEnabler: level,
+		encoder:      zapcore.NewConsoleEncoder(encoderConfig),
+		buffer:       buffer,
+	}
+}
+
+func (c *logBufferCore) With(fields []zapcore.Field) zapcore.Core {
+	encoder := c.encoder.Clone()
+	for _, field := range fields {
+		field.AddTo(encoder)
+	}
+
+	return &logBufferCore{
+		LevelEnabler: c.LevelEnabler,
+		encoder:      encoder,
+		buffer:       c.buffer,
+	}
+}
+
+func (c *logBufferCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+
+	return checked
+}
+
+func (c *logBufferCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	encoded, err := c.encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return err
+	}
+
+	text := strings.TrimSuffix(encoded.String(), "\n")
+	encoded.Free()
+
+	c.buffer.add(LogLine{
+		Time:    entry.Time,
+		Level:   entry.Level.String(),
+		Logger:  entry.LoggerName,
+		Message: entry.Message,
+		Text:    text,
+		level:   entry.Level,
+	})
+
+	return nil
+}
+
+func (c *logBufferCore) Sync() error {
+	return nil
+}
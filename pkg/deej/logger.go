@@ -1,8 +1,10 @@
 package deej
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/omriharel/deej/pkg/deej/util"
@@ -19,6 +21,38 @@ const (
 	logFilename  = "deej-latest-run.log"
 )
 
+// LoggedProblem is a warning or error that was logged by some part of deej
+type LoggedProblem struct {
+	Level   string    `json:"level"`
+	Logger  string    `json:"logger"`
+	Message string    `json:"message"`
+	Error   string    `json:"error,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// problemRecorder is a zap core that remembers the last warning or error logged anywhere in deej, so it can be
+// shown (see the diagnostics endpoint) without anyone having to dig through the logs
+type problemRecorder struct {
+	fields []zapcore.Field
+	last   *problemLog
+}
+
+type problemLog struct {
+	lock    sync.Mutex
+	problem *LoggedProblem
+}
+
+// the one every logger made by NewLogger records to
+var loggedProblems = &problemLog{}
+
+var errInvalidLogLevel = errors.New("invalid log level")
+
+// the level of every logger made by NewLogger, which can be changed while deej is running (see /api/loglevel)
+var logLevel = zap.NewAtomicLevel()
+
+// the levels that can be switched to at runtime, the rest would hide everything deej logs
+var runtimeLogLevels = []zapcore.Level{zapcore.DebugLevel, zapcore.InfoLevel, zapcore.WarnLevel, zapcore.ErrorLevel}
+
 // NewLogger provides a logger instance for the whole program
 func NewLogger(buildType string) (*zap.SugaredLogger, error) {
 	var loggerConfig zap.Config
@@ -42,6 +76,10 @@ func NewLogger(buildType string) (*zap.SugaredLogger, error) {
 		loggerConfig.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
 	}
 
+	// all build types: start at the build type's level, but leave it changeable
+	logLevel.SetLevel(loggerConfig.Level.Level())
+	loggerConfig.Level = logLevel
+
 	// all build types: make it readable
 	loggerConfig.EncoderConfig.EncodeCaller = nil
 	loggerConfig.EncoderConfig.EncodeTime = func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
@@ -57,8 +95,96 @@ func NewLogger(buildType string) (*zap.SugaredLogger, error) {
 		return nil, fmt.Errorf("create zap logger: %w", err)
 	}
 
+	logger = logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(core,
+			&problemRecorder{last: loggedProblems},
+			newLogBufferCore(loggerConfig.Level, loggerConfig.EncoderConfig, recentLogs))
+	}))
+
 	// no reason not to use the sugared logger - it's fast enough for anything we're gonna do
 	sugar := logger.Sugar()
 
 	return sugar, nil
 }
+
+// LastLoggedProblem returns the last warning or error that was logged, or nil if there wasn't one yet
+func LastLoggedProblem() *LoggedProblem {
+	loggedProblems.lock.Lock()
+	defer loggedProblems.lock.Unlock()
+
+	if loggedProblems.problem == nil {
+		return nil
+	}
+
+	problem := *loggedProblems.problem
+	return &problem
+}
+
+func (r *problemRecorder) Enabled(level zapcore.Level) bool {
+	return level >= zapcore.WarnLevel
+}
+
+func (r *problemRecorder) With(fields []zapcore.Field) zapcore.Core {
+	return &problemRecorder{
+		fields: append(append([]zapcore.Field{}, r.fields...), fields...),
+		last:   r.last,
+	}
+}
+
+func (r *problemRecorder) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if r.Enabled(entry.Level) {
+		return checked.AddCore(entry, r)
+	}
+
+	return checked
+}
+
+func (r *problemRecorder) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	problem := &LoggedProblem{
+		Level:   entry.Level.String(),
+		Logger:  entry.LoggerName,
+		Message: entry.Message,
+		Time:    entry.Time,
+	}
+
+	// the message alone is usually just "Failed to ...", the error field says why
+	encoder := zapcore.NewMapObjectEncoder()
+	for _, field := range append(append([]zapcore.Field{}, r.fields...), fields...) {
+		field.AddTo(encoder)
+	}
+
+	if err, ok := encoder.Fields["error"]; ok {
+		problem.Error = fmt.Sprint(err)
+	}
+
+	r.last.lock.Lock()
+	r.last.problem = problem
+	r.last.lock.Unlock()
+
+	return nil
+}
+
+func (r *problemRecorder) Sync() error {
+	return nil
+}
+
+// LogLevel returns the level deej currently logs at
+func LogLevel() zapcore.Level {
+	return logLevel.Level()
+}
+
+// SetLogLevel changes the level deej logs at from now on. only debug, info, warn and error are accepted
+func SetLogLevel(level string) error {
+	var parsed zapcore.Level
+
+	if err := parsed.UnmarshalText([]byte(level)); err == nil && level != "" {
+		for _, allowed := range runtimeLogLevels {
+			if parsed == allowed {
+				logLevel.SetLevel(parsed)
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("%w: %q, must be one of debug, info, warn or error", errInvalidLogLevel, level)
+}
@@ -0,0 +1,103 @@
+package deej
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// mappingHistory keeps the slider mappings from before each edit, so edits can be undone (and redone).
+// it only lives in memory, a restart starts over with a clean slate
+type mappingHistory struct {
+	lock sync.Mutex
+	undo []map[int][]string
+	redo []map[int][]string
+}
+
+// enough to back out of any experiment, without holding on to every edit ever made
+const maxMappingHistory = 50
+
+var (
+	errNothingToUndo = errors.New("no slider mapping edits to undo")
+	errNothingToRedo = errors.New("no undone slider mapping edits to redo")
+)
+
+// record remembers the mapping from before an edit. a new edit makes anything undone before it unreachable
+func (h *mappingHistory) record(previous map[int][]string, next map[int][]string) {
+	if reflect.DeepEqual(previous, next) {
+		return
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.undo = appendBounded(h.undo, previous)
+	h.redo = nil
+}
+
+// stepBack saves the mapping from before the last edit and returns it, remembering current so it can be redone.
+// nothing changes if it can't be saved
+func (h *mappingHistory) stepBack(
+	current map[int][]string,
+	save func(mapping map[int][]string) error,
+) (map[int][]string, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if len(h.undo) == 0 {
+		return nil, errNothingToUndo
+	}
+
+	previous := h.undo[len(h.undo)-1]
+	if err := save(previous); err != nil {
+		return nil, err
+	}
+
+	h.undo = h.undo[:len(h.undo)-1]
+	h.redo = appendBounded(h.redo, current)
+
+	return previous, nil
+}
+
+// stepForward saves the mapping from before the last undo and returns it, remembering current so it can be undone
+// again. nothing changes if it can't be saved
+func (h *mappingHistory) stepForward(
+	current map[int][]string,
+	save func(mapping map[int][]string) error,
+) (map[int][]string, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if len(h.redo) == 0 {
+		return nil, errNothingToRedo
+	}
+
+	next := h.redo[len(h.redo)-1]
+	if err := save(next); err != nil {
+		return nil, err
+	}
+
+	h.redo = h.redo[:len(h.redo)-1]
+	h.undo = appendBounded(h.undo, current)
+
+	return next, nil
+}
+
+// clear forgets everything, for when the mapping is replaced wholesale (i.e. by switching profiles)
+func (h *mappingHistory) clear() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.undo = nil
+	h.redo = nil
+}
+
+func appendBounded(snapshots []map[int][]string, snapshot map[int][]string) []map[int][]string {
+	snapshots = append(snapshots, snapshot)
+
+	if len(snapshots) > maxMappingHistory {
+		snapshots = snapshots[len(snapshots)-maxMappingHistory:]
+	}
+
+	return snapshots
+}
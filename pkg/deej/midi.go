@@ -0,0 +1,319 @@
+package deej
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// MIDISettings holds everything about deej's MIDI input. it's comparable, so a config
+// reload can tell whether the device has to be reopened
+type MIDISettings struct {
+	Enabled bool
+
+	// part of the input's name, case-insensitive. empty means the first input found
+	Device string
+}
+
+// MIDIControlChange is a single CC message, as reported to MIDI learn
+type MIDIControlChange struct {
+	Channel    int `json:"channel"`
+	Controller int `json:"controller"`
+	Value      int `json:"value"`
+}
+
+// MIDIInput moves sliders from a MIDI control surface (i.e. a fader bank), mapping CC numbers to slider indices.
+// the actual driver is only compiled in with the "midi" build tag, see midi_driver.go
+type MIDIInput struct {
+	deej   *Deej
+	logger *zap.SugaredLogger
+
+	lock     sync.Mutex
+	driver   midiDriver
+	port     midiInputPort
+	settings MIDISettings
+
+	// everyone waiting on MIDI learn, each receiving the next CC message only
+	learners []chan MIDIControlChange
+}
+
+// midiInputPort and midiDriver are the parts of a MIDI driver deej needs, so it only depends on one when built with it
+type midiInputPort interface {
+	String() string
+	Open() error
+	Close() error
+	SetListener(func(data []byte, deltaMicroseconds int64)) error
+	StopListening() error
+}
+
+type midiDriver interface {
+	Ins() ([]midiInputPort, error)
+	Close() error
+}
+
+const (
+	midiMaxControllerValue = 127
+
+	// control change messages carry their channel in the low nibble of the status byte
+	midiStatusControlChange = 0xB0
+)
+
+var (
+	errMIDIUnsupported   = errors.New("midi: this build of deej doesn't include MIDI support (build with -tags midi)")
+	errMIDINotRunning    = errors.New("midi: no MIDI input is open")
+	errMIDIInputNotFound = errors.New("midi: no matching MIDI input found")
+)
+
+// NewMIDIInput creates a MIDIInput
+func NewMIDIInput(deej *Deej, logger *zap.SugaredLogger) *MIDIInput {
+	logger = logger.Named("midi")
+
+	m := &MIDIInput{
+		deej:   deej,
+		logger: logger,
+	}
+
+	m.setupOnConfigReload()
+
+	logger.Debug("Created MIDI input instance")
+
+	return m
+}
+
+// Start opens the configured MIDI input, if enabled
+func (m *MIDIInput) Start() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.port != nil {
+		return nil
+	}
+
+	m.settings = m.deej.config.MIDI
+
+	if !m.settings.Enabled {
+		m.logger.Debug("MIDI is disabled, not opening any input")
+		return nil
+	}
+
+	driver, err := openMIDIDriver()
+	if err != nil {
+		return err
+	}
+
+	port, err := findMIDIInput(driver, m.settings.Device)
+	if err != nil {
+		driver.Close()
+		return err
+	}
+
+	if err := port.Open(); err != nil {
+		driver.Close()
+		return fmt.Errorf("open MIDI input %s: %w", port, err)
+	}
+
+	if err := port.SetListener(m.handleData); err != nil {
+		port.Close()
+		driver.Close()
+		return fmt.Errorf("listen to MIDI input %s: %w", port, err)
+	}
+
+	m.driver = driver
+	m.port = port
+
+	m.logger.Infow("Listening to MIDI input", "input", port.String())
+
+	return nil
+}
+
+// Stop closes the MIDI input
+func (m *MIDIInput) Stop() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.port == nil {
+		return
+	}
+
+	if err := m.port.StopListening(); err != nil {
+		m.logger.Warnw("Failed to stop listening to MIDI input", "error", err)
+	}
+
+	if err := m.port.Close(); err != nil {
+		m.logger.Warnw("Failed to close MIDI input", "error", err)
+	}
+
+	if err := m.driver.Close(); err != nil {
+		m.logger.Warnw("Failed to close MIDI driver", "error", err)
+	}
+
+	m.port = nil
+	m.driver = nil
+
+	m.logger.Debug("Closed MIDI input")
+}
+
+// InputName returns the name of the open MIDI input, or an empty string if there isn't one
+func (m *MIDIInput) InputName() string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.port == nil {
+		return ""
+	}
+
+	return m.port.String()
+}
+
+// Learn waits for the next CC message from the open MIDI input, so it can be assigned to a slider
+func (m *MIDIInput) Learn(ctx context.Context) (MIDIControlChange, error) {
+	m.lock.Lock()
+
+	if m.port == nil {
+		m.lock.Unlock()
+		return MIDIControlChange{}, errMIDINotRunning
+	}
+
+	// buffered, so delivering to a learner that just gave up never blocks
+	ch := make(chan MIDIControlChange, 1)
+	m.learners = append(m.learners, ch)
+
+	m.lock.Unlock()
+
+	select {
+	case change := <-ch:
+		return change, nil
+	case <-ctx.Done():
+		m.lock.Lock()
+		for idx, learner := range m.learners {
+			if learner == ch {
+				m.learners = append(m.learners[:idx], m.learners[idx+1:]...)
+				break
+			}
+		}
+		m.lock.Unlock()
+
+		return MIDIControlChange{}, ctx.Err()
+	}
+}
+
+func (m *MIDIInput) setupOnConfigReload() {
+	configReloadedChannel := m.deej.config.SubscribeToChanges()
+
+	go func() {
+		for {
+			select {
+			case <-configReloadedChannel:
+				m.lock.Lock()
+				changed := m.settings != m.deej.config.MIDI
+				m.lock.Unlock()
+
+				if changed {
+					m.logger.Info("Detected change in MIDI settings, reopening input")
+					m.Stop()
+
+					if err := m.Start(); err != nil {
+						m.logger.Warnw("Failed to reopen MIDI input", "error", err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// handleData is called by the driver for every message from the input. only CC messages mean anything to deej
+func (m *MIDIInput) handleData(data []byte, _ int64) {
+	if len(data) < 3 || data[0]&0xF0 != midiStatusControlChange {
+		return
+	}
+
+	change := MIDIControlChange{
+		Channel:    int(data[0] & 0x0F),
+		Controller: int(data[1]),
+		Value:      int(data[2]),
+	}
+
+	m.lock.Lock()
+	learners := m.learners
+	m.learners = nil
+	m.lock.Unlock()
+
+	for _, learner := range learners {
+		learner <- change
+	}
+
+	if m.deej.Verbose() {
+		m.logger.Debugw("Got MIDI control change", "change", change)
+	}
+
+	for sliderIdx, controller := range m.deej.config.MIDIMapping {
+		if controller == change.Controller {
+			m.deej.serial.SetSliderValue(sliderIdx, float32(change.Value)/midiMaxControllerValue)
+		}
+	}
+}
+
+func findMIDIInput(driver midiDriver, device string) (midiInputPort, error) {
+	ports, err := driver.Ins()
+	if err != nil {
+		return nil, fmt.Errorf("list MIDI inputs: %w", err)
+	}
+
+	for _, port := range ports {
+		if device == "" || strings.Contains(strings.ToLower(port.String()), strings.ToLower(device)) {
+			return port, nil
+		}
+	}
+
+	return nil, errMIDIInputNotFound
+}
+
+func midiMappingFromConfig(
+	userConfig *viper.Viper,
+	warnInvalidValue func(message string, key string, keysAndValues ...interface{}),
+) map[int]int {
+	result := make(map[int]int)
+
+	// one CC can move several sliders, just like one app can be on several sliders
+	for key, rawController := range userConfig.GetStringMapString(configKeyMIDIMapping) {
+		sliderIdx, err := strconv.Atoi(key)
+		if err != nil || sliderIdx < 0 {
+			warnInvalidValue("Invalid slider index in MIDI mapping, ignoring",
+				configKeyMIDIMapping,
+				"invalidValue", key)
+
+			continue
+		}
+
+		controller, err := strconv.Atoi(rawController)
+		if err != nil || controller < 0 || controller > midiMaxControllerValue {
+			warnInvalidValue("Invalid MIDI CC number specified (must be 0-127), ignoring",
+				configKeyMIDIMapping,
+				"sliderIdx", sliderIdx,
+				"invalidValue", rawController)
+
+			continue
+		}
+
+		result[sliderIdx] = controller
+	}
+
+	return result
+}
+
+// midiMappingToConfigValue returns the MIDI mapping in the shape it's written to config.yaml in
+func midiMappingToConfigValue(mapping map[int]int) map[string]interface{} {
+	value := make(map[string]interface{}, len(mapping))
+
+	for sliderIdx, controller := range mapping {
+		value[strconv.Itoa(sliderIdx)] = controller
+	}
+
+	return value
+}
@@ -0,0 +1,38 @@
+//go:build midi
+// +build midi
+
+package deej
+
+import (
+	"fmt"
+
+	"gitlab.com/gomidi/rtmididrv"
+)
+
+// rtmidiDriver adapts RtMidi, which needs its native library (i.e. libasound2-dev on Linux) to build
+type rtmidiDriver struct {
+	*rtmididrv.Driver
+}
+
+func openMIDIDriver() (midiDriver, error) {
+	driver, err := rtmididrv.New()
+	if err != nil {
+		return nil, fmt.Errorf("open MIDI driver: %w", err)
+	}
+
+	return rtmidiDriver{driver}, nil
+}
+
+func (d rtmidiDriver) Ins() ([]midiInputPort, error) {
+	ins, err := d.Driver.Ins()
+	if err != nil {
+		return nil, err
+	}
+
+	ports := make([]midiInputPort, len(ins))
+	for idx, in := range ins {
+		ports[idx] = in
+	}
+
+	return ports, nil
+}
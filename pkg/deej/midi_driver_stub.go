@@ -0,0 +1,9 @@
+//go:build !midi
+// +build !midi
+
+package deej
+
+// without the "midi" build tag, deej doesn't depend on any MIDI library (or its native dependencies)
+func openMIDIDriver() (midiDriver, error) {
+	return nil, errMIDIUnsupported
+}
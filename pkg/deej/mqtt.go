@@ -0,0 +1,286 @@
+package deej
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"go.uber.org/zap"
+
+	"github.com/omriharel/deej/pkg/deej/util"
+)
+
+// MQTTSettings holds everything about deej's MQTT connection. it's comparable, so a config
+// reload can tell whether the connection has to be renewed
+type MQTTSettings struct {
+	Enabled     bool
+	Broker      string
+	ClientID    string
+	Username    string
+	Password    string
+	TopicPrefix string
+
+	// remote volume changes to a target are ignored for this long after one of its sliders physically moved
+	SliderLockout time.Duration
+}
+
+// MQTTPublisher mirrors slider positions and the volumes deej applies to an MQTT broker (i.e. for Home Assistant),
+// and takes volume changes for any target from it in return
+type MQTTPublisher struct {
+	deej   *Deej
+	logger *zap.SugaredLogger
+
+	lock     sync.Mutex
+	client   mqtt.Client
+	settings MQTTSettings
+
+	// the last payload published to every topic. everything is published as retained, and republished
+	// whenever we (re)connect, in case the broker restarted and lost it
+	published map[string]string
+
+	// only touched by the slider values consumer
+	lastSliderValues []float32
+}
+
+const (
+	mqttTopicStatus = "status"
+	mqttTopicSlider = "slider"
+	mqttTopicVolume = "volume"
+	mqttTopicSet    = "set"
+
+	mqttPayloadOnline  = "online"
+	mqttPayloadOffline = "offline"
+
+	// the broker's last will already covers deej going away unexpectedly, this only covers a clean stop
+	mqttDisconnectTimeout = time.Second
+
+	mqttConnectRetryInterval = 5 * time.Second
+	mqttMaxReconnectInterval = 30 * time.Second
+)
+
+type mqttVolumePayload struct {
+	Volume int  `json:"volume"`
+	Muted  bool `json:"muted"`
+}
+
+// NewMQTTPublisher creates an MQTTPublisher. it consumes slider values and volume changes right away,
+// whether or not MQTT is enabled, so the components producing them are never blocked on it
+func NewMQTTPublisher(deej *Deej, logger *zap.SugaredLogger) *MQTTPublisher {
+	logger = logger.Named("mqtt")
+
+	p := &MQTTPublisher{
+		deej:      deej,
+		logger:    logger,
+		published: make(map[string]string),
+	}
+
+	go p.consumeSliderValues(deej.serial.SubscribeToSliderValues())
+	go p.consumeVolumeChanges(deej.sessions.SubscribeToVolumeChanges())
+
+	p.setupOnConfigReload()
+
+	logger.Debug("Created MQTT publisher instance")
+
+	return p
+}
+
+// Start connects to the configured broker in the background, if MQTT is enabled.
+// the client keeps retrying on its own if the broker can't be reached, and reconnects if it drops
+func (p *MQTTPublisher) Start() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.client != nil {
+		return
+	}
+
+	p.settings = p.deej.config.MQTT
+
+	if !p.settings.Enabled {
+		p.logger.Debug("MQTT is disabled, not connecting")
+		return
+	}
+
+	statusTopic := p.topic(mqttTopicStatus)
+
+	options := mqtt.NewClientOptions().
+		AddBroker(p.settings.Broker).
+		SetClientID(p.settings.ClientID).
+		SetUsername(p.settings.Username).
+		SetPassword(p.settings.Password).
+		SetWill(statusTopic, mqttPayloadOffline, 1, true).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(mqttConnectRetryInterval).
+		SetMaxReconnectInterval(mqttMaxReconnectInterval).
+		SetOnConnectHandler(p.onConnect).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			p.logger.Warnw("Lost connection to MQTT broker, reconnecting", "error", err)
+		})
+
+	p.client = mqtt.NewClient(options)
+
+	// with connect retry on, this token only completes once we're connected
+	p.client.Connect()
+
+	p.logger.Infow("Connecting to MQTT broker", "broker", p.settings.Broker, "topicPrefix", p.settings.TopicPrefix)
+}
+
+// Stop marks deej as offline and disconnects from the broker
+func (p *MQTTPublisher) Stop() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.client == nil {
+		return
+	}
+
+	if p.client.IsConnected() {
+		token := p.client.Publish(p.topic(mqttTopicStatus), 1, true, mqttPayloadOffline)
+		if !token.WaitTimeout(mqttDisconnectTimeout) || token.Error() != nil {
+			p.logger.Debugw("Failed to publish offline status", "error", token.Error())
+		}
+	}
+
+	p.client.Disconnect(uint(mqttDisconnectTimeout / time.Millisecond))
+	p.client = nil
+
+	p.logger.Debug("Disconnected from MQTT broker")
+}
+
+func (p *MQTTPublisher) setupOnConfigReload() {
+	configReloadedChannel := p.deej.config.SubscribeToChanges()
+
+	go func() {
+		for {
+			select {
+			case <-configReloadedChannel:
+				p.lock.Lock()
+				changed := p.settings != p.deej.config.MQTT
+				p.lock.Unlock()
+
+				if changed {
+					p.logger.Info("Detected change in MQTT settings, renewing connection")
+					p.Stop()
+					p.Start()
+				}
+			}
+		}
+	}()
+}
+
+// onConnect runs on every successful (re)connection
+func (p *MQTTPublisher) onConnect(client mqtt.Client) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// connect handlers run on their own goroutine, so this might be a client we've since stopped
+	if client != p.client {
+		return
+	}
+
+	p.logger.Infow("Connected to MQTT broker", "broker", p.settings.Broker)
+
+	// subscriptions don't survive a reconnect, since the client starts a clean session every time
+	setPrefix := p.topic(mqttTopicSet) + "/"
+	client.Subscribe(setPrefix+"+", 0, func(_ mqtt.Client, message mqtt.Message) {
+		p.handleSetMessage(strings.TrimPrefix(message.Topic(), setPrefix), message.Payload())
+	})
+
+	client.Publish(p.topic(mqttTopicStatus), 1, true, mqttPayloadOnline)
+
+	for topic, payload := range p.published {
+		client.Publish(topic, 0, true, payload)
+	}
+}
+
+// consumeSliderValues publishes every slider that moved further than its noise threshold since it was last published
+func (p *MQTTPublisher) consumeSliderValues(valuesChannel chan []float32) {
+	for values := range valuesChannel {
+		if len(values) != len(p.lastSliderValues) {
+			p.lastSliderValues = make([]float32, len(values))
+			for idx := range p.lastSliderValues {
+				p.lastSliderValues[idx] = -1
+			}
+		}
+
+		for idx, value := range values {
+			if p.lastSliderValues[idx] >= 0 &&
+				!util.SignificantlyDifferentByThreshold(p.lastSliderValues[idx], value, p.deej.config.SliderNoiseThreshold(idx)) {
+				continue
+			}
+
+			p.lastSliderValues[idx] = value
+			p.publish(strconv.FormatFloat(float64(value), 'f', 2, 32), mqttTopicSlider, strconv.Itoa(idx))
+		}
+	}
+}
+
+func (p *MQTTPublisher) consumeVolumeChanges(changesChannel chan VolumeChangeEvent) {
+	for change := range changesChannel {
+		payload, err := json.Marshal(mqttVolumePayload{
+			Volume: int(math.Round(float64(change.Volume) * 100)),
+			Muted:  change.Muted,
+		})
+
+		if err != nil {
+			p.logger.Warnw("Failed to marshal volume payload", "error", err)
+			continue
+		}
+
+		p.publish(string(payload), mqttTopicVolume, mqttTopicLevel(change.Key))
+	}
+}
+
+// handleSetMessage applies a remote volume change, given as a number from 0 to 100, to a target
+func (p *MQTTPublisher) handleSetMessage(target string, payload []byte) {
+	value, err := strconv.ParseFloat(strings.TrimSpace(string(payload)), 32)
+	if err != nil || math.IsNaN(value) || value < 0 || value > 100 {
+		p.logger.Warnw("Ignoring malformed volume command, expected a number from 0 to 100",
+			"target", target,
+			"payload", string(payload))
+
+		return
+	}
+
+	p.lock.Lock()
+	lockout := p.settings.SliderLockout
+	p.lock.Unlock()
+
+	p.logger.Debugw("Got remote volume command", "target", target, "volume", value)
+	p.deej.sessions.SetTargetVolume(target, float32(math.Round(value)/100), lockout)
+}
+
+// publish remembers the payload and sends it right away if we're connected.
+// if we aren't, it goes out with everything else once we are
+func (p *MQTTPublisher) publish(payload string, levels ...string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	topic := p.topic(levels...)
+
+	if p.published[topic] == payload {
+		return
+	}
+
+	p.published[topic] = payload
+
+	if p.client != nil && p.client.IsConnected() {
+		p.client.Publish(topic, 0, true, payload)
+	}
+}
+
+// topic joins topic levels under the configured prefix. must be called with the lock held
+func (p *MQTTPublisher) topic(levels ...string) string {
+	return fmt.Sprintf("%s/%s", p.settings.TopicPrefix, strings.Join(levels, "/"))
+}
+
+// session keys are process and device names, which can contain characters that mean something else in a topic
+func mqttTopicLevel(key string) string {
+	return strings.NewReplacer("/", "_", "+", "_", "#", "_").Replace(key)
+}
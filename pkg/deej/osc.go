@@ -0,0 +1,213 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hypebeast/go-osc/osc"
+	"go.uber.org/zap"
+)
+
+// OSCSettings holds everything about deej's OSC listener. it's comparable, so a config
+// reload can tell whether the listener has to be restarted
+type OSCSettings struct {
+	Enabled bool
+	Host    string
+	Port    int
+}
+
+// OSCListener takes slider values from OSC controllers (i.e. a TouchOSC layout) over UDP.
+// they're treated as sliders of their own, which can share an index with a physical slider
+type OSCListener struct {
+	deej   *Deej
+	logger *zap.SugaredLogger
+
+	lock     sync.Mutex
+	conn     net.PacketConn
+	settings OSCSettings
+}
+
+// virtual sliders are addressed by index, i.e. /deej/slider/0 with a single float argument from 0.0 to 1.0
+const oscSliderAddressPrefix = "/deej/slider/"
+
+// the largest datagram UDP can carry
+const oscMaxPacketSize = 65535
+
+// NewOSCListener creates an OSCListener
+func NewOSCListener(deej *Deej, logger *zap.SugaredLogger) *OSCListener {
+	logger = logger.Named("osc")
+
+	l := &OSCListener{
+		deej:   deej,
+		logger: logger,
+	}
+
+	l.setupOnConfigReload()
+
+	logger.Debug("Created OSC listener instance")
+
+	return l
+}
+
+// Start begins listening for OSC messages, if enabled
+func (l *OSCListener) Start() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.conn != nil {
+		return nil
+	}
+
+	l.settings = l.deej.config.OSC
+
+	if !l.settings.Enabled {
+		l.logger.Debug("OSC is disabled, not listening")
+		return nil
+	}
+
+	address := net.JoinHostPort(l.settings.Host, strconv.Itoa(l.settings.Port))
+
+	conn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", address, err)
+	}
+
+	l.conn = conn
+	go l.serve(conn)
+
+	l.logger.Infow("Listening for OSC messages", "address", address)
+
+	return nil
+}
+
+// Stop stops listening for OSC messages
+func (l *OSCListener) Stop() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.conn == nil {
+		return
+	}
+
+	if err := l.conn.Close(); err != nil {
+		l.logger.Warnw("Failed to close OSC listener", "error", err)
+	}
+
+	l.conn = nil
+	l.logger.Debug("Stopped listening for OSC messages")
+}
+
+func (l *OSCListener) setupOnConfigReload() {
+	configReloadedChannel := l.deej.config.SubscribeToChanges()
+
+	go func() {
+		for {
+			select {
+			case <-configReloadedChannel:
+				l.lock.Lock()
+				changed := l.settings != l.deej.config.OSC
+				l.lock.Unlock()
+
+				if changed {
+					l.logger.Info("Detected change in OSC settings, restarting listener")
+					l.Stop()
+
+					if err := l.Start(); err != nil {
+						l.logger.Warnw("Failed to restart OSC listener", "error", err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// serve reads packets until the connection is closed. a bad packet is only logged, it doesn't stop the listener
+func (l *OSCListener) serve(conn net.PacketConn) {
+	buf := make([]byte, oscMaxPacketSize)
+
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				l.logger.Warnw("Failed to read from OSC listener, stopping", "error", err)
+			}
+
+			return
+		}
+
+		packet, err := osc.ParsePacket(string(buf[:n]))
+		if err != nil {
+			l.logger.Debugw("Got malformed OSC packet, ignoring", "error", err)
+			continue
+		}
+
+		l.handlePacket(packet)
+	}
+}
+
+// handlePacket handles a message, or every message in a bundle. bundles are applied right away
+// regardless of their time tag, since a slider value that arrives late is only worth less
+func (l *OSCListener) handlePacket(packet osc.Packet) {
+	switch p := packet.(type) {
+	case *osc.Message:
+		l.handleMessage(p)
+
+	case *osc.Bundle:
+		for _, message := range p.Messages {
+			l.handleMessage(message)
+		}
+
+		for _, bundle := range p.Bundles {
+			l.handlePacket(bundle)
+		}
+	}
+}
+
+func (l *OSCListener) handleMessage(message *osc.Message) {
+
+	// controllers tend to send everything they have, only slider addresses are ours
+	if !strings.HasPrefix(message.Address, oscSliderAddressPrefix) {
+		return
+	}
+
+	sliderIdx, err := strconv.Atoi(strings.TrimPrefix(message.Address, oscSliderAddressPrefix))
+	if err != nil || sliderIdx < 0 {
+		l.logger.Debugw("Got OSC message for an invalid slider, ignoring", "address", message.Address)
+		return
+	}
+
+	if len(message.Arguments) != 1 {
+		l.logger.Debugw("Got OSC slider message without exactly one argument, ignoring",
+			"address", message.Address,
+			"arguments", len(message.Arguments))
+
+		return
+	}
+
+	var value float64
+
+	switch argument := message.Arguments[0].(type) {
+	case float32:
+		value = float64(argument)
+	case float64:
+		value = argument
+	default:
+		l.logger.Debugw("Got OSC slider message with a non-float argument, ignoring",
+			"address", message.Address,
+			"argument", argument)
+
+		return
+	}
+
+	if math.IsNaN(value) || value < 0 || value > 1 {
+		l.logger.Debugw("Got OSC slider value out of range, ignoring", "address", message.Address, "value", value)
+		return
+	}
+
+	l.deej.serial.SetSliderValue(sliderIdx, float32(value))
+}
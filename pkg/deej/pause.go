@@ -0,0 +1,66 @@
+package deej
+
+import (
+	"sort"
+	"time"
+)
+
+// pausing keeps slider moves (from the board, OSC and MIDI alike) from changing any volumes, i.e. while the board
+// is being cleaned. the sliders are still read the whole time, and once unpaused, every slider's current position
+// is applied right away - so the volumes end up where the sliders are, not where they were before the pause
+
+// Paused returns whether slider moves are currently being ignored
+func (m *sessionMap) Paused() bool {
+	m.pauseLock.Lock()
+	defer m.pauseLock.Unlock()
+
+	return m.paused
+}
+
+// SetPaused pauses or unpauses applying slider moves. unpausing applies every slider's current position,
+// on the slider move goroutine
+func (m *sessionMap) SetPaused(paused bool) {
+	m.pauseLock.Lock()
+	changed := m.paused != paused
+	m.paused = paused
+	m.pauseLock.Unlock()
+
+	if !changed {
+		return
+	}
+
+	if paused {
+		m.logger.Info("Paused applying slider moves")
+		return
+	}
+
+	m.logger.Info("Unpaused applying slider moves, applying current slider positions")
+	m.resumeRequests <- true
+}
+
+// applyCurrentSliderPositions applies every slider's latest value as if the slider was just moved there, skipping
+// smoothing - the volumes are caught up at once. must only be called from the slider move goroutine
+func (m *sessionMap) applyCurrentSliderPositions() {
+	values := m.deej.serial.KnownSliderValues()
+
+	sliderIDs := make([]int, 0, len(values))
+	for sliderID := range values {
+		sliderIDs = append(sliderIDs, sliderID)
+	}
+
+	sort.Ints(sliderIDs)
+
+	for _, sliderID := range sliderIDs {
+		smoother, ok := m.smoothers[sliderID]
+		if !ok {
+			smoother = &sliderSmoother{}
+			m.smoothers[sliderID] = smoother
+		}
+
+		smoother.target = values[sliderID]
+		smoother.position = values[sliderID]
+		smoother.lastStep = time.Time{}
+
+		m.applySliderPosition(sliderID, values[sliderID])
+	}
+}
@@ -0,0 +1,114 @@
+package deej
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	ole "github.com/go-ole/go-ole"
+	wca "github.com/moutend/go-wca"
+)
+
+// IPolicyConfig is the (undocumented) interface Windows' own sound settings use to change the default device.
+// it's been stable since Windows 7, and there's no public API that does the same thing
+var (
+	clsidPolicyConfigClient = ole.NewGUID("{870af99c-171d-4f9e-af0d-e63df40c2bc9}")
+	iidPolicyConfig         = ole.NewGUID("{f8679f50-850a-41cf-9c72-430f290290c8}")
+)
+
+type iPolicyConfig struct {
+	ole.IUnknown
+}
+
+type iPolicyConfigVtbl struct {
+	ole.IUnknownVtbl
+	GetMixFormat          uintptr
+	GetDeviceFormat       uintptr
+	ResetDeviceFormat     uintptr
+	SetDeviceFormat       uintptr
+	GetProcessingPeriod   uintptr
+	SetProcessingPeriod   uintptr
+	GetShareMode          uintptr
+	SetShareMode          uintptr
+	GetPropertyValue      uintptr
+	SetPropertyValue      uintptr
+	SetDefaultEndpoint    uintptr
+	SetEndpointVisibility uintptr
+}
+
+func (v *iPolicyConfig) vTable() *iPolicyConfigVtbl {
+	return (*iPolicyConfigVtbl)(unsafe.Pointer(v.RawVTable))
+}
+
+func (v *iPolicyConfig) setDefaultEndpoint(id string, role uint32) error {
+	idPtr, err := syscall.UTF16PtrFromString(id)
+	if err != nil {
+		return err
+	}
+
+	hr, _, _ := syscall.Syscall(
+		v.vTable().SetDefaultEndpoint,
+		3,
+		uintptr(unsafe.Pointer(v)),
+		uintptr(unsafe.Pointer(idPtr)),
+		uintptr(role))
+
+	if hr != 0 {
+		return ole.NewError(hr)
+	}
+
+	return nil
+}
+
+// setDefaultEndpoint makes the given endpoint the default for every role, just like the sound settings do.
+// COM must already be initialized on the calling thread
+func setDefaultEndpoint(id string) error {
+	var policyConfig *iPolicyConfig
+
+	if err := wca.CoCreateInstance(
+		clsidPolicyConfigClient,
+		0,
+		wca.CLSCTX_ALL,
+		iidPolicyConfig,
+		&policyConfig,
+	); err != nil {
+		return fmt.Errorf("create policy config client: %w", err)
+	}
+	defer policyConfig.Release()
+
+	for _, role := range []uint32{wca.EConsole, wca.EMultimedia, wca.ECommunications} {
+		if err := policyConfig.setDefaultEndpoint(id, role); err != nil {
+			return fmt.Errorf("set default endpoint for role %d: %w", role, err)
+		}
+	}
+
+	return nil
+}
+
+// getEndpointID reads a device's endpoint ID string. go-wca's own GetId truncates the
+// returned pointer to 32 bits, which breaks on 64-bit builds
+func getEndpointID(endpoint *wca.IMMDevice) (string, error) {
+	var idPtr unsafe.Pointer
+
+	hr, _, _ := syscall.Syscall(
+		endpoint.VTable().GetId,
+		2,
+		uintptr(unsafe.Pointer(endpoint)),
+		uintptr(unsafe.Pointer(&idPtr)),
+		0)
+
+	if hr != 0 {
+		return "", ole.NewError(hr)
+	}
+	defer ole.CoTaskMemFree(uintptr(idPtr))
+
+	// the ID is a null-terminated wide string
+	chars := (*[1 << 16]uint16)(idPtr)
+
+	length := 0
+	for length < len(chars) && chars[length] != 0 {
+		length++
+	}
+
+	return syscall.UTF16ToString(chars[:length:length]), nil
+}
@@ -0,0 +1,65 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// profiles live under their own key in config.yaml, each one holding a slider mapping of its own:
+//
+//	profiles:
+//	  gaming:
+//	    slider_mapping:
+//	      0: master
+//	      1: discord.exe
+//
+// activating a profile copies its mapping over slider_mapping, which is what deej actually uses
+var errProfileNotFound = errors.New("no profile with this name")
+
+// viper treats dots in keys as nesting, so a profile named "a.b" could never be read back.
+// names are also case-insensitive, since viper lowercases every key it reads
+func validProfileName(name string) bool {
+	return name != "" && !strings.Contains(name, ".")
+}
+
+func profilesFromConfig(
+	userConfig *viper.Viper,
+	warnInvalidValue func(message string, key string, keysAndValues ...interface{}),
+) map[string]*sliderMap {
+	result := make(map[string]*sliderMap)
+
+	for name := range userConfig.GetStringMap(configKeyProfiles) {
+		if !validProfileName(name) {
+			warnInvalidValue("Invalid profile name specified, ignoring profile",
+				configKeyProfiles,
+				"invalidValue", name)
+
+			continue
+		}
+
+		mappingKey := fmt.Sprintf("%s.%s.%s", configKeyProfiles, name, configKeySliderMapping)
+
+		result[name] = sliderMapFromConfigs(
+			userConfig.GetStringMapStringSlice(mappingKey),
+			map[string][]string{},
+		)
+	}
+
+	return result
+}
+
+// profilesToConfigValue returns the profiles in the shape they're written to config.yaml in
+func profilesToConfigValue(profiles map[string]*sliderMap) map[string]interface{} {
+	value := make(map[string]interface{}, len(profiles))
+
+	for name, mapping := range profiles {
+		value[name] = map[string]interface{}{
+			configKeySliderMapping: mappingToConfigValue(mapping.raw()),
+		}
+	}
+
+	return value
+}
@@ -1,38 +1,53 @@
 package deej
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
-	"io"
 	"regexp"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/jacobsa/go-serial/serial"
 	"go.uber.org/zap"
 
 	"github.com/omriharel/deej/pkg/deej/util"
 )
 
-// SerialIO provides a deej-aware abstraction layer to managing serial I/O
+// SerialIO provides a deej-aware abstraction layer to managing serial I/O, with any amount of boards
+// feeding the same sliders and buttons (see serial_connection.go)
 type SerialIO struct {
-	comPort  string
-	baudRate uint
-
 	deej   *Deej
 	logger *zap.SugaredLogger
 
-	stopChannel chan bool
-	connected   bool
-	connOptions serial.OpenOptions
-	conn        io.ReadWriteCloser
+	// one per configured board, the main one first. config reloads replace the ones whose settings changed
+	connectionsLock sync.Mutex
+	connections     []*serialConnection
+
+	// held while connections are being replaced, so a restart and a config reload don't renew them at once
+	renewLock sync.Mutex
+
+	// physical sliders (read from serial) and virtual ones (such as OSC) both move sliders, from different goroutines
+	sliderLock sync.Mutex
 
-	lastKnownNumSliders        int
+	// the current value of every slider, set by whichever input moved it last
 	currentSliderPercentValues []float32
 
-	sliderMoveConsumers []chan SliderMoveEvent
+	// when each slider was last moved (zero if it never was), see slider_activity.go
+	lastSliderMoves []time.Time
+
+	// sliders that are being calibrated, and the raw values they reached so far (see calibration.go)
+	calibrating map[int]*calibrationRecorder
+
+	// the mapped sliders that weren't on the board when it was last checked, see slider_warnings.go
+	loggedOrphanedSliders string
+
+	sliderMoveConsumers  []chan SliderMoveEvent
+	sliderValueConsumers []chan []float32
+	buttonPressConsumers []chan ButtonPressEvent
+	rawLineConsumers     []chan RawSerialLine
+
+	// wakes up the goroutine that writes back to the boards, see serial_feedback.go
+	feedbackKick chan bool
 }
 
 // SliderMoveEvent represents a single slider move captured by deej
@@ -41,100 +56,316 @@ type SliderMoveEvent struct {
 	PercentValue float32
 }
 
-var expectedLinePattern = regexp.MustCompile(`^\d{1,4}(\|\d{1,4})*\r\n$`)
+// ButtonPressEvent represents a single (debounced) button press captured by deej
+type ButtonPressEvent struct {
+	ButtonID int
+
+	// only ever set with buttons.long_press configured, see button_press.go
+	LongPress bool
+}
+
+const (
+
+	// when configured as the com port, deej will look for the arduino on its own
+	autoDetectCOMPort = "auto"
+
+	// how long a candidate port gets to send a valid line during auto-detection
+	autoDetectProbeTimeout = 3 * time.Second
+
+	// bounds for the exponential backoff used when trying to (re)connect to a board that isn't there
+	minReconnectDelay = 1 * time.Second
+	maxReconnectDelay = 30 * time.Second
+
+	// how long a connection gets to close before it's replaced on config reload
+	serialStopDelay = 50 * time.Millisecond
+)
+
+// slider values, optionally followed by a semicolon and button states (i.e. "1023|512|0;0|1"). values can also
+// be separated by commas instead of pipes. this only tells deej lines apart from garbage, frameParser does the rest
+var expectedLinePattern = regexp.MustCompile(`^\d{1,4}([|,]\d{1,4})*(;[01]([|,][01])*)?\r?\n$`)
+
+var errNoCOMPortDetected = errors.New("serial: no port sending valid slider data was detected")
+
+var errInvalidBaudRate = errors.New("invalid baud rate")
+
+// the rates sketches (and the arduino serial monitor) commonly use. anything else is far more likely to be a typo
+// than a board that actually talks at it, and would only have deej read garbage
+var standardBaudRates = []int{
+	300, 600, 1200, 2400, 4800, 9600, 14400, 19200, 28800, 31250, 38400, 57600, 74880,
+	115200, 230400, 250000, 460800, 500000, 921600, 1000000, 2000000,
+}
+
+// when auto-detecting, every port is probed at the configured baud rate first, and then at these.
+// each one costs a probe timeout per port, so only the most common ones are tried
+var autoDetectFallbackBaudRates = []int{9600, 115200}
+
+func validBaudRate(baudRate int) bool {
+	for _, standard := range standardBaudRates {
+		if baudRate == standard {
+			return true
+		}
+	}
+
+	return false
+}
+
+func invalidBaudRateError(baudRate int) error {
+	return fmt.Errorf("%w %d, it should be a standard one that matches what your sketch passes to Serial.begin (i.e. 9600 or 115200)",
+		errInvalidBaudRate, baudRate)
+}
 
 // NewSerialIO creates a SerialIO instance that uses the provided deej
-// instance's connection info to establish communications with the arduino chip
+// instance's connection info to establish communications with the arduino chips
 func NewSerialIO(deej *Deej, logger *zap.SugaredLogger) (*SerialIO, error) {
 	logger = logger.Named("serial")
 
 	sio := &SerialIO{
-		deej:                deej,
-		logger:              logger,
-		stopChannel:         make(chan bool),
-		connected:           false,
-		conn:                nil,
-		sliderMoveConsumers: []chan SliderMoveEvent{},
+		deej:                 deej,
+		logger:               logger,
+		sliderMoveConsumers:  []chan SliderMoveEvent{},
+		sliderValueConsumers: []chan []float32{},
 	}
 
 	logger.Debug("Created serial i/o instance")
 
 	// respond to config changes
 	sio.setupOnConfigReload()
+	sio.setupSerialFeedback()
 
 	return sio, nil
 }
 
-// Start attempts to connect to our arduino chip
+// Start attempts to connect to every configured board. only the main board's error is returned - the others
+// are retried in the background until they show up
 func (sio *SerialIO) Start() error {
 
-	// don't allow multiple concurrent connections
-	if sio.connected {
-		sio.logger.Warn("Already connected, can't start another without closing first")
-		return errors.New("serial: connection already active")
-	}
+	// fixed sliders don't need the board to be there
+	sio.applyFixedValues()
 
-	// set minimum read size according to platform (0 for windows, 1 for linux)
-	// this prevents a rare bug on windows where serial reads get congested,
-	// resulting in significant lag
-	minimumReadSize := 0
-	if util.Linux() {
-		minimumReadSize = 1
-	}
+	sources := sio.deej.config.SerialSources
+	connections := make([]*serialConnection, len(sources))
 
-	sio.connOptions = serial.OpenOptions{
-		PortName:        sio.deej.config.ConnectionInfo.COMPort,
-		BaudRate:        uint(sio.deej.config.ConnectionInfo.BaudRate),
-		DataBits:        8,
-		StopBits:        1,
-		MinimumReadSize: uint(minimumReadSize),
+	for idx, source := range sources {
+		connections[idx] = newSerialConnection(sio, source, idx == 0, serialIndexLimit(sources, source))
 	}
 
-	sio.logger.Debugw("Attempting serial connection",
-		"comPort", sio.connOptions.PortName,
-		"baudRate", sio.connOptions.BaudRate,
-		"minReadSize", minimumReadSize)
+	sio.connectionsLock.Lock()
+	sio.connections = connections
+	sio.connectionsLock.Unlock()
 
 	var err error
-	sio.conn, err = serial.Open(sio.connOptions)
+
+	for _, connection := range connections {
+		connectionErr := sio.startConnection(connection)
+
+		if connection.main {
+			err = connectionErr
+		}
+	}
+
+	return err
+}
+
+func (sio *SerialIO) startConnection(connection *serialConnection) error {
+	err := connection.start()
+
+	// not reaching a board yet isn't fatal - it might just not be plugged in (or not be found, when
+	// auto-detecting), so deej keeps trying to reach it until it's there. the main board's error is
+	// still returned, so the user can be told about it
 	if err != nil {
+		connection.startReconnecting()
+	}
+
+	return err
+}
+
+// Stop signals us to shut down our serial connections, if any are active
+func (sio *SerialIO) Stop() {
+	for _, connection := range sio.currentConnections() {
+		connection.stop()
+	}
+}
+
+// Restart closes every connection and connects to the configured boards all over again, i.e. after the board
+// was moved to a different port. like Start, only the main board's error is returned, but this keeps trying
+// to reach it in the background either way
+func (sio *SerialIO) Restart() error {
+	sio.renewLock.Lock()
+	defer sio.renewLock.Unlock()
+
+	sio.logger.Info("Restarting serial connections")
+
+	sio.Stop()
+
+	// let the connections close
+	<-time.After(serialStopDelay)
+
+	return sio.Start()
+}
+
+// currentConnections returns a copy of the connections, since config reloads may replace them
+func (sio *SerialIO) currentConnections() []*serialConnection {
+	sio.connectionsLock.Lock()
+	defer sio.connectionsLock.Unlock()
+
+	return append([]*serialConnection{}, sio.connections...)
+}
 
-		// might need a user notification here, TBD
-		sio.logger.Warnw("Failed to open serial connection", "error", err)
-		return fmt.Errorf("open serial connection: %w", err)
+// mainConnection returns the connection to the main board, nil before Start
+func (sio *SerialIO) mainConnection() *serialConnection {
+	sio.connectionsLock.Lock()
+	defer sio.connectionsLock.Unlock()
+
+	if len(sio.connections) == 0 {
+		return nil
 	}
 
-	namedLogger := sio.logger.Named(strings.ToLower(sio.connOptions.PortName))
+	return sio.connections[0]
+}
 
-	namedLogger.Infow("Connected", "conn", sio.conn)
-	sio.connected = true
+// portTaken returns whether a port is configured for a board other than the given one
+func (sio *SerialIO) portTaken(connection *serialConnection, port string) bool {
+	for _, other := range sio.currentConnections() {
+		if other != connection && strings.EqualFold(other.source.COMPort, port) {
+			return true
+		}
+	}
 
-	// read lines or await a stop
-	go func() {
-		connReader := bufio.NewReader(sio.conn)
-		lineChannel := sio.readLine(namedLogger, connReader)
+	return false
+}
 
-		for {
-			select {
-			case <-sio.stopChannel:
-				sio.close(namedLogger)
-			case line := <-lineChannel:
-				sio.handleLine(namedLogger, line)
+// Connected returns true if deej currently has an open serial connection to every board
+func (sio *SerialIO) Connected() bool {
+	connections := sio.currentConnections()
+
+	for _, connection := range connections {
+		if !connection.isConnected() {
+			return false
+		}
+	}
+
+	return len(connections) > 0
+}
+
+// DisconnectedFor returns how long deej has been without a serial connection to one of the boards (the one that's
+// been down the longest), or 0 while it's connected to all of them
+func (sio *SerialIO) DisconnectedFor() time.Duration {
+	var longest time.Duration
+
+	for _, connection := range sio.currentConnections() {
+		if disconnectedFor := connection.disconnectedFor(); disconnectedFor > longest {
+			longest = disconnectedFor
+		}
+	}
+
+	return longest
+}
+
+// FrameFormat returns a description of the line format the main board was detected to send (empty if it wasn't yet),
+// and how many of its lines were dropped for being malformed since deej started
+func (sio *SerialIO) FrameFormat() (string, uint64) {
+	connection := sio.mainConnection()
+	if connection == nil {
+		return "", 0
+	}
+
+	return connection.frameFormat()
+}
+
+// WaitForFrame waits up to the given timeout for every connected board to send its next valid line, and returns
+// whether they all did. it only watches what the reading goroutines parse, so it never competes with them for ports
+func (sio *SerialIO) WaitForFrame(timeout time.Duration) bool {
+	const pollInterval = 20 * time.Millisecond
+
+	connections := []*serialConnection{}
+	startingCounts := []uint64{}
+
+	for _, connection := range sio.currentConnections() {
+		if connection.isConnected() {
+			connections = append(connections, connection)
+			startingCounts = append(startingCounts, connection.frameParser.frameCount())
+		}
+	}
+
+	allReceived := func() bool {
+		for idx, connection := range connections {
+			if connection.frameParser.frameCount() == startingCounts[idx] {
+				return false
 			}
 		}
-	}()
 
-	return nil
+		return len(connections) > 0
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	for time.Now().Before(deadline) {
+		if allReceived() {
+			return true
+		}
+
+		<-time.After(pollInterval)
+	}
+
+	return allReceived()
 }
 
-// Stop signals us to shut down our serial connection, if one is active
-func (sio *SerialIO) Stop() {
-	if sio.connected {
-		sio.logger.Debug("Shutting down serial connection")
-		sio.stopChannel <- true
-	} else {
-		sio.logger.Debug("Not currently connected, nothing to stop")
+// HardwareSliderCount returns how many sliders the boards have, going by the lines they send. with more than
+// one board, that's up to the highest slider index any of them uses. it's 0 while there's no board connected,
+// and a newly connected board's sliders only count once it has sent its first valid line
+func (sio *SerialIO) HardwareSliderCount() int {
+	connections := sio.currentConnections()
+
+	sio.sliderLock.Lock()
+	defer sio.sliderLock.Unlock()
+
+	count := 0
+
+	for _, connection := range connections {
+		if connection.lastKnownNumSliders > 0 && connection.source.IndexOffset+connection.lastKnownNumSliders > count {
+			count = connection.source.IndexOffset + connection.lastKnownNumSliders
+		}
+	}
+
+	return count
+}
+
+// ActivePort returns the name of the serial port deej is currently connected to the main board on, or an empty
+// string. when auto-detection is used, this is the port that was actually detected
+func (sio *SerialIO) ActivePort() string {
+	connection := sio.mainConnection()
+	if connection == nil {
+		return ""
 	}
+
+	return connection.activePort()
+}
+
+// Sources returns the state of the connection to each board, the main one first
+func (sio *SerialIO) Sources() []SerialSourceStatus {
+	connections := sio.currentConnections()
+	statuses := make([]SerialSourceStatus, len(connections))
+
+	sio.sliderLock.Lock()
+	defer sio.sliderLock.Unlock()
+
+	for idx, connection := range connections {
+		format, malformedFrames := connection.frameFormat()
+
+		statuses[idx] = SerialSourceStatus{
+			COMPort:         connection.source.COMPort,
+			ActivePort:      connection.activePort(),
+			BaudRate:        connection.activeBaudRate(),
+			IndexOffset:     connection.source.IndexOffset,
+			Connected:       connection.isConnected(),
+			DisconnectedFor: int64(connection.disconnectedFor() / time.Second),
+			SliderCount:     connection.lastKnownNumSliders,
+			FrameFormat:     format,
+			MalformedFrames: malformedFrames,
+		}
+	}
+
+	return statuses
 }
 
 // SubscribeToSliderMoveEvents returns an unbuffered channel that receives
@@ -146,156 +377,211 @@ func (sio *SerialIO) SubscribeToSliderMoveEvents() chan SliderMoveEvent {
 	return ch
 }
 
+// SubscribeToButtonPressEvents returns an unbuffered channel that receives
+// a ButtonPressEvent struct every time a button is pressed
+func (sio *SerialIO) SubscribeToButtonPressEvents() chan ButtonPressEvent {
+	ch := make(chan ButtonPressEvent)
+	sio.buttonPressConsumers = append(sio.buttonPressConsumers, ch)
+
+	return ch
+}
+
+// SubscribeToSliderValues returns an unbuffered channel that receives the normalized
+// values of all sliders every time a full line is parsed, regardless of whether any slider moved
+func (sio *SerialIO) SubscribeToSliderValues() chan []float32 {
+	ch := make(chan []float32)
+	sio.sliderValueConsumers = append(sio.sliderValueConsumers, ch)
+
+	return ch
+}
+
 func (sio *SerialIO) setupOnConfigReload() {
 	configReloadedChannel := sio.deej.config.SubscribeToChanges()
 
-	const stopDelay = 50 * time.Millisecond
-
 	go func() {
 		for {
 			select {
 			case <-configReloadedChannel:
 
-				// make any config reload unset our slider number to ensure process volumes are being re-set
-				// (the next read line will emit SliderMoveEvent instances for all sliders)\
+				// make any config reload unset our slider numbers to ensure process volumes are being re-set
+				// (the next read lines will emit SliderMoveEvent instances for all sliders)\
 				// this needs to happen after a small delay, because the session map will also re-acquire sessions
 				// whenever the config file is reloaded, and we don't want it to receive these move events while the map
 				// is still cleared. this is kind of ugly, but shouldn't cause any issues
 				go func() {
-					<-time.After(stopDelay)
-					sio.lastKnownNumSliders = 0
-				}()
+					<-time.After(serialStopDelay)
 
-				// if connection params have changed, attempt to stop and start the connection
-				if sio.deej.config.ConnectionInfo.COMPort != sio.connOptions.PortName ||
-					uint(sio.deej.config.ConnectionInfo.BaudRate) != sio.connOptions.BaudRate {
+					connections := sio.currentConnections()
 
-					sio.logger.Info("Detected change in connection parameters, attempting to renew connection")
-					sio.Stop()
+					sio.sliderLock.Lock()
+					for _, connection := range connections {
+						connection.lastKnownNumSliders = 0
+					}
+					sio.sliderLock.Unlock()
 
-					// let the connection close
-					<-time.After(stopDelay)
+					// the board won't send these, and fixed values could've been set or changed
+					sio.applyFixedValues()
+				}()
 
-					if err := sio.Start(); err != nil {
-						sio.logger.Warnw("Failed to renew connection after parameter change", "error", err)
-					} else {
-						sio.logger.Debug("Renewed connection successfully")
-					}
-				}
+				sio.renewConnections()
+
+				// the mapping (or what's sent back) might've changed
+				sio.kickSerialFeedback()
 			}
 		}
 	}()
 }
 
-func (sio *SerialIO) close(logger *zap.SugaredLogger) {
-	if err := sio.conn.Close(); err != nil {
-		logger.Warnw("Failed to close serial connection", "error", err)
-	} else {
-		logger.Debug("Serial connection closed")
+// renewConnections stops and starts the connections to boards whose settings changed, and to boards that were
+// added or removed. this compares against the configured ports rather than the connected ones,
+// which may have been auto-detected
+func (sio *SerialIO) renewConnections() {
+	sio.renewLock.Lock()
+	defer sio.renewLock.Unlock()
+
+	sources := sio.deej.config.SerialSources
+	previous := sio.currentConnections()
+
+	// not started yet, Start will pick up the new config on its own
+	if len(previous) == 0 {
+		return
 	}
 
-	sio.conn = nil
-	sio.connected = false
-}
+	connections := make([]*serialConnection, len(sources))
+	stale := []*serialConnection{}
+	fresh := []*serialConnection{}
 
-func (sio *SerialIO) readLine(logger *zap.SugaredLogger, reader *bufio.Reader) chan string {
-	ch := make(chan string)
+	for idx, source := range sources {
+		indexLimit := serialIndexLimit(sources, source)
 
-	go func() {
-		for {
-			line, err := reader.ReadString('\n')
-			if err != nil {
+		if idx < len(previous) && previous[idx].source == source && previous[idx].indexLimit == indexLimit {
+			connections[idx] = previous[idx]
+			continue
+		}
 
-				if sio.deej.Verbose() {
-					logger.Warnw("Failed to read line from serial", "error", err, "line", line)
-				}
+		if idx < len(previous) {
+			stale = append(stale, previous[idx])
+		}
 
-				// just ignore the line, the read loop will stop after this
-				return
-			}
+		connections[idx] = newSerialConnection(sio, source, idx == 0, indexLimit)
+		fresh = append(fresh, connections[idx])
+	}
 
-			if sio.deej.Verbose() {
-				logger.Debugw("Read new line", "line", line)
-			}
+	if len(previous) > len(sources) {
+		stale = append(stale, previous[len(sources):]...)
+	}
 
-			// deliver the line to the channel
-			ch <- line
-		}
-	}()
+	if len(stale) == 0 && len(fresh) == 0 {
+		return
+	}
 
-	return ch
+	sio.logger.Infow("Detected change in connection parameters, attempting to renew connections",
+		"stopping", len(stale),
+		"starting", len(fresh))
+
+	for _, connection := range stale {
+		connection.stop()
+	}
+
+	// let the connections close
+	<-time.After(serialStopDelay)
+
+	sio.connectionsLock.Lock()
+	sio.connections = connections
+	sio.connectionsLock.Unlock()
+
+	for _, connection := range fresh {
+		if err := sio.startConnection(connection); err != nil {
+			sio.logger.Warnw("Failed to renew connection after parameter change",
+				"comPort", connection.source.COMPort,
+				"error", err)
+		} else {
+			sio.logger.Debugw("Renewed connection successfully", "comPort", connection.source.COMPort)
+		}
+	}
 }
 
-func (sio *SerialIO) handleLine(logger *zap.SugaredLogger, line string) {
+// SetSliderValue moves a slider from an input other than the board (such as OSC), sending it through the same
+// path as a physical slider move. the slider doesn't have to exist on the board, and whichever input moved
+// a slider last wins, until the other one moves it again
+func (sio *SerialIO) SetSliderValue(sliderIdx int, value float32) {
+	if _, fixed := sio.deej.config.SliderFixedValue(sliderIdx); fixed {
+		if sio.deej.Verbose() {
+			sio.logger.Debugw("Ignoring virtual move of fixed slider", "sliderID", sliderIdx)
+		}
 
-	// this function receives an unsanitized line which is guaranteed to end with LF,
-	// but most lines will end with CRLF. it may also have garbage instead of
-	// deej-formatted values, so we must check for that! just ignore bad ones
-	if !expectedLinePattern.MatchString(line) {
 		return
 	}
 
-	// trim the suffix
-	line = strings.TrimSuffix(line, "\r\n")
+	normalizedScalar := util.NormalizeScalar(value)
 
-	// split on pipe (|), this gives a slice of numerical strings between "0" and "1023"
-	splitLine := strings.Split(line, "|")
-	numSliders := len(splitLine)
+	sio.sliderLock.Lock()
 
-	// update our slider count, if needed - this will send slider move events for all
-	if numSliders != sio.lastKnownNumSliders {
-		logger.Infow("Detected sliders", "amount", numSliders)
-		sio.lastKnownNumSliders = numSliders
-		sio.currentSliderPercentValues = make([]float32, numSliders)
+	sio.growSliderValues(sliderIdx + 1)
 
-		// reset everything to be an impossible value to force the slider move event later
-		for idx := range sio.currentSliderPercentValues {
-			sio.currentSliderPercentValues[idx] = -1.0
-		}
+	if sio.currentSliderPercentValues[sliderIdx] == normalizedScalar {
+		sio.sliderLock.Unlock()
+		return
 	}
 
-	// for each slider:
-	moveEvents := []SliderMoveEvent{}
-	for sliderIdx, stringValue := range splitLine {
+	sio.currentSliderPercentValues[sliderIdx] = normalizedScalar
+	sio.markSliderMoved(sliderIdx, time.Now())
 
-		// convert string values to integers ("1023" -> 1023)
-		number, _ := strconv.Atoi(stringValue)
+	values := sio.sliderValues()
+	sio.sliderLock.Unlock()
 
-		// turns out the first line could come out dirty sometimes (i.e. "4558|925|41|643|220")
-		// so let's check the first number for correctness just in case
-		if sliderIdx == 0 && number > 1023 {
-			sio.logger.Debugw("Got malformed line from serial, ignoring", "line", line)
-			return
-		}
+	moveEvent := SliderMoveEvent{
+		SliderID:     sliderIdx,
+		PercentValue: normalizedScalar,
+	}
 
-		// map the value from raw to a "dirty" float between 0 and 1 (e.g. 0.15451...)
-		dirtyFloat := float32(number) / 1023.0
+	if sio.deej.Verbose() {
+		sio.logger.Debugw("Virtual slider moved", "event", moveEvent)
+	}
 
-		// normalize it to an actual volume scalar between 0.0 and 1.0 with 2 points of precision
-		normalizedScalar := util.NormalizeScalar(dirtyFloat)
+	sio.deliverSliderMoves([]SliderMoveEvent{moveEvent}, values)
+}
 
-		// if sliders are inverted, take the complement of 1.0
-		if sio.deej.config.InvertSliders {
-			normalizedScalar = 1 - normalizedScalar
-		}
+// growSliderValues makes room for at least numSliders current values, keeping the existing ones.
+// must be called with the slider lock held
+func (sio *SerialIO) growSliderValues(numSliders int) {
+	for len(sio.currentSliderPercentValues) < numSliders {
+		sio.currentSliderPercentValues = append(sio.currentSliderPercentValues, -1.0)
+	}
+}
 
-		// check if it changes the desired state (could just be a jumpy raw slider value)
-		if util.SignificantlyDifferent(sio.currentSliderPercentValues[sliderIdx], normalizedScalar, sio.deej.config.NoiseReductionLevel) {
+// KnownSliderValues returns the current value of every slider that has one, by index
+func (sio *SerialIO) KnownSliderValues() map[int]float32 {
+	sio.sliderLock.Lock()
+	defer sio.sliderLock.Unlock()
 
-			// if it does, update the saved value and create a move event
-			sio.currentSliderPercentValues[sliderIdx] = normalizedScalar
+	values := make(map[int]float32)
 
-			moveEvents = append(moveEvents, SliderMoveEvent{
-				SliderID:     sliderIdx,
-				PercentValue: normalizedScalar,
-			})
+	for idx, value := range sio.currentSliderPercentValues {
+		if value >= 0 {
+			values[idx] = value
+		}
+	}
 
-			if sio.deej.Verbose() {
-				logger.Debugw("Slider moved", "event", moveEvents[len(moveEvents)-1])
-			}
+	return values
+}
+
+// sliderValues returns a copy of all current slider values, reporting sliders that were never set as zero.
+// must be called with the slider lock held
+func (sio *SerialIO) sliderValues() []float32 {
+	values := make([]float32, len(sio.currentSliderPercentValues))
+
+	for idx, value := range sio.currentSliderPercentValues {
+		if value >= 0 {
+			values[idx] = value
 		}
 	}
 
+	return values
+}
+
+func (sio *SerialIO) deliverSliderMoves(moveEvents []SliderMoveEvent, values []float32) {
+
 	// deliver move events if there are any, towards all potential consumers
 	if len(moveEvents) > 0 {
 		for _, consumer := range sio.sliderMoveConsumers {
@@ -304,4 +590,22 @@ func (sio *SerialIO) handleLine(logger *zap.SugaredLogger, line string) {
 			}
 		}
 	}
+
+	// deliver the full set of values for this line to anyone interested in every frame
+	for _, consumer := range sio.sliderValueConsumers {
+		consumer <- values
+	}
+
+	if len(moveEvents) > 0 {
+		sio.kickSerialFeedback()
+	}
+}
+
+// cutLine splits a line around the first instance of sep, like strings.Cut does in newer versions of go
+func cutLine(line string, sep string) (string, string, bool) {
+	if idx := strings.Index(line, sep); idx >= 0 {
+		return line[:idx], line[idx+len(sep):], true
+	}
+
+	return line, "", false
 }
@@ -0,0 +1,742 @@
+package deej
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jacobsa/go-serial/serial"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/omriharel/deej/pkg/deej/util"
+)
+
+// SerialSource is a board deej reads from. the main one is configured by com_port and baud_rate, and any others
+// are listed under serial_sources, each with an offset that's added to its slider and button indices:
+//
+//	serial_sources:
+//	  - com_port: COM5
+//	    baud_rate: 9600
+//	    index_offset: 5
+//
+// a board's sliders and buttons stop short of the next board's offset, so two boards never move the same slider
+// (or press the same button) - the ones that don't fit are ignored
+type SerialSource struct {
+	COMPort     string `json:"comPort" mapstructure:"com_port"`
+	BaudRate    int    `json:"baudRate" mapstructure:"baud_rate"`
+	IndexOffset int    `json:"indexOffset" mapstructure:"index_offset"`
+}
+
+// serialConnection reads from a single board, and reconnects to it on its own whenever it's lost
+type serialConnection struct {
+	sio    *SerialIO
+	logger *zap.SugaredLogger
+
+	source SerialSource
+
+	// the main board's errors are reported to the user, any other board is just retried until it shows up
+	main bool
+
+	// the first slider and button index that belongs to another board, 0 if none do
+	indexLimit int
+
+	stopChannel chan bool
+	conn        io.ReadWriteCloser
+
+	// the reading goroutine, the reconnecting one and the API all look at these, so they're guarded by stateLock
+	stateLock    sync.Mutex
+	connected    bool
+	reconnecting bool
+	connOptions  serial.OpenOptions
+
+	// feedback lines are written from their own goroutine, so conn can't be swapped out from under them.
+	// lastFeedback is the last line of each kind written since connecting, see serial_feedback.go
+	writeLock    sync.Mutex
+	lastFeedback map[byte]string
+
+	// when the connection was last lost, or when it was created if it was never up
+	disconnectedSince time.Time
+
+	// guarded by the SerialIO's slider lock
+	lastKnownNumSliders int
+
+	// the last value read for every slider on this board (by its own index). these are compared against rather
+	// than the current values, so a slider only takes over from a virtual input once it's actually moved
+	physicalSliderPercentValues []float32
+
+	// works out the board's line format, see serial_frame.go
+	frameParser frameParser
+
+	// nil until the first line with buttons has been seen, which only sets their baseline
+	buttons []*buttonTracker
+
+	// when a raw line was last sent to the raw line consumers, and how many were held back since, see serial_raw.go
+	lastRawLine     time.Time
+	skippedRawLines uint64
+}
+
+// SerialSourceStatus is the state of the connection to one of the boards, as reported by the API
+type SerialSourceStatus struct {
+
+	// as configured, may be "auto" for the main board. activePort is the port that's actually connected
+	COMPort     string `json:"comPort"`
+	ActivePort  string `json:"activePort"`
+	IndexOffset int    `json:"indexOffset"`
+
+	// the one the board is connected at, which auto-detection can find to differ from the configured one
+	BaudRate int `json:"baudRate"`
+
+	Connected bool `json:"connected"`
+
+	// in whole seconds, 0 while connected
+	DisconnectedFor int64 `json:"disconnectedFor"`
+
+	// how many of the board's sliders are in use (0 until it's sent its first valid line)
+	SliderCount int `json:"sliderCount"`
+
+	FrameFormat     string `json:"frameFormat"`
+	MalformedFrames uint64 `json:"malformedFrames"`
+}
+
+var errInvalidSerialSource = errors.New("invalid serial source")
+
+func (source *SerialSource) validate() error {
+	source.COMPort = strings.TrimSpace(source.COMPort)
+
+	if source.COMPort == "" {
+		return fmt.Errorf("%w: com_port can't be empty", errInvalidSerialSource)
+	}
+
+	// every board probing every port would have them fight over each other's, so only the main one can do that
+	if strings.EqualFold(source.COMPort, autoDetectCOMPort) {
+		return fmt.Errorf("%w: only the main com_port can be auto-detected", errInvalidSerialSource)
+	}
+
+	// leaving it out means the default
+	if source.BaudRate == 0 {
+		source.BaudRate = defaultBaudRate
+	}
+
+	if !validBaudRate(source.BaudRate) {
+		return fmt.Errorf("%w: %v", errInvalidSerialSource, invalidBaudRateError(source.BaudRate))
+	}
+
+	// the main board's indices start at 0
+	if source.IndexOffset <= 0 {
+		return fmt.Errorf("%w: index_offset must be at least 1, got %d", errInvalidSerialSource, source.IndexOffset)
+	}
+
+	return nil
+}
+
+// serialSourcesFromConfig returns every board to read from, starting with the main one
+func serialSourcesFromConfig(
+	userConfig *viper.Viper,
+	main SerialSource,
+	warnInvalidValue func(message string, key string, keysAndValues ...interface{}),
+) []SerialSource {
+	sources := []SerialSource{main}
+
+	var rawSources []SerialSource
+
+	if err := userConfig.UnmarshalKey(configKeySerialSources, &rawSources); err != nil {
+		warnInvalidValue("Invalid serial sources specified, ignoring all of them",
+			configKeySerialSources,
+			"error", err)
+
+		return sources
+	}
+
+	for idx, source := range rawSources {
+		if err := source.validate(); err != nil {
+			warnInvalidValue("Invalid serial source specified, ignoring it",
+				configKeySerialSources,
+				"index", idx,
+				"error", err)
+
+			continue
+		}
+
+		if duplicate, ok := duplicateSerialSource(sources, source); ok {
+			warnInvalidValue("Serial source uses the same port or index offset as another, ignoring it",
+				configKeySerialSources,
+				"index", idx,
+				"comPort", source.COMPort,
+				"indexOffset", source.IndexOffset,
+				"otherComPort", duplicate.COMPort)
+
+			continue
+		}
+
+		sources = append(sources, source)
+	}
+
+	return sources
+}
+
+func duplicateSerialSource(sources []SerialSource, source SerialSource) (SerialSource, bool) {
+	for _, other := range sources {
+		if strings.EqualFold(other.COMPort, source.COMPort) || other.IndexOffset == source.IndexOffset {
+			return other, true
+		}
+	}
+
+	return SerialSource{}, false
+}
+
+// serialIndexLimit returns the first index past a source's offset that another source starts at, 0 if there's none
+func serialIndexLimit(sources []SerialSource, source SerialSource) int {
+	limit := 0
+
+	for _, other := range sources {
+		if other.IndexOffset > source.IndexOffset && (limit == 0 || other.IndexOffset < limit) {
+			limit = other.IndexOffset
+		}
+	}
+
+	return limit
+}
+
+func newSerialConnection(sio *SerialIO, source SerialSource, main bool, indexLimit int) *serialConnection {
+	return &serialConnection{
+		sio:               sio,
+		logger:            sio.logger,
+		source:            source,
+		main:              main,
+		indexLimit:        indexLimit,
+		stopChannel:       make(chan bool),
+		disconnectedSince: time.Now(),
+	}
+}
+
+func (c *serialConnection) start() error {
+
+	// don't allow multiple concurrent connections
+	if c.isConnected() {
+		c.logger.Warn("Already connected, can't start another without closing first")
+		return errors.New("serial: connection already active")
+	}
+
+	portName := c.source.COMPort
+	baudRate := c.source.BaudRate
+
+	if portName == autoDetectCOMPort {
+		detectedPortName, detectedBaudRate, err := c.detectCOMPort()
+		if err != nil {
+			c.logger.Warnw("Failed to auto-detect serial port", "error", err)
+			return fmt.Errorf("auto-detect serial port: %w", err)
+		}
+
+		c.logger.Infow("Auto-detected serial port", "comPort", detectedPortName, "baudRate", detectedBaudRate)
+
+		if detectedBaudRate != baudRate {
+			c.logger.Warnw("Board was only found at a different baud rate than configured, consider changing baud_rate",
+				"configuredBaudRate", baudRate,
+				"detectedBaudRate", detectedBaudRate)
+		}
+
+		portName, baudRate = detectedPortName, detectedBaudRate
+	}
+
+	connOptions := c.openOptions(portName, baudRate)
+
+	c.logger.Debugw("Attempting serial connection",
+		"comPort", connOptions.PortName,
+		"baudRate", connOptions.BaudRate,
+		"minReadSize", connOptions.MinimumReadSize)
+
+	conn, err := serial.Open(connOptions)
+	if err != nil {
+
+		// might need a user notification here, TBD
+		c.logger.Warnw("Failed to open serial connection", "comPort", connOptions.PortName, "error", err)
+		return fmt.Errorf("open serial connection: %w", err)
+	}
+
+	c.stateLock.Lock()
+	c.connOptions = connOptions
+	c.stateLock.Unlock()
+
+	c.writeLock.Lock()
+	c.conn = conn
+	c.writeLock.Unlock()
+
+	namedLogger := c.logger.Named(strings.ToLower(connOptions.PortName))
+
+	namedLogger.Infow("Connected", "conn", conn, "baudRate", connOptions.BaudRate, "indexOffset", c.source.IndexOffset)
+
+	c.stateLock.Lock()
+	c.connected = true
+	c.stateLock.Unlock()
+
+	// read lines or await a stop
+	go func() {
+		connReader := bufio.NewReader(c.conn)
+		lineChannel := c.readLine(namedLogger, connReader)
+
+		for {
+			select {
+			case <-c.stopChannel:
+				c.close(namedLogger)
+				return
+			case line, ok := <-lineChannel:
+
+				// the line reader gave up, which means the board was most likely unplugged
+				if !ok {
+					namedLogger.Warn("Lost serial connection, will attempt to reconnect")
+					c.close(namedLogger)
+					c.startReconnecting()
+
+					return
+				}
+
+				c.handleLine(namedLogger, line)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// stop shuts down the connection, if it's active
+func (c *serialConnection) stop() {
+
+	c.stateLock.Lock()
+
+	// an explicit stop also means we shouldn't come back on our own
+	c.reconnecting = false
+	connected := c.connected
+
+	c.stateLock.Unlock()
+
+	if connected {
+		c.logger.Debugw("Shutting down serial connection", "comPort", c.activePort())
+		c.stopChannel <- true
+	} else {
+		c.logger.Debugw("Not currently connected, nothing to stop", "comPort", c.source.COMPort)
+	}
+}
+
+func (c *serialConnection) isConnected() bool {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	return c.connected
+}
+
+func (c *serialConnection) disconnectedFor() time.Duration {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	if c.connected {
+		return 0
+	}
+
+	return time.Since(c.disconnectedSince)
+}
+
+func (c *serialConnection) activePort() string {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	if !c.connected {
+		return ""
+	}
+
+	return c.connOptions.PortName
+}
+
+// activeBaudRate returns the baud rate the board is connected at, or the configured one while it isn't
+func (c *serialConnection) activeBaudRate() int {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	if !c.connected {
+		return c.source.BaudRate
+	}
+
+	return int(c.connOptions.BaudRate)
+}
+
+func (c *serialConnection) frameFormat() (string, uint64) {
+	format, detected, malformedFrames := c.frameParser.stats()
+	if !detected {
+		return "", malformedFrames
+	}
+
+	return format.String(), malformedFrames
+}
+
+// fitting returns how many of the board's sliders (or buttons) fit before the next board's indices start
+func (c *serialConnection) fitting(amount int) int {
+	if c.indexLimit > 0 && c.source.IndexOffset+amount > c.indexLimit {
+		return c.indexLimit - c.source.IndexOffset
+	}
+
+	return amount
+}
+
+func (c *serialConnection) openOptions(portName string, baudRate int) serial.OpenOptions {
+
+	// set minimum read size according to platform (0 for windows, 1 for linux and macos)
+	// this prevents a rare bug on windows where serial reads get congested,
+	// resulting in significant lag
+	minimumReadSize := 0
+	if util.Linux() || util.MacOS() {
+		minimumReadSize = 1
+	}
+
+	return serial.OpenOptions{
+		PortName:        portName,
+		BaudRate:        uint(baudRate),
+		DataBits:        8,
+		StopBits:        1,
+		MinimumReadSize: uint(minimumReadSize),
+	}
+}
+
+// detectCOMPort goes over all serial ports on this machine and returns the first one that sends a valid deej
+// line within a reasonable amount of time, along with the baud rate it did at. every port gets a go at the
+// configured baud rate before any other is tried. ports of other boards are left alone
+func (c *serialConnection) detectCOMPort() (string, int, error) {
+	ports, err := util.ListSerialPorts()
+	if err != nil {
+		return "", 0, fmt.Errorf("list serial ports: %w", err)
+	}
+
+	candidates := []string{}
+
+	for _, port := range ports {
+		if !c.sio.portTaken(c, port) {
+			candidates = append(candidates, port)
+		}
+	}
+
+	baudRates := []int{c.source.BaudRate}
+	for _, baudRate := range autoDetectFallbackBaudRates {
+		if baudRate != c.source.BaudRate {
+			baudRates = append(baudRates, baudRate)
+		}
+	}
+
+	c.logger.Debugw("Probing serial ports", "candidates", candidates, "baudRates", baudRates)
+
+	for _, baudRate := range baudRates {
+		for _, candidate := range candidates {
+			if c.probeCOMPort(candidate, baudRate) {
+				return candidate, baudRate, nil
+			}
+		}
+	}
+
+	return "", 0, errNoCOMPortDetected
+}
+
+func (c *serialConnection) probeCOMPort(portName string, baudRate int) bool {
+	conn, err := serial.Open(c.openOptions(portName, baudRate))
+	if err != nil {
+		c.logger.Debugw("Failed to open serial port for probing", "comPort", portName, "error", err)
+		return false
+	}
+
+	// closing the port also unblocks the reading goroutine below if we time out
+	defer conn.Close()
+
+	foundValidLine := make(chan bool, 1)
+
+	go func() {
+		reader := bufio.NewReader(conn)
+
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				foundValidLine <- false
+				return
+			}
+
+			if expectedLinePattern.MatchString(line) {
+				foundValidLine <- true
+				return
+			}
+		}
+	}()
+
+	select {
+	case found := <-foundValidLine:
+		c.logger.Debugw("Probed serial port", "comPort", portName, "baudRate", baudRate, "valid", found)
+		return found
+	case <-time.After(autoDetectProbeTimeout):
+		c.logger.Debugw("Timed out probing serial port", "comPort", portName, "baudRate", baudRate)
+		return false
+	}
+}
+
+func (c *serialConnection) startReconnecting() {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	if c.reconnecting {
+		return
+	}
+
+	c.reconnecting = true
+	go c.reconnect()
+}
+
+// reconnect keeps trying to establish the serial connection with an exponential backoff,
+// until it succeeds or we're explicitly stopped
+func (c *serialConnection) reconnect() {
+	defer func() {
+		c.stateLock.Lock()
+		c.reconnecting = false
+		c.stateLock.Unlock()
+	}()
+
+	delay := minReconnectDelay
+
+	for attempt := 1; ; attempt++ {
+		<-time.After(delay)
+
+		// we might've been stopped, or reconnected by someone else in the meantime
+		c.stateLock.Lock()
+		done := !c.reconnecting || c.connected
+		c.stateLock.Unlock()
+
+		if done {
+			return
+		}
+
+		c.logger.Debugw("Attempting to reconnect", "comPort", c.source.COMPort, "attempt", attempt, "delay", delay)
+
+		if err := c.start(); err == nil {
+			c.logger.Infow("Reconnected successfully", "comPort", c.activePort(), "attempts", attempt)
+			return
+		}
+
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+func (c *serialConnection) close(logger *zap.SugaredLogger) {
+
+	// closed before taking the write lock, since closing is also what gets a stuck write to give up
+	if err := c.conn.Close(); err != nil {
+		logger.Warnw("Failed to close serial connection", "error", err)
+	} else {
+		logger.Debug("Serial connection closed")
+	}
+
+	c.writeLock.Lock()
+	c.conn = nil
+	c.lastFeedback = nil
+	c.writeLock.Unlock()
+
+	c.stateLock.Lock()
+	c.connected = false
+	c.disconnectedSince = time.Now()
+	c.stateLock.Unlock()
+
+	// whenever we connect again (possibly to a different board), all sliders should be re-sent
+	c.sio.sliderLock.Lock()
+	c.lastKnownNumSliders = 0
+	c.sio.sliderLock.Unlock()
+	c.buttons = nil
+	c.frameParser.reset()
+}
+
+func (c *serialConnection) readLine(logger *zap.SugaredLogger, reader *bufio.Reader) chan string {
+	ch := make(chan string)
+
+	go func() {
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+
+				if c.sio.deej.Verbose() {
+					logger.Warnw("Failed to read line from serial", "error", err, "line", line)
+				}
+
+				// just ignore the line, closing the channel lets the read loop know we're done
+				close(ch)
+				return
+			}
+
+			if c.sio.deej.Verbose() {
+				logger.Debugw("Read new line", "line", line)
+			}
+
+			// deliver the line to the channel
+			ch <- line
+		}
+	}()
+
+	return ch
+}
+
+func (c *serialConnection) handleLine(logger *zap.SugaredLogger, line string) {
+
+	// this function receives an unsanitized line which is guaranteed to end with LF,
+	// but most lines will end with CRLF. it may also have garbage instead of
+	// deej-formatted values, which the parser counts and we just ignore
+	frame, formatChanged, err := c.frameParser.parse(line)
+	c.publishRawLine(line, frame, err)
+
+	if err != nil {
+		if errors.Is(err, errMalformedFrame) {
+			logger.Debugw("Got malformed line from serial, ignoring", "line", line, "error", err)
+		}
+
+		return
+	}
+
+	if formatChanged {
+		format, _, _ := c.frameParser.stats()
+		logger.Infow("Detected serial frame format", "format", format.String())
+	}
+
+	sio := c.sio
+	offset := c.source.IndexOffset
+	numSliders := c.fitting(len(frame.sliders))
+	detected := false
+
+	sio.sliderLock.Lock()
+
+	// update our slider count, if needed - this will send slider move events for all
+	if numSliders != c.lastKnownNumSliders {
+		logger.Infow("Detected sliders", "amount", len(frame.sliders), "indexOffset", offset)
+
+		if numSliders < len(frame.sliders) {
+			logger.Warnw("Board has more sliders than fit before the next serial source's index_offset, ignoring the rest",
+				"used", numSliders,
+				"ignored", len(frame.sliders)-numSliders,
+				"nextIndexOffset", c.indexLimit)
+		}
+
+		c.lastKnownNumSliders = numSliders
+		c.physicalSliderPercentValues = make([]float32, numSliders)
+
+		// reset everything to be an impossible value to force the slider move event later
+		for idx := range c.physicalSliderPercentValues {
+			c.physicalSliderPercentValues[idx] = -1.0
+		}
+
+		sio.growSliderValues(offset + numSliders)
+		detected = true
+	}
+
+	// for each slider:
+	moveEvents := []SliderMoveEvent{}
+	for boardIdx, number := range frame.sliders[:numSliders] {
+		sliderIdx := offset + boardIdx
+
+		if recorder, ok := sio.calibrating[sliderIdx]; ok {
+			recorder.observe(number)
+		}
+
+		// a fixed slider stays at its value whatever the board says, see slider_fixed_value.go
+		if _, fixed := sio.deej.config.SliderFixedValue(sliderIdx); fixed {
+			continue
+		}
+
+		// map the value from its calibrated raw range to a "dirty" float between 0 and 1 (e.g. 0.15451...)
+		dirtyFloat := sio.deej.config.SliderCalibration(sliderIdx).apply(number)
+
+		// snap it to either end if it's within the slider's dead zone there (see dead_zone.go)
+		dirtyFloat = applyDeadZone(dirtyFloat, sio.deej.config.SliderDeadZone(sliderIdx))
+
+		// normalize it to an actual volume scalar between 0.0 and 1.0 with 2 points of precision
+		normalizedScalar := util.NormalizeScalar(dirtyFloat)
+
+		// if this slider is inverted, take the complement of 1.0
+		if sio.deej.config.SliderInverted(sliderIdx) {
+			normalizedScalar = 1 - normalizedScalar
+		}
+
+		// check if it changes the desired state (could just be a jumpy raw slider value)
+		if util.SignificantlyDifferentByThreshold(c.physicalSliderPercentValues[boardIdx], normalizedScalar, sio.deej.config.SliderNoiseThreshold(sliderIdx)) {
+
+			// if it does, update the saved value and create a move event
+			c.physicalSliderPercentValues[boardIdx] = normalizedScalar
+			sio.currentSliderPercentValues[sliderIdx] = normalizedScalar
+			sio.markSliderMoved(sliderIdx, time.Now())
+
+			moveEvents = append(moveEvents, SliderMoveEvent{
+				SliderID:     sliderIdx,
+				PercentValue: normalizedScalar,
+			})
+
+			if sio.deej.Verbose() {
+				logger.Debugw("Slider moved", "event", moveEvents[len(moveEvents)-1])
+			}
+		}
+	}
+
+	values := sio.sliderValues()
+	sio.sliderLock.Unlock()
+
+	// the board's sliders (or the mapping, on config reload) changed, so they might not match anymore.
+	// it's also the first the board is heard from since connecting, so it gets its feedback lines again
+	if detected {
+		sio.warnOrphanedSliders()
+		sio.kickSerialFeedback()
+	}
+
+	sio.deliverSliderMoves(moveEvents, values)
+
+	if frame.buttons != nil {
+		c.handleButtons(logger, frame.buttons)
+	}
+}
+
+// handleButtons turns button states into press events, see button_press.go
+func (c *serialConnection) handleButtons(logger *zap.SugaredLogger, buttonStates []bool) {
+	now := time.Now()
+
+	numButtons := c.fitting(len(buttonStates))
+	buttonStates = buttonStates[:numButtons]
+
+	// a new amount of buttons means we can't compare to the previous states. take these as the new baseline
+	if len(buttonStates) != len(c.buttons) {
+		logger.Infow("Detected buttons", "amount", numButtons, "indexOffset", c.source.IndexOffset)
+
+		c.buttons = make([]*buttonTracker, len(buttonStates))
+		for buttonIdx, pressed := range buttonStates {
+			c.buttons[buttonIdx] = newButtonTracker(pressed, now)
+		}
+
+		return
+	}
+
+	settings := c.sio.deej.config.Buttons
+	pressEvents := []ButtonPressEvent{}
+
+	for buttonIdx, pressed := range buttonStates {
+		press := c.buttons[buttonIdx].update(pressed, now, settings)
+		if press == buttonPressNone {
+			continue
+		}
+
+		pressEvents = append(pressEvents, ButtonPressEvent{
+			ButtonID:  c.source.IndexOffset + buttonIdx,
+			LongPress: press == buttonPressLong,
+		})
+
+		if c.sio.deej.Verbose() {
+			logger.Debugw("Button pressed", "event", pressEvents[len(pressEvents)-1])
+		}
+	}
+
+	for _, consumer := range c.sio.buttonPressConsumers {
+		for _, pressEvent := range pressEvents {
+			consumer <- pressEvent
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package deej
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// with serial_feedback enabled, deej writes lines back to every board it's connected to, i.e. for a display that
+// shows which app each slider controls. each line starts with a letter that says what it is, followed by one
+// pipe-separated field per slider on that board (by the board's own index, so a board past index_offset still
+// starts at 0), and ends with a newline:
+//
+//	M|spotify.exe|discord.exe,chrome.exe|-|Game
+//	V|80|35|100|0
+//
+// M is what each slider controls: its label if it has one, otherwise its targets separated by commas, and "-"
+// when it isn't mapped. these are plain ASCII (anything else becomes "?"), never contain a pipe and are cut
+// short at serialFeedbackMaxLabel characters. V is each slider's volume, as a whole percent after its volume
+// curve. fields are never empty, so strtok splits them just fine.
+//
+// a line is only sent when it changed since the last time, once the board has sent its first line (so deej knows
+// how many sliders it has) and again whenever it's reconnected. writes are at least serial_feedback.interval
+// apart, and anything that changes in between goes out with the next one - only the latest state is ever sent
+
+const (
+	serialFeedbackMapping byte = 'M'
+	serialFeedbackVolumes byte = 'V'
+
+	// enough for most displays, and keeps lines short of what an arduino's serial buffer holds with a few sliders
+	serialFeedbackMaxLabel = 20
+
+	// in seconds
+	defaultSerialFeedbackInterval = 0.1
+	minSerialFeedbackInterval     = 0.02
+)
+
+// SerialFeedbackSettings decides whether and what deej writes back to the boards
+type SerialFeedbackSettings struct {
+	Enabled bool
+
+	// the least amount of time between two writes
+	Interval time.Duration
+
+	// which lines are sent
+	Mapping bool
+	Volumes bool
+}
+
+func serialFeedbackFromConfig(
+	userConfig *viper.Viper,
+	warnInvalidValue func(message string, key string, keysAndValues ...interface{}),
+) SerialFeedbackSettings {
+	settings := SerialFeedbackSettings{
+		Enabled: userConfig.GetBool(configKeyFeedbackEnabled),
+		Mapping: userConfig.GetBool(configKeyFeedbackMapping),
+		Volumes: userConfig.GetBool(configKeyFeedbackVolumes),
+	}
+
+	interval := userConfig.GetFloat64(configKeyFeedbackInterval)
+	if interval < minSerialFeedbackInterval {
+		warnInvalidValue("Serial feedback interval is too short, using the minimum instead",
+			configKeyFeedbackInterval,
+			"invalidValue", interval,
+			"minimumValue", minSerialFeedbackInterval)
+
+		interval = minSerialFeedbackInterval
+	}
+
+	settings.Interval = time.Duration(interval * float64(time.Second))
+
+	return settings
+}
+
+// setupSerialFeedback starts the goroutine that writes feedback lines. it's kicked whenever they might've
+// changed, and never more often than the configured interval
+func (sio *SerialIO) setupSerialFeedback() {
+	sio.feedbackKick = make(chan bool, 1)
+
+	go func() {
+		for range sio.feedbackKick {
+			settings := sio.deej.config.SerialFeedback
+			if !settings.Enabled {
+				continue
+			}
+
+			for _, connection := range sio.currentConnections() {
+				connection.writeFeedback(sio.feedbackLines(connection, settings))
+			}
+
+			// a kick that came while writing (or while waiting here) is picked up right after
+			<-time.After(settings.Interval)
+		}
+	}()
+}
+
+// kickSerialFeedback lets the feedback goroutine know there might be something new to send. it never blocks,
+// one pending kick is as good as several
+func (sio *SerialIO) kickSerialFeedback() {
+	if sio.feedbackKick == nil {
+		return
+	}
+
+	select {
+	case sio.feedbackKick <- true:
+	default:
+	}
+}
+
+// feedbackLines returns the lines a board should currently show, nil until it's sent a line of its own
+func (sio *SerialIO) feedbackLines(connection *serialConnection, settings SerialFeedbackSettings) []string {
+	sio.sliderLock.Lock()
+	numSliders := connection.lastKnownNumSliders
+	values := sio.sliderValues()
+	sio.sliderLock.Unlock()
+
+	if numSliders == 0 {
+		return nil
+	}
+
+	config := sio.deej.config
+	offset := connection.source.IndexOffset
+	lines := []string{}
+
+	if settings.Mapping {
+		mapping := config.sliderMapping()
+		labels := config.SliderLabels()
+
+		fields := make([]string, numSliders)
+		for boardIdx := range fields {
+			sliderIdx := offset + boardIdx
+
+			label, ok := labels[sliderIdx]
+			if !ok {
+				targets, _ := mapping.get(sliderIdx)
+				label = strings.Join(targets, ",")
+			}
+
+			fields[boardIdx] = serialFeedbackLabel(label)
+		}
+
+		lines = append(lines, serialFeedbackLine(serialFeedbackMapping, fields))
+	}
+
+	if settings.Volumes {
+		fields := make([]string, numSliders)
+		for boardIdx := range fields {
+			sliderIdx := offset + boardIdx
+
+			var volume float32
+			if sliderIdx < len(values) {
+				volume = config.SliderVolumeCurve(sliderIdx).apply(values[sliderIdx])
+			}
+
+			fields[boardIdx] = strconv.Itoa(int(math.Round(float64(volume) * 100)))
+		}
+
+		lines = append(lines, serialFeedbackLine(serialFeedbackVolumes, fields))
+	}
+
+	return lines
+}
+
+func serialFeedbackLine(kind byte, fields []string) string {
+	return string(kind) + "|" + strings.Join(fields, "|") + "\n"
+}
+
+// serialFeedbackLabel makes a label safe to send: printable ASCII only, no pipes, never empty and never too long
+func serialFeedbackLabel(label string) string {
+	var result strings.Builder
+
+	for _, char := range strings.TrimSpace(label) {
+		if result.Len() == serialFeedbackMaxLabel {
+			break
+		}
+
+		switch {
+		case char == '|':
+			result.WriteRune('/')
+		case char < ' ' || char > '~':
+			result.WriteRune('?')
+		default:
+			result.WriteRune(char)
+		}
+	}
+
+	if result.Len() == 0 {
+		return "-"
+	}
+
+	return result.String()
+}
+
+// writeFeedback writes whichever of the lines differ from what was last sent to this board
+func (c *serialConnection) writeFeedback(lines []string) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	if c.conn == nil {
+		return
+	}
+
+	if c.lastFeedback == nil {
+		c.lastFeedback = make(map[byte]string)
+	}
+
+	for _, line := range lines {
+		if c.lastFeedback[line[0]] == line {
+			continue
+		}
+
+		if _, err := c.conn.Write([]byte(line)); err != nil {
+			c.logger.Warnw("Failed to write feedback line to serial", "comPort", c.connOptions.PortName, "error", err)
+			return
+		}
+
+		c.lastFeedback[line[0]] = line
+
+		if c.sio.deej.Verbose() {
+			c.logger.Debugw("Wrote feedback line", "comPort", c.connOptions.PortName, "line", line)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// frameFormat is the shape of the lines a board sends. different sketches separate values with pipes ("1023|512|0")
+// or commas ("1023,512,0"), and may follow them with button states after a semicolon ("1023|512|0;0|1")
+type frameFormat struct {
+	separator  string
+	numSliders int
+
+	// 0 for boards that don't send any button states
+	numButtons int
+}
+
+func (f frameFormat) String() string {
+	name := "pipe-separated"
+	if f.separator == frameSeparatorComma {
+		name = "comma-separated"
+	}
+
+	if f.numButtons == 0 {
+		return fmt.Sprintf("%s, %d sliders", name, f.numSliders)
+	}
+
+	return fmt.Sprintf("%s, %d sliders, %d buttons", name, f.numSliders, f.numButtons)
+}
+
+// serialFrame is a single line from the board, parsed
+type serialFrame struct {
+	sliders []int
+
+	// nil if the board doesn't send button states
+	buttons []bool
+}
+
+// frameParser works out which format the board sends from the first few lines it reads, and from then on only
+// accepts lines in that format. a line of any other shape is dropped, unless the board keeps sending that shape
+// (i.e. it was reflashed), in which case that becomes the new format
+type frameParser struct {
+	lock sync.Mutex
+
+	format   frameFormat
+	detected bool
+
+	// the shape that's been seen in a row lately, and how many times, either while detecting or while
+	// seeing lines that don't match the detected format
+	candidate       frameFormat
+	candidateFrames int
+
+	malformedFrames uint64
+
+	// lines that made it through as an actual frame, ever
+	parsedFrames uint64
+}
+
+const (
+	frameSeparatorPipe  = "|"
+	frameSeparatorComma = ","
+
+	// slider values and button states are separated by this
+	frameButtonSeparator = ";"
+
+	// the maximum value of the board's 10-bit analog reads
+	frameMaxSliderValue = 1023
+
+	// lines in a row that have to agree before their format is trusted. the board sends lines much faster than
+	// anyone can move a slider, so waiting for these isn't noticeable
+	frameDetectionFrames = 3
+
+	// lines in a row of a different shape that make it the new format
+	frameRedetectionFrames = 20
+)
+
+var (
+	errMalformedFrame = errors.New("malformed serial frame")
+
+	// not an actual problem, only that the line was used towards detecting the format rather than applied
+	errFrameFormatUndetected = errors.New("serial frame format not detected yet")
+)
+
+// parse parses a single line from the board. changed is true whenever the line established a new format
+func (p *frameParser) parse(line string) (frame serialFrame, changed bool, err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	frame, format, err := p.parseShape(line)
+	if err != nil {
+		p.malformedFrames++
+		return serialFrame{}, false, err
+	}
+
+	if p.detected && format == p.format {
+		p.candidateFrames = 0
+		p.parsedFrames++
+
+		return frame, false, nil
+	}
+
+	if format == p.candidate {
+		p.candidateFrames++
+	} else {
+		p.candidate = format
+		p.candidateFrames = 1
+	}
+
+	if !p.detected {
+		if p.candidateFrames < frameDetectionFrames {
+			return serialFrame{}, false, errFrameFormatUndetected
+		}
+	} else if p.candidateFrames < frameRedetectionFrames {
+		p.malformedFrames++
+
+		return serialFrame{}, false, fmt.Errorf("%w: expected %s, got %s", errMalformedFrame, p.format, format)
+	}
+
+	p.format = format
+	p.detected = true
+	p.candidateFrames = 0
+	p.parsedFrames++
+
+	return frame, true, nil
+}
+
+// parseShape parses a line on its own, without comparing it to the detected format
+func (p *frameParser) parseShape(line string) (serialFrame, frameFormat, error) {
+	line = strings.TrimRight(line, "\r\n")
+
+	sliderPart, buttonPart, hasButtons := cutLine(line, frameButtonSeparator)
+
+	// sliders and buttons are separated the same way, so either can tell which separator it is
+	hasPipes := strings.Contains(line, frameSeparatorPipe)
+	hasCommas := strings.Contains(line, frameSeparatorComma)
+
+	if hasPipes && hasCommas {
+		return serialFrame{}, frameFormat{}, fmt.Errorf("%w: mixed separators in %q", errMalformedFrame, line)
+	}
+
+	format := frameFormat{separator: frameSeparatorPipe}
+
+	if hasCommas {
+		format.separator = frameSeparatorComma
+	}
+
+	// a single slider (and button) doesn't say which separator it uses, so it's assumed to be the one in use
+	if !hasPipes && !hasCommas && p.detected {
+		format.separator = p.format.separator
+	}
+
+	frame := serialFrame{}
+
+	for _, stringValue := range strings.Split(sliderPart, format.separator) {
+
+		// turns out the first line could come out dirty sometimes (i.e. "4558|925|41|643|220"),
+		// which this also takes care of
+		number, err := strconv.Atoi(stringValue)
+		if err != nil || number < 0 || number > frameMaxSliderValue {
+			return serialFrame{}, frameFormat{}, fmt.Errorf("%w: bad slider value %q", errMalformedFrame, stringValue)
+		}
+
+		frame.sliders = append(frame.sliders, number)
+	}
+
+	if hasButtons {
+		for _, stringValue := range strings.Split(buttonPart, format.separator) {
+			if stringValue != "0" && stringValue != "1" {
+				return serialFrame{}, frameFormat{}, fmt.Errorf("%w: bad button state %q", errMalformedFrame, stringValue)
+			}
+
+			frame.buttons = append(frame.buttons, stringValue == "1")
+		}
+	}
+
+	format.numSliders = len(frame.sliders)
+	format.numButtons = len(frame.buttons)
+
+	return frame, format, nil
+}
+
+// reset forgets the detected format, for when a (possibly different) board connects
+func (p *frameParser) reset() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.format = frameFormat{}
+	p.detected = false
+	p.candidate = frameFormat{}
+	p.candidateFrames = 0
+}
+
+// stats returns the detected format (if there is one), and how many lines were dropped for being malformed
+func (p *frameParser) stats() (frameFormat, bool, uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.format, p.detected, p.malformedFrames
+}
+
+// detectionState returns the shape that's been seen in a row lately, how many lines in a row it's been seen in,
+// and how many it takes to become the format - it's 0 lines while the lines match the detected format
+func (p *frameParser) detectionState() (frameFormat, int, int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if !p.detected {
+		return p.candidate, p.candidateFrames, frameDetectionFrames
+	}
+
+	return p.candidate, p.candidateFrames, frameRedetectionFrames
+}
+
+// frameCount returns how many lines were parsed into a frame since deej started
+func (p *frameParser) frameCount() uint64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.parsedFrames
+}
@@ -0,0 +1,116 @@
+package deej
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFrameParseShape(t *testing.T) {
+	tests := []struct {
+		line        string
+		wantSliders []int
+		wantButtons []bool
+		wantFormat  frameFormat
+		wantErr     bool
+	}{
+		{"1023|512|0", []int{1023, 512, 0}, nil, frameFormat{separator: frameSeparatorPipe, numSliders: 3}, false},
+		{"1023,512,0", []int{1023, 512, 0}, nil, frameFormat{separator: frameSeparatorComma, numSliders: 3}, false},
+		{"1023|512|0\r\n", []int{1023, 512, 0}, nil, frameFormat{separator: frameSeparatorPipe, numSliders: 3}, false},
+		{"512", []int{512}, nil, frameFormat{separator: frameSeparatorPipe, numSliders: 1}, false},
+		{"1023|512;0|1", []int{1023, 512}, []bool{false, true},
+			frameFormat{separator: frameSeparatorPipe, numSliders: 2, numButtons: 2}, false},
+		{"1,2,3;1", []int{1, 2, 3}, []bool{true}, frameFormat{separator: frameSeparatorComma, numSliders: 3, numButtons: 1}, false},
+
+		{"1023|512,0", nil, nil, frameFormat{}, true},
+		{"1024|512", nil, nil, frameFormat{}, true},
+		{"-1|512", nil, nil, frameFormat{}, true},
+		{"4558|925|41", nil, nil, frameFormat{}, true},
+		{"abc", nil, nil, frameFormat{}, true},
+		{"", nil, nil, frameFormat{}, true},
+		{"1|2|", nil, nil, frameFormat{}, true},
+		{"1|2;2", nil, nil, frameFormat{}, true},
+		{"1|2;", nil, nil, frameFormat{}, true},
+	}
+
+	for _, test := range tests {
+		parser := &frameParser{}
+		frame, format, err := parser.parseShape(test.line)
+
+		if (err != nil) != test.wantErr {
+			t.Errorf("parseShape(%q) returned %v, want an error: %v", test.line, err, test.wantErr)
+			continue
+		}
+
+		if err != nil {
+			if !errors.Is(err, errMalformedFrame) {
+				t.Errorf("parseShape(%q) returned %v, want a malformed frame", test.line, err)
+			}
+
+			continue
+		}
+
+		if !reflect.DeepEqual(frame.sliders, test.wantSliders) || !reflect.DeepEqual(frame.buttons, test.wantButtons) {
+			t.Errorf("parseShape(%q) = %v and %v, want %v and %v",
+				test.line, frame.sliders, frame.buttons, test.wantSliders, test.wantButtons)
+		}
+
+		if format != test.wantFormat {
+			t.Errorf("parseShape(%q) detected %v, want %v", test.line, format, test.wantFormat)
+		}
+	}
+}
+
+func TestFrameParserDetection(t *testing.T) {
+	parser := &frameParser{}
+
+	tests := []struct {
+		name  string
+		line  string
+		times int
+
+		wantChanged  bool
+		wantErr      error
+		wantFormat   string
+		wantMalforms uint64
+	}{
+		{"detecting", "1|2|3", frameDetectionFrames - 1, false, errFrameFormatUndetected, "", 0},
+		{"detected", "1|2|3", 1, true, nil, "pipe-separated, 3 sliders", 0},
+		{"detected format", "4|5|6", 1, false, nil, "pipe-separated, 3 sliders", 0},
+		{"garbage", "4|x|6", 1, false, errMalformedFrame, "pipe-separated, 3 sliders", 1},
+		{"other shape", "1,2;1", frameRedetectionFrames - 1, false, errMalformedFrame, "pipe-separated, 3 sliders", 20},
+		{"reflashed", "1,2;1", 1, true, nil, "comma-separated, 2 sliders, 1 buttons", 20},
+
+		// a single slider takes after the separator in use
+		{"single slider", "7;0", frameRedetectionFrames, true, nil, "comma-separated, 1 sliders, 1 buttons", 39},
+	}
+
+	for _, test := range tests {
+		var changed bool
+		var err error
+
+		for idx := 0; idx < test.times; idx++ {
+			_, changed, err = parser.parse(test.line)
+		}
+
+		if changed != test.wantChanged || !errors.Is(err, test.wantErr) || (err == nil) != (test.wantErr == nil) {
+			t.Errorf("%s: parse(%q) = %v, %v, want %v, %v", test.name, test.line, changed, err, test.wantChanged, test.wantErr)
+		}
+
+		format, detected, malformed := parser.stats()
+		if test.wantFormat != "" && (!detected || format.String() != test.wantFormat) {
+			t.Errorf("%s: detected %v (%v), want %s", test.name, format, detected, test.wantFormat)
+		}
+
+		if malformed != test.wantMalforms {
+			t.Errorf("%s: %d malformed lines, want %d", test.name, malformed, test.wantMalforms)
+		}
+	}
+
+	// a new board starts from scratch
+	parser.reset()
+
+	if _, _, err := parser.parse("1|2|3"); !errors.Is(err, errFrameFormatUndetected) {
+		t.Errorf("parse after reset returned %v, want the format to be detected again", err)
+	}
+}
@@ -0,0 +1,121 @@
+package deej
+
+import (
+	"errors"
+	"time"
+)
+
+// RawSerialLine is a line as the board sent it, along with what the frame parser made of it - for figuring out
+// why a sketch's lines aren't being picked up. boards send lines far faster than anyone can read them, so only
+// a few per second make it out, and the rest are only counted
+type RawSerialLine struct {
+	COMPort string `json:"comPort"`
+
+	// cut off past serialRawLineMaxLength, which no valid line comes close to
+	Line      string `json:"line"`
+	Truncated bool   `json:"truncated"`
+
+	// one of the rawLineResult constants, with the parser's error for anything but a frame
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+
+	// the format lines are expected in, empty while it's still being detected
+	Format string `json:"format"`
+
+	// the shape that's been seen in a row lately, while detecting or while it differs from the format, and how
+	// many lines in a row it takes to become the format
+	Candidate            string `json:"candidate,omitempty"`
+	CandidateLines       int    `json:"candidateLines"`
+	CandidateLinesNeeded int    `json:"candidateLinesNeeded"`
+
+	// the values read from the line, for lines that were taken as a frame
+	Sliders []int  `json:"sliders,omitempty"`
+	Buttons []bool `json:"buttons,omitempty"`
+
+	MalformedFrames uint64 `json:"malformedFrames"`
+
+	// lines from the same board that came in since the previous one and weren't sent, to keep the rate down
+	Skipped uint64 `json:"skipped"`
+}
+
+const (
+	rawLineResultFrame     = "frame"
+	rawLineResultDetecting = "detecting"
+	rawLineResultMalformed = "malformed"
+
+	// at most one line per board per this long is sent
+	serialRawLineInterval = 100 * time.Millisecond
+
+	serialRawLineMaxLength = 256
+
+	// consumers that can't take a line in time miss it, the serial reader never waits for them
+	serialRawLineBufferSize = 16
+)
+
+// SubscribeToRawLines provides a buffered channel that receives raw lines from every board, see RawSerialLine
+func (sio *SerialIO) SubscribeToRawLines() chan RawSerialLine {
+	ch := make(chan RawSerialLine, serialRawLineBufferSize)
+	sio.rawLineConsumers = append(sio.rawLineConsumers, ch)
+
+	return ch
+}
+
+// publishRawLine hands a line and its parse result to the raw line consumers, unless one was sent too recently.
+// it's only called from the connection's read loop
+func (c *serialConnection) publishRawLine(line string, frame serialFrame, parseErr error) {
+	if len(c.sio.rawLineConsumers) == 0 {
+		return
+	}
+
+	if time.Since(c.lastRawLine) < serialRawLineInterval {
+		c.skippedRawLines++
+		return
+	}
+
+	format, detected, malformedFrames := c.frameParser.stats()
+	candidate, candidateLines, candidateLinesNeeded := c.frameParser.detectionState()
+
+	raw := RawSerialLine{
+		COMPort:              c.connOptions.PortName,
+		Line:                 line,
+		Result:               rawLineResultFrame,
+		CandidateLines:       candidateLines,
+		CandidateLinesNeeded: candidateLinesNeeded,
+		MalformedFrames:      malformedFrames,
+		Skipped:              c.skippedRawLines,
+	}
+
+	if len(raw.Line) > serialRawLineMaxLength {
+		raw.Line = raw.Line[:serialRawLineMaxLength]
+		raw.Truncated = true
+	}
+
+	if detected {
+		raw.Format = format.String()
+	}
+
+	if candidateLines > 0 {
+		raw.Candidate = candidate.String()
+	}
+
+	switch {
+	case errors.Is(parseErr, errFrameFormatUndetected):
+		raw.Result = rawLineResultDetecting
+	case parseErr != nil:
+		raw.Result = rawLineResultMalformed
+		raw.Error = parseErr.Error()
+	default:
+		raw.Sliders = frame.sliders
+		raw.Buttons = frame.buttons
+	}
+
+	c.lastRawLine = time.Now()
+	c.skippedRawLines = 0
+
+	for _, consumer := range c.sio.rawLineConsumers {
+		select {
+		case consumer <- raw:
+		default:
+		}
+	}
+}
@@ -1,13 +1,21 @@
 package deej
 
 import (
+	"bufio"
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
 	"embed"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/fs"
+	"io"
+	"math"
 	"net"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -20,75 +28,161 @@ import (
 var webAssets embed.FS
 
 const (
-	defaultServerPort     = 9123
 	serverShutdownTimeout = 5 * time.Second
+
+	// when the default port is taken, this many subsequent ports are tried before giving up
+	maxServerPortFallbacks = 10
+
+	// how long MIDI learn waits for a control to be moved
+	midiLearnTimeout = 10 * time.Second
 )
 
 // Server provides an HTTP server for the web-based configuration UI
 type Server struct {
 	logger     *zap.SugaredLogger
 	httpServer *http.Server
+	host       string
 	port       int
 
-	deej *Deej
+	deej  *Deej
+	wsHub *wsHub
+
+	// the slider, session and status handlers only go through these, see server_backend.go
+	config   serverConfig
+	sessions serverSessions
+
+	// streaming handlers return once this is closed, otherwise they'd hold up shutdown
+	streamsDone    chan struct{}
+	sessionEvents  *sseBroker
+	serialRawLines *sseBroker
+
+	// throttles API writes, see server_ratelimit.go
+	writeLimiter writeLimiter
+
+	// picks which requests to excluded paths still get logged, see server_accesslog.go
+	accessLogSampler accessLogSampler
+
+	// recent slider mapping changes, see server_audit.go
+	audit *auditLog
+
+	// whether the UI is served over HTTPS, see server_tls.go
+	tls bool
+
+	// the path everything is served under, without a trailing slash (empty for the root), see server_basepath.go
+	basePath string
 
 	lock    sync.Mutex
 	running bool
+
+	// told whenever the server starts or stops, see server_lifecycle.go
+	stateConsumers []chan bool
 }
 
 // NewServer creates a new web server instance
 func NewServer(logger *zap.SugaredLogger, deej *Deej) *Server {
-	return &Server{
-		logger: logger.Named("server"),
-		port:   defaultServerPort,
+	logger = logger.Named("server")
+
+	s := &Server{
+		logger: logger,
+		host:   defaultWebServerHost,
+		port:   defaultWebServerPort,
 		deej:   deej,
+		wsHub:  newWSHub(logger),
+
+		config:   deej.config,
+		sessions: deej.sessions,
+
+		sessionEvents:  newSSEBroker(),
+		serialRawLines: newSSEBroker(),
+
+		audit: newAuditLog(auditLogSize),
 	}
+
+	// the hub keeps consuming slider values even while the server is stopped,
+	// so that the serial reader is never blocked on us
+	go s.wsHub.consume(deej.serial.SubscribeToSliderValues(), deej.serial.SliderActivity)
+	go s.consumeSessionChanges(deej.sessions.SubscribeToSessionChanges())
+	go s.consumeRawSerialLines(deej.serial.SubscribeToRawLines())
+	go s.consumeConfigLoads(deej.config.SubscribeToLoads())
+
+	return s
 }
 
-// Start begins serving the web UI
+// Start begins serving the web UI, on the configured port (or the next free one, unless it's strict)
 func (s *Server) Start() error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	return s.start()
+}
+
+// start must be called while holding the lock
+func (s *Server) start() error {
 	if s.running {
 		return fmt.Errorf("server already running")
 	}
 
-	mux := http.NewServeMux()
+	s.host = s.deej.config.WebServer.Host
+	s.port = s.deej.config.WebServer.Port
+	s.basePath = s.deej.config.WebServer.BasePath
 
-	// API routes
-	mux.HandleFunc("/api/sliders", s.handleSliders)
-	mux.HandleFunc("/api/sliders/", s.handleSliderByID)
-	mux.HandleFunc("/api/sessions", s.handleSessions)
-	mux.HandleFunc("/api/status", s.handleStatus)
+	handler, err := s.newHandler()
+	if err != nil {
+		return err
+	}
 
-	// Static files - serve embedded SPA
-	staticFS, err := fs.Sub(webAssets, "web")
+	s.wsHub.setMaxRate(s.deej.config.WebServer.WebSocketMaxRate)
+	s.streamsDone = make(chan struct{})
+
+	// loaded up front, so a broken certificate fails Start instead of every connection
+	certificate, err := s.loadTLSCertificate()
 	if err != nil {
-		return fmt.Errorf("get static fs: %w", err)
+		return err
 	}
-	mux.Handle("/", http.FileServer(http.FS(staticFS)))
 
-	// Wrap with middleware
-	handler := s.corsMiddleware(s.loggingMiddleware(mux))
+	s.tls = certificate != nil
+
+	listener, err := s.listen()
+	if err != nil {
+		return err
+	}
 
 	s.httpServer = &http.Server{
-		Addr:    fmt.Sprintf(":%d", s.port),
+		Addr:    listener.Addr().String(),
 		Handler: handler,
 	}
 
-	listener, err := net.Listen("tcp", s.httpServer.Addr)
-	if err != nil {
-		return fmt.Errorf("listen on port %d: %w", s.port, err)
+	s.applyTimeouts(s.httpServer)
+
+	if s.tls {
+		s.httpServer.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{*certificate},
+			MinVersion:   tls.VersionTLS12,
+		}
 	}
 
 	s.running = true
 	s.logger.Infow("Web server started",
-		"port", s.port,
-		"url", fmt.Sprintf("http://localhost:%d", s.port))
+		"address", s.httpServer.Addr,
+		"url", s.GetURL())
+
+	s.notifyStateChange()
+
+	// the next Start replaces these, and this goroutine shouldn't care
+	httpServer := s.httpServer
+	useTLS := s.tls
 
 	go func() {
-		if err := s.httpServer.Serve(listener); err != http.ErrServerClosed {
+		var err error
+
+		// the certificate is already in TLSConfig, so ServeTLS doesn't need its files
+		if useTLS {
+			err = httpServer.ServeTLS(listener, "", "")
+		} else {
+			err = httpServer.Serve(listener)
+		}
+
+		if err != http.ErrServerClosed {
 			s.logger.Errorw("Server error", "error", err)
 		}
 	}()
@@ -96,11 +190,126 @@ func (s *Server) Start() error {
 	return nil
 }
 
-// Stop gracefully shuts down the server
+// newHandler builds the web UI and API with all of their middleware, as it's served under the configured base path.
+// it doesn't need a listener, so it can also be served on its own (i.e. by httptest)
+func (s *Server) newHandler() (http.Handler, error) {
+	mux := http.NewServeMux()
+
+	// Static files - serve embedded SPA, along with its translation bundles. a build without it still serves
+	// the API, see server_static.go
+	var static, i18n http.Handler
+
+	if staticFS, err := webAssetsFS(webAssets); err != nil {
+		s.logger.Warnw("Web UI assets are missing, serving the API only", "error", err)
+
+		static = s.noWebUIHandler()
+		i18n = s.noWebUIHandler()
+	} else {
+		if i18n, err = s.i18nHandler(staticFS); err != nil {
+			return nil, err
+		}
+
+		if static, err = s.staticHandler(staticFS); err != nil {
+			return nil, err
+		}
+	}
+
+	// API routes
+	mux.HandleFunc("/api/sliders", s.handleSliders)
+	mux.HandleFunc("/api/sliders/", s.handleSliderByID)
+	mux.HandleFunc("/api/sliders/swap", s.handleSwapSliders)
+	mux.HandleFunc("/api/buttons", s.handleButtons)
+	mux.HandleFunc("/api/sessions", s.handleSessions)
+	mux.HandleFunc("/api/sessions/stream", s.handleSessionsStream)
+	mux.HandleFunc("/api/sessions/template", s.handleSessionsTemplate)
+	mux.HandleFunc("/api/targets", s.handleTargets)
+	mux.HandleFunc("/api/logs/stream", s.handleLogsStream)
+	mux.HandleFunc("/api/serial/raw", s.handleSerialRawStream)
+	mux.HandleFunc("/api/status", s.handleStatus)
+	mux.HandleFunc("/api/health", s.handleHealth)
+	mux.HandleFunc("/api/diagnostics", s.handleDiagnostics)
+	mux.HandleFunc("/api/mute", s.handleMute)
+	mux.HandleFunc("/api/pause", s.handlePause)
+	mux.HandleFunc("/api/reset", s.handleReset)
+	mux.HandleFunc("/api/audit", s.handleAudit)
+	mux.HandleFunc("/api/devices", s.handleDevices)
+	mux.HandleFunc("/api/config/reload", s.handleConfigReload)
+	mux.HandleFunc("/api/shutdown", s.handleShutdown)
+	mux.HandleFunc("/api/restart", s.handleRestart)
+	mux.HandleFunc("/api/config/export", s.handleConfigExport)
+	mux.HandleFunc("/api/config/import", s.handleConfigImport)
+	mux.HandleFunc("/api/schema", s.handleSchema)
+	mux.HandleFunc("/api/config/undo", s.handleMappingUndo)
+	mux.HandleFunc("/api/config/redo", s.handleMappingRedo)
+	mux.HandleFunc("/api/profiles", s.handleProfiles)
+	mux.HandleFunc("/api/profiles/activate", s.handleActivateProfile)
+	mux.HandleFunc("/api/devices/default", s.handleDefaultDevice)
+	mux.HandleFunc("/api/midi", s.handleMIDI)
+	mux.HandleFunc("/api/midi/learn", s.handleMIDILearn)
+	mux.HandleFunc("/api/midi/mapping", s.handleMIDIMapping)
+	mux.HandleFunc("/api/webhooks", s.handleWebhooks)
+	mux.HandleFunc("/api/slider-actions", s.handleSliderActions)
+	mux.HandleFunc("/api/slider-groups", s.handleSliderGroups)
+	mux.HandleFunc("/api/volume-limits", s.handleVolumeLimits)
+	mux.HandleFunc("/api/loglevel", s.handleLogLevel)
+	mux.HandleFunc("/api/balance", s.handleBalance)
+	mux.HandleFunc("/api/preferences", s.handlePreferences)
+	mux.Handle("/api/i18n", i18n)
+	mux.Handle("/api/i18n/", i18n)
+	mux.HandleFunc("/api/ws", s.wsHub.serve)
+
+	mux.Handle("/", static)
+
+	return s.withBasePath(s.streamDeadlineMiddleware(s.corsMiddleware(s.loggingMiddleware(s.gzipMiddleware(
+		s.authMiddleware(s.rateLimitMiddleware(s.bodyLimitMiddleware(mux)))))))), nil
+}
+
+// listen binds to the configured port. unless that port was explicitly configured,
+// a busy port makes us move on to the next few until we find a free one
+func (s *Server) listen() (net.Listener, error) {
+	maxFallbacks := maxServerPortFallbacks
+	if s.deej.config.WebServer.StrictPort {
+		maxFallbacks = 0
+	}
+
+	configuredPort := s.port
+
+	var lastErr error
+
+	for port := configuredPort; port <= configuredPort+maxFallbacks; port++ {
+		address := net.JoinHostPort(s.host, strconv.Itoa(port))
+
+		listener, err := net.Listen("tcp", address)
+		if err == nil {
+			if port != configuredPort {
+				s.logger.Infow("Configured port is busy, using another one",
+					"configuredPort", configuredPort,
+					"port", port)
+			}
+
+			// this is also what GetURL reports from now on
+			s.port = port
+
+			return listener, nil
+		}
+
+		s.logger.Debugw("Failed to listen on port", "port", port, "error", err)
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("listen on %s (port %d, %d fallbacks): %w", s.host, configuredPort, maxFallbacks, lastErr)
+}
+
+// Stop gracefully shuts down the server. it can be started again afterwards
 func (s *Server) Stop() error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	return s.stop()
+}
+
+// stop must be called while holding the lock
+func (s *Server) stop() error {
 	if !s.running {
 		return nil
 	}
@@ -108,30 +317,81 @@ func (s *Server) Stop() error {
 	ctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
 	defer cancel()
 
+	// websocket connections are hijacked, so Shutdown doesn't know about them and we close them ourselves
+	deadline, _ := ctx.Deadline()
+	s.wsHub.closeAll(deadline)
+
+	// same goes for event streams, which never become idle on their own
+	close(s.streamsDone)
+
+	// whether or not the rest goes smoothly, the streams are done for, and stopping again mustn't close them twice
+	s.running = false
+	defer s.notifyStateChange()
+
 	if err := s.httpServer.Shutdown(ctx); err != nil {
+
+		// the listener is closed by now, so only the requests that didn't finish in time are left to cut off
+		if closeErr := s.httpServer.Close(); closeErr != nil {
+			s.logger.Debugw("Failed to close remaining connections", "error", closeErr)
+		}
+
 		return fmt.Errorf("shutdown server: %w", err)
 	}
 
-	s.running = false
 	s.logger.Info("Web server stopped")
 	return nil
 }
 
 // GetURL returns the server URL
 func (s *Server) GetURL() string {
-	return fmt.Sprintf("http://localhost:%d", s.port)
+	host := s.host
+
+	// a wildcard address isn't something a browser can open, but this machine is always reachable as localhost
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+
+	scheme := "http"
+	if s.tls {
+		scheme = "https"
+	}
+
+	url := fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, strconv.Itoa(s.port)))
+
+	// the UI lives at the base path with its trailing slash, anything else is a redirect away
+	if s.basePath != "" {
+		url += s.basePath + "/"
+	}
+
+	return url
 }
 
 // Middleware
 
-func (s *Server) corsMiddleware(next http.Handler) http.Handler {
+// authMiddleware requires a bearer token on all API requests, if one is configured.
+// static assets stay public so the UI itself can always load and ask the user for the token
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		token := s.deej.config.WebServer.Token
+
+		if token == "" || !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		provided := ""
+		if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+			provided = strings.TrimPrefix(header, "Bearer ")
+		}
 
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
+		// browsers can't set headers on websocket connections or event streams, so those may pass it as a query parameter
+		if isStreamingRequest(r) && provided == "" {
+			provided = r.URL.Query().Get("token")
+		}
+
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			s.writeError(w, http.StatusUnauthorized, errorCodeUnauthorized, "Unauthorized")
 			return
 		}
 
@@ -139,13 +399,27 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// loggingMiddleware tags every request with an ID, which is sent back in a response header and attached
+// to a request-scoped logger. handlers should log through requestLogger, so their lines can be matched up
 func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+
+		requestID := newRequestID()
+		logger := s.logger.With("requestID", requestID)
+
+		w.Header().Set(requestIDHeader, requestID)
+		ctx := context.WithValue(r.Context(), requestLoggerKey{}, logger)
+		r = r.WithContext(context.WithValue(ctx, requestIDKey{}, requestID))
+
 		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 		next.ServeHTTP(wrapped, r)
 
-		s.logger.Debugw("HTTP request",
+		if !s.shouldLogRequest(r, wrapped.statusCode) {
+			return
+		}
+
+		logger.Debugw("HTTP request",
 			"method", r.Method,
 			"path", r.URL.Path,
 			"status", wrapped.statusCode,
@@ -153,6 +427,36 @@ func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+const requestIDHeader = "X-Request-ID"
+
+type requestLoggerKey struct{}
+type requestIDKey struct{}
+
+// newRequestID returns a short random ID. it only has to tell apart requests from around the same time
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// requestID returns the ID the logging middleware gave a request, or an empty string outside of it
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestLogger returns the logger for a single request, falling back to the server's own logger
+func (s *Server) requestLogger(r *http.Request) *zap.SugaredLogger {
+	if logger, ok := r.Context().Value(requestLoggerKey{}).(*zap.SugaredLogger); ok {
+		return logger
+	}
+
+	return s.logger
+}
+
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
@@ -163,123 +467,1952 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Flush allows streaming responses to go through the status-capturing wrapper
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack allows websocket upgrades to go through the status-capturing wrapper
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("underlying response writer doesn't support hijacking")
+	}
+
+	return hijacker.Hijack()
+}
+
 // API Handlers
 
 type slidersResponse struct {
-	Sliders map[string][]string `json:"sliders"`
+	Sliders map[string][]string `json:"sliders" schema:"keys=index,values.items.minLength=1,values.items.examples=targets"`
+
+	// slider index -> pattern target -> keys of the live sessions it currently controls. ignored on PUT
+	Matches map[string]map[string][]string `json:"matches,omitempty" schema:"readOnly"`
+
+	// slider index -> target as mapped -> its weight, for targets that have one (i.e. "spotify.exe@0.6").
+	// ignored on PUT, the weights are part of the targets themselves
+	Weights map[string]map[string]float32 `json:"weights,omitempty" schema:"readOnly"`
+
+	// slider index -> its label, for sliders that have one. ignored on PUT, see /api/sliders/{id}/label
+	Labels map[string]string `json:"labels,omitempty" schema:"readOnly"`
+
+	// slider index -> a mapped slider that can't be moved, which isn't listed under sliders. only on GET
+	Invalid map[string]invalidSlider `json:"invalid,omitempty" schema:"readOnly"`
+
+	// target as mapped (without its weight) -> the session key it stands for, for targets that session_names
+	// changes (see session_names.go). ignored on PUT
+	Normalized map[string]string `json:"normalized,omitempty" schema:"readOnly"`
+}
+
+// invalidSlider is a negative slider (which only config.yaml can have), or one that isn't on any connected board
+type invalidSlider struct {
+	Apps   []string `json:"apps"`
+	Reason string   `json:"reason"`
+}
+
+// sliderLabelRequest is also the response, with the label as saved (empty when there's none)
+type sliderLabelRequest struct {
+	Label string `json:"label" schema:"maxLength=maxSliderLabelLength"`
+}
+
+type sliderFixedValueRequest struct {
+	Value *float64 `json:"value" schema:"required,minimum=0,maximum=1"`
+}
+
+// sliderFixedValueResponse is a slider's fixed value, see slider_fixed_value.go. value is 0 when it isn't fixed
+type sliderFixedValueResponse struct {
+	Fixed bool    `json:"fixed"`
+	Value float32 `json:"value"`
+}
+
+type buttonsResponse struct {
+	Buttons map[string][]string `json:"buttons" schema:"keys=index,values.items.minLength=1,values.items.examples=targets"`
 }
 
 type sessionsResponse struct {
 	Sessions []SessionInfo `json:"sessions"`
 }
 
+// sessionsTemplateResponse is a suggested mapping, see session_template.go
+type sessionsTemplateResponse struct {
+	Sliders     map[string][]string `json:"sliders"`
+	SliderCount int                 `json:"sliderCount"`
+
+	// active apps there weren't enough sliders for
+	Remaining []string `json:"remaining"`
+}
+
+type targetsResponse struct {
+	Targets []SpecialTarget `json:"targets"`
+}
+
 type updateSliderRequest struct {
-	Apps []string `json:"apps"`
+	Apps []string `json:"apps" schema:"items.minLength=1,items.examples=targets"`
+}
+
+type updateSliderResponse struct {
+	genericResponse
+
+	// the slider's apps as they were saved, and the whole slider mapping that's now in effect, in the same
+	// shape as GET /api/sliders - so there's no need to GET them again (and race other edits while at it)
+	Apps    []string            `json:"apps"`
+	Sliders map[string][]string `json:"sliders"`
+
+	// apps that were given more than once (i.e. in different case) and were only saved the first time,
+	// and apps that other sliders control too, along with those sliders
+	Duplicates      []string         `json:"duplicates"`
+	MappedElsewhere map[string][]int `json:"mappedElsewhere"`
+
+	// apps that don't match any running session or special target right now. they're saved all the same,
+	// since the app might just not be running yet, but it might also be a typo
+	Warnings []string `json:"warnings"`
+}
+
+// sliderSettingsResponse holds a slider's effective settings, after falling back to global ones
+type sliderSettingsResponse struct {
+	Invert         bool        `json:"invert"`
+	NoiseThreshold float64     `json:"noiseThreshold"`
+	VolumeCurve    VolumeCurve `json:"volumeCurve"`
+	Smoothing      Smoothing   `json:"smoothing"`
+	Calibration    Calibration `json:"calibration"`
+	DeadZone       float64     `json:"deadZone"`
+}
+
+// fields left out of the request are left as they are
+type updateSliderSettingsRequest struct {
+	Invert         *bool        `json:"invert"`
+	NoiseThreshold *float64     `json:"noiseThreshold" schema:"minimum=0,exclusiveMaximum=1"`
+	VolumeCurve    *VolumeCurve `json:"volumeCurve"`
+	Smoothing      *Smoothing   `json:"smoothing"`
+	Calibration    *Calibration `json:"calibration"`
+	DeadZone       *float64     `json:"deadZone" schema:"minimum=0,exclusiveMaximum=maxDeadZone"`
+}
+
+// calibrationResponse holds a slider's calibration, and while it's being calibrated, the raw range reached so far
+type calibrationResponse struct {
+	Success     bool         `json:"success"`
+	Message     string       `json:"message,omitempty"`
+	Calibration Calibration  `json:"calibration"`
+	Calibrating bool         `json:"calibrating"`
+	Observed    *Calibration `json:"observed,omitempty"`
 }
 
 type genericResponse struct {
 	Success bool   `json:"success"`
 	Message string `json:"message"`
+
+	// only set on errors, for clients that need to tell them apart without parsing the message
+	Code string `json:"code,omitempty"`
+}
+
+// the machine-readable codes error responses carry, broadly matching their status codes
+const (
+	errorCodeInvalidRequest   = "invalid_request"
+	errorCodeUnauthorized     = "unauthorized"
+	errorCodeForbidden        = "forbidden"
+	errorCodeNotFound         = "not_found"
+	errorCodeMethodNotAllowed = "method_not_allowed"
+	errorCodeTimeout          = "timeout"
+	errorCodeConflict         = "conflict"
+	errorCodeInvalidConfig    = "invalid_config"
+	errorCodeRateLimited      = "rate_limited"
+	errorCodeBodyTooLarge     = "body_too_large"
+	errorCodeInternal         = "internal_error"
+	errorCodeSaveFailed       = "save_failed"
+	errorCodeUnsupported      = "unsupported"
+	errorCodeUnavailable      = "unavailable"
+)
+
+type targetVolume struct {
+	Volume int  `json:"volume"`
+	Muted  bool `json:"muted"`
+}
+
+// externalVolumeChange is a session whose volume was changed from outside of deej, both volumes in percent
+type externalVolumeChange struct {
+	Key        string    `json:"key"`
+	Set        int       `json:"set"`
+	Observed   int       `json:"observed"`
+	DetectedAt time.Time `json:"detectedAt"`
+	Respected  bool      `json:"respected"`
 }
 
 type statusResponse struct {
-	Status      string `json:"status"`
-	SliderCount int    `json:"sliderCount"`
-	WebURL      string `json:"webUrl"`
+	Status string `json:"status"`
+
+	// true when deej created a default config on this launch, since there was none
+	FirstRun bool `json:"firstRun"`
+
+	// how many sliders are mapped, and how many the board actually has (0 while it's not connected).
+	// sliders can be on the board without being mapped, or mapped without being on the board (i.e. OSC ones)
+	SliderCount         int `json:"sliderCount"`
+	HardwareSliderCount int `json:"hardwareSliderCount"`
+
+	WebURL string `json:"webUrl"`
+
+	// the main board's port. connected is only true while every board is connected
+	SerialPort string `json:"serialPort"`
+	Connected  bool   `json:"connected"`
+
+	// what the main board was detected to send (empty until it's been detected), and how many of its lines were dropped
+	FrameFormat     string `json:"frameFormat"`
+	MalformedFrames uint64 `json:"malformedFrames"`
+
+	// the connection to each board, the main one first
+	SerialSources []SerialSourceStatus `json:"serialSources"`
+
+	// while true, slider moves are read but don't change any volumes
+	Paused bool `json:"paused"`
+
+	// things about the config that are probably not what the user meant, i.e. mapped sliders that aren't on the board
+	Warnings []string `json:"warnings"`
+
+	// how often audio sessions are re-acquired, in seconds
+	SessionRefreshInterval float64 `json:"sessionRefreshInterval"`
+
+	// how many session volume, mute or balance changes were abandoned for taking too long, since deej started
+	SessionTimeouts uint64 `json:"sessionTimeouts"`
+
+	// slider index -> the value it's held at, for sliders that are fixed (see slider_fixed_value.go)
+	FixedSliders map[string]float32 `json:"fixedSliders"`
+
+	// bumped on every successful config load, see config_version.go
+	ConfigVersion  uint64    `json:"configVersion"`
+	ConfigLoadedAt time.Time `json:"configLoadedAt"`
+
+	// slider index -> mapped target -> last applied state (null if the target has no active session)
+	Volumes map[string]map[string]*targetVolume `json:"volumes"`
+
+	// the global curve, and the one each mapped slider actually uses
+	VolumeCurve        VolumeCurve            `json:"volumeCurve"`
+	SliderVolumeCurves map[string]VolumeCurve `json:"sliderVolumeCurves"`
+
+	// last applied state of the default output and input devices (null if there isn't one)
+	Master *targetVolume `json:"master"`
+	Mic    *targetVolume `json:"mic"`
+
+	// the session keys deej.current is controlling right now (always empty where it isn't supported)
+	CurrentWindowTargets []string `json:"currentWindowTargets"`
+
+	// what external_volume_changes does, and the sessions it found changed (always empty with the ignore policy)
+	ExternalVolumePolicy  string                 `json:"externalVolumePolicy"`
+	ExternalVolumeChanges []externalVolumeChange `json:"externalVolumeChanges"`
+
+	// button index -> mapped target -> whether it's muted right now (null if the target has no active session)
+	ButtonMutes map[string]map[string]*bool `json:"buttonMutes"`
+
+	// slider index -> when it was last moved, and whether it's being moved right now
+	SliderActivity map[string]SliderActivity `json:"sliderActivity"`
 }
 
 func (s *Server) handleSliders(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
+	switch r.Method {
+	case http.MethodGet:
+		rawMapping := s.config.GetSliderMappingRaw()
+		invalidReasons := s.deej.serial.InvalidSliders(rawMapping)
 
-	rawMapping := s.deej.config.GetSliderMappingRaw()
+		// Convert int keys to string keys for JSON
+		sliders := make(map[string][]string)
+		matches := make(map[string]map[string][]string)
+		invalid := make(map[string]invalidSlider)
 
-	// Convert int keys to string keys for JSON
-	sliders := make(map[string][]string)
-	for k, v := range rawMapping {
-		sliders[strconv.Itoa(k)] = v
-	}
+		// a pattern only lists the sessions it actually controls
+		controllers := s.sessions.resolveControllers()
+		normalized := make(map[string]string)
 
-	s.writeJSON(w, slidersResponse{Sliders: sliders})
-}
+		for k, v := range rawMapping {
+			if reason, ok := invalidReasons[k]; ok {
+				invalid[strconv.Itoa(k)] = invalidSlider{Apps: v, Reason: reason}
+				delete(rawMapping, k)
+				continue
+			}
 
-func (s *Server) handleSliderByID(w http.ResponseWriter, r *http.Request) {
-	// Extract slider ID from path: /api/sliders/0
-	path := strings.TrimPrefix(r.URL.Path, "/api/sliders/")
-	sliderID, err := strconv.Atoi(path)
-	if err != nil {
-		http.Error(w, "Invalid slider ID", http.StatusBadRequest)
-		return
+			sliders[strconv.Itoa(k)] = v
+
+			for _, target := range v {
+				if !isTargetPattern(target) {
+					name := strings.ToLower(targetName(target))
+					if key := s.sessions.normalizeTarget(name); key != name {
+						normalized[targetName(target)] = key
+					}
+
+					continue
+				}
+
+				if matches[strconv.Itoa(k)] == nil {
+					matches[strconv.Itoa(k)] = make(map[string][]string)
+				}
+
+				matches[strconv.Itoa(k)][target] = controllers.sliderKeys(k, target)
+			}
+		}
+
+		s.writeJSON(w, slidersResponse{
+			Sliders: sliders,
+			Matches: matches,
+			Weights: sliderTargetWeights(rawMapping),
+			Labels:  sliderLabelsByKey(s.config.SliderLabels()),
+			Invalid: invalid,
+
+			Normalized: normalized,
+		})
+
+	case http.MethodPut:
+		var req slidersResponse
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeBodyError(w, err)
+			return
+		}
+
+		// validate every key before touching anything, so a bad request never partially applies
+		newMapping := make(map[int][]string)
+		for key, apps := range req.Sliders {
+			sliderID, err := strconv.Atoi(key)
+			if err != nil || sliderID < 0 {
+				s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, fmt.Sprintf("Invalid slider ID %q: slider IDs must be non-negative integers", key))
+				return
+			}
+
+			if target, ok := invalidTargetWeight(apps); ok {
+				s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, fmt.Sprintf("Invalid weight in %q: weights must be non-negative numbers", target))
+				return
+			}
+
+			newMapping[sliderID] = apps
+		}
+
+		// written all at once, this only triggers a single config reload
+		oldMapping, _, err := s.config.UpdateSliderMapping(func(mapping map[int][]string) error {
+			for sliderID := range mapping {
+				delete(mapping, sliderID)
+			}
+
+			for sliderID, apps := range newMapping {
+				mapping[sliderID] = apps
+			}
+
+			return nil
+		})
+		if err != nil {
+			s.requestLogger(r).Errorw("Failed to write config", "error", err)
+			s.writeError(w, http.StatusInternalServerError, errorCodeSaveFailed, "Failed to save configuration")
+			return
+		}
+
+		s.auditMappingChange(r, auditActionReplace, oldMapping, newMapping)
+
+		s.writeJSON(w, genericResponse{
+			Success: true,
+			Message: "Slider mapping replaced - config will auto-reload",
+		})
+
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
 	}
+}
 
+// handleButtons reads or replaces the whole button mapping
+func (s *Server) handleButtons(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		rawMapping := s.deej.config.GetSliderMappingRaw()
-		apps, ok := rawMapping[sliderID]
-		if !ok {
-			apps = []string{}
+		buttons := make(map[string][]string)
+		for k, v := range s.deej.config.GetButtonMappingRaw() {
+			buttons[strconv.Itoa(k)] = v
 		}
-		s.writeJSON(w, map[string][]string{"apps": apps})
+
+		s.writeJSON(w, buttonsResponse{Buttons: buttons})
 
 	case http.MethodPut:
-		var req updateSliderRequest
+		var req buttonsResponse
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			s.writeBodyError(w, err)
 			return
 		}
 
-		// Get current mapping, update the specific slider, write back
-		currentMapping := s.deej.config.GetSliderMappingRaw()
-		currentMapping[sliderID] = req.Apps
+		newMapping := make(map[int][]string)
+		for key, targets := range req.Buttons {
+			buttonID, err := strconv.Atoi(key)
+			if err != nil || buttonID < 0 {
+				s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, fmt.Sprintf("Invalid button ID %q: button IDs must be non-negative integers", key))
+				return
+			}
+
+			newMapping[buttonID] = targets
+		}
 
-		if err := s.deej.config.WriteSliderMapping(currentMapping); err != nil {
-			s.logger.Errorw("Failed to write config", "error", err)
-			s.writeJSON(w, genericResponse{
-				Success: false,
-				Message: "Failed to save configuration",
-			})
+		if err := s.deej.config.WriteButtonMapping(newMapping); err != nil {
+			s.requestLogger(r).Errorw("Failed to write config", "error", err)
+			s.writeError(w, http.StatusInternalServerError, errorCodeSaveFailed, "Failed to save configuration")
 			return
 		}
 
 		s.writeJSON(w, genericResponse{
 			Success: true,
-			Message: "Slider updated - config will auto-reload",
+			Message: "Buttons updated - config will auto-reload",
 		})
 
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
 	}
 }
 
-func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+func (s *Server) handleSliderByID(w http.ResponseWriter, r *http.Request) {
+	// Extract slider ID from path: /api/sliders/0 or /api/sliders/0/settings
+	pathParts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/api/sliders/"), "/", 2)
+	sliderID, err := strconv.Atoi(pathParts[0])
+
+	// only config.yaml can map a negative slider, but it's still up to the API to remove it
+	if err != nil || sliderID < 0 && (len(pathParts) > 1 || r.Method != http.MethodDelete) {
+		s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, "Invalid slider ID: slider IDs must be non-negative integers")
 		return
 	}
 
-	sessions := s.deej.sessions.GetAllSessionKeys()
-	s.writeJSON(w, sessionsResponse{Sessions: sessions})
-}
+	if len(pathParts) > 1 {
+		switch pathParts[1] {
+		case "settings":
+			s.handleSliderSettings(w, r, sliderID)
+		case "calibration":
+			s.handleSliderCalibration(w, r, sliderID)
+		case "calibration/start":
+			s.handleStartSliderCalibration(w, r, sliderID)
+		case "calibration/finish":
+			s.handleFinishSliderCalibration(w, r, sliderID)
+		case "label":
+			s.handleSliderLabel(w, r, sliderID)
+		case "fixed":
+			s.handleSliderFixedValue(w, r, sliderID)
+		default:
+			s.writeError(w, http.StatusNotFound, errorCodeNotFound, "Not found")
+		}
 
-func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	rawMapping := s.deej.config.GetSliderMappingRaw()
+	switch r.Method {
+	case http.MethodGet:
+		rawMapping := s.config.GetSliderMappingRaw()
+		apps, ok := rawMapping[sliderID]
+		if !ok {
+			apps = []string{}
+		}
+		s.writeJSON(w, map[string][]string{"apps": apps})
 
-	s.writeJSON(w, statusResponse{
-		Status:      "running",
-		SliderCount: len(rawMapping),
-		WebURL:      s.GetURL(),
-	})
-}
+	case http.MethodPut:
+		var req updateSliderRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeBodyError(w, err)
+			return
+		}
+
+		if target, ok := invalidTargetWeight(req.Apps); ok {
+			s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, fmt.Sprintf("Invalid weight in %q: weights must be non-negative numbers", target))
+			return
+		}
+
+		// the same app twice would only have it set twice
+		apps, duplicates := dedupeTargets(req.Apps)
+
+		// read and written back in one go, so a concurrent edit (or reload) can't get in between
+		oldApps, currentMapping, err := s.config.UpdateSlider(sliderID, apps)
+		if err != nil {
+			s.requestLogger(r).Errorw("Failed to write config", "error", err)
+			s.writeError(w, http.StatusInternalServerError, errorCodeSaveFailed, "Failed to save configuration")
+			return
+		}
+
+		s.auditMappingChange(r, auditActionUpdate, map[int][]string{sliderID: oldApps}, map[int][]string{sliderID: apps})
+
+		s.writeJSON(w, updateSliderResponse{
+			genericResponse: genericResponse{
+				Success: true,
+				Message: "Slider updated - config will auto-reload",
+			},
+			Apps:            apps,
+			Sliders:         slidersByKey(currentMapping),
+			Duplicates:      duplicates,
+			MappedElsewhere: otherSlidersMapping(currentMapping, sliderID),
+			Warnings:        s.sessions.UnmatchedTargets(apps),
+		})
+
+	case http.MethodDelete:
+		oldApps, err := s.config.RemoveSlider(sliderID)
+		if errors.Is(err, errSliderNotMapped) {
+			s.writeError(w, http.StatusNotFound, errorCodeNotFound, "Slider is not mapped")
+			return
+		}
+
+		if err != nil {
+			s.requestLogger(r).Errorw("Failed to write config", "error", err)
+			s.writeError(w, http.StatusInternalServerError, errorCodeSaveFailed, "Failed to save configuration")
+			return
+		}
+
+		s.auditMappingChange(r, auditActionDelete, map[int][]string{sliderID: oldApps}, map[int][]string{})
+
+		s.writeJSON(w, genericResponse{
+			Success: true,
+			Message: "Slider mapping removed - config will auto-reload",
+		})
+
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (s *Server) handleSliderSettings(w http.ResponseWriter, r *http.Request, sliderID int) {
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSON(w, sliderSettingsResponse{
+			Invert:         s.deej.config.SliderInverted(sliderID),
+			NoiseThreshold: s.deej.config.SliderNoiseThreshold(sliderID),
+			VolumeCurve:    s.deej.config.SliderVolumeCurve(sliderID),
+			Smoothing:      s.deej.config.SliderSmoothing(sliderID),
+			Calibration:    s.deej.config.SliderCalibration(sliderID),
+			DeadZone:       s.deej.config.SliderDeadZone(sliderID),
+		})
+
+	case http.MethodPut:
+		var req updateSliderSettingsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeBodyError(w, err)
+			return
+		}
+
+		if req.NoiseThreshold != nil && !validNoiseThreshold(*req.NoiseThreshold) {
+			s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, "Noise threshold must be at least 0 and less than 1")
+			return
+		}
+
+		if req.VolumeCurve != nil {
+			if err := req.VolumeCurve.validate(); err != nil {
+				s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, fmt.Sprintf("Invalid volume curve: %v", err))
+				return
+			}
+		}
+
+		if req.Smoothing != nil {
+			if err := req.Smoothing.validate(); err != nil {
+				s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, fmt.Sprintf("Invalid smoothing: %v", err))
+				return
+			}
+		}
+
+		if req.Calibration != nil {
+			if err := req.Calibration.validate(); err != nil {
+				s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, fmt.Sprintf("Invalid calibration: %v", err))
+				return
+			}
+		}
+
+		if req.DeadZone != nil && !validDeadZone(*req.DeadZone) {
+			s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, "Dead zone must be at least 0 and less than 0.5")
+			return
+		}
+
+		// update the specific slider on top of the current settings, and write them back
+		err := s.deej.config.UpdateSliderSettings(func(currentSettings map[int]SliderSettings) error {
+			settings := currentSettings[sliderID]
+
+			if req.Invert != nil {
+				settings.Invert = req.Invert
+			}
+
+			if req.NoiseThreshold != nil {
+				settings.NoiseThreshold = req.NoiseThreshold
+			}
+
+			if req.VolumeCurve != nil {
+				settings.VolumeCurve = req.VolumeCurve
+			}
+
+			if req.Smoothing != nil {
+				settings.Smoothing = req.Smoothing
+			}
+
+			if req.Calibration != nil {
+				settings.Calibration = req.Calibration
+			}
+
+			// 0 turns it off, which doesn't need to stay in the config
+			if req.DeadZone != nil {
+				settings.DeadZone = req.DeadZone
+				if *req.DeadZone == 0 {
+					settings.DeadZone = nil
+				}
+			}
+
+			currentSettings[sliderID] = settings
+			return nil
+		})
+
+		if err != nil {
+			s.requestLogger(r).Errorw("Failed to write config", "error", err)
+			s.writeError(w, http.StatusInternalServerError, errorCodeSaveFailed, "Failed to save configuration")
+			return
+		}
+
+		s.writeJSON(w, genericResponse{
+			Success: true,
+			Message: "Slider settings updated - config will auto-reload",
+		})
+
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleSliderLabel reads or changes a slider's label. it goes with the slider's settings, but has nothing
+// to do with its behavior, so it gets an endpoint of its own
+func (s *Server) handleSliderLabel(w http.ResponseWriter, r *http.Request, sliderID int) {
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSON(w, sliderLabelRequest{Label: s.deej.config.SliderLabels()[sliderID]})
+
+	case http.MethodPut, http.MethodDelete:
+		var req sliderLabelRequest
+		if r.Method == http.MethodPut {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				s.writeBodyError(w, err)
+				return
+			}
+		}
+
+		label, err := normalizeSliderLabel(req.Label)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, fmt.Sprintf("Invalid label: %v", err))
+			return
+		}
+
+		err = s.deej.config.UpdateSliderSettings(func(currentSettings map[int]SliderSettings) error {
+			settings := currentSettings[sliderID]
+
+			// an empty label removes it
+			settings.Label = &label
+			if label == "" {
+				settings.Label = nil
+			}
+
+			currentSettings[sliderID] = settings
+			return nil
+		})
+
+		if err != nil {
+			s.requestLogger(r).Errorw("Failed to write config", "error", err)
+			s.writeError(w, http.StatusInternalServerError, errorCodeSaveFailed, "Failed to save configuration")
+			return
+		}
+
+		s.writeJSON(w, sliderLabelRequest{Label: label})
+
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleSliderFixedValue reads, sets or clears the value a slider is held at regardless of its input
+func (s *Server) handleSliderFixedValue(w http.ResponseWriter, r *http.Request, sliderID int) {
+	switch r.Method {
+	case http.MethodGet:
+		value, fixed := s.deej.config.SliderFixedValue(sliderID)
+		s.writeJSON(w, sliderFixedValueResponse{Fixed: fixed, Value: value})
+
+	case http.MethodPut, http.MethodDelete:
+		var req sliderFixedValueRequest
+		if r.Method == http.MethodPut {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Value == nil {
+				s.writeBodyError(w, err)
+				return
+			}
+
+			if !validFixedValue(*req.Value) {
+				s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, "The fixed value must be between 0 and 1")
+				return
+			}
+		}
+
+		err := s.deej.config.UpdateSliderSettings(func(currentSettings map[int]SliderSettings) error {
+			settings := currentSettings[sliderID]
+
+			// the config reload this triggers moves the slider there (or lets the board take it back)
+			settings.FixedValue = req.Value
+			currentSettings[sliderID] = settings
+
+			return nil
+		})
+
+		if err != nil {
+			s.requestLogger(r).Errorw("Failed to write config", "error", err)
+			s.writeError(w, http.StatusInternalServerError, errorCodeSaveFailed, "Failed to save configuration")
+			return
+		}
+
+		response := sliderFixedValueResponse{Fixed: req.Value != nil}
+		if req.Value != nil {
+			response.Value = float32(*req.Value)
+		}
+
+		s.writeJSON(w, response)
+
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (s *Server) handleSliderCalibration(w http.ResponseWriter, r *http.Request, sliderID int) {
+	switch r.Method {
+	case http.MethodGet:
+		response := calibrationResponse{
+			Success:     true,
+			Calibration: s.deej.config.SliderCalibration(sliderID),
+		}
+
+		observed, seen, calibrating := s.deej.serial.CalibrationProgress(sliderID)
+		response.Calibrating = calibrating
+
+		if seen {
+			response.Observed = &observed
+		}
+
+		s.writeJSON(w, response)
+
+	case http.MethodDelete:
+		err := s.deej.config.UpdateSliderSettings(func(currentSettings map[int]SliderSettings) error {
+			settings, ok := currentSettings[sliderID]
+			if !ok || settings.Calibration == nil {
+				return errNotCalibrated
+			}
+
+			settings.Calibration = nil
+			currentSettings[sliderID] = settings
+
+			return nil
+		})
+
+		if errors.Is(err, errNotCalibrated) {
+			s.writeError(w, http.StatusNotFound, errorCodeNotFound, "Slider is not calibrated")
+			return
+		}
+
+		if err != nil {
+			s.requestLogger(r).Errorw("Failed to write config", "error", err)
+			s.writeError(w, http.StatusInternalServerError, errorCodeSaveFailed, "Failed to save configuration")
+			return
+		}
+
+		s.writeJSON(w, genericResponse{
+			Success: true,
+			Message: "Slider calibration removed - config will auto-reload",
+		})
+
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (s *Server) handleStartSliderCalibration(w http.ResponseWriter, r *http.Request, sliderID int) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	s.deej.serial.StartCalibration(sliderID)
+	s.requestLogger(r).Infow("Started slider calibration", "sliderID", sliderID)
+
+	s.writeJSON(w, genericResponse{
+		Success: true,
+		Message: "Calibrating - move the slider all the way to both ends, then finish",
+	})
+}
+
+func (s *Server) handleFinishSliderCalibration(w http.ResponseWriter, r *http.Request, sliderID int) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	calibration, err := s.deej.serial.FinishCalibration(sliderID)
+	if errors.Is(err, errNotCalibrating) {
+		s.writeError(w, http.StatusConflict, errorCodeConflict, "Slider isn't being calibrated")
+		return
+	}
+
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, fmt.Sprintf("Calibration failed: %v", err))
+		return
+	}
+
+	err = s.deej.config.UpdateSliderSettings(func(currentSettings map[int]SliderSettings) error {
+		settings := currentSettings[sliderID]
+		settings.Calibration = &calibration
+		currentSettings[sliderID] = settings
+
+		return nil
+	})
+
+	if err != nil {
+		s.requestLogger(r).Errorw("Failed to write config", "error", err)
+		s.writeError(w, http.StatusInternalServerError, errorCodeSaveFailed, "Failed to save configuration")
+		return
+	}
+
+	s.requestLogger(r).Infow("Finished slider calibration", "sliderID", sliderID, "calibration", calibration)
+
+	s.writeJSON(w, calibrationResponse{
+		Success:     true,
+		Message:     "Slider calibrated - config will auto-reload",
+		Calibration: calibration,
+	})
+}
+
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	sessions := s.sessions.GetAllSessionKeys()
+	s.writeJSON(w, sessionsResponse{Sessions: sessions})
+}
+
+// handleSessionsTemplate suggests a mapping from the apps that are playing right now, for as many sliders as the
+// board has (or ?sliders=N). it never changes anything
+func (s *Server) handleSessionsTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	sliderCount := s.deej.serial.HardwareSliderCount()
+	if sliderCount == 0 {
+		sliderCount = defaultTemplateSliders
+	}
+
+	if raw := r.URL.Query().Get("sliders"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxTemplateSliders {
+			s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest,
+				fmt.Sprintf("sliders must be an integer between 1 and %d", maxTemplateSliders))
+
+			return
+		}
+
+		sliderCount = parsed
+	}
+
+	mapping, remaining := s.deej.sessions.MappingTemplate(sliderCount)
+
+	sliders := make(map[string][]string, len(mapping))
+	for sliderID, targets := range mapping {
+		sliders[strconv.Itoa(sliderID)] = targets
+	}
+
+	s.writeJSON(w, sessionsTemplateResponse{
+		Sliders:     sliders,
+		SliderCount: sliderCount,
+		Remaining:   remaining,
+	})
+}
+
+func (s *Server) handleTargets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	s.writeJSON(w, targetsResponse{Targets: s.deej.sessions.SpecialTargets()})
+}
+
+// targetVolume returns a target's last applied state, or nil if it has no active session
+func (s *Server) targetVolume(target string) *targetVolume {
+	state, ok := s.sessions.getTargetState(target)
+	if !ok {
+		return nil
+	}
+
+	return &targetVolume{
+		Volume: int(math.Round(float64(state.volume) * 100)),
+		Muted:  state.muted,
+	}
+}
+
+type muteRequest struct {
+	Target string `json:"target"`
+	Muted  bool   `json:"muted"`
+}
+
+// handleMute mutes or unmutes any target that could be mapped to a slider, such as master or mic
+func (s *Server) handleMute(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req muteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Target == "" {
+		s.writeBodyError(w, err)
+		return
+	}
+
+	found, err := s.deej.sessions.SetTargetMute(req.Target, req.Muted)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, errorCodeInternal, "Failed to change mute state")
+		return
+	}
+
+	if !found {
+		s.writeError(w, http.StatusNotFound, errorCodeNotFound, "Target has no active audio session")
+		return
+	}
+
+	s.writeJSON(w, genericResponse{Success: true, Message: "Mute state updated"})
+}
+
+// pauseRequest leaves paused out to toggle it
+type pauseRequest struct {
+	Paused *bool `json:"paused"`
+}
+
+type pauseResponse struct {
+	Paused bool `json:"paused"`
+}
+
+// handlePause reports whether slider moves are paused, or pauses or unpauses them. a POST without a body toggles
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSON(w, pauseResponse{Paused: s.deej.sessions.Paused()})
+
+	case http.MethodPost:
+		var req pauseRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			s.writeBodyError(w, err)
+			return
+		}
+
+		paused := !s.deej.sessions.Paused()
+		if req.Paused != nil {
+			paused = *req.Paused
+		}
+
+		s.deej.sessions.SetPaused(paused)
+
+		s.writeJSON(w, pauseResponse{Paused: paused})
+
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+type resetResponse struct {
+	genericResponse
+	VolumeResetSummary
+}
+
+// handleReset sets every mapped target back to its reset volume and unmutes it, see volume_reset.go
+func (s *Server) handleReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	summary := s.deej.sessions.ResetVolumes()
+
+	message := fmt.Sprintf("Reset %d targets", len(summary.Reset))
+	if summary.Paused {
+		message += " - slider moves are paused, and unpausing will apply the sliders' positions again"
+	}
+
+	s.writeJSON(w, resetResponse{
+		genericResponse:    genericResponse{Success: len(summary.Failed) == 0, Message: message},
+		VolumeResetSummary: summary,
+	})
+}
+
+type devicesResponse struct {
+	Devices []AudioDevice `json:"devices"`
+}
+
+type defaultDeviceRequest struct {
+	ID string `json:"id"`
+}
+
+func (s *Server) handleDevices(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	devices, err := s.deej.sessions.GetPlaybackDevices()
+	if err != nil {
+		s.writeDeviceError(w, r, err, "Failed to list playback devices")
+		return
+	}
+
+	s.writeJSON(w, devicesResponse{Devices: devices})
+}
+
+// handleDefaultDevice switches the OS default playback device, which master follows
+func (s *Server) handleDefaultDevice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req defaultDeviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+		s.writeBodyError(w, err)
+		return
+	}
+
+	if err := s.deej.sessions.SetDefaultPlaybackDevice(req.ID); err != nil {
+		s.writeDeviceError(w, r, err, "Failed to change default playback device")
+		return
+	}
+
+	s.writeJSON(w, genericResponse{Success: true, Message: "Default playback device changed"})
+}
+
+func (s *Server) writeDeviceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	statusCode := http.StatusInternalServerError
+	code := errorCodeInternal
+
+	switch {
+	case errors.Is(err, errPlaybackDeviceNotFound):
+		statusCode = http.StatusNotFound
+		code = errorCodeNotFound
+		message = "Playback device not found"
+	case errors.Is(err, errPlaybackDevicesUnsupported):
+		statusCode = http.StatusNotImplemented
+		code = errorCodeUnsupported
+		message = "Playback device management isn't supported on this platform"
+	default:
+		s.requestLogger(r).Warnw(message, "error", err)
+	}
+
+	s.writeError(w, statusCode, code, message)
+}
+
+type configReloadResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Code    string `json:"code,omitempty"`
+
+	// why the file couldn't be loaded at all, in which case the previous config stays in effect
+	Errors []string `json:"errors"`
+
+	// values that were invalid and replaced with their defaults, the rest of the config still applies
+	Warnings []string `json:"warnings"`
+}
+
+// handleConfigReload reloads the config from disk on demand, for when the file watcher misses an edit
+func (s *Server) handleConfigReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	warnings, err := s.deej.config.Reload()
+	if err != nil {
+		s.writeJSONStatus(w, http.StatusUnprocessableEntity, configReloadResponse{
+			Success:  false,
+			Message:  "Failed to reload config - the previous config is still in effect",
+			Code:     errorCodeInvalidConfig,
+			Errors:   []string{err.Error()},
+			Warnings: []string{},
+		})
+		return
+	}
+
+	if warnings == nil {
+		warnings = []string{}
+	}
+
+	s.writeJSON(w, configReloadResponse{
+		Success:  true,
+		Message:  "Config reloaded",
+		Errors:   []string{},
+		Warnings: warnings,
+	})
+}
+
+// handleConfigExport serves every tunable setting as a JSON file, for backing up or sharing a setup
+func (s *Server) handleConfigExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="deej-config.json"`)
+	s.writeJSON(w, s.deej.config.Export())
+}
+
+// handleSchema serves a JSON Schema for the mapping and settings requests, see config_schema.go
+func (s *Server) handleSchema(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	targets := []string{}
+	for _, target := range s.deej.sessions.SpecialTargets() {
+		if target.Supported {
+			targets = append(targets, target.Key)
+		}
+	}
+
+	schema, err := buildConfigSchema(targets)
+	if err != nil {
+		s.requestLogger(r).Errorw("Failed to build config schema", "error", err)
+		s.writeError(w, http.StatusInternalServerError, errorCodeInternal, "Failed to build config schema")
+		return
+	}
+
+	s.writeJSON(w, schema)
+}
+
+// handleConfigImport replaces every tunable setting with the ones from an export, if all of them are valid
+func (s *Server) handleConfigImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req ConfigExport
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeBodyError(w, err)
+		return
+	}
+
+	oldMapping := s.deej.config.GetSliderMappingRaw()
+
+	if err := s.deej.config.Import(req); err != nil {
+		if errors.Is(err, errInvalidConfigImport) {
+			s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, err.Error())
+			return
+		}
+
+		s.requestLogger(r).Errorw("Failed to import config", "error", err)
+		s.writeError(w, http.StatusInternalServerError, errorCodeSaveFailed, "Failed to save configuration")
+		return
+	}
+
+	// the import was validated, so its mapping is known to be fine
+	newMapping, _ := mappingFromExport("sliderMapping", "slider", req.SliderMapping)
+	s.auditMappingChange(r, auditActionImport, oldMapping, newMapping)
+
+	s.writeJSON(w, genericResponse{Success: true, Message: "Config imported"})
+}
+
+type profileInfo struct {
+	Name    string              `json:"name"`
+	Sliders map[string][]string `json:"sliders"`
+}
+
+type profilesResponse struct {
+	Profiles []profileInfo `json:"profiles"`
+
+	// empty if slider_mapping isn't coming from any profile
+	Active string `json:"active"`
+}
+
+type activateProfileRequest struct {
+	Name string `json:"name"`
+}
+
+func (s *Server) handleProfiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	profiles := []profileInfo{}
+
+	for name, mapping := range s.deej.config.GetProfilesRaw() {
+		sliders := make(map[string][]string)
+		for k, v := range mapping {
+			sliders[strconv.Itoa(k)] = v
+		}
+
+		profiles = append(profiles, profileInfo{Name: name, Sliders: sliders})
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+
+	s.writeJSON(w, profilesResponse{
+		Profiles: profiles,
+		Active:   s.deej.config.ActiveProfile,
+	})
+}
+
+// handleActivateProfile swaps in a profile's slider mapping, applying it right away
+func (s *Server) handleActivateProfile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req activateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Name == "" {
+		s.writeBodyError(w, err)
+		return
+	}
+
+	oldMapping := s.deej.config.GetSliderMappingRaw()
+
+	if err := s.deej.config.ActivateProfile(req.Name); err != nil {
+		if errors.Is(err, errProfileNotFound) {
+			s.writeError(w, http.StatusNotFound, errorCodeNotFound, "Profile not found")
+			return
+		}
+
+		s.requestLogger(r).Warnw("Failed to activate profile", "name", req.Name, "error", err)
+		s.writeError(w, http.StatusInternalServerError, errorCodeInternal, "Failed to activate profile")
+		return
+	}
+
+	s.auditMappingChange(r, auditActionProfile, oldMapping, s.deej.config.GetSliderMappingRaw())
+
+	s.writeJSON(w, genericResponse{Success: true, Message: "Profile activated"})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	rawMapping := s.config.GetSliderMappingRaw()
+
+	volumes := make(map[string]map[string]*targetVolume)
+	sliderVolumeCurves := make(map[string]VolumeCurve)
+
+	for sliderIdx, targets := range rawMapping {
+		sliderVolumeCurves[strconv.Itoa(sliderIdx)] = s.config.SliderVolumeCurve(sliderIdx)
+
+		sliderVolumes := make(map[string]*targetVolume)
+
+		for _, target := range targets {
+			sliderVolumes[target] = s.targetVolume(target)
+		}
+
+		volumes[strconv.Itoa(sliderIdx)] = sliderVolumes
+	}
+
+	frameFormat, malformedFrames := s.deej.serial.FrameFormat()
+
+	fixedSliders := make(map[string]float32)
+	for sliderIdx, value := range s.config.FixedSliders() {
+		fixedSliders[strconv.Itoa(sliderIdx)] = value
+	}
+
+	configVersion, configLoadedAt := s.config.Version()
+
+	s.writeJSON(w, statusResponse{
+		Status:              "running",
+		FirstRun:            s.config.FirstRun(),
+		SliderCount:         len(rawMapping),
+		HardwareSliderCount: s.deej.serial.HardwareSliderCount(),
+		WebURL:              s.GetURL(),
+		SerialPort:          s.deej.serial.ActivePort(),
+		Connected:           s.deej.serial.Connected(),
+		Volumes:             volumes,
+
+		FrameFormat:     frameFormat,
+		MalformedFrames: malformedFrames,
+		SerialSources:   s.deej.serial.Sources(),
+		Paused:          s.sessions.Paused(),
+		Warnings:        s.deej.serial.SliderWarnings(),
+
+		SessionRefreshInterval: s.config.SessionRefreshPeriod().Seconds(),
+		SessionTimeouts:        s.sessions.SessionTimeouts(),
+
+		FixedSliders: fixedSliders,
+
+		ConfigVersion:  configVersion,
+		ConfigLoadedAt: configLoadedAt,
+
+		VolumeCurve:        s.config.DefaultVolumeCurve(),
+		SliderVolumeCurves: sliderVolumeCurves,
+
+		Master: s.targetVolume(masterSessionName),
+		Mic:    s.targetVolume(inputSessionName),
+
+		CurrentWindowTargets: s.sessions.CurrentWindowTargets(),
+
+		ExternalVolumePolicy:  s.config.ExternalVolumePolicy(),
+		ExternalVolumeChanges: s.externalVolumeChanges(),
+
+		ButtonMutes:    s.buttonMutes(),
+		SliderActivity: s.sliderActivity(),
+	})
+}
+
+// sliderActivity keys the activity of every slider there's a value for by its index
+func (s *Server) sliderActivity() map[string]SliderActivity {
+	activity := make(map[string]SliderActivity)
+
+	for sliderIdx, sliderActivity := range s.deej.serial.SliderActivity() {
+		activity[strconv.Itoa(sliderIdx)] = sliderActivity
+	}
+
+	return activity
+}
+
+// buttonMutes returns the mute state of every target a button is mapped to
+func (s *Server) buttonMutes() map[string]map[string]*bool {
+	mutes := make(map[string]map[string]*bool)
+
+	for buttonIdx, targets := range s.config.GetButtonMappingRaw() {
+		buttonMutes := make(map[string]*bool)
+
+		for _, target := range targets {
+			if state := s.targetVolume(target); state != nil {
+				muted := state.Muted
+				buttonMutes[target] = &muted
+			} else {
+				buttonMutes[target] = nil
+			}
+		}
+
+		mutes[strconv.Itoa(buttonIdx)] = buttonMutes
+	}
+
+	return mutes
+}
+
+// externalVolumeChanges lists the sessions changed from outside of deej, sorted by key
+func (s *Server) externalVolumeChanges() []externalVolumeChange {
+	changes := []externalVolumeChange{}
+
+	for _, change := range s.sessions.ExternalVolumeChanges() {
+		changes = append(changes, externalVolumeChange{
+			Key:        change.Key,
+			Set:        int(math.Round(float64(change.Set) * 100)),
+			Observed:   int(math.Round(float64(change.Observed) * 100)),
+			DetectedAt: change.DetectedAt,
+			Respected:  change.Respected,
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+
+	return changes
+}
+
+const (
+	healthSerialConnected    = "connected"
+	healthSerialDisconnected = "disconnected"
+)
+
+type healthResponse struct {
+	Serial string `json:"serial"`
+
+	// both in whole seconds. disconnectedFor is 0 while the serial connection is up
+	Uptime          int64 `json:"uptime"`
+	DisconnectedFor int64 `json:"disconnectedFor"`
+}
+
+// handleHealth is meant for monitoring, so it only looks at state deej already has and never queries the OS.
+// it reports unhealthy once the serial connection has been down for longer than the configured grace period
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	disconnectedFor := s.deej.serial.DisconnectedFor()
+
+	response := healthResponse{
+		Serial:          healthSerialConnected,
+		Uptime:          int64(s.deej.Uptime() / time.Second),
+		DisconnectedFor: int64(disconnectedFor / time.Second),
+	}
+
+	if disconnectedFor > 0 {
+		response.Serial = healthSerialDisconnected
+	}
+
+	statusCode := http.StatusOK
+	if disconnectedFor > s.deej.config.WebServer.HealthGracePeriod {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	s.writeJSONStatus(w, statusCode, response)
+}
+
+type midiResponse struct {
+	Enabled bool `json:"enabled"`
+
+	// the name of the open MIDI input, empty when there isn't one
+	Input string `json:"input"`
+
+	// CC numbers keyed by slider index
+	Mapping map[int]int `json:"mapping"`
+}
+
+func (s *Server) handleMIDI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	s.writeJSON(w, midiResponse{
+		Enabled: s.deej.config.MIDI.Enabled,
+		Input:   s.deej.midi.InputName(),
+		Mapping: s.deej.config.GetMIDIMappingRaw(),
+	})
+}
+
+// handleMIDILearn holds the request until a control is moved on the MIDI input, and reports which one it was
+func (s *Server) handleMIDILearn(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), midiLearnTimeout)
+	defer cancel()
+
+	change, err := s.deej.midi.Learn(ctx)
+
+	switch {
+	case err == nil:
+		s.writeJSON(w, change)
+	case errors.Is(err, errMIDINotRunning):
+		s.writeError(w, http.StatusServiceUnavailable, errorCodeUnavailable, "No MIDI input is open, check that MIDI is enabled and your device is connected")
+	case errors.Is(err, context.DeadlineExceeded):
+		s.writeError(w, http.StatusRequestTimeout, errorCodeTimeout, "No MIDI control was moved in time")
+	}
+
+	// otherwise the client went away, and there's no one left to respond to
+}
+
+type midiMappingRequest struct {
+	Slider int `json:"slider"`
+
+	// omitted or null unassigns the slider
+	Controller *int `json:"controller"`
+}
+
+// handleMIDIMapping assigns a CC number to a single slider (or takes it away), leaving the rest of the mapping as-is
+func (s *Server) handleMIDIMapping(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req midiMappingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Slider < 0 {
+		s.writeBodyError(w, err)
+		return
+	}
+
+	if req.Controller != nil && (*req.Controller < 0 || *req.Controller > midiMaxControllerValue) {
+		s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, "CC numbers must be between 0 and 127")
+		return
+	}
+
+	// assigned on top of the current mapping under the config's lock, so a PUT right after another builds on it
+	err := s.deej.config.UpdateMIDIMapping(func(mapping map[int]int) error {
+		if req.Controller == nil {
+			delete(mapping, req.Slider)
+		} else {
+			mapping[req.Slider] = *req.Controller
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		s.requestLogger(r).Errorw("Failed to write config", "error", err)
+		s.writeError(w, http.StatusInternalServerError, errorCodeSaveFailed, "Failed to save configuration")
+		return
+	}
+
+	s.writeJSON(w, genericResponse{
+		Success: true,
+		Message: "MIDI mapping updated - config will auto-reload",
+	})
+}
+
+type webhooksMessage struct {
+	Webhooks []WebhookRule `json:"webhooks"`
+}
+
+// handleWebhooks lists the webhook rules, or replaces all of them at once
+func (s *Server) handleWebhooks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSON(w, webhooksMessage{Webhooks: s.deej.config.GetWebhooksRaw()})
+
+	case http.MethodPut:
+		var req webhooksMessage
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeBodyError(w, err)
+			return
+		}
+
+		for idx, rule := range req.Webhooks {
+			if err := rule.validate(); err != nil {
+				s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, fmt.Sprintf("webhooks[%d]: %s", idx, err))
+				return
+			}
+		}
+
+		if err := s.deej.config.WriteWebhooks(req.Webhooks); err != nil {
+			s.requestLogger(r).Errorw("Failed to write config", "error", err)
+			s.writeError(w, http.StatusInternalServerError, errorCodeSaveFailed, "Failed to save configuration")
+			return
+		}
+
+		s.writeJSON(w, genericResponse{
+			Success: true,
+			Message: "Webhooks replaced - config will auto-reload",
+		})
+
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+type sliderActionsMessage struct {
+	Actions []SliderAction `json:"actions"`
+}
+
+type sliderActionsResponse struct {
+
+	// whether the actions run at all, and whether PUT can replace them. both can only be changed in config.yaml
+	Enabled  bool `json:"enabled"`
+	Editable bool `json:"editable"`
+
+	Actions []SliderAction `json:"actions"`
+}
+
+// handleSliderActions lists the slider actions, or replaces all of them at once if the config allows it
+func (s *Server) handleSliderActions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		settings := s.deej.config.GetSliderActionsRaw()
+
+		s.writeJSON(w, sliderActionsResponse{
+			Enabled:  settings.Enabled,
+			Editable: settings.AllowAPIEdit,
+			Actions:  settings.Actions,
+		})
+
+	case http.MethodPut:
+		if !s.deej.config.SliderActions.AllowAPIEdit {
+			s.writeError(w, http.StatusForbidden, errorCodeForbidden,
+				"Slider actions can only be edited in config.yaml (set allow_api_edit under slider_actions to allow it)")
+			return
+		}
+
+		var req sliderActionsMessage
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeBodyError(w, err)
+			return
+		}
+
+		for idx := range req.Actions {
+			if err := req.Actions[idx].validate(); err != nil {
+				s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, fmt.Sprintf("actions[%d]: %s", idx, err))
+				return
+			}
+		}
+
+		if err := s.deej.config.WriteSliderActions(req.Actions); err != nil {
+			s.requestLogger(r).Errorw("Failed to write config", "error", err)
+			s.writeError(w, http.StatusInternalServerError, errorCodeSaveFailed, "Failed to save configuration")
+			return
+		}
+
+		s.requestLogger(r).Infow("Slider actions replaced over the API", "actions", len(req.Actions))
+
+		s.writeJSON(w, genericResponse{
+			Success: true,
+			Message: "Slider actions replaced - config will auto-reload",
+		})
+
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+type sliderMappingResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+
+	// the slider mapping that's now in effect, in the same shape as GET /api/sliders
+	Sliders map[string][]string `json:"sliders"`
+}
+
+// both are required, since 0 is a valid slider ID
+type swapSlidersRequest struct {
+	First  *int `json:"first"`
+	Second *int `json:"second"`
+}
+
+// handleSwapSliders exchanges what two sliders control, in a single write. a slider that isn't mapped
+// counts as mapped to nothing, so swapping with it moves the other one's apps over
+func (s *Server) handleSwapSliders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req swapSlidersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeBodyError(w, err)
+		return
+	}
+
+	if req.First == nil || req.Second == nil || *req.First < 0 || *req.Second < 0 {
+		s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, "first and second must both be non-negative slider IDs")
+		return
+	}
+
+	first, second := *req.First, *req.Second
+
+	if first == second {
+		s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, "Can't swap a slider with itself")
+		return
+	}
+
+	oldMapping, mapping, err := s.deej.config.UpdateSliderMapping(func(mapping map[int][]string) error {
+		firstApps, firstMapped := mapping[first]
+		secondApps, secondMapped := mapping[second]
+
+		delete(mapping, first)
+		delete(mapping, second)
+
+		if secondMapped {
+			mapping[first] = secondApps
+		}
+
+		if firstMapped {
+			mapping[second] = firstApps
+		}
+
+		return nil
+	})
+	if err != nil {
+		s.requestLogger(r).Errorw("Failed to write config", "error", err)
+		s.writeError(w, http.StatusInternalServerError, errorCodeSaveFailed, "Failed to save configuration")
+		return
+	}
+
+	s.auditMappingChange(r, auditActionSwap, oldMapping, mapping)
+
+	s.writeJSON(w, sliderMappingResponse{
+		Success: true,
+		Message: fmt.Sprintf("Swapped sliders %d and %d - config will auto-reload", first, second),
+		Sliders: slidersByKey(mapping),
+	})
+}
+
+// handleMappingUndo restores the slider mapping from before the last edit
+func (s *Server) handleMappingUndo(w http.ResponseWriter, r *http.Request) {
+	s.handleMappingHistory(w, r, s.deej.config.UndoSliderMapping, errNothingToUndo, auditActionUndo, "Slider mapping edit undone")
+}
+
+// handleMappingRedo restores the slider mapping from before the last undo
+func (s *Server) handleMappingRedo(w http.ResponseWriter, r *http.Request) {
+	s.handleMappingHistory(w, r, s.deej.config.RedoSliderMapping, errNothingToRedo, auditActionRedo, "Slider mapping edit redone")
+}
+
+func (s *Server) handleMappingHistory(
+	w http.ResponseWriter,
+	r *http.Request,
+	step func() (map[int][]string, error),
+	emptyErr error,
+	auditAction string,
+	message string,
+) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	oldMapping := s.deej.config.GetSliderMappingRaw()
+
+	mapping, err := step()
+	if err != nil {
+		if errors.Is(err, emptyErr) {
+			s.writeError(w, http.StatusConflict, errorCodeConflict, err.Error())
+			return
+		}
+
+		s.requestLogger(r).Errorw("Failed to write config", "error", err)
+		s.writeError(w, http.StatusInternalServerError, errorCodeSaveFailed, "Failed to save configuration")
+		return
+	}
+
+	s.auditMappingChange(r, auditAction, oldMapping, mapping)
+
+	s.writeJSON(w, sliderMappingResponse{
+		Success: true,
+		Message: message + " - config will auto-reload",
+		Sliders: slidersByKey(mapping),
+	})
+}
+
+// sliderLabelsByKey converts slider labels to the string-keyed shape used in JSON
+func sliderLabelsByKey(labels map[int]string) map[string]string {
+	byKey := make(map[string]string, len(labels))
+	for sliderIdx, label := range labels {
+		byKey[strconv.Itoa(sliderIdx)] = label
+	}
+
+	return byKey
+}
+
+// slidersByKey converts a slider mapping to the string-keyed shape used in JSON
+func slidersByKey(mapping map[int][]string) map[string][]string {
+	sliders := make(map[string][]string, len(mapping))
+	for sliderIdx, targets := range mapping {
+		sliders[strconv.Itoa(sliderIdx)] = targets
+	}
+
+	return sliders
+}
+
+type volumeLimitsMessage struct {
+	Limits []VolumeLimit `json:"limits"`
+}
+
+// handleVolumeLimits lists the volume limits, or replaces all of them at once
+func (s *Server) handleVolumeLimits(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSON(w, volumeLimitsMessage{Limits: s.deej.config.GetVolumeLimitsRaw()})
+
+	case http.MethodPut:
+		var req volumeLimitsMessage
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeBodyError(w, err)
+			return
+		}
+
+		targets := make(map[string]bool, len(req.Limits))
+
+		for idx := range req.Limits {
+			if err := req.Limits[idx].validate(); err != nil {
+				s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, fmt.Sprintf("limits[%d]: %s", idx, err))
+				return
+			}
+
+			if targets[req.Limits[idx].Target] {
+				s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, fmt.Sprintf("limits[%d]: duplicate target %q", idx, req.Limits[idx].Target))
+				return
+			}
+
+			targets[req.Limits[idx].Target] = true
+		}
+
+		if err := s.deej.config.WriteVolumeLimits(req.Limits); err != nil {
+			s.requestLogger(r).Errorw("Failed to write config", "error", err)
+			s.writeError(w, http.StatusInternalServerError, errorCodeSaveFailed, "Failed to save configuration")
+			return
+		}
+
+		s.writeJSON(w, genericResponse{
+			Success: true,
+			Message: "Volume limits replaced - config will auto-reload",
+		})
+
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+type balancesResponse struct {
+	Balances map[string]float32 `json:"balances"`
+}
+
+type balanceRequest struct {
+	Target  string  `json:"target" schema:"required,minLength=1,examples=targets"`
+	Balance float64 `json:"balance" schema:"minimum=-1,maximum=1"`
+}
+
+type balanceResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+
+	// false when none of the target's current sessions can be balanced, in which case it's saved but does nothing
+	Supported bool `json:"supported"`
+}
+
+// handleBalance lists the targets' balances, and sets (or with 0, removes) a single target's balance
+func (s *Server) handleBalance(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSON(w, balancesResponse{Balances: s.deej.config.GetBalancesRaw()})
+
+	case http.MethodPut:
+		var req balanceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeBodyError(w, err)
+			return
+		}
+
+		target := strings.ToLower(strings.TrimSpace(req.Target))
+		if target == "" {
+			s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, "Target can't be empty")
+			return
+		}
+
+		if !validBalance(req.Balance) {
+			s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, "Balance must be between -1 and 1")
+			return
+		}
+
+		// set on top of the current balances under the config's lock, so a PUT right after another builds on it
+		err := s.deej.config.UpdateBalances(func(balances map[string]float32) error {
+			if req.Balance == 0 {
+				delete(balances, target)
+			} else {
+				balances[target] = float32(req.Balance)
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			s.requestLogger(r).Errorw("Failed to write config", "error", err)
+			s.writeError(w, http.StatusInternalServerError, errorCodeSaveFailed, "Failed to save configuration")
+			return
+		}
+
+		response := balanceResponse{
+			Success:   true,
+			Message:   "Balance saved - config will auto-reload",
+			Supported: s.deej.sessions.TargetCanBalance(target),
+		}
+
+		if !response.Supported {
+			response.Message = "Balance saved, but none of the target's current sessions support balance"
+		}
+
+		s.writeJSON(w, response)
+
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+type preferencesResponse struct {
+	Preferences map[string]interface{} `json:"preferences"`
+}
+
+// handlePreferences reads the web UI's preferences, or changes some of them
+func (s *Server) handlePreferences(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSON(w, preferencesResponse{Preferences: s.deej.config.GetUIPreferencesRaw()})
+
+	case http.MethodPut:
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req == nil {
+			s.writeBodyError(w, err)
+			return
+		}
+
+		// merged into the current preferences under the config's lock, so a PUT right after another builds on it
+		var invalid error
+		preferences, err := s.deej.config.UpdateUIPreferences(func(current map[string]interface{}) (map[string]interface{}, error) {
+			merged, err := mergeUIPreferences(current, req)
+			invalid = err
+
+			return merged, err
+		})
+
+		if invalid != nil {
+			s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, fmt.Sprintf("Invalid preferences: %v", invalid))
+			return
+		}
+
+		if err != nil {
+			s.requestLogger(r).Errorw("Failed to write config", "error", err)
+			s.writeError(w, http.StatusInternalServerError, errorCodeSaveFailed, "Failed to save configuration")
+			return
+		}
+
+		// the config reload that picks them up is still on its way
+		s.writeJSON(w, preferencesResponse{Preferences: preferences})
+
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+type logLevelMessage struct {
+	Level string `json:"level"`
+}
+
+// handleLogLevel shows and changes the level deej logs at, without a restart. it goes back to the build's
+// default on the next launch
+func (s *Server) handleLogLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSON(w, logLevelMessage{Level: LogLevel().String()})
+
+	case http.MethodPut:
+		var req logLevelMessage
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeBodyError(w, err)
+			return
+		}
+
+		previous := LogLevel()
+
+		if err := SetLogLevel(strings.ToLower(strings.TrimSpace(req.Level))); err != nil {
+			s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, err.Error())
+			return
+		}
+
+		s.requestLogger(r).Infow("Changed log level", "from", previous, "to", LogLevel())
+
+		s.writeJSON(w, logLevelMessage{Level: LogLevel().String()})
+
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+type sliderGroupsResponse struct {
+	Groups []SliderGroup `json:"groups"`
+}
+
+// handleSliderGroups lists the slider groups. they're only configured in config.yaml
+func (s *Server) handleSliderGroups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	s.writeJSON(w, sliderGroupsResponse{Groups: s.deej.config.GetSliderGroupsRaw()})
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, data interface{}) {
+	s.writeJSONStatus(w, http.StatusOK, data)
+}
+
+// writeError is how every API error is answered, so they all look the same no matter where they come from
+func (s *Server) writeError(w http.ResponseWriter, statusCode int, code string, message string) {
+	s.writeJSONStatus(w, statusCode, genericResponse{Success: false, Message: message, Code: code})
+}
+
+func (s *Server) writeJSONStatus(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
 
-func (s *Server) writeJSON(w http.ResponseWriter, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(data); err != nil {
 		s.logger.Errorw("Failed to encode JSON response", "error", err)
 	}
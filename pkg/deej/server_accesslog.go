@@ -0,0 +1,97 @@
+package deej
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// something polling /api/health every second would otherwise bury every other line of the debug log. requests
+// to the paths listed under web_server.access_log_exclude are left out of it, or with access_log_sample set to N,
+// only every Nth of them is logged. requests to those paths that fail are always logged
+
+// accessLogSampler counts requests to each excluded path, to pick out every Nth one
+type accessLogSampler struct {
+	lock   sync.Mutex
+	counts map[string]uint64
+}
+
+// sample returns whether this request to an excluded path is the one out of every n that gets logged
+func (a *accessLogSampler) sample(path string, n int) bool {
+	if n <= 0 {
+		return false
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.counts == nil {
+		a.counts = make(map[string]uint64)
+	}
+
+	count := a.counts[path]
+	a.counts[path] = count + 1
+
+	return count%uint64(n) == 0
+}
+
+// shouldLogRequest decides whether a finished request goes to the access log
+func (s *Server) shouldLogRequest(r *http.Request, status int) bool {
+	settings := s.deej.config.WebServer
+
+	if status >= http.StatusBadRequest {
+		return true
+	}
+
+	pattern, excluded := accessLogExcludedPattern(settings.AccessLogExclude, r.URL.Path)
+	if !excluded {
+		return true
+	}
+
+	// by the pattern rather than the path, so a wildcard samples all of its paths together
+	return s.accessLogSampler.sample(pattern, settings.AccessLogSample)
+}
+
+// accessLogExcludedPattern returns the first exclusion a path matches. patterns match a path exactly, unless
+// they end in "*", in which case they match every path starting with the rest (i.e. "/api/serial/*")
+func accessLogExcludedPattern(patterns []string, path string) (string, bool) {
+	for _, pattern := range patterns {
+		if prefix := strings.TrimSuffix(pattern, "*"); prefix != pattern {
+			if strings.HasPrefix(path, prefix) {
+				return pattern, true
+			}
+
+			continue
+		}
+
+		if path == pattern {
+			return pattern, true
+		}
+	}
+
+	return "", false
+}
+
+// accessLogExcludeFromConfig cleans up the configured exclusions, which are relative to the base path
+func accessLogExcludeFromConfig(
+	patterns []string,
+	warnInvalidValue func(message string, key string, keysAndValues ...interface{}),
+) []string {
+	result := []string{}
+
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+
+		if !strings.HasPrefix(pattern, "/") {
+			warnInvalidValue("Invalid access log exclusion specified, it must start with a slash - ignoring it",
+				configKeyAccessLogExclude,
+				"invalidValue", pattern)
+
+			continue
+		}
+
+		result = append(result, pattern)
+	}
+
+	return result
+}
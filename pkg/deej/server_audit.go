@@ -0,0 +1,186 @@
+package deej
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// every change the API makes to the slider mapping is kept in an audit log, so shared setups can tell who changed
+// what. it only lives in memory and holds the most recent auditLogSize changes, but every entry is also logged.
+// GET /api/audit lists them, newest first
+
+const (
+	auditLogSize = 200
+
+	// a change to several sliders at once gets an entry for each
+	auditActionUpdate  = "update"
+	auditActionDelete  = "delete"
+	auditActionReplace = "replace"
+	auditActionSwap    = "swap"
+	auditActionUndo    = "undo"
+	auditActionRedo    = "redo"
+	auditActionImport  = "import"
+	auditActionProfile = "profile"
+)
+
+// AuditEntry is a change to a single slider's apps
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	Action   string    `json:"action"`
+	SliderID int       `json:"sliderId"`
+
+	// empty when the slider wasn't mapped before, or isn't anymore
+	OldApps []string `json:"oldApps"`
+	NewApps []string `json:"newApps"`
+
+	// where the request came from. forwardedFor is whatever a reverse proxy claims the client was, if there is one
+	RemoteAddr   string `json:"remoteAddr"`
+	ForwardedFor string `json:"forwardedFor,omitempty"`
+	RequestID    string `json:"requestId"`
+
+	// deej has a single token, so this only tells which one was in use (i.e. before or after it was changed).
+	// a short hash of it, empty with auth off
+	Token string `json:"token,omitempty"`
+}
+
+type auditResponse struct {
+	Entries []AuditEntry `json:"entries"`
+}
+
+// auditLog keeps the most recent entries in a ring, same as the log buffer
+type auditLog struct {
+	lock sync.Mutex
+
+	entries []AuditEntry
+	next    int
+}
+
+func newAuditLog(size int) *auditLog {
+	return &auditLog{
+		entries: make([]AuditEntry, 0, size),
+	}
+}
+
+// add never blocks on anything but the ring itself
+func (a *auditLog) add(entry AuditEntry) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if len(a.entries) < cap(a.entries) {
+		a.entries = append(a.entries, entry)
+	} else {
+		a.entries[a.next] = entry
+		a.next = (a.next + 1) % len(a.entries)
+	}
+}
+
+// recent returns up to limit entries, newest first. 0 means all of them
+func (a *auditLog) recent(limit int) []AuditEntry {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	ordered := make([]AuditEntry, 0, len(a.entries))
+	ordered = append(ordered, a.entries[a.next:]...)
+	ordered = append(ordered, a.entries[:a.next]...)
+
+	result := make([]AuditEntry, 0, len(ordered))
+	for idx := len(ordered) - 1; idx >= 0 && (limit <= 0 || len(result) < limit); idx-- {
+		result = append(result, ordered[idx])
+	}
+
+	return result
+}
+
+// auditMappingChange records every slider whose apps differ between two mappings, once the new one was written
+func (s *Server) auditMappingChange(r *http.Request, action string, oldMapping, newMapping map[int][]string) {
+	sliderIDs := []int{}
+	for sliderID := range oldMapping {
+		sliderIDs = append(sliderIDs, sliderID)
+	}
+
+	for sliderID := range newMapping {
+		if _, ok := oldMapping[sliderID]; !ok {
+			sliderIDs = append(sliderIDs, sliderID)
+		}
+	}
+
+	sort.Ints(sliderIDs)
+
+	remoteAddr := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		remoteAddr = host
+	}
+
+	now := time.Now()
+
+	for _, sliderID := range sliderIDs {
+		oldApps, newApps := oldMapping[sliderID], newMapping[sliderID]
+		if len(oldApps) == 0 && len(newApps) == 0 || reflect.DeepEqual(oldApps, newApps) {
+			continue
+		}
+
+		entry := AuditEntry{
+			Time:         now,
+			Action:       action,
+			SliderID:     sliderID,
+			OldApps:      auditApps(oldApps),
+			NewApps:      auditApps(newApps),
+			RemoteAddr:   remoteAddr,
+			ForwardedFor: r.Header.Get("X-Forwarded-For"),
+			RequestID:    requestID(r),
+			Token:        auditTokenHash(s.deej.config.WebServer.Token),
+		}
+
+		s.audit.add(entry)
+
+		s.requestLogger(r).Infow("Slider mapping changed",
+			"action", action,
+			"sliderID", sliderID,
+			"oldApps", entry.OldApps,
+			"newApps", entry.NewApps,
+			"remoteAddr", remoteAddr)
+	}
+}
+
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 {
+			s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, "limit must be a non-negative integer")
+			return
+		}
+
+		limit = parsed
+	}
+
+	s.writeJSON(w, auditResponse{Entries: s.audit.recent(limit)})
+}
+
+// auditApps copies a slider's apps, so later edits to the mapping don't reach into the log
+func auditApps(apps []string) []string {
+	return append([]string{}, apps...)
+}
+
+// auditTokenHash returns the first few bytes of the token's hash, enough to tell two tokens apart
+func auditTokenHash(token string) string {
+	if token == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:4])
+}
@@ -0,0 +1,39 @@
+package deej
+
+import "time"
+
+// the slider, session and status handlers read and change deej's state through these rather than the config and
+// session map themselves, so they can be run against fakes (see server_test.go). NewServer hands them the real ones
+
+// serverConfig is the part of the config those handlers use
+type serverConfig interface {
+	GetSliderMappingRaw() map[int][]string
+	UpdateSliderMapping(update func(mapping map[int][]string) error) (map[int][]string, map[int][]string, error)
+	UpdateSlider(sliderID int, apps []string) ([]string, map[int][]string, error)
+	RemoveSlider(sliderID int) ([]string, error)
+
+	GetButtonMappingRaw() map[int][]string
+	SliderLabels() map[int]string
+	SliderVolumeCurve(sliderIdx int) VolumeCurve
+	DefaultVolumeCurve() VolumeCurve
+	FixedSliders() map[int]float32
+
+	Version() (uint64, time.Time)
+	FirstRun() bool
+	SessionRefreshPeriod() time.Duration
+	ExternalVolumePolicy() string
+}
+
+// serverSessions is the part of the session map those handlers use
+type serverSessions interface {
+	GetAllSessionKeys() []SessionInfo
+	UnmatchedTargets(targets []string) []string
+	CurrentWindowTargets() []string
+	ExternalVolumeChanges() []ExternalVolumeChange
+	Paused() bool
+	SessionTimeouts() uint64
+
+	resolveControllers() sessionControllers
+	normalizeTarget(target string) string
+	getTargetState(target string) (sessionState, bool)
+}
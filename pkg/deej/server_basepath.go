@@ -0,0 +1,58 @@
+package deej
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// withBasePath serves everything under the configured base path (i.e. /deej, for running behind a reverse proxy
+// that keeps the path), stripping it before it's handled so everything else can go on routing by /api/ and /.
+// requests outside of it are not found, apart from the base path itself without its trailing slash
+func (s *Server) withBasePath(next http.Handler) http.Handler {
+	if s.basePath == "" {
+		return next
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(s.basePath+"/", http.StripPrefix(s.basePath, next))
+
+	// relative links only resolve against the UI with the trailing slash, so that's where it lives
+	mux.HandleFunc(s.basePath, func(w http.ResponseWriter, r *http.Request) {
+		target := s.basePath + "/"
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	})
+
+	return mux
+}
+
+// normalizeBasePath turns a configured base path into the form routes are registered with: a leading slash,
+// no trailing one, and an empty string for the root (i.e. "deej/" becomes "/deej", and "/" becomes "")
+func normalizeBasePath(basePath string) (string, error) {
+	basePath = strings.TrimSpace(basePath)
+	if basePath == "" {
+		return "", nil
+	}
+
+	parsed, err := url.Parse(basePath)
+	if err != nil {
+		return "", fmt.Errorf("parse base path: %w", err)
+	}
+
+	if parsed.Scheme != "" || parsed.Host != "" || parsed.RawQuery != "" || parsed.Fragment != "" {
+		return "", fmt.Errorf("base path must be just a path (i.e. /deej), got %q", basePath)
+	}
+
+	cleaned := path.Clean("/" + parsed.Path)
+	if cleaned == "/" {
+		return "", nil
+	}
+
+	return cleaned, nil
+}
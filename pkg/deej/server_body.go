@@ -0,0 +1,65 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// request bodies are only ever small bits of JSON, so rather than read whatever a client sends into memory, every
+// request's body is cut off at web_server.max_body_size. a handler that hits the limit while decoding answers
+// with 413 Request Entity Too Large instead of its usual 400
+
+// in kilobytes, plenty for even a full config import
+const defaultMaxBodySize = 1024
+
+var errBodyTooLarge = errors.New("request body too large")
+
+// limitedBody tells reading past the limit apart from any other read error, since http.MaxBytesReader's error
+// can't be matched on before go 1.19
+type limitedBody struct {
+	io.ReadCloser
+
+	read  int64
+	limit int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+
+	// http.MaxBytesReader hands out exactly the limit before failing
+	if err != nil && err != io.EOF && b.read >= b.limit {
+		return n, fmt.Errorf("%w: %v", errBodyTooLarge, err)
+	}
+
+	return n, err
+}
+
+// bodyLimitMiddleware caps every request's body at the configured size. a body over it fails to read once it
+// gets there, and the connection is closed after the response so the rest of it isn't read either
+func (s *Server) bodyLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := s.deej.config.WebServer.MaxBodySize
+
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, limit), limit: limit}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeBodyError answers a request whose body couldn't be decoded (or didn't have what it needs, in which case
+// err may be nil)
+func (s *Server) writeBodyError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errBodyTooLarge) {
+		s.writeError(w, http.StatusRequestEntityTooLarge, errorCodeBodyTooLarge,
+			fmt.Sprintf("Request body is too large, the limit is %d KB", s.deej.config.WebServer.MaxBodySize/1024))
+
+		return
+	}
+
+	s.writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, "Invalid request body")
+}
@@ -0,0 +1,127 @@
+package deej
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// allows pages from any origin to call the API, which has to be opted into explicitly
+const corsAnyOrigin = "*"
+
+var (
+	defaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions}
+	defaultCORSHeaders = []string{"Content-Type", "Authorization"}
+)
+
+// corsMiddleware lets pages from the configured origins call the API. the allowed origin is echoed back rather
+// than answering with a wildcard, which also lets those pages send credentials. with no origins configured,
+// only the UI deej serves itself can use the API (browsers don't need CORS headers for that)
+func (s *Server) corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		settings := s.deej.config.WebServer
+		origin := r.Header.Get("Origin")
+
+		allowOrigin, allowed := corsAllowedOrigin(settings.CORSOrigins, origin)
+
+		// the response depends on the origin unless every origin gets the same one
+		if allowOrigin != corsAnyOrigin {
+			w.Header().Add("Vary", "Origin")
+		}
+
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+
+			// browsers refuse credentials along with a wildcard anyway
+			if allowOrigin != corsAnyOrigin {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+
+		if r.Method != http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// a preflight asks ahead of time whether the actual request (with its method) would be allowed
+		if requestedMethod := r.Header.Get("Access-Control-Request-Method"); requestedMethod != "" {
+			if !allowed || !containsFold(settings.CORSMethods, requestedMethod) {
+				s.requestLogger(r).Debugw("Rejecting CORS preflight", "origin", origin, "requestedMethod", requestedMethod)
+				s.writeError(w, http.StatusForbidden, errorCodeForbidden, "CORS request not allowed")
+
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(settings.CORSMethods, ", "))
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(settings.CORSHeaders, ", "))
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// corsAllowedOrigin returns the Access-Control-Allow-Origin value for the request's origin, if it's allowed at all
+func corsAllowedOrigin(allowedOrigins []string, origin string) (string, bool) {
+	for _, allowedOrigin := range allowedOrigins {
+		if allowedOrigin == corsAnyOrigin {
+			return corsAnyOrigin, true
+		}
+	}
+
+	if origin == "" {
+		return "", false
+	}
+
+	normalized := strings.ToLower(strings.TrimSuffix(origin, "/"))
+
+	for _, allowedOrigin := range allowedOrigins {
+		if allowedOrigin == normalized {
+			return origin, true
+		}
+	}
+
+	return "", false
+}
+
+// normalizeCORSOrigin turns a configured origin into the form browsers send (scheme://host[:port], lowercase)
+func normalizeCORSOrigin(origin string) (string, error) {
+	if origin == corsAnyOrigin {
+		return origin, nil
+	}
+
+	parsed, err := url.Parse(strings.TrimSuffix(origin, "/"))
+	if err != nil {
+		return "", fmt.Errorf("parse origin: %w", err)
+	}
+
+	if parsed.Scheme == "" || parsed.Host == "" || parsed.Path != "" || parsed.RawQuery != "" {
+		return "", fmt.Errorf("origin must look like scheme://host[:port], got %q", origin)
+	}
+
+	return strings.ToLower(parsed.Scheme + "://" + parsed.Host), nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// corsListFromConfig cleans up a list of methods or headers, dropping empty entries (i.e. from a trailing comma)
+func corsListFromConfig(values []string, normalize func(string) string) []string {
+	result := []string{}
+
+	for _, value := range values {
+		value = normalize(strings.Trim(value, " ,"))
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,183 @@
+package deej
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+
+	// how long the frames check waits for the board to send a fresh line. boards send dozens a second,
+	// so this is plenty while still keeping the endpoint quick
+	diagnosticsFrameProbeTimeout = 1 * time.Second
+
+	diagnosticsCheckSerial   = "serial"
+	diagnosticsCheckFrames   = "frames"
+	diagnosticsCheckSessions = "sessions"
+	diagnosticsCheckConfig   = "config"
+)
+
+type diagnosticsCheck struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message"`
+}
+
+type diagnosticsResponse struct {
+	// true only if every check passed
+	Passed bool               `json:"passed"`
+	Checks []diagnosticsCheck `json:"checks"`
+
+	// the last warning or error logged since deej started, null if there wasn't one
+	LastError *LoggedProblem `json:"lastError"`
+
+	Uptime int64 `json:"uptime"`
+}
+
+// handleDiagnostics runs through everything that commonly goes wrong and reports on each, for pasting into
+// a bug report. it only reads state, so it's safe to call at any time - the one active check just waits a moment
+// to see whether the board is still sending valid lines
+func (s *Server) handleDiagnostics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	response := diagnosticsResponse{
+		Passed: true,
+		Checks: []diagnosticsCheck{
+			s.diagnoseSerial(),
+			s.diagnoseFrames(),
+			s.diagnoseSessions(),
+			s.diagnoseConfig(),
+		},
+		LastError: LastLoggedProblem(),
+		Uptime:    int64(s.deej.Uptime() / time.Second),
+	}
+
+	for _, check := range response.Checks {
+		response.Passed = response.Passed && check.Passed
+	}
+
+	s.writeJSON(w, response)
+}
+
+func (s *Server) diagnoseSerial() diagnosticsCheck {
+	check := diagnosticsCheck{Name: diagnosticsCheckSerial}
+
+	connected := []string{}
+	disconnected := []string{}
+
+	for _, source := range s.deej.serial.Sources() {
+		if source.Connected {
+			connected = append(connected, source.ActivePort)
+		} else {
+			disconnected = append(disconnected, fmt.Sprintf("%s for %s",
+				source.COMPort, time.Duration(source.DisconnectedFor)*time.Second))
+		}
+	}
+
+	if len(disconnected) == 0 {
+		check.Passed = true
+		check.Message = fmt.Sprintf("Connected to %s", strings.Join(connected, ", "))
+
+		return check
+	}
+
+	check.Message = fmt.Sprintf("Not connected to %s", strings.Join(disconnected, ", "))
+
+	return check
+}
+
+func (s *Server) diagnoseFrames() diagnosticsCheck {
+	check := diagnosticsCheck{Name: diagnosticsCheckFrames}
+
+	// boards that aren't connected are already covered by the serial check, so only the connected ones are probed
+	if !s.anySerialSourceConnected() {
+		check.Message = "No serial connection to read from"
+		return check
+	}
+
+	received := s.deej.serial.WaitForFrame(diagnosticsFrameProbeTimeout)
+
+	connected := []SerialSourceStatus{}
+	var malformedFrames uint64
+
+	for _, source := range s.deej.serial.Sources() {
+		if source.Connected {
+			connected = append(connected, source)
+			malformedFrames += source.MalformedFrames
+		}
+	}
+
+	formats := make([]string, len(connected))
+	for idx, source := range connected {
+		formats[idx] = fmt.Sprintf("%s: %s", source.ActivePort, source.FrameFormat)
+	}
+
+	format := strings.Join(formats, "; ")
+
+	// with a single board, its port is already in the serial check
+	if len(connected) == 1 {
+		format = connected[0].FrameFormat
+	}
+
+	switch {
+	case !received && malformedFrames > 0:
+		check.Message = fmt.Sprintf("No valid line received in %s, %d malformed lines so far (check the baud rate)",
+			diagnosticsFrameProbeTimeout, malformedFrames)
+	case !received:
+		check.Message = fmt.Sprintf("No valid line received in %s", diagnosticsFrameProbeTimeout)
+	default:
+		check.Passed = true
+		check.Message = fmt.Sprintf("Receiving valid lines (%s), %d malformed lines so far", format, malformedFrames)
+	}
+
+	return check
+}
+
+func (s *Server) anySerialSourceConnected() bool {
+	for _, source := range s.deej.serial.Sources() {
+		if source.Connected {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Server) diagnoseSessions() diagnosticsCheck {
+	check := diagnosticsCheck{Name: diagnosticsCheckSessions}
+
+	count, err := s.deej.sessions.RefreshStatus()
+	if err != nil {
+		check.Message = fmt.Sprintf("Failed to get audio sessions: %v", err)
+		return check
+	}
+
+	check.Passed = true
+	check.Message = fmt.Sprintf("Found %d audio sessions", count)
+
+	return check
+}
+
+func (s *Server) diagnoseConfig() diagnosticsCheck {
+	check := diagnosticsCheck{Name: diagnosticsCheckConfig}
+
+	issues, err := s.deej.config.LoadStatus()
+
+	switch {
+	case err != nil:
+		check.Message = fmt.Sprintf("Failed to reload, the previous config is still in effect: %v", err)
+	case len(issues) > 0:
+		check.Message = fmt.Sprintf("%d invalid values were replaced with their defaults: %s",
+			len(issues), strings.Join(issues, "; "))
+	default:
+		check.Passed = true
+		check.Message = "Loaded without problems"
+	}
+
+	return check
+}
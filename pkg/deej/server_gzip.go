@@ -0,0 +1,211 @@
+package deej
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// responses smaller than this aren't worth the overhead of compressing them
+const gzipMinSize = 1024
+
+// content types that are already compressed, and would only grow by compressing them again
+var gzipIncompressibleContentTypes = []string{
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"video/",
+	"audio/",
+	"font/woff",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+}
+
+// gzipMiddleware compresses responses for clients that accept it
+func (s *Server) gzipMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		// websocket upgrades need the raw connection, so stay out of their way
+		if !acceptsGzip(r) || r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		gw := &gzipResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		defer gw.Close()
+
+		next.ServeHTTP(gw, r)
+	})
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		parts := strings.Split(encoding, ";")
+		if strings.ToLower(strings.TrimSpace(parts[0])) != "gzip" {
+			continue
+		}
+
+		// an explicit zero quality value means the client refuses gzip
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+
+			if q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil && q == 0 {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// gzipResponseWriter holds onto the beginning of a response until it knows whether it's
+// worth compressing, and then either compresses the rest of it or passes it through as-is
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz *gzip.Writer
+
+	buf        []byte
+	statusCode int
+	decided    bool
+}
+
+func (gw *gzipResponseWriter) WriteHeader(code int) {
+	if gw.decided {
+		return
+	}
+
+	gw.statusCode = code
+}
+
+func (gw *gzipResponseWriter) Write(p []byte) (int, error) {
+	if gw.decided {
+		if gw.gz != nil {
+			return gw.gz.Write(p)
+		}
+
+		return gw.ResponseWriter.Write(p)
+	}
+
+	gw.buf = append(gw.buf, p...)
+	if len(gw.buf) < gzipMinSize {
+		return len(p), nil
+	}
+
+	gw.decide()
+
+	if err := gw.flushBuffer(); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Flush sends whatever we have so far. if we haven't decided whether to compress yet, it's too late to now
+func (gw *gzipResponseWriter) Flush() {
+	if !gw.decided {
+		gw.decide()
+		gw.flushBuffer()
+	}
+
+	if gw.gz != nil {
+		gw.gz.Flush()
+	}
+
+	if flusher, ok := gw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close finishes the response, and must be called once the handler is done with it
+func (gw *gzipResponseWriter) Close() error {
+	if !gw.decided {
+		gw.decide()
+
+		if err := gw.flushBuffer(); err != nil {
+			return err
+		}
+	}
+
+	if gw.gz != nil {
+		return gw.gz.Close()
+	}
+
+	return nil
+}
+
+// decide whether to compress based on what's been buffered so far, and write the header accordingly
+func (gw *gzipResponseWriter) decide() {
+	gw.decided = true
+
+	header := gw.Header()
+
+	// net/http would sniff the content type from the body, which won't work once it's compressed
+	if header.Get("Content-Type") == "" && len(gw.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(gw.buf))
+	}
+
+	if len(gw.buf) >= gzipMinSize &&
+		header.Get("Content-Encoding") == "" &&
+		gzipAllowedForStatus(gw.statusCode) &&
+		gzipAllowedForContentType(header.Get("Content-Type")) {
+
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+
+		// the ETag was computed over the uncompressed body. 304s still work, since If-None-Match compares weakly
+		if etag := header.Get("ETag"); etag != "" {
+			header.Set("ETag", weakETag(etag))
+		}
+
+		gw.gz = gzip.NewWriter(gw.ResponseWriter)
+	}
+
+	gw.ResponseWriter.WriteHeader(gw.statusCode)
+}
+
+func (gw *gzipResponseWriter) flushBuffer() error {
+	if len(gw.buf) == 0 {
+		return nil
+	}
+
+	buf := gw.buf
+	gw.buf = nil
+
+	if gw.gz != nil {
+		_, err := gw.gz.Write(buf)
+		return err
+	}
+
+	_, err := gw.ResponseWriter.Write(buf)
+	return err
+}
+
+// partial content refers to byte ranges of the uncompressed body, so leave it alone
+func gzipAllowedForStatus(code int) bool {
+	return code >= http.StatusOK &&
+		code != http.StatusNoContent &&
+		code != http.StatusPartialContent &&
+		code != http.StatusNotModified
+}
+
+func gzipAllowedForContentType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+
+	for _, incompressible := range gzipIncompressibleContentTypes {
+		if strings.HasPrefix(contentType, incompressible) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,176 @@
+package deej
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// the UI's strings are kept in translation bundles, one JSON file of key -> string per language under web/i18n.
+// GET /api/i18n/{lang} serves one of them, and GET /api/i18n picks the best one from Accept-Language. every
+// bundle is filled in with english for whatever keys it doesn't translate, so the UI can rely on all of them.
+// adding a language only takes dropping its file in next to the others (named by its tag, i.e. "pt-br.json")
+
+const (
+	i18nDirectory       = "i18n"
+	i18nDefaultLanguage = "en"
+)
+
+type i18nResponse struct {
+	Lang      string            `json:"lang"`
+	Languages []string          `json:"languages"`
+	Strings   map[string]string `json:"strings"`
+}
+
+// i18nHandler serves the bundles embedded with the UI. they're read (and completed) once here, since embedded
+// files only change with a rebuild
+func (s *Server) i18nHandler(staticFS fs.FS) (http.Handler, error) {
+	bundles, err := loadI18nBundles(staticFS)
+	if err != nil {
+		return nil, err
+	}
+
+	languages := []string{}
+	for lang := range bundles {
+		languages = append(languages, lang)
+	}
+
+	sort.Strings(languages)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		requested := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/i18n"), "/")
+
+		var lang string
+		if requested != "" {
+			var ok bool
+			if lang, ok = matchI18nLanguage(bundles, requested); !ok {
+				s.writeError(w, http.StatusNotFound, errorCodeNotFound, fmt.Sprintf("No translations for %q", requested))
+				return
+			}
+		} else {
+			w.Header().Add("Vary", "Accept-Language")
+			lang = preferredI18nLanguage(bundles, r.Header.Get("Accept-Language"))
+		}
+
+		s.writeJSON(w, i18nResponse{
+			Lang:      lang,
+			Languages: languages,
+			Strings:   bundles[lang],
+		})
+	}), nil
+}
+
+// loadI18nBundles reads every bundle, keyed by its lowercase language tag
+func loadI18nBundles(staticFS fs.FS) (map[string]map[string]string, error) {
+	names, err := fs.Glob(staticFS, path.Join(i18nDirectory, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("list translation bundles: %w", err)
+	}
+
+	bundles := make(map[string]map[string]string)
+
+	for _, name := range names {
+		content, err := fs.ReadFile(staticFS, name)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+
+		bundle := make(map[string]string)
+		if err := json.Unmarshal(content, &bundle); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", name, err)
+		}
+
+		bundles[strings.ToLower(strings.TrimSuffix(path.Base(name), ".json"))] = bundle
+	}
+
+	fallback, ok := bundles[i18nDefaultLanguage]
+	if !ok {
+		return nil, fmt.Errorf("no %s translation bundle", i18nDefaultLanguage)
+	}
+
+	for _, bundle := range bundles {
+		for key, value := range fallback {
+			if _, ok := bundle[key]; !ok {
+				bundle[key] = value
+			}
+		}
+	}
+
+	return bundles, nil
+}
+
+// matchI18nLanguage finds the bundle for a language tag, or for its base language (i.e. "de" for "de-AT")
+func matchI18nLanguage(bundles map[string]map[string]string, tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+
+	if _, ok := bundles[tag]; ok {
+		return tag, true
+	}
+
+	if idx := strings.Index(tag, "-"); idx > 0 {
+		if _, ok := bundles[tag[:idx]]; ok {
+			return tag[:idx], true
+		}
+	}
+
+	return "", false
+}
+
+// preferredI18nLanguage goes through an Accept-Language header (i.e. "de-AT,de;q=0.9,en;q=0.8") highest
+// quality first, and takes the first language there's a bundle for. english if there's none
+func preferredI18nLanguage(bundles map[string]map[string]string, acceptLanguage string) string {
+	type weightedTag struct {
+		tag     string
+		quality float64
+	}
+
+	tags := []weightedTag{}
+
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(part, ";")
+
+		tag := weightedTag{tag: strings.TrimSpace(fields[0]), quality: 1}
+		if tag.tag == "" || tag.tag == "*" {
+			continue
+		}
+
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+
+			if quality, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+				tag.quality = quality
+			}
+		}
+
+		// q=0 means "not this one"
+		if tag.quality > 0 {
+			tags = append(tags, tag)
+		}
+	}
+
+	// stable, since tags of the same quality go by the order they're listed in
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].quality > tags[j].quality
+	})
+
+	for _, tag := range tags {
+		if lang, ok := matchI18nLanguage(bundles, tag.tag); ok {
+			return lang
+		}
+	}
+
+	return i18nDefaultLanguage
+}
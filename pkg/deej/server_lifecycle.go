@@ -0,0 +1,131 @@
+package deej
+
+import (
+	"net/http"
+)
+
+// handleShutdown stops deej just like quitting from the tray does. that's a lot more than any other endpoint can
+// do, so it's only available with an API token configured - otherwise anything that can reach the server could
+// turn deej off
+func (s *Server) handleShutdown(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if s.deej.config.WebServer.Token == "" {
+		s.writeError(w, http.StatusForbidden, errorCodeForbidden, "Shutting down over the API needs web_server.token to be set")
+		return
+	}
+
+	s.requestLogger(r).Info("Shutdown requested over the API")
+
+	s.writeJSON(w, genericResponse{Success: true, Message: "deej is shutting down"})
+
+	// the response has to be out before the server goes down. stopping it waits for this request to finish,
+	// so this only makes sure nothing is left sitting in a buffer
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	go s.deej.signalStop()
+}
+
+// handleRestart reloads the config and reconnects to the boards from scratch, without exiting deej
+func (s *Server) handleRestart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	logger := s.requestLogger(r)
+	logger.Info("Restart requested over the API")
+
+	warnings, err := s.deej.config.Reload()
+	if err != nil {
+		s.writeJSONStatus(w, http.StatusUnprocessableEntity, configReloadResponse{
+			Success:  false,
+			Message:  "Failed to reload config - the previous config is still in effect, and the boards weren't reconnected",
+			Code:     errorCodeInvalidConfig,
+			Errors:   []string{err.Error()},
+			Warnings: []string{},
+		})
+		return
+	}
+
+	if warnings == nil {
+		warnings = []string{}
+	}
+
+	if err := s.deej.serial.Restart(); err != nil {
+		logger.Warnw("Failed to reconnect to the main board after restart", "error", err)
+
+		s.writeJSONStatus(w, http.StatusServiceUnavailable, configReloadResponse{
+			Success:  false,
+			Message:  "Config reloaded, but the main board couldn't be connected to - deej keeps trying in the background",
+			Code:     errorCodeUnavailable,
+			Errors:   []string{err.Error()},
+			Warnings: warnings,
+		})
+		return
+	}
+
+	s.writeJSON(w, configReloadResponse{
+		Success:  true,
+		Message:  "Config reloaded and boards reconnected",
+		Errors:   []string{},
+		Warnings: warnings,
+	})
+}
+
+// IsRunning returns whether the server is serving the web UI right now
+func (s *Server) IsRunning() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.running
+}
+
+// Toggle stops the server if it's running and starts it otherwise, on the configured port just like Start.
+// it returns whether the server is running now. stopping always works (if not always gracefully, which is only
+// logged), so an error means it couldn't start
+func (s *Server) Toggle() (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.running {
+		if err := s.stop(); err != nil {
+			s.logger.Warnw("Web server didn't stop gracefully", "error", err)
+		}
+
+		return false, nil
+	}
+
+	if err := s.start(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// SubscribeToStateChanges returns a channel that's signalled whenever the server starts or stops. a consumer
+// that falls behind only gets one signal for several changes, and should check IsRunning for the current state
+func (s *Server) SubscribeToStateChanges() chan bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	c := make(chan bool, 1)
+	s.stateConsumers = append(s.stateConsumers, c)
+
+	return c
+}
+
+// notifyStateChange must be called while holding the lock, so it never blocks
+func (s *Server) notifyStateChange() {
+	for _, consumer := range s.stateConsumers {
+		select {
+		case consumer <- true:
+		default:
+		}
+	}
+}
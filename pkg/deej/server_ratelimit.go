@@ -0,0 +1,81 @@
+package deej
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// writeLimiter is a token bucket shared by every client, since what it protects (config.yaml) is shared too.
+// it holds up to a second's worth of writes, so a short burst of clicks goes through untouched
+type writeLimiter struct {
+	lock   sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// allow takes a token if there's one left at the given rate, or returns how long until there will be
+func (l *writeLimiter) allow(perSecond float64) (bool, time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	capacity := math.Max(1, perSecond)
+
+	if l.last.IsZero() {
+		l.tokens = capacity
+	} else {
+		l.tokens = math.Min(capacity, l.tokens+now.Sub(l.last).Seconds()*perSecond)
+	}
+
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return true, 0
+	}
+
+	return false, time.Duration((1 - l.tokens) / perSecond * float64(time.Second))
+}
+
+// rateLimitMiddleware throttles requests that change something (POST, PUT and DELETE under /api/), so a UI
+// firing one on every drag event can't keep rewriting the config. reads are never throttled
+func (s *Server) rateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		perSecond := s.deej.config.WebServer.WriteRateLimit
+
+		if perSecond == 0 || !isMutatingAPIRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, retryAfter := s.writeLimiter.allow(perSecond)
+		if !allowed {
+			s.requestLogger(r).Debugw("Throttling API write", "retryAfter", retryAfter)
+
+			// in whole seconds, rounded up so a client that waits exactly that long gets through
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			s.writeError(w, http.StatusTooManyRequests, errorCodeRateLimited, "Too many changes at once, try again in a moment")
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isMutatingAPIRequest(r *http.Request) bool {
+	if !strings.HasPrefix(r.URL.Path, "/api/") {
+		return false
+	}
+
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
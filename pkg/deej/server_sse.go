@@ -0,0 +1,236 @@
+package deej
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+const (
+
+	// events that a stream can't take in time are dropped for that stream only
+	sseSubscriberBufferSize = 16
+
+	sseEventSessions   = "sessions"
+	sseEventLog        = "log"
+	sseEventSerialLine = "line"
+)
+
+type sessionsStreamEvent struct {
+	Added    []string      `json:"added"`
+	Removed  []string      `json:"removed"`
+	Sessions []SessionInfo `json:"sessions"`
+}
+
+// sseBroker fans out already-encoded server-sent events to any number of open streams
+type sseBroker struct {
+	lock        sync.Mutex
+	subscribers map[chan []byte]bool
+}
+
+func newSSEBroker() *sseBroker {
+	return &sseBroker{
+		subscribers: make(map[chan []byte]bool),
+	}
+}
+
+func (b *sseBroker) subscribe() chan []byte {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	ch := make(chan []byte, sseSubscriberBufferSize)
+	b.subscribers[ch] = true
+
+	return ch
+}
+
+func (b *sseBroker) unsubscribe(ch chan []byte) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	delete(b.subscribers, ch)
+}
+
+// publish never blocks - a stream that's too slow to keep up just misses the event
+func (b *sseBroker) publish(data []byte) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- data:
+		default:
+		}
+	}
+}
+
+// formatSSE encodes a single event in the text/event-stream wire format
+func formatSSE(event string, payload interface{}) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal event payload: %w", err)
+	}
+
+	return []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", event, data)), nil
+}
+
+// startSSE sends the headers that make a response an event stream. it fails (and says so to the client) if
+// the connection can't stream
+func (s *Server) startSSE(w http.ResponseWriter) (http.Flusher, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.writeError(w, http.StatusInternalServerError, errorCodeInternal, "Streaming not supported")
+		return nil, false
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return flusher, true
+}
+
+// serveSSE streams everything published to the given broker until the client goes away or the server stops
+func (s *Server) serveSSE(w http.ResponseWriter, r *http.Request, broker *sseBroker) {
+	flusher, ok := s.startSSE(w)
+	if !ok {
+		return
+	}
+
+	// a later Start replaces it with a new one, which this stream has nothing to do with
+	streamsDone := s.streamsDone
+
+	events := broker.subscribe()
+	defer broker.unsubscribe(events)
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-streamsDone:
+			return
+
+		case data := <-events:
+			if _, err := w.Write(data); err != nil {
+				s.requestLogger(r).Debugw("Failed to write event to stream", "error", err)
+				return
+			}
+
+			flusher.Flush()
+		}
+	}
+}
+
+// consumeSessionChanges publishes every session change to the sessions stream, forever
+func (s *Server) consumeSessionChanges(changes chan SessionChangeEvent) {
+	for change := range changes {
+		data, err := formatSSE(sseEventSessions, sessionsStreamEvent{
+			Added:    change.Added,
+			Removed:  change.Removed,
+			Sessions: s.deej.sessions.GetAllSessionKeys(),
+		})
+
+		if err != nil {
+			s.logger.Warnw("Failed to format session change event", "error", err)
+			continue
+		}
+
+		s.sessionEvents.publish(data)
+	}
+}
+
+// consumeRawSerialLines publishes the boards' raw lines to the raw serial stream, forever
+func (s *Server) consumeRawSerialLines(lines chan RawSerialLine) {
+	for line := range lines {
+		data, err := formatSSE(sseEventSerialLine, line)
+		if err != nil {
+			s.logger.Warnw("Failed to format raw serial line event", "error", err)
+			continue
+		}
+
+		s.serialRawLines.publish(data)
+	}
+}
+
+// handleSerialRawStream streams the lines the boards send, and what deej made of them. it only ever reads
+func (s *Server) handleSerialRawStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	s.serveSSE(w, r, s.serialRawLines)
+}
+
+func (s *Server) handleSessionsStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	s.serveSSE(w, r, s.sessionEvents)
+}
+
+// handleLogsStream streams log lines at or above the configured level, starting with the ones that are still
+// in the buffer, so the browser shows what happened right before it connected as well
+func (s *Server) handleLogsStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	flusher, ok := s.startSSE(w)
+	if !ok {
+		return
+	}
+
+	streamsDone := s.streamsDone
+
+	backlog, lines := recentLogs.subscribe()
+	defer recentLogs.unsubscribe(lines)
+
+	writeLine := func(line LogLine) bool {
+		if line.level < s.deej.config.WebServer.LogStreamLevel {
+			return true
+		}
+
+		data, err := formatSSE(sseEventLog, line)
+		if err != nil {
+			return true
+		}
+
+		// logging this would only feed the stream that's broken, so it's left to the request log
+		if _, err := w.Write(data); err != nil {
+			return false
+		}
+
+		flusher.Flush()
+
+		return true
+	}
+
+	for _, line := range backlog {
+		if !writeLine(line) {
+			return
+		}
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-streamsDone:
+			return
+
+		case line := <-lines:
+			if !writeLine(line) {
+				return
+			}
+		}
+	}
+}
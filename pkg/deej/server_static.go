@@ -0,0 +1,159 @@
+package deej
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// the placeholder in index.html the base path is filled into, so the UI knows where the API is
+const basePathMetaTag = `<meta name="deej-base-path" content="">`
+
+const (
+
+	// index.html (and anything else without a hash in its name) can change with any rebuild, so browsers have
+	// to check back every time. with the ETag, that's a quick 304 unless it actually changed
+	staticRevalidateCacheControl = "no-cache"
+
+	// a hashed name changes along with the file, so whatever's under it never does
+	staticImmutableCacheControl = "public, max-age=31536000, immutable"
+)
+
+// asset names with a content hash in them, i.e. "app.3f2a9c1b.js"
+var hashedAssetPattern = regexp.MustCompile(`\.[0-9a-f]{8,}\.[a-z0-9]+$`)
+
+// what's served at / by a build without the UI
+const noWebUIMessage = "deej is running, but this build doesn't include the web UI. The API is still available under /api/."
+
+var errNoWebAssets = errors.New("no web UI assets embedded")
+
+// webAssetsFS returns the UI's files from what was embedded. a build can leave them out (or end up without them),
+// in which case it's errNoWebAssets: the API doesn't need them, so that's only worth a warning
+func webAssetsFS(assets fs.FS) (fs.FS, error) {
+	staticFS, err := fs.Sub(assets, "web")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errNoWebAssets, err)
+	}
+
+	// there's no UI without it, whatever else there might be
+	if _, err := fs.Stat(staticFS, "index.html"); err != nil {
+		return nil, fmt.Errorf("%w: %v", errNoWebAssets, err)
+	}
+
+	return staticFS, nil
+}
+
+// noWebUIHandler stands in for the UI (and its translation bundles) when there are no assets to serve
+func (s *Server) noWebUIHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			s.writeError(w, http.StatusNotFound, errorCodeNotFound, "This build doesn't include the web UI")
+			return
+		}
+
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", staticRevalidateCacheControl)
+
+		fmt.Fprintln(w, noWebUIMessage)
+	})
+}
+
+// staticHandler serves the embedded UI, with the base path filled into index.html. every file gets a
+// content-hash ETag, worked out once here since embedded files only change with a rebuild
+func (s *Server) staticHandler(staticFS fs.FS) (http.Handler, error) {
+	index, err := fs.ReadFile(staticFS, "index.html")
+	if err != nil {
+		return nil, fmt.Errorf("read index.html: %w", err)
+	}
+
+	index = bytes.Replace(index,
+		[]byte(basePathMetaTag),
+		[]byte(fmt.Sprintf(`<meta name="deej-base-path" content="%s">`, html.EscapeString(s.basePath))),
+		1)
+
+	etags, err := staticETags(staticFS)
+	if err != nil {
+		return nil, fmt.Errorf("hash static files: %w", err)
+	}
+
+	// the base path that was filled in is part of what's served, so it goes by that. /index.html itself is
+	// only ever a redirect to /
+	etags["/"] = contentETag(index)
+	delete(etags, "/index.html")
+
+	fileServer := http.FileServer(http.FS(staticFS))
+	modTime := time.Now()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		// both ServeContent and the file server answer If-None-Match on their own, given the ETag
+		if etag, ok := etags[r.URL.Path]; ok {
+			w.Header().Set("ETag", etag)
+
+			if hashedAssetPattern.MatchString(path.Base(r.URL.Path)) {
+				w.Header().Set("Cache-Control", staticImmutableCacheControl)
+			} else {
+				w.Header().Set("Cache-Control", staticRevalidateCacheControl)
+			}
+		}
+
+		if r.URL.Path != "/" {
+			fileServer.ServeHTTP(w, r)
+			return
+		}
+
+		http.ServeContent(w, r, "index.html", modTime, bytes.NewReader(index))
+	}), nil
+}
+
+// staticETags hashes every file, keyed by the path it's requested at
+func staticETags(staticFS fs.FS) (map[string]string, error) {
+	etags := make(map[string]string)
+
+	err := fs.WalkDir(staticFS, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		content, err := fs.ReadFile(staticFS, name)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", name, err)
+		}
+
+		etags["/"+name] = contentETag(content)
+
+		return nil
+	})
+
+	return etags, err
+}
+
+func contentETag(content []byte) string {
+	hash := sha256.Sum256(content)
+
+	// plenty to tell versions apart, and keeps the header short
+	return `"` + hex.EncodeToString(hash[:8]) + `"`
+}
+
+// weakETag marks an ETag as weak, for when the response isn't byte for byte what it was computed over
+func weakETag(etag string) string {
+	if etag == "" || strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+
+	return "W/" + etag
+}
@@ -0,0 +1,88 @@
+package deej
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWebAssetsFS(t *testing.T) {
+	tests := []struct {
+		name    string
+		assets  fs.FS
+		wantErr bool
+	}{
+		{"nothing embedded", fstest.MapFS{}, true},
+		{"no index.html", fstest.MapFS{"web/app.js": {Data: []byte("app")}}, true},
+		{"index.html outside web", fstest.MapFS{"index.html": {Data: []byte("<html>")}}, true},
+		{"index.html", fstest.MapFS{"web/index.html": {Data: []byte("<html>")}}, false},
+		{"this build's", webAssets, false},
+	}
+
+	for _, test := range tests {
+		staticFS, err := webAssetsFS(test.assets)
+
+		if test.wantErr {
+			if !errors.Is(err, errNoWebAssets) {
+				t.Errorf("%s: got %v, want missing assets", test.name, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: got %v, want the assets", test.name, err)
+			continue
+		}
+
+		// the UI's files are served from the root, not under web/
+		if _, err := staticFS.Open("index.html"); err != nil {
+			t.Errorf("%s: index.html isn't at the root: %v", test.name, err)
+		}
+	}
+}
+
+func TestNoWebUIHandler(t *testing.T) {
+	s, _ := newTestServerHandler(t, testServerConfig)
+	handler := s.noWebUIHandler()
+
+	tests := []struct {
+		path        string
+		wantStatus  int
+		wantType    string
+		wantMessage bool
+	}{
+		{"/", http.StatusOK, "text/plain; charset=utf-8", true},
+		{"/app.js", http.StatusNotFound, "text/plain; charset=utf-8", false},
+		{"/index.html", http.StatusNotFound, "text/plain; charset=utf-8", false},
+		{"/api/i18n/en", http.StatusNotFound, "application/json", false},
+	}
+
+	for _, test := range tests {
+		recorder := serveTestRequest(handler, http.MethodGet, test.path, "")
+
+		if recorder.Code != test.wantStatus {
+			t.Errorf("GET %s: got status %d, want %d", test.path, recorder.Code, test.wantStatus)
+		}
+
+		if got := recorder.Header().Get("Content-Type"); !strings.HasPrefix(got, test.wantType) {
+			t.Errorf("GET %s: got content type %q, want %q", test.path, got, test.wantType)
+		}
+
+		if got := strings.Contains(recorder.Body.String(), noWebUIMessage); got != test.wantMessage {
+			t.Errorf("GET %s: says there's no web UI: %v, want %v (%s)", test.path, got, test.wantMessage, recorder.Body)
+		}
+
+		// the API's own paths still get the API's errors
+		if strings.HasPrefix(test.path, "/api/") {
+			var response genericResponse
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil || response.Code != errorCodeNotFound {
+				t.Errorf("GET %s: got %s, want a not found error", test.path, recorder.Body)
+			}
+		}
+	}
+}
@@ -0,0 +1,1184 @@
+package deej
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fakeServerConfig keeps the slider mapping in memory, and fails every write while writeErr is set
+type fakeServerConfig struct {
+	lock     sync.Mutex
+	mapping  map[int][]string
+	writeErr error
+}
+
+func (c *fakeServerConfig) GetSliderMappingRaw() map[int][]string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	mapping := make(map[int][]string, len(c.mapping))
+	for sliderID, apps := range c.mapping {
+		mapping[sliderID] = apps
+	}
+
+	return mapping
+}
+
+func (c *fakeServerConfig) UpdateSliderMapping(
+	update func(mapping map[int][]string) error,
+) (map[int][]string, map[int][]string, error) {
+	oldMapping := c.GetSliderMappingRaw()
+	newMapping := c.GetSliderMappingRaw()
+
+	if err := update(newMapping); err != nil {
+		return nil, nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.writeErr != nil {
+		return nil, nil, c.writeErr
+	}
+
+	c.mapping = newMapping
+
+	return oldMapping, newMapping, nil
+}
+
+func (c *fakeServerConfig) UpdateSlider(sliderID int, apps []string) ([]string, map[int][]string, error) {
+	oldMapping, newMapping, err := c.UpdateSliderMapping(func(mapping map[int][]string) error {
+		mapping[sliderID] = apps
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return oldMapping[sliderID], newMapping, nil
+}
+
+func (c *fakeServerConfig) RemoveSlider(sliderID int) ([]string, error) {
+	oldMapping, _, err := c.UpdateSliderMapping(func(mapping map[int][]string) error {
+		if _, ok := mapping[sliderID]; !ok {
+			return errSliderNotMapped
+		}
+
+		delete(mapping, sliderID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return oldMapping[sliderID], nil
+}
+
+func (c *fakeServerConfig) GetButtonMappingRaw() map[int][]string { return map[int][]string{} }
+func (c *fakeServerConfig) SliderLabels() map[int]string          { return map[int]string{0: "Music"} }
+func (c *fakeServerConfig) FixedSliders() map[int]float32         { return map[int]float32{} }
+func (c *fakeServerConfig) FirstRun() bool                        { return false }
+func (c *fakeServerConfig) ExternalVolumePolicy() string          { return externalVolumePolicyIgnore }
+
+func (c *fakeServerConfig) SliderVolumeCurve(sliderIdx int) VolumeCurve {
+	return VolumeCurve{Type: defaultVolumeCurve}
+}
+
+func (c *fakeServerConfig) DefaultVolumeCurve() VolumeCurve {
+	return VolumeCurve{Type: defaultVolumeCurve}
+}
+
+func (c *fakeServerConfig) Version() (uint64, time.Time) {
+	return 3, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (c *fakeServerConfig) SessionRefreshPeriod() time.Duration {
+	return 45 * time.Second
+}
+
+// fakeServerSessions has a fixed set of sessions, each at a fixed volume
+type fakeServerSessions struct {
+	states map[string]sessionState
+}
+
+func (f *fakeServerSessions) GetAllSessionKeys() []SessionInfo {
+	sessions := []SessionInfo{}
+	for key := range f.states {
+		sessions = append(sessions, SessionInfo{Key: key, SessionType: "process", DisplayName: key})
+	}
+
+	return sessions
+}
+
+func (f *fakeServerSessions) UnmatchedTargets(targets []string) []string {
+	unmatched := []string{}
+	for _, target := range targets {
+		if _, ok := f.states[target]; !ok {
+			unmatched = append(unmatched, target)
+		}
+	}
+
+	return unmatched
+}
+
+func (f *fakeServerSessions) CurrentWindowTargets() []string                { return []string{} }
+func (f *fakeServerSessions) ExternalVolumeChanges() []ExternalVolumeChange { return nil }
+func (f *fakeServerSessions) Paused() bool                                  { return false }
+func (f *fakeServerSessions) SessionTimeouts() uint64                       { return 2 }
+func (f *fakeServerSessions) resolveControllers() sessionControllers        { return sessionControllers{} }
+func (f *fakeServerSessions) normalizeTarget(target string) string          { return target }
+func (f *fakeServerSessions) getTargetState(target string) (sessionState, bool) {
+	state, ok := f.states[target]
+	return state, ok
+}
+
+// unthrottled, since the tests write much faster than anyone clicking around
+const testServerConfig = "com_port: x\nweb_server:\n  write_rate_limit: 0\n"
+
+// newTestServerHandler serves the API of a (mock audio) deej, see newTestDeej
+func newTestServerHandler(t *testing.T, configYAML string) (*Server, http.Handler) {
+	t.Helper()
+
+	s := NewServer(zap.NewNop().Sugar(), newTestDeej(t, zap.NewNop().Sugar(), configYAML))
+
+	handler, err := s.newHandler()
+	if err != nil {
+		t.Fatalf("create handler: %v", err)
+	}
+
+	return s, handler
+}
+
+// newFakeServerHandler serves the API on top of the given fakes. only the middleware and the serial side still
+// come from deej
+func newFakeServerHandler(t *testing.T, config serverConfig, sessions serverSessions) http.Handler {
+	t.Helper()
+
+	s, handler := newTestServerHandler(t, testServerConfig)
+	s.config = config
+	s.sessions = sessions
+
+	return handler
+}
+
+func serveTestRequest(handler http.Handler, method string, path string, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+
+	return recorder
+}
+
+func TestSliderHandlers(t *testing.T) {
+	errWriteFailed := errors.New("disk full")
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		writeErr error
+
+		wantStatus  int
+		wantSuccess bool
+		wantCode    string
+		wantMapping map[int][]string
+	}{
+		{"replace mapping", http.MethodPut, "/api/sliders", `{"sliders": {"0": ["discord.exe"], "2": ["master"]}}`, nil,
+			http.StatusOK, true, "", map[int][]string{0: {"discord.exe"}, 2: {"master"}}},
+		{"replace with bad JSON", http.MethodPut, "/api/sliders", `{"sliders": `, nil,
+			http.StatusBadRequest, false, errorCodeInvalidRequest, nil},
+		{"replace with invalid ID", http.MethodPut, "/api/sliders", `{"sliders": {"one": ["master"]}}`, nil,
+			http.StatusBadRequest, false, errorCodeInvalidRequest, nil},
+		{"replace with negative ID", http.MethodPut, "/api/sliders", `{"sliders": {"-1": ["master"]}}`, nil,
+			http.StatusBadRequest, false, errorCodeInvalidRequest, nil},
+		{"replace fails to save", http.MethodPut, "/api/sliders", `{"sliders": {"0": ["master"]}}`, errWriteFailed,
+			http.StatusInternalServerError, false, errorCodeSaveFailed, nil},
+		{"replace with wrong method", http.MethodPost, "/api/sliders", `{}`, nil,
+			http.StatusMethodNotAllowed, false, errorCodeMethodNotAllowed, nil},
+
+		{"update slider", http.MethodPut, "/api/sliders/1", `{"apps": ["game.exe"]}`, nil,
+			http.StatusOK, true, "", map[int][]string{0: {"spotify.exe"}, 1: {"game.exe"}}},
+		{"update with bad JSON", http.MethodPut, "/api/sliders/1", `{"apps": [`, nil,
+			http.StatusBadRequest, false, errorCodeInvalidRequest, nil},
+		{"update with invalid weight", http.MethodPut, "/api/sliders/1", `{"apps": ["game.exe@-1"]}`, nil,
+			http.StatusBadRequest, false, errorCodeInvalidRequest, nil},
+		{"update invalid ID", http.MethodPut, "/api/sliders/one", `{"apps": ["game.exe"]}`, nil,
+			http.StatusBadRequest, false, errorCodeInvalidRequest, nil},
+		{"update negative ID", http.MethodPut, "/api/sliders/-1", `{"apps": ["game.exe"]}`, nil,
+			http.StatusBadRequest, false, errorCodeInvalidRequest, nil},
+		{"update fails to save", http.MethodPut, "/api/sliders/1", `{"apps": ["game.exe"]}`, errWriteFailed,
+			http.StatusInternalServerError, false, errorCodeSaveFailed, nil},
+
+		{"remove slider", http.MethodDelete, "/api/sliders/0", "", nil,
+			http.StatusOK, true, "", map[int][]string{}},
+		{"remove unmapped slider", http.MethodDelete, "/api/sliders/4", "", nil,
+			http.StatusNotFound, false, errorCodeNotFound, nil},
+		{"remove fails to save", http.MethodDelete, "/api/sliders/0", "", errWriteFailed,
+			http.StatusInternalServerError, false, errorCodeSaveFailed, nil},
+		{"slider with wrong method", http.MethodPost, "/api/sliders/0", `{}`, nil,
+			http.StatusMethodNotAllowed, false, errorCodeMethodNotAllowed, nil},
+		{"unknown slider path", http.MethodGet, "/api/sliders/0/nonsense", "", nil,
+			http.StatusNotFound, false, errorCodeNotFound, nil},
+	}
+
+	for _, test := range tests {
+		initialMapping := map[int][]string{0: {"spotify.exe"}}
+
+		config := &fakeServerConfig{mapping: initialMapping, writeErr: test.writeErr}
+		handler := newFakeServerHandler(t, config, &fakeServerSessions{})
+
+		recorder := serveTestRequest(handler, test.method, test.path, test.body)
+
+		if recorder.Code != test.wantStatus {
+			t.Errorf("%s: got status %d, want %d (%s)", test.name, recorder.Code, test.wantStatus, recorder.Body)
+			continue
+		}
+
+		var response genericResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Errorf("%s: response isn't JSON: %v", test.name, err)
+			continue
+		}
+
+		if response.Success != test.wantSuccess || response.Code != test.wantCode {
+			t.Errorf("%s: got success %v and code %q, want %v and %q",
+				test.name, response.Success, response.Code, test.wantSuccess, test.wantCode)
+		}
+
+		// a request that fails changes nothing
+		wantMapping := test.wantMapping
+		if wantMapping == nil {
+			wantMapping = initialMapping
+		}
+
+		if got := config.GetSliderMappingRaw(); !reflect.DeepEqual(got, wantMapping) {
+			t.Errorf("%s: mapping is %v, want %v", test.name, got, wantMapping)
+		}
+	}
+}
+
+func TestSliderReadHandlers(t *testing.T) {
+	config := &fakeServerConfig{mapping: map[int][]string{0: {"spotify.exe"}, 3: {"master", "mic"}, -1: {"game.exe"}}}
+	handler := newFakeServerHandler(t, config, &fakeServerSessions{})
+
+	tests := []struct {
+		path string
+		want interface{}
+	}{
+		{"/api/sliders/0", map[string]interface{}{"apps": []interface{}{"spotify.exe"}}},
+		{"/api/sliders/3", map[string]interface{}{"apps": []interface{}{"master", "mic"}}},
+		{"/api/sliders/7", map[string]interface{}{"apps": []interface{}{}}},
+	}
+
+	for _, test := range tests {
+		recorder := serveTestRequest(handler, http.MethodGet, test.path, "")
+
+		var got interface{}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &got); err != nil || recorder.Code != http.StatusOK {
+			t.Errorf("GET %s: got status %d (%s)", test.path, recorder.Code, recorder.Body)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("GET %s = %v, want %v", test.path, got, test.want)
+		}
+	}
+
+	// without a board to go by, only the negative slider is known to be invalid
+	recorder := serveTestRequest(handler, http.MethodGet, "/api/sliders", "")
+
+	var sliders slidersResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &sliders); err != nil {
+		t.Fatalf("GET /api/sliders: response isn't JSON: %v", err)
+	}
+
+	if len(sliders.Sliders) != 2 || len(sliders.Invalid) != 1 || sliders.Invalid["-1"].Reason != invalidSliderNegative {
+		t.Errorf("GET /api/sliders listed %v as mapped and %v as invalid", sliders.Sliders, sliders.Invalid)
+	}
+
+	if sliders.Labels["0"] != "Music" {
+		t.Errorf("GET /api/sliders labeled slider 0 %q, want %q", sliders.Labels["0"], "Music")
+	}
+}
+
+func TestSessionsAndStatusHandlers(t *testing.T) {
+	config := &fakeServerConfig{mapping: map[int][]string{0: {"spotify.exe"}, 1: {"master", "closed.exe"}}}
+	sessions := &fakeServerSessions{states: map[string]sessionState{
+		masterSessionName: {volume: 0.8},
+		"spotify.exe":     {volume: 0.25, muted: true},
+	}}
+
+	handler := newFakeServerHandler(t, config, sessions)
+
+	spotify := &targetVolume{Volume: 25, Muted: true}
+	master := &targetVolume{Volume: 80}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		check      func(body []byte) error
+	}{
+		{"sessions", http.MethodGet, "/api/sessions", http.StatusOK, func(body []byte) error {
+			var response sessionsResponse
+			if err := json.Unmarshal(body, &response); err != nil {
+				return err
+			}
+
+			if len(response.Sessions) != 2 {
+				return errors.New("didn't list both sessions")
+			}
+
+			return nil
+		}},
+		{"sessions with wrong method", http.MethodPost, "/api/sessions", http.StatusMethodNotAllowed, nil},
+
+		{"status", http.MethodGet, "/api/status", http.StatusOK, func(body []byte) error {
+			var response statusResponse
+			if err := json.Unmarshal(body, &response); err != nil {
+				return err
+			}
+
+			want := map[string]map[string]*targetVolume{
+				"0": {"spotify.exe": spotify},
+				"1": {"master": master, "closed.exe": nil},
+			}
+
+			if !reflect.DeepEqual(response.Volumes, want) {
+				return errors.New("volumes don't match the sessions")
+			}
+
+			if response.SliderCount != 2 || response.SessionTimeouts != 2 || response.ConfigVersion != 3 {
+				return errors.New("counts don't match the config and sessions")
+			}
+
+			if !reflect.DeepEqual(response.Master, master) || response.Mic != nil {
+				return errors.New("master and mic don't match the sessions")
+			}
+
+			if response.SessionRefreshInterval != 45 || response.Connected {
+				return errors.New("refresh interval or connection state is off")
+			}
+
+			return nil
+		}},
+		{"status with wrong method", http.MethodPut, "/api/status", http.StatusMethodNotAllowed, nil},
+	}
+
+	for _, test := range tests {
+		recorder := serveTestRequest(handler, test.method, test.path, "")
+
+		if recorder.Code != test.wantStatus {
+			t.Errorf("%s: got status %d, want %d (%s)", test.name, recorder.Code, test.wantStatus, recorder.Body)
+			continue
+		}
+
+		if test.check != nil {
+			if err := test.check(recorder.Body.Bytes()); err != nil {
+				t.Errorf("%s: %v (%s)", test.name, err, recorder.Body)
+			}
+		}
+	}
+}
+
+func TestConfigWriteFailures(t *testing.T) {
+	s, handler := newTestServerHandler(t, testServerConfig+"slider_mapping:\n  0: spotify.exe\n")
+
+	// two edits to undo and redo, before config.yaml can't be written anymore
+	for _, apps := range []string{`["discord.exe"]`, `["game.exe"]`} {
+		if recorder := serveTestRequest(handler, http.MethodPut, "/api/sliders/1", `{"apps": `+apps+`}`); recorder.Code != http.StatusOK {
+			t.Fatalf("setting up history: got status %d (%s)", recorder.Code, recorder.Body)
+		}
+	}
+
+	if recorder := serveTestRequest(handler, http.MethodPost, "/api/config/undo", ""); recorder.Code != http.StatusOK {
+		t.Fatalf("setting up history: got status %d (%s)", recorder.Code, recorder.Body)
+	}
+
+	// otherwise the first of the edits below would be queued right behind these, and only fail later on
+	if err := s.deej.config.FlushPendingWrites(); err != nil {
+		t.Fatalf("write history: %v", err)
+	}
+
+	before, err := ioutil.ReadFile(userConfigFilepath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+
+	// config.yaml is written by way of a temporary file next to it, which can't be created over a directory
+	// (not even by root)
+	if err := os.Mkdir(userConfigFilepath+".tmp", 0755); err != nil {
+		t.Fatalf("block config writes: %v", err)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPut, "/api/sliders", `{"sliders": {"0": ["master"]}}`},
+		{http.MethodPut, "/api/sliders/0", `{"apps": ["master"]}`},
+		{http.MethodDelete, "/api/sliders/0", ""},
+		{http.MethodPost, "/api/sliders/swap", `{"first": 0, "second": 1}`},
+		{http.MethodPost, "/api/config/undo", ""},
+		{http.MethodPost, "/api/config/redo", ""},
+		{http.MethodPut, "/api/sliders/0/settings", `{"invert": true}`},
+		{http.MethodPut, "/api/sliders/0/label", `{"label": "Music"}`},
+		{http.MethodPut, "/api/sliders/0/fixed", `{"value": 0.5}`},
+		{http.MethodPut, "/api/buttons", `{"buttons": {"0": ["master"]}}`},
+		{http.MethodPut, "/api/midi/mapping", `{"slider": 0, "controller": 7}`},
+		{http.MethodPut, "/api/balance", `{"target": "spotify.exe", "balance": 0.5}`},
+	}
+
+	for _, test := range tests {
+		recorder := serveTestRequest(handler, test.method, test.path, test.body)
+
+		var response genericResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Errorf("%s %s: response isn't JSON: %v", test.method, test.path, err)
+			continue
+		}
+
+		if recorder.Code != http.StatusInternalServerError || response.Success || response.Code != errorCodeSaveFailed {
+			t.Errorf("%s %s: got status %d, success %v and code %q, want %d, false and %q",
+				test.method, test.path, recorder.Code, response.Success, response.Code,
+				http.StatusInternalServerError, errorCodeSaveFailed)
+		}
+	}
+
+	after, err := ioutil.ReadFile(userConfigFilepath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+
+	if string(after) != string(before) {
+		t.Errorf("config changed by failed writes:\n%s\nwas:\n%s", after, before)
+	}
+
+	// nothing that failed to save was recorded, so undo still takes back the edit it did before
+	if err := os.Remove(userConfigFilepath + ".tmp"); err != nil {
+		t.Fatalf("unblock config writes: %v", err)
+	}
+
+	if recorder := serveTestRequest(handler, http.MethodPost, "/api/config/undo", ""); recorder.Code != http.StatusOK {
+		t.Fatalf("undo once writes work again: got status %d (%s)", recorder.Code, recorder.Body)
+	}
+
+	recorder := serveTestRequest(handler, http.MethodGet, "/api/sliders/1", "")
+	if body := strings.TrimSpace(recorder.Body.String()); body != `{"apps":[]}` {
+		t.Errorf("slider 1 after undoing both edits: %s", body)
+	}
+}
+
+func TestAPIErrorResponses(t *testing.T) {
+	_, handler := newTestServerHandler(t, testServerConfig+`  token: secret
+  cors_origins: [http://allowed.example]
+`)
+
+	const allowedOrigin = "http://allowed.example"
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		token   string
+		headers map[string]string
+
+		wantStatus int
+		wantCode   string
+	}{
+		{"no token", http.MethodGet, "/api/status", "", "", nil, http.StatusUnauthorized, errorCodeUnauthorized},
+		{"wrong token", http.MethodGet, "/api/status", "", "nope", nil, http.StatusUnauthorized, errorCodeUnauthorized},
+		{"token in query outside streams", http.MethodGet, "/api/status?token=secret", "", "", nil, http.StatusUnauthorized, errorCodeUnauthorized},
+		{"right token", http.MethodGet, "/api/status", "", "secret", nil, http.StatusOK, ""},
+
+		{"preflight from allowed origin", http.MethodOptions, "/api/sliders", "", "",
+			map[string]string{"Origin": allowedOrigin, "Access-Control-Request-Method": http.MethodPut}, http.StatusOK, ""},
+		{"preflight from other origin", http.MethodOptions, "/api/sliders", "", "",
+			map[string]string{"Origin": "http://other.example", "Access-Control-Request-Method": http.MethodPut}, http.StatusForbidden, errorCodeForbidden},
+		{"preflight for disallowed method", http.MethodOptions, "/api/sliders", "", "",
+			map[string]string{"Origin": allowedOrigin, "Access-Control-Request-Method": http.MethodPatch}, http.StatusForbidden, errorCodeForbidden},
+
+		{"wrong method on status", http.MethodPost, "/api/status", "", "secret", nil, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed},
+		{"wrong method on sessions", http.MethodDelete, "/api/sessions", "", "secret", nil, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed},
+		{"wrong method on swap", http.MethodGet, "/api/sliders/swap", "", "secret", nil, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed},
+		{"wrong method on undo", http.MethodGet, "/api/config/undo", "", "secret", nil, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed},
+
+		{"bad JSON", http.MethodPut, "/api/sliders", `{"sliders": [`, "secret", nil, http.StatusBadRequest, errorCodeInvalidRequest},
+		{"JSON of the wrong shape", http.MethodPut, "/api/buttons", `{"buttons": ["master"]}`, "secret", nil, http.StatusBadRequest, errorCodeInvalidRequest},
+		{"invalid slider ID", http.MethodGet, "/api/sliders/abc", "", "secret", nil, http.StatusBadRequest, errorCodeInvalidRequest},
+		{"swap with itself", http.MethodPost, "/api/sliders/swap", `{"first": 1, "second": 1}`, "secret", nil, http.StatusBadRequest, errorCodeInvalidRequest},
+		{"unknown slider path", http.MethodGet, "/api/sliders/0/nonsense", "", "secret", nil, http.StatusNotFound, errorCodeNotFound},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		if test.token != "" {
+			request.Header.Set("Authorization", "Bearer "+test.token)
+		}
+
+		for name, value := range test.headers {
+			request.Header.Set(name, value)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		if recorder.Code != test.wantStatus {
+			t.Errorf("%s: got status %d, want %d (%s)", test.name, recorder.Code, test.wantStatus, recorder.Body)
+			continue
+		}
+
+		if test.wantCode == "" {
+			continue
+		}
+
+		if contentType := recorder.Header().Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("%s: error sent as %q, not JSON", test.name, contentType)
+		}
+
+		var response genericResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Errorf("%s: response isn't JSON: %v (%s)", test.name, err, recorder.Body)
+			continue
+		}
+
+		if response.Success || response.Code != test.wantCode || response.Message == "" {
+			t.Errorf("%s: got success %v, code %q and message %q, want an error with code %q",
+				test.name, response.Success, response.Code, response.Message, test.wantCode)
+		}
+	}
+}
+
+// streams can't send an Authorization header from a browser, so they take the token in the query instead
+func TestStreamTokenInQuery(t *testing.T) {
+	_, handler := newTestServerHandler(t, testServerConfig+"  token: secret\n")
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{"/api/serial/raw?token=secret", http.StatusOK},
+		{"/api/serial/raw?token=nope", http.StatusUnauthorized},
+		{"/api/serial/raw", http.StatusUnauthorized},
+		{"/api/sessions/stream?token=secret", http.StatusOK},
+		{"/api/logs/stream?token=nope", http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		// a stream that was let in stays open until its client goes away
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, test.path, nil).WithContext(ctx))
+		cancel()
+
+		if recorder.Code != test.wantStatus {
+			t.Errorf("GET %s: got status %d, want %d (%s)", test.path, recorder.Code, test.wantStatus, recorder.Body)
+			continue
+		}
+
+		if contentType := recorder.Header().Get("Content-Type"); test.wantStatus == http.StatusOK && contentType != "text/event-stream" {
+			t.Errorf("GET %s: sent as %q, not an event stream", test.path, contentType)
+		}
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	_, handler := newTestServerHandler(t, testServerConfig+"  max_body_size: 1\n")
+
+	// one target that's long enough to take a valid body past the 1 KB limit, or not
+	body := func(targetLength int) string {
+		return `{"apps": ["` + strings.Repeat("a", targetLength) + `"]}`
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{"under the limit", http.MethodPut, "/api/sliders/0", body(900), http.StatusOK, ""},
+		{"over the limit", http.MethodPut, "/api/sliders/0", body(2000), http.StatusRequestEntityTooLarge, errorCodeBodyTooLarge},
+		{"far over the limit", http.MethodPut, "/api/sliders/0", body(100000), http.StatusRequestEntityTooLarge, errorCodeBodyTooLarge},
+		{"mapping over the limit", http.MethodPut, "/api/sliders", `{"sliders": {"0": ["` + strings.Repeat("a", 2000) + `"]}}`,
+			http.StatusRequestEntityTooLarge, errorCodeBodyTooLarge},
+		{"swap over the limit", http.MethodPost, "/api/sliders/swap", `{"first": 0, "second": 1` + strings.Repeat(" ", 2000) + `}`,
+			http.StatusRequestEntityTooLarge, errorCodeBodyTooLarge},
+
+		// bad JSON inside the limit is still just a bad request
+		{"bad JSON under the limit", http.MethodPut, "/api/sliders/0", `{"apps": [`, http.StatusBadRequest, errorCodeInvalidRequest},
+	}
+
+	for _, test := range tests {
+		recorder := serveTestRequest(handler, test.method, test.path, test.body)
+
+		if recorder.Code != test.wantStatus {
+			t.Errorf("%s: got status %d, want %d (%s)", test.name, recorder.Code, test.wantStatus, recorder.Body)
+			continue
+		}
+
+		var response genericResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Errorf("%s: response isn't JSON: %v", test.name, err)
+			continue
+		}
+
+		if response.Code != test.wantCode {
+			t.Errorf("%s: got code %q, want %q", test.name, response.Code, test.wantCode)
+		}
+	}
+
+	// nothing over the limit was applied
+	recorder := serveTestRequest(handler, http.MethodGet, "/api/sliders/0", "")
+	if want := `{"apps":["` + strings.Repeat("a", 900) + `"]}`; strings.TrimSpace(recorder.Body.String()) != want {
+		t.Errorf("slider 0 was changed by a body over the limit")
+	}
+}
+
+func TestConcurrentSliderEdits(t *testing.T) {
+	s, handler := newTestServerHandler(t, testServerConfig)
+
+	const edits = 32
+
+	var wg sync.WaitGroup
+	failures := make(chan string, edits*2)
+
+	for idx := 0; idx < edits; idx++ {
+		wg.Add(2)
+
+		go func(sliderID int) {
+			defer wg.Done()
+
+			path := "/api/sliders/" + strconv.Itoa(sliderID)
+			body := `{"apps": ["app` + strconv.Itoa(sliderID) + `.exe"]}`
+
+			if recorder := serveTestRequest(handler, http.MethodPut, path, body); recorder.Code != http.StatusOK {
+				failures <- path + ": " + recorder.Body.String()
+			}
+		}(idx)
+
+		// reads in between shouldn't see (or cause) a half-made edit
+		go func() {
+			defer wg.Done()
+
+			if recorder := serveTestRequest(handler, http.MethodGet, "/api/status", ""); recorder.Code != http.StatusOK {
+				failures <- "/api/status: " + recorder.Body.String()
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(failures)
+
+	for failure := range failures {
+		t.Errorf("request failed: %s", failure)
+	}
+
+	want := make(map[int][]string, edits)
+	for idx := 0; idx < edits; idx++ {
+		want[idx] = []string{"app" + strconv.Itoa(idx) + ".exe"}
+	}
+
+	if got := s.deej.config.GetSliderMappingRaw(); !reflect.DeepEqual(got, want) {
+		t.Errorf("mapping after %d concurrent edits is %v, want %v", edits, got, want)
+	}
+
+	// and none of them were lost on the way to config.yaml
+	if err := s.deej.config.FlushPendingWrites(); err != nil {
+		t.Fatalf("write queued edits: %v", err)
+	}
+
+	if err := s.deej.config.Load(); err != nil {
+		t.Fatalf("reload config: %v", err)
+	}
+
+	if got := s.deej.config.GetSliderMappingRaw(); !reflect.DeepEqual(got, want) {
+		t.Errorf("mapping reloaded after %d concurrent edits is %v, want %v", edits, got, want)
+	}
+}
+
+func TestUpdateSliderResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		body string
+
+		wantApps       []string
+		wantSliders    map[string][]string
+		wantDuplicates []string
+		wantElsewhere  map[string][]int
+		wantWarnings   []string
+	}{
+		{"new slider", "/api/sliders/1", `{"apps": ["discord.exe"]}`,
+			[]string{"discord.exe"},
+			map[string][]string{"0": {"spotify.exe"}, "1": {"discord.exe"}, "2": {"master"}},
+			[]string{}, map[string][]int{}, []string{}},
+		{"replaced slider", "/api/sliders/0", `{"apps": ["discord.exe", "game.exe"]}`,
+			[]string{"discord.exe", "game.exe"},
+			map[string][]string{"0": {"discord.exe", "game.exe"}, "2": {"master"}},
+			[]string{}, map[string][]int{}, []string{"game.exe"}},
+		{"no apps", "/api/sliders/0", `{}`,
+			[]string{},
+			map[string][]string{"0": {}, "2": {"master"}},
+			[]string{}, map[string][]int{}, []string{}},
+		{"repeated app", "/api/sliders/1", `{"apps": ["discord.exe", "Discord.EXE"]}`,
+			[]string{"discord.exe"},
+			map[string][]string{"0": {"spotify.exe"}, "1": {"discord.exe"}, "2": {"master"}},
+			[]string{"Discord.EXE"}, map[string][]int{}, []string{}},
+		{"app on another slider", "/api/sliders/1", `{"apps": ["Master", "discord.exe"]}`,
+			[]string{"Master", "discord.exe"},
+			map[string][]string{"0": {"spotify.exe"}, "1": {"Master", "discord.exe"}, "2": {"master"}},
+			[]string{}, map[string][]int{"Master": {2}}, []string{"Master"}},
+	}
+
+	for _, test := range tests {
+		config := &fakeServerConfig{mapping: map[int][]string{0: {"spotify.exe"}, 2: {"master"}}}
+		sessions := &fakeServerSessions{states: map[string]sessionState{"spotify.exe": {}, "discord.exe": {}, "master": {}}}
+		handler := newFakeServerHandler(t, config, sessions)
+
+		recorder := serveTestRequest(handler, http.MethodPut, test.path, test.body)
+		if recorder.Code != http.StatusOK {
+			t.Errorf("%s: got status %d, want 200 (%s)", test.name, recorder.Code, recorder.Body)
+			continue
+		}
+
+		var response updateSliderResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Errorf("%s: response isn't JSON: %v", test.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(response.Apps, test.wantApps) {
+			t.Errorf("%s: saved apps are %v, want %v", test.name, response.Apps, test.wantApps)
+		}
+
+		if !reflect.DeepEqual(response.Sliders, test.wantSliders) {
+			t.Errorf("%s: mapping in effect is %v, want %v", test.name, response.Sliders, test.wantSliders)
+		}
+
+		if !reflect.DeepEqual(response.Duplicates, test.wantDuplicates) {
+			t.Errorf("%s: duplicates are %v, want %v", test.name, response.Duplicates, test.wantDuplicates)
+		}
+
+		if !reflect.DeepEqual(response.MappedElsewhere, test.wantElsewhere) {
+			t.Errorf("%s: mapped elsewhere is %v, want %v", test.name, response.MappedElsewhere, test.wantElsewhere)
+		}
+
+		if !reflect.DeepEqual(response.Warnings, test.wantWarnings) {
+			t.Errorf("%s: warnings are %v, want %v", test.name, response.Warnings, test.wantWarnings)
+		}
+
+		// what the response says was saved is what was saved
+		var sliders struct {
+			Sliders map[string][]string `json:"sliders"`
+		}
+
+		if err := json.Unmarshal(serveTestRequest(handler, http.MethodGet, "/api/sliders", "").Body.Bytes(), &sliders); err != nil {
+			t.Errorf("%s: GET /api/sliders isn't JSON: %v", test.name, err)
+		} else if !reflect.DeepEqual(sliders.Sliders, response.Sliders) {
+			t.Errorf("%s: GET /api/sliders has %v, but the PUT said %v", test.name, sliders.Sliders, response.Sliders)
+		}
+	}
+}
+
+func TestSliderDeadZoneSettings(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		want       float64
+	}{
+		{"set", `{"deadZone": 0.1}`, http.StatusOK, 0.1},
+		{"left out", `{"invert": true}`, http.StatusOK, 0.05},
+		{"negative", `{"deadZone": -0.1}`, http.StatusBadRequest, 0.05},
+		{"half the travel", `{"deadZone": 0.5}`, http.StatusBadRequest, 0.05},
+		{"turned off", `{"deadZone": 0}`, http.StatusOK, 0},
+	}
+
+	for _, test := range tests {
+		_, handler := newTestServerHandler(t, testServerConfig+`
+slider_settings:
+  0:
+    dead_zone: 0.05
+`)
+
+		recorder := serveTestRequest(handler, http.MethodPut, "/api/sliders/0/settings", test.body)
+		if recorder.Code != test.wantStatus {
+			t.Errorf("%s: got status %d, want %d (%s)", test.name, recorder.Code, test.wantStatus, recorder.Body)
+		}
+
+		configYAML, err := ioutil.ReadFile(userConfigFilepath)
+		if err != nil {
+			t.Fatalf("%s: read config: %v", test.name, err)
+		}
+
+		settings, _ := sliderSettingsFromYAML(t, string(configYAML))
+		if got := (&CanonicalConfig{SliderSettings: settings}).SliderDeadZone(0); got != test.want {
+			t.Errorf("%s: config.yaml has a dead zone of %v, want %v", test.name, got, test.want)
+		}
+
+		// turning it off leaves nothing behind
+		if test.want == 0 && strings.Contains(string(configYAML), "dead_zone") {
+			t.Errorf("%s: config.yaml still has a dead zone:\n%s", test.name, configYAML)
+		}
+	}
+}
+
+// every endpoint that changes a slider's settings writes all of them back, so each has to build on the edits
+// before it, even the ones config.yaml hasn't been reloaded with yet
+func TestSliderSettingsEdits(t *testing.T) {
+	s, handler := newTestServerHandler(t, testServerConfig+`
+slider_settings:
+  3:
+    calibration_min: 100
+    calibration_max: 900
+`)
+
+	edits := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPut, "/api/sliders/0/settings", `{"invert": true}`},
+		{http.MethodPut, "/api/sliders/1/label", `{"label": "Music"}`},
+		{http.MethodPut, "/api/sliders/2/fixed", `{"value": 0.5}`},
+		{http.MethodDelete, "/api/sliders/3/calibration", ""},
+		{http.MethodPut, "/api/sliders/0/settings", `{"deadZone": 0.1}`},
+		{http.MethodPut, "/api/sliders/3/label", `{"label": "Voice"}`},
+	}
+
+	for _, edit := range edits {
+		if recorder := serveTestRequest(handler, edit.method, edit.path, edit.body); recorder.Code != http.StatusOK {
+			t.Fatalf("%s %s: got status %d (%s)", edit.method, edit.path, recorder.Code, recorder.Body)
+		}
+	}
+
+	const concurrentEdits = 16
+
+	var wg sync.WaitGroup
+	failures := make(chan string, concurrentEdits*2)
+
+	for sliderID := 10; sliderID < 10+concurrentEdits; sliderID++ {
+		wg.Add(2)
+
+		go func(sliderID int) {
+			defer wg.Done()
+
+			path := "/api/sliders/" + strconv.Itoa(sliderID) + "/label"
+			if recorder := serveTestRequest(handler, http.MethodPut, path, `{"label": "Slider"}`); recorder.Code != http.StatusOK {
+				failures <- path + ": " + recorder.Body.String()
+			}
+		}(sliderID)
+
+		go func(sliderID int) {
+			defer wg.Done()
+
+			path := "/api/sliders/" + strconv.Itoa(sliderID) + "/settings"
+			if recorder := serveTestRequest(handler, http.MethodPut, path, `{"invert": true}`); recorder.Code != http.StatusOK {
+				failures <- path + ": " + recorder.Body.String()
+			}
+		}(sliderID)
+	}
+
+	wg.Wait()
+	close(failures)
+
+	for failure := range failures {
+		t.Errorf("request failed: %s", failure)
+	}
+
+	// the edits that came right behind the first one are still queued
+	if err := s.deej.config.FlushPendingWrites(); err != nil {
+		t.Fatalf("write queued edits: %v", err)
+	}
+
+	configYAML, err := ioutil.ReadFile(userConfigFilepath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+
+	written, _ := sliderSettingsFromYAML(t, string(configYAML))
+
+	for name, settings := range map[string]map[int]SliderSettings{
+		"GetSliderSettingsRaw": s.deej.config.GetSliderSettingsRaw(),
+		"config.yaml":          written,
+	} {
+		cc := &CanonicalConfig{SliderSettings: settings}
+
+		type check struct {
+			name string
+			got  interface{}
+			want interface{}
+		}
+
+		tests := []check{
+			{"slider 0 inverted", cc.SliderInverted(0), true},
+			{"slider 0 dead zone", cc.SliderDeadZone(0), 0.1},
+			{"slider 1 label", cc.SliderLabels()[1], "Music"},
+			{"slider 2 fixed", settings[2].FixedValue != nil && *settings[2].FixedValue == 0.5, true},
+			{"slider 3 calibration", cc.SliderCalibration(3), defaultCalibration},
+			{"slider 3 label", cc.SliderLabels()[3], "Voice"},
+		}
+
+		for sliderID := 10; sliderID < 10+concurrentEdits; sliderID++ {
+			tests = append(tests,
+				check{"slider " + strconv.Itoa(sliderID) + " label", cc.SliderLabels()[sliderID], "Slider"},
+				check{"slider " + strconv.Itoa(sliderID) + " inverted", cc.SliderInverted(sliderID), true})
+		}
+
+		for _, test := range tests {
+			if test.got != test.want {
+				t.Errorf("%s: %s is %v, want %v", name, test.name, test.got, test.want)
+			}
+		}
+	}
+}
+
+func TestPreferencesEdits(t *testing.T) {
+	s, handler := newTestServerHandler(t, testServerConfig)
+
+	tests := []struct {
+		body       string
+		wantStatus int
+	}{
+		{`{"theme": "dark"}`, http.StatusOK},
+		{`{"units": "decimal"}`, http.StatusOK},
+		{`{"pollInterval": 1000}`, http.StatusBadRequest},
+		{`{"pollInterval": 10}`, http.StatusOK},
+	}
+
+	// back to back, each within the reload window of the one before
+	for _, test := range tests {
+		if recorder := serveTestRequest(handler, http.MethodPut, "/api/preferences", test.body); recorder.Code != test.wantStatus {
+			t.Errorf("PUT %s: got status %d, want %d (%s)", test.body, recorder.Code, test.wantStatus, recorder.Body)
+		}
+	}
+
+	want := map[string]interface{}{"theme": "dark", "units": "decimal", "pollInterval": float64(10)}
+
+	var response preferencesResponse
+	if err := json.Unmarshal(serveTestRequest(handler, http.MethodGet, "/api/preferences", "").Body.Bytes(), &response); err != nil {
+		t.Fatalf("preferences aren't JSON: %v", err)
+	}
+
+	if !reflect.DeepEqual(response.Preferences, want) {
+		t.Errorf("GET /api/preferences has %v, want %v", response.Preferences, want)
+	}
+
+	// the edits that came right behind the first one are still queued
+	if err := s.deej.config.FlushPendingWrites(); err != nil {
+		t.Fatalf("write queued edits: %v", err)
+	}
+
+	configYAML, err := ioutil.ReadFile(userConfigFilepath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+
+	written := uiPreferencesFromConfig(userConfigFromYAML(t, string(configYAML)),
+		func(message string, key string, keysAndValues ...interface{}) {
+			t.Errorf("config.yaml has an invalid preference under %s: %s", key, message)
+		})
+
+	for name, value := range want {
+		if fmt.Sprint(written[name]) != fmt.Sprint(value) {
+			t.Errorf("config.yaml has %s at %v, want %v", name, written[name], value)
+		}
+	}
+}
+
+func TestBalanceEdits(t *testing.T) {
+	s, handler := newTestServerHandler(t, testServerConfig)
+
+	// back to back, each within the reload window of the one before
+	for _, body := range []string{
+		`{"target": "spotify.exe", "balance": 0.5}`,
+		`{"target": "chrome.exe", "balance": -0.25}`,
+		`{"target": "discord.exe", "balance": 1}`,
+		`{"target": "discord.exe", "balance": 0}`,
+	} {
+		if recorder := serveTestRequest(handler, http.MethodPut, "/api/balance", body); recorder.Code != http.StatusOK {
+			t.Errorf("PUT %s: got status %d (%s)", body, recorder.Code, recorder.Body)
+		}
+	}
+
+	want := map[string]float32{"spotify.exe": 0.5, "chrome.exe": -0.25}
+
+	const edits = 16
+
+	var wg sync.WaitGroup
+	failures := make(chan string, edits)
+
+	for idx := 0; idx < edits; idx++ {
+		target := "app" + strconv.Itoa(idx) + ".exe"
+		want[target] = -0.5
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			body := `{"target": "` + target + `", "balance": -0.5}`
+			if recorder := serveTestRequest(handler, http.MethodPut, "/api/balance", body); recorder.Code != http.StatusOK {
+				failures <- target + ": " + recorder.Body.String()
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(failures)
+
+	for failure := range failures {
+		t.Errorf("request failed: %s", failure)
+	}
+
+	if got := s.deej.config.GetBalancesRaw(); !reflect.DeepEqual(got, want) {
+		t.Errorf("balances after the edits are %v, want %v", got, want)
+	}
+
+	// the edits that came right behind the first one are still queued
+	if err := s.deej.config.FlushPendingWrites(); err != nil {
+		t.Fatalf("write queued edits: %v", err)
+	}
+
+	configYAML, err := ioutil.ReadFile(userConfigFilepath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+
+	written := balancesFromConfig(userConfigFromYAML(t, string(configYAML)),
+		func(message string, key string, keysAndValues ...interface{}) {
+			t.Errorf("config.yaml has an invalid balance under %s: %s", key, message)
+		})
+
+	if !reflect.DeepEqual(written, want) {
+		t.Errorf("config.yaml has balances %v, want %v", written, want)
+	}
+}
+
+func TestMIDIMappingEdits(t *testing.T) {
+	s, handler := newTestServerHandler(t, testServerConfig)
+
+	// back to back, each within the reload window of the one before
+	for _, body := range []string{
+		`{"slider": 0, "controller": 7}`,
+		`{"slider": 1, "controller": 8}`,
+		`{"slider": 2, "controller": 9}`,
+		`{"slider": 2}`,
+	} {
+		if recorder := serveTestRequest(handler, http.MethodPut, "/api/midi/mapping", body); recorder.Code != http.StatusOK {
+			t.Errorf("PUT %s: got status %d (%s)", body, recorder.Code, recorder.Body)
+		}
+	}
+
+	want := map[int]int{0: 7, 1: 8}
+
+	const edits = 16
+
+	var wg sync.WaitGroup
+	failures := make(chan string, edits)
+
+	for idx := 10; idx < 10+edits; idx++ {
+		sliderID := idx
+		want[sliderID] = sliderID + 10
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			body := `{"slider": ` + strconv.Itoa(sliderID) + `, "controller": ` + strconv.Itoa(sliderID+10) + `}`
+			if recorder := serveTestRequest(handler, http.MethodPut, "/api/midi/mapping", body); recorder.Code != http.StatusOK {
+				failures <- body + ": " + recorder.Body.String()
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(failures)
+
+	for failure := range failures {
+		t.Errorf("request failed: %s", failure)
+	}
+
+	if got := s.deej.config.GetMIDIMappingRaw(); !reflect.DeepEqual(got, want) {
+		t.Errorf("MIDI mapping after the edits is %v, want %v", got, want)
+	}
+
+	// the edits that came right behind the first one are still queued
+	if err := s.deej.config.FlushPendingWrites(); err != nil {
+		t.Fatalf("write queued edits: %v", err)
+	}
+
+	configYAML, err := ioutil.ReadFile(userConfigFilepath)
+	if err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+
+	written := midiMappingFromConfig(userConfigFromYAML(t, string(configYAML)),
+		func(message string, key string, keysAndValues ...interface{}) {
+			t.Errorf("config.yaml has an invalid MIDI mapping under %s: %s", key, message)
+		})
+
+	if !reflect.DeepEqual(written, want) {
+		t.Errorf("config.yaml has MIDI mapping %v, want %v", written, want)
+	}
+}
+
+func TestQueuedEdits(t *testing.T) {
+	s, handler := newTestServerHandler(t, testServerConfig)
+
+	writtenMapping := func() map[string][]string {
+		configYAML, err := ioutil.ReadFile(userConfigFilepath)
+		if err != nil {
+			t.Fatalf("read config: %v", err)
+		}
+
+		return userConfigFromYAML(t, string(configYAML)).GetStringMapStringSlice(configKeySliderMapping)
+	}
+
+	for sliderID := 0; sliderID < 3; sliderID++ {
+		path := "/api/sliders/" + strconv.Itoa(sliderID)
+		body := `{"apps": ["app` + strconv.Itoa(sliderID) + `.exe"]}`
+
+		if recorder := serveTestRequest(handler, http.MethodPut, path, body); recorder.Code != http.StatusOK {
+			t.Fatalf("PUT %s: got status %d (%s)", path, recorder.Code, recorder.Body)
+		}
+	}
+
+	// the first edit is written right away, and the ones right behind it wait for a quiet moment
+	if got, want := writtenMapping(), map[string][]string{"0": {"app0.exe"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("config.yaml has mapping %v right after the edits, want %v", got, want)
+	}
+
+	want := map[int][]string{0: {"app0.exe"}, 1: {"app1.exe"}, 2: {"app2.exe"}}
+	if got := s.deej.config.GetSliderMappingRaw(); !reflect.DeepEqual(got, want) {
+		t.Errorf("mapping right after the edits is %v, want %v", got, want)
+	}
+
+	deadline := time.Now().Add(editWriteDelay + 2*time.Second)
+	for len(writtenMapping()) != len(want) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got, want := writtenMapping(), map[string][]string{"0": {"app0.exe"}, "1": {"app1.exe"}, "2": {"app2.exe"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("config.yaml has mapping %v once the edits were quiet, want %v", got, want)
+	}
+}
@@ -0,0 +1,107 @@
+package deej
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// a client that sends its request (or reads its response) slowly enough could otherwise hold a connection open
+// forever, which matters once the UI is reachable from the network. every request gets web_server.read_timeout
+// to be read and write_timeout to be answered, and a kept-alive connection is closed once it's been idle for
+// idle_timeout. 0 turns any of them off.
+//
+// event streams and the websocket are long-lived by design, so they're exempt: their connection's deadlines are
+// cleared as soon as they're routed, on a per-request basis since a kept-alive connection can go on to serve
+// anything. the websocket sets deadlines of its own for every message it writes. HTTP/2 multiplexes requests over
+// a single connection, whose deadlines can't be cleared for just one of them, so the UI sticks to HTTP/1.1 over
+// TLS as well
+
+// in seconds
+const (
+	defaultServerReadTimeout  = 15
+	defaultServerWriteTimeout = 30
+	defaultServerIdleTimeout  = 120
+)
+
+// ServerTimeouts are how long the web server waits on clients, 0 meaning it doesn't give up
+type ServerTimeouts struct {
+	Read  time.Duration
+	Write time.Duration
+	Idle  time.Duration
+}
+
+type serverConnContextKey struct{}
+
+// applyTimeouts sets the web server's timeouts, and what it takes to exempt streams from them
+func (s *Server) applyTimeouts(httpServer *http.Server) {
+	timeouts := s.deej.config.WebServer.Timeouts
+
+	httpServer.ReadTimeout = timeouts.Read
+	httpServer.WriteTimeout = timeouts.Write
+	httpServer.IdleTimeout = timeouts.Idle
+
+	// requests need to get to their connection to exempt themselves
+	httpServer.ConnContext = func(ctx context.Context, conn net.Conn) context.Context {
+		return context.WithValue(ctx, serverConnContextKey{}, conn)
+	}
+
+	// a non-nil, empty map keeps ServeTLS from setting HTTP/2 up
+	httpServer.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+}
+
+// isStreamingRequest returns whether a request stays open for as long as the client wants it to
+func isStreamingRequest(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/api/ws", "/api/serial/raw":
+		return true
+	}
+
+	return strings.HasSuffix(r.URL.Path, "/stream")
+}
+
+// streamDeadlineMiddleware lifts the read and write deadlines off a streaming request's connection. the server
+// puts them back before reading the connection's next request
+func (s *Server) streamDeadlineMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isStreamingRequest(r) {
+			if conn, ok := r.Context().Value(serverConnContextKey{}).(net.Conn); ok {
+				if err := conn.SetDeadline(time.Time{}); err != nil {
+					s.requestLogger(r).Debugw("Failed to clear connection deadlines for stream", "error", err)
+				}
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func serverTimeoutsFromConfig(
+	userConfig *viper.Viper,
+	warnInvalidValue func(message string, key string, keysAndValues ...interface{}),
+) ServerTimeouts {
+	timeout := func(key string, defaultValue float64) time.Duration {
+		seconds := userConfig.GetFloat64(key)
+		if seconds < 0 {
+			warnInvalidValue("Invalid web server timeout specified, using default value",
+				key,
+				"invalidValue", seconds,
+				"defaultValue", defaultValue)
+
+			seconds = defaultValue
+		}
+
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	return ServerTimeouts{
+		Read:  timeout(configKeyServerReadTimeout, defaultServerReadTimeout),
+		Write: timeout(configKeyServerWriteTimeout, defaultServerWriteTimeout),
+		Idle:  timeout(configKeyServerIdleTimeout, defaultServerIdleTimeout),
+	}
+}
@@ -0,0 +1,197 @@
+package deej
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/omriharel/deej/pkg/deej/util"
+)
+
+const (
+
+	// where the self-signed certificate is kept, next to config.yaml so it survives restarts (and stays trusted)
+	selfSignedCertFilepath = "deej-cert.pem"
+	selfSignedKeyFilepath  = "deej-key.pem"
+
+	// browsers and OSes cap how long a certificate they're told to trust can be valid for, 825 days being the
+	// strictest (macOS and iOS). this stays under it, after which a new one is generated
+	selfSignedCertValidity = 800 * 24 * time.Hour
+)
+
+// loadTLSCertificate returns the certificate to serve the UI over HTTPS with, or nil if TLS isn't configured
+func (s *Server) loadTLSCertificate() (*tls.Certificate, error) {
+	settings := s.deej.config.WebServer
+
+	if settings.TLSCert != "" {
+		certificate, err := tls.LoadX509KeyPair(settings.TLSCert, settings.TLSKey)
+		if err != nil {
+			return nil, fmt.Errorf("load TLS certificate: %w", err)
+		}
+
+		return &certificate, nil
+	}
+
+	if !settings.TLSSelfSigned {
+		return nil, nil
+	}
+
+	// reuse the one from last time, since a new one would have to be trusted all over again
+	if util.FileExists(selfSignedCertFilepath) && util.FileExists(selfSignedKeyFilepath) {
+		certificate, err := tls.LoadX509KeyPair(selfSignedCertFilepath, selfSignedKeyFilepath)
+		if err == nil {
+			var leaf *x509.Certificate
+
+			if leaf, err = x509.ParseCertificate(certificate.Certificate[0]); err == nil {
+				if time.Now().Before(leaf.NotAfter) {
+					return &certificate, nil
+				}
+
+				err = fmt.Errorf("certificate expired on %s", leaf.NotAfter.Format("2006-01-02"))
+			}
+		}
+
+		s.logger.Infow("Existing self-signed certificate can't be used, generating a new one",
+			"path", selfSignedCertFilepath,
+			"error", err)
+	}
+
+	if err := s.generateSelfSignedCertificate(); err != nil {
+		return nil, fmt.Errorf("generate self-signed certificate: %w", err)
+	}
+
+	certificate, err := tls.LoadX509KeyPair(selfSignedCertFilepath, selfSignedKeyFilepath)
+	if err != nil {
+		return nil, fmt.Errorf("load self-signed certificate: %w", err)
+	}
+
+	return &certificate, nil
+}
+
+// generateSelfSignedCertificate writes a new certificate (and its key) that covers every name and address
+// this machine can be reached at, so the browser doesn't flag it as being for a different site once it's trusted
+func (s *Server) generateSelfSignedCertificate() error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("generate key: %w", err)
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return fmt.Errorf("generate serial number: %w", err)
+	}
+
+	dnsNames, ipAddresses := s.selfSignedCertificateHosts()
+	now := time.Now()
+
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: "deej", Organization: []string{"deej"}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(selfSignedCertValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     dnsNames,
+		IPAddresses:  ipAddresses,
+
+		BasicConstraintsValid: true,
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return fmt.Errorf("create certificate: %w", err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("marshal key: %w", err)
+	}
+
+	// the key is what makes the certificate worth trusting, so only we get to read it
+	if err := os.WriteFile(selfSignedKeyFilepath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return fmt.Errorf("write key: %w", err)
+	}
+
+	if err := os.WriteFile(selfSignedCertFilepath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0644); err != nil {
+		return fmt.Errorf("write certificate: %w", err)
+	}
+
+	// shown so it can be compared against what the browser says when asked to trust the certificate
+	fingerprint := sha256.Sum256(certDER)
+
+	s.logger.Infow("Generated self-signed certificate for the web UI",
+		"path", selfSignedCertFilepath,
+		"hosts", append(dnsNames, ipStrings(ipAddresses)...),
+		"expires", template.NotAfter.Format("2006-01-02"),
+		"sha256", formatFingerprint(fingerprint[:]))
+
+	return nil
+}
+
+// selfSignedCertificateHosts returns the names and addresses the UI can be opened at. when listening on every
+// address, that's every address this machine has. otherwise it's the configured host, and this machine itself
+func (s *Server) selfSignedCertificateHosts() ([]string, []net.IP) {
+	dnsNames := []string{"localhost"}
+	ipAddresses := []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		dnsNames = append(dnsNames, hostname)
+	}
+
+	ip := net.ParseIP(s.host)
+
+	switch {
+	case s.host == "" || (ip != nil && ip.IsUnspecified()):
+		addresses, err := net.InterfaceAddrs()
+		if err != nil {
+			s.logger.Warnw("Failed to list network addresses for the self-signed certificate", "error", err)
+			break
+		}
+
+		for _, address := range addresses {
+			if network, ok := address.(*net.IPNet); ok && !network.IP.IsLoopback() {
+				ipAddresses = append(ipAddresses, network.IP)
+			}
+		}
+
+	case ip != nil:
+		if !ip.IsLoopback() {
+			ipAddresses = append(ipAddresses, ip)
+		}
+
+	case s.host != "localhost":
+		dnsNames = append(dnsNames, s.host)
+	}
+
+	return dnsNames, ipAddresses
+}
+
+func ipStrings(ips []net.IP) []string {
+	result := make([]string, len(ips))
+	for idx, ip := range ips {
+		result[idx] = ip.String()
+	}
+
+	return result
+}
+
+// formatFingerprint formats a certificate fingerprint the way browsers show it (i.e. "AB:CD:...")
+func formatFingerprint(fingerprint []byte) string {
+	parts := make([]string, len(fingerprint))
+	for idx, b := range fingerprint {
+		parts[idx] = fmt.Sprintf("%02X", b)
+	}
+
+	return strings.Join(parts, ":")
+}
@@ -0,0 +1,239 @@
+package deej
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+const (
+
+	// each client gets a small buffer of outgoing messages. a client that can't keep up
+	// with it is considered too slow and gets dropped, rather than holding up everyone else
+	wsClientSendBufferSize = 16
+
+	// how long we're willing to wait on a single write to a client before giving up on it
+	wsWriteTimeout = 2 * time.Second
+
+	wsMessageTypeSliderValues   = "sliderValues"
+	wsMessageTypeConfigReloaded = "configReloaded"
+)
+
+type sliderValuesMessage struct {
+	Type   string    `json:"type"`
+	Values []float32 `json:"values"`
+
+	// by slider index, same as values
+	Activity []SliderActivity `json:"activity"`
+}
+
+// configReloadedMessage is a successful config load, see config_version.go
+type configReloadedMessage struct {
+	Type string `json:"type"`
+	ConfigLoadEvent
+}
+
+// wsHub fans out live slider values to all connected websocket clients
+type wsHub struct {
+	logger   *zap.SugaredLogger
+	upgrader websocket.Upgrader
+
+	lock        sync.Mutex
+	clients     map[*wsClient]bool
+	minInterval time.Duration
+}
+
+type wsClient struct {
+	conn *websocket.Conn
+	send chan []byte
+}
+
+func newWSHub(logger *zap.SugaredLogger) *wsHub {
+	return &wsHub{
+		logger:  logger.Named("ws"),
+		clients: make(map[*wsClient]bool),
+	}
+}
+
+// setMaxRate limits the amount of messages sent to each client per second
+func (h *wsHub) setMaxRate(messagesPerSecond int) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.minInterval = time.Second / time.Duration(messagesPerSecond)
+}
+
+func (h *wsHub) getMinInterval() time.Duration {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return h.minInterval
+}
+
+// consume reads slider values forever, broadcasting them at no more than the configured rate.
+// the most recent values are always the ones that get sent, so the final resting position is never lost.
+// once the sliders settle, they're sent once more so clients see them go inactive
+func (h *wsHub) consume(valuesChannel chan []float32, activity func() []SliderActivity) {
+	var (
+		pending  []float32
+		lastSent time.Time
+		flush    <-chan time.Time
+		settled  <-chan time.Time
+	)
+
+	for {
+		select {
+		case values := <-valuesChannel:
+			pending = values
+
+			// if there's no flush scheduled yet, schedule one according to the rate limit
+			if flush == nil {
+				wait := h.getMinInterval() - time.Since(lastSent)
+				if wait < 0 {
+					wait = 0
+				}
+
+				flush = time.After(wait)
+			}
+
+		case <-flush:
+			flush = nil
+			lastSent = time.Now()
+			settled = time.After(sliderActiveDuration)
+
+			h.broadcast(sliderValuesMessage{
+				Type:     wsMessageTypeSliderValues,
+				Values:   pending,
+				Activity: activity(),
+			})
+
+		case <-settled:
+			settled = nil
+
+			h.broadcast(sliderValuesMessage{
+				Type:     wsMessageTypeSliderValues,
+				Values:   pending,
+				Activity: activity(),
+			})
+		}
+	}
+}
+
+func (h *wsHub) broadcast(message interface{}) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if len(h.clients) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		h.logger.Warnw("Failed to marshal websocket message", "error", err)
+		return
+	}
+
+	for client := range h.clients {
+		select {
+		case client.send <- data:
+		default:
+			h.logger.Debugw("Dropping slow websocket client", "remoteAddr", client.conn.RemoteAddr())
+			h.removeLocked(client)
+		}
+	}
+}
+
+func (h *wsHub) serve(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+
+		// the upgrader already responded with an appropriate error
+		h.logger.Debugw("Failed to upgrade websocket connection", "error", err)
+		return
+	}
+
+	client := &wsClient{
+		conn: conn,
+		send: make(chan []byte, wsClientSendBufferSize),
+	}
+
+	h.lock.Lock()
+	h.clients[client] = true
+	h.lock.Unlock()
+
+	h.logger.Debugw("Websocket client connected", "remoteAddr", conn.RemoteAddr())
+
+	go h.writeLoop(client)
+	go h.readLoop(client)
+}
+
+// writeLoop delivers queued messages to a single client, until its send channel is closed
+func (h *wsHub) writeLoop(client *wsClient) {
+	for data := range client.send {
+		client.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+
+		if err := client.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			h.logger.Debugw("Failed to write to websocket client", "error", err)
+			h.remove(client)
+			return
+		}
+	}
+}
+
+// readLoop discards anything the client sends us, but is required for control frames
+// (pings and close) to be processed. it returns once the connection is gone
+func (h *wsHub) readLoop(client *wsClient) {
+	for {
+		if _, _, err := client.conn.ReadMessage(); err != nil {
+			h.remove(client)
+			return
+		}
+	}
+}
+
+func (h *wsHub) remove(client *wsClient) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.removeLocked(client)
+}
+
+// assumes the hub's lock is held
+func (h *wsHub) removeLocked(client *wsClient) {
+	if !h.clients[client] {
+		return
+	}
+
+	delete(h.clients, client)
+	close(client.send)
+	client.conn.Close()
+
+	h.logger.Debugw("Websocket client disconnected", "remoteAddr", client.conn.RemoteAddr())
+}
+
+// closeAll politely tells every client we're going away and closes its connection
+func (h *wsHub) closeAll(deadline time.Time) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	closeMessage := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+
+	for client := range h.clients {
+		if err := client.conn.WriteControl(websocket.CloseMessage, closeMessage, deadline); err != nil {
+			h.logger.Debugw("Failed to send close message to websocket client", "error", err)
+		}
+
+		h.removeLocked(client)
+	}
+}
+
+// consumeConfigLoads sends every config load to the websocket clients, forever
+func (s *Server) consumeConfigLoads(loads chan ConfigLoadEvent) {
+	for load := range loads {
+		s.wsHub.broadcast(configReloadedMessage{Type: wsMessageTypeConfigReloaded, ConfigLoadEvent: load})
+	}
+}
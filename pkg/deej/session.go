@@ -1,24 +1,45 @@
 package deej
 
 import (
+	"context"
 	"strings"
 
 	"go.uber.org/zap"
 )
 
-// Session represents a single addressable audio session
+// Session represents a single addressable audio session. changing it takes a context, which is checked before
+// every request to the OS - deej gives up on calls that take too long, see session_timeout.go
 type Session interface {
 	GetVolume() float32
-	SetVolume(v float32) error
+	SetVolume(ctx context.Context, v float32) error
 
-	// TODO: future mute support
-	// GetMute() bool
-	// SetMute(m bool) error
+	GetMute() bool
+	SetMute(ctx context.Context, m bool) error
 
 	Key() string
+	DisplayName() string
+	Icon() []byte
+	Controllable() bool
+
+	// what's known about where the session comes from and goes to, see SessionInstance
+	PID() int
+	Device() string
+	State() string
+
 	Release()
 }
 
+// balancedSession is a session whose sound can be moved between its left and right channels, on top of (and
+// without changing) its volume. sessions that don't implement it, or can't right now, are simply left centered
+type balancedSession interface {
+
+	// CanBalance returns whether SetBalance can work for the session, i.e. it has exactly a left and right channel
+	CanBalance() bool
+
+	// SetBalance takes anything from -1 (fully left) to 1 (fully right), 0 being centered
+	SetBalance(ctx context.Context, balance float32) error
+}
+
 const (
 
 	// ideally these would share a common ground in baseSession
@@ -29,6 +50,13 @@ const (
 	sessionStringFormat = "<session: %s, vol: %.2f>"
 )
 
+// a session is inactive while its app isn't playing anything (i.e. paused), and expired once the app let go of it
+const (
+	sessionStateActive   = "active"
+	sessionStateInactive = "inactive"
+	sessionStateExpired  = "expired"
+)
+
 type baseSession struct {
 	logger *zap.SugaredLogger
 	system bool
@@ -37,8 +65,25 @@ type baseSession struct {
 	// used by Key(), needs to be set by child
 	name string
 
+	// what an app's session is keyed by instead of its name, if it was normalized (see session_names.go)
+	normalizedName string
+
 	// used by String(), needs to be set by child
 	humanReadableDesc string
+
+	// optionally set by child, shown to the user in place of the key where available
+	displayName string
+	icon        []byte
+
+	// set by child for sessions the platform lists but doesn't let us change (i.e. apps on macOS)
+	uncontrollable bool
+
+	// optionally set by child: the process playing, and the output device it's routed to, where they're known
+	processID    int
+	outputDevice string
+
+	// set by child for sessions that were found to not be playing anything, unless it checks its state on its own
+	inactive bool
 }
 
 func (s *baseSession) Key() string {
@@ -50,5 +95,63 @@ func (s *baseSession) Key() string {
 		return strings.ToLower(s.name) // could be master or mic, or any device's friendly name
 	}
 
+	if s.normalizedName != "" {
+		return s.normalizedName
+	}
+
 	return strings.ToLower(s.name)
 }
+
+// DisplayName returns a human-friendly name for the session, or an empty string if we don't know one
+func (s *baseSession) DisplayName() string {
+	return s.displayName
+}
+
+// Icon returns the session's PNG-encoded icon, or nil if it doesn't have one
+func (s *baseSession) Icon() []byte {
+	return s.icon
+}
+
+// Controllable returns whether the session's volume and mute state can actually be changed
+func (s *baseSession) Controllable() bool {
+	return !s.uncontrollable
+}
+
+// PID returns the ID of the process behind the session, or 0 if there's none (or it isn't known)
+func (s *baseSession) PID() int {
+	return s.processID
+}
+
+// Device returns the name of the device the session plays on (or is), or an empty string if it isn't known
+func (s *baseSession) Device() string {
+	if s.outputDevice != "" {
+		return s.outputDevice
+	}
+
+	name, _ := s.deviceName()
+
+	return name
+}
+
+// State returns whether the session is active, inactive or expired
+func (s *baseSession) State() string {
+	if s.inactive {
+		return sessionStateInactive
+	}
+
+	return sessionStateActive
+}
+
+// deviceName returns the name a device's own session is addressed by, as the user would spell it. it's false for
+// every other session, including master and mic, which follow the default devices instead
+func (s *baseSession) deviceName() (string, bool) {
+	if !s.master || s.system {
+		return "", false
+	}
+
+	if key := s.Key(); key == masterSessionName || key == inputSessionName {
+		return "", false
+	}
+
+	return s.name, true
+}
@@ -0,0 +1,110 @@
+package deej
+
+import (
+	"context"
+	"strings"
+)
+
+// SessionBackend is everything applying slider moves needs from an audio backend: its sessions, their volume and
+// mute state, and what the special targets it provides stand for. the session map only goes through this, so it
+// doesn't care which platform (or mock) is behind it. deej.unmapped isn't the backend's to resolve, it only
+// depends on the mapping
+type SessionBackend interface {
+	ListSessions() ([]Session, error)
+
+	GetVolume(session Session) float32
+	SetVolume(ctx context.Context, session Session, v float32) error
+
+	GetMute(session Session) bool
+	SetMute(ctx context.Context, session Session, m bool) error
+
+	// SpecialTargetSupported returns whether the backend can resolve a special target (master, mic, system or
+	// deej.current) at all, even if there's nothing behind it right now
+	SpecialTargetSupported(target string) bool
+
+	// ResolveSpecialTarget returns the (lowercase) keys of the sessions a special target stands for right now, or
+	// nil for one the backend doesn't support
+	ResolveSpecialTarget(target string) ([]string, error)
+
+	Release() error
+}
+
+// finderSessionBackend is the SessionBackend of every platform, made out of its session finder and the optional
+// interfaces it implements
+type finderSessionBackend struct {
+	finder SessionFinder
+}
+
+func newFinderSessionBackend(finder SessionFinder) *finderSessionBackend {
+	return &finderSessionBackend{finder: finder}
+}
+
+func (b *finderSessionBackend) ListSessions() ([]Session, error) {
+	return b.finder.GetAllSessions()
+}
+
+func (b *finderSessionBackend) GetVolume(session Session) float32 {
+	return session.GetVolume()
+}
+
+func (b *finderSessionBackend) SetVolume(ctx context.Context, session Session, v float32) error {
+	return session.SetVolume(ctx, v)
+}
+
+func (b *finderSessionBackend) GetMute(session Session) bool {
+	return session.GetMute()
+}
+
+func (b *finderSessionBackend) SetMute(ctx context.Context, session Session, m bool) error {
+	return session.SetMute(ctx, m)
+}
+
+func (b *finderSessionBackend) SpecialTargetSupported(target string) bool {
+	switch target {
+	case masterSessionName, inputSessionName:
+		return true
+
+	case systemSessionName:
+		finder, ok := b.finder.(systemSoundsFinder)
+		return ok && finder.HasSystemSoundsSession()
+
+	case specialTargetTransformPrefix + specialTargetCurrentWindow:
+		_, ok := b.finder.(focusedProcessFinder)
+		return ok
+	}
+
+	return false
+}
+
+func (b *finderSessionBackend) ResolveSpecialTarget(target string) ([]string, error) {
+	switch target {
+
+	// these are sessions of their own, keyed just like the target. whether there's one is up to the backend
+	case masterSessionName, inputSessionName, systemSessionName:
+		return []string{target}, nil
+
+	// the focused window's processes go by their names, which could be in any case
+	case specialTargetTransformPrefix + specialTargetCurrentWindow:
+		finder, ok := b.finder.(focusedProcessFinder)
+		if !ok {
+			return nil, nil
+		}
+
+		processNames, err := finder.GetCurrentWindowProcessNames()
+		if err != nil {
+			return nil, err
+		}
+
+		for idx, processName := range processNames {
+			processNames[idx] = strings.ToLower(processName)
+		}
+
+		return processNames, nil
+	}
+
+	return nil, nil
+}
+
+func (b *finderSessionBackend) Release() error {
+	return b.finder.Release()
+}
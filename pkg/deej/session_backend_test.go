@@ -0,0 +1,53 @@
+package deej
+
+import (
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// bareSessionFinder only finds sessions, like a backend that implements none of the optional interfaces
+type bareSessionFinder struct {
+	SessionFinder
+}
+
+func TestFinderSessionBackendSpecialTargets(t *testing.T) {
+	mock := newMockSessionFinder(zap.NewNop().Sugar())
+
+	tests := []struct {
+		name          string
+		finder        SessionFinder
+		target        string
+		wantSupported bool
+		wantKeys      []string
+	}{
+		{"master", mock, masterSessionName, true, []string{masterSessionName}},
+		{"mic", mock, inputSessionName, true, []string{inputSessionName}},
+		{"system", mock, systemSessionName, true, []string{systemSessionName}},
+		{"focused window", mock, "deej.current", true, []string{mockFocusedProcess}},
+		{"unmapped isn't the backend's", mock, "deej.unmapped", false, nil},
+		{"app", mock, "spotify.exe", false, nil},
+
+		{"master without capabilities", bareSessionFinder{mock}, masterSessionName, true, []string{masterSessionName}},
+		{"system without capabilities", bareSessionFinder{mock}, systemSessionName, false, []string{systemSessionName}},
+		{"focused window without capabilities", bareSessionFinder{mock}, "deej.current", false, nil},
+	}
+
+	for _, test := range tests {
+		backend := newFinderSessionBackend(test.finder)
+
+		if got := backend.SpecialTargetSupported(test.target); got != test.wantSupported {
+			t.Errorf("%s: SpecialTargetSupported(%q) = %v, want %v", test.name, test.target, got, test.wantSupported)
+		}
+
+		keys, err := backend.ResolveSpecialTarget(test.target)
+		if err != nil {
+			t.Errorf("%s: ResolveSpecialTarget(%q) failed: %v", test.name, test.target, err)
+		}
+
+		if !reflect.DeepEqual(keys, test.wantKeys) {
+			t.Errorf("%s: ResolveSpecialTarget(%q) = %v, want %v", test.name, test.target, keys, test.wantKeys)
+		}
+	}
+}
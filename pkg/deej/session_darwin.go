@@ -0,0 +1,229 @@
+//go:build darwin && cgo
+// +build darwin,cgo
+
+package deej
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+var (
+	errNoSuchProcess = errors.New("No such process")
+
+	// macOS lists apps that play audio, but has no volume of their own for them
+	errSessionUncontrollable = errors.New("session can't be controlled on macOS")
+)
+
+type caSession struct {
+	baseSession
+
+	processName string
+}
+
+type masterSession struct {
+	baseSession
+
+	// 0 for master and mic, which follow whichever device is the default at the time
+	device  audioObjectID
+	isInput bool
+}
+
+func newCASession(logger *zap.SugaredLogger, processName string) *caSession {
+	s := &caSession{
+		processName: processName,
+	}
+
+	s.name = processName
+	s.humanReadableDesc = processName
+	s.uncontrollable = true
+
+	// use a self-identifying session name e.g. deej.sessions.spotify
+	s.logger = logger.Named(s.Key())
+	s.logger.Debugw(sessionCreationLogMessage, "session", s)
+
+	return s
+}
+
+func newMasterSession(
+	logger *zap.SugaredLogger,
+	device audioObjectID,
+	isInput bool,
+	key string,
+	loggerKey string,
+) *masterSession {
+
+	s := &masterSession{
+		device:  device,
+		isInput: isInput,
+	}
+
+	s.logger = logger.Named(loggerKey)
+	s.master = true
+	s.name = key
+	s.humanReadableDesc = key
+
+	// a device's volume can only be changed if the device has one (i.e. not over HDMI)
+	if currentDevice, err := s.currentDevice(); err == nil {
+		s.uncontrollable = !coreAudioVolumeSettable(currentDevice, isInput)
+	}
+
+	s.logger.Debugw(sessionCreationLogMessage, "session", s)
+
+	return s
+}
+
+func (s *caSession) GetVolume() float32 {
+	return 1
+}
+
+func (s *caSession) SetVolume(ctx context.Context, v float32) error {
+	return errSessionUncontrollable
+}
+
+func (s *caSession) GetMute() bool {
+	return false
+}
+
+func (s *caSession) SetMute(ctx context.Context, m bool) error {
+	return errSessionUncontrollable
+}
+
+func (s *caSession) Release() {
+	s.logger.Debug("Releasing audio session")
+}
+
+func (s *caSession) String() string {
+	return fmt.Sprintf(sessionStringFormat, s.humanReadableDesc, s.GetVolume())
+}
+
+// currentDevice returns the device the session controls right now
+func (s *masterSession) currentDevice() (audioObjectID, error) {
+	if s.device != 0 {
+		return s.device, nil
+	}
+
+	return coreAudioDefaultDevice(s.isInput)
+}
+
+func (s *masterSession) GetVolume() float32 {
+	device, err := s.currentDevice()
+	if err != nil {
+		s.logger.Warnw("Failed to get session volume", "error", err)
+		return 0
+	}
+
+	volume, err := coreAudioVolume(device, s.isInput)
+	if err != nil {
+		s.logger.Warnw("Failed to get session volume", "error", err)
+		return 0
+	}
+
+	return volume
+}
+
+func (s *masterSession) SetVolume(ctx context.Context, v float32) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("adjust session volume: %w", err)
+	}
+
+	device, err := s.currentDevice()
+	if err != nil {
+		s.logger.Warnw("Failed to set session volume", "error", err)
+		return fmt.Errorf("adjust session volume: %w", err)
+	}
+
+	if err := coreAudioSetVolume(device, s.isInput, v); err != nil {
+		s.logger.Warnw("Failed to set session volume",
+			"error", err,
+			"volume", v)
+
+		return fmt.Errorf("adjust session volume: %w", err)
+	}
+
+	s.logger.Debugw("Adjusting session volume", "to", fmt.Sprintf("%.2f", v))
+
+	return nil
+}
+
+func (s *masterSession) GetMute() bool {
+	device, err := s.currentDevice()
+	if err != nil {
+		s.logger.Warnw("Failed to get session mute state", "error", err)
+		return false
+	}
+
+	mute, err := coreAudioMute(device, s.isInput)
+	if err != nil {
+		s.logger.Warnw("Failed to get session mute state", "error", err)
+		return false
+	}
+
+	return mute
+}
+
+func (s *masterSession) SetMute(ctx context.Context, m bool) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("adjust session mute state: %w", err)
+	}
+
+	device, err := s.currentDevice()
+	if err != nil {
+		s.logger.Warnw("Failed to set session mute state", "error", err)
+		return fmt.Errorf("adjust session mute state: %w", err)
+	}
+
+	if err := coreAudioSetMute(device, s.isInput, m); err != nil {
+		s.logger.Warnw("Failed to set session mute state",
+			"error", err,
+			"mute", m)
+
+		return fmt.Errorf("adjust session mute state: %w", err)
+	}
+
+	s.logger.Debugw("Adjusting session mute state", "to", m)
+
+	return nil
+}
+
+// CanBalance is only true for stereo devices
+func (s *masterSession) CanBalance() bool {
+	device, err := s.currentDevice()
+	return err == nil && coreAudioBalanceSettable(device, s.isInput)
+}
+
+// SetBalance changes the device's own balance, which is separate from its volume on macOS
+func (s *masterSession) SetBalance(ctx context.Context, balance float32) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("adjust session balance: %w", err)
+	}
+
+	device, err := s.currentDevice()
+	if err != nil {
+		s.logger.Warnw("Failed to set session balance", "error", err)
+		return fmt.Errorf("adjust session balance: %w", err)
+	}
+
+	if err := coreAudioSetBalance(device, s.isInput, balance); err != nil {
+		s.logger.Warnw("Failed to set session balance",
+			"error", err,
+			"balance", balance)
+
+		return fmt.Errorf("adjust session balance: %w", err)
+	}
+
+	s.logger.Debugw("Adjusting session balance", "to", fmt.Sprintf("%.2f", balance))
+
+	return nil
+}
+
+func (s *masterSession) Release() {
+	s.logger.Debug("Releasing audio session")
+}
+
+func (s *masterSession) String() string {
+	return fmt.Sprintf(sessionStringFormat, s.humanReadableDesc, s.GetVolume())
+}
@@ -1,8 +1,63 @@
 package deej
 
-// SessionFinder represents an entity that can find all current audio sessions
+import "errors"
+
+// SessionFinder represents an entity that can find all current audio sessions. it's the whole of what an audio
+// backend has to provide: everything else (applying slider moves, special targets, the API) only goes through
+// the SessionBackend made out of it and the sessions it returns (see session_backend.go). anything more a backend
+// can do is one of the optional interfaces below
 type SessionFinder interface {
 	GetAllSessions() ([]Session, error)
 
 	Release() error
 }
+
+// focusedProcessFinder is implemented by session finders that can tell which processes the focused window
+// belongs to, which is what deej.current controls
+type focusedProcessFinder interface {
+	GetCurrentWindowProcessNames() ([]string, error)
+}
+
+// systemSoundsFinder is implemented by session finders whose backend has a system sounds session (only windows).
+// the session only shows up once a system sound has played, so this is how deej knows to offer it before then
+type systemSoundsFinder interface {
+	HasSystemSoundsSession() bool
+}
+
+// prefix for device sessions in logger
+const deviceSessionFormat = "device.%s"
+
+// defaultDeviceChangeNotifier is implemented by session finders that can tell when the OS default devices change.
+// the returned channel is buffered and never blocks the notifier: several changes in a row may arrive as one
+type defaultDeviceChangeNotifier interface {
+	SubscribeToDefaultDeviceChanges() chan bool
+}
+
+// notifyDefaultDeviceChange delivers a default device change to all consumers without blocking, since
+// notifiers call this from OS callbacks. a consumer that hasn't seen the last change yet doesn't need another
+func notifyDefaultDeviceChange(consumers []chan bool) {
+	for _, consumer := range consumers {
+		select {
+		case consumer <- true:
+		default:
+		}
+	}
+}
+
+// AudioDevice describes a playback device that can be made the OS default
+type AudioDevice struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Default bool   `json:"default"`
+}
+
+// playbackDeviceManager is implemented by session finders that can list playback devices and switch the default one
+type playbackDeviceManager interface {
+	GetPlaybackDevices() ([]AudioDevice, error)
+	SetDefaultPlaybackDevice(id string) error
+}
+
+var (
+	errPlaybackDevicesUnsupported = errors.New("playback device management isn't supported on this platform")
+	errPlaybackDeviceNotFound     = errors.New("no active playback device with this ID")
+)
@@ -0,0 +1,120 @@
+//go:build darwin && cgo
+// +build darwin,cgo
+
+package deej
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// caSessionFinder finds sessions through CoreAudio. master and mic, as well as every device, can be fully
+// controlled. apps that play audio are listed (on macOS 14.2 and later), but macOS doesn't let anyone else
+// change their volume, so they're uncontrollable sessions
+type caSessionFinder struct {
+	logger        *zap.SugaredLogger
+	sessionLogger *zap.SugaredLogger
+}
+
+// the input side of a device that also plays, i.e. "AirPods Pro (input)"
+const inputDeviceSessionFormat = "%s (input)"
+
+func newSessionFinder(logger *zap.SugaredLogger) (SessionFinder, error) {
+	sf := &caSessionFinder{
+		logger:        logger.Named("session_finder"),
+		sessionLogger: logger.Named("sessions"),
+	}
+
+	sf.logger.Debug("Created CoreAudio session finder instance")
+
+	return sf, nil
+}
+
+func (sf *caSessionFinder) GetAllSessions() ([]Session, error) {
+
+	// these follow the default devices on their own, so they don't need to be recreated when those change
+	sessions := []Session{
+		newMasterSession(sf.sessionLogger, 0, false, masterSessionName, masterSessionName),
+		newMasterSession(sf.sessionLogger, 0, true, inputSessionName, inputSessionName),
+	}
+
+	// every device can also be addressed on its own, by its name
+	if err := sf.enumerateAndAddDeviceSessions(&sessions); err != nil {
+		sf.logger.Warnw("Failed to enumerate audio devices", "error", err)
+		return nil, fmt.Errorf("enumerate audio devices: %w", err)
+	}
+
+	// not being able to list apps isn't fatal, they can't be controlled anyway
+	if err := sf.enumerateAndAddProcessSessions(&sessions); err != nil {
+		sf.logger.Warnw("Failed to enumerate audio processes", "error", err)
+	}
+
+	return sessions, nil
+}
+
+func (sf *caSessionFinder) Release() error {
+	sf.logger.Debug("Released CoreAudio session finder instance")
+
+	return nil
+}
+
+func (sf *caSessionFinder) enumerateAndAddDeviceSessions(sessions *[]Session) error {
+	devices, err := coreAudioDevices()
+	if err != nil {
+		return fmt.Errorf("get device list: %w", err)
+	}
+
+	for _, device := range devices {
+		name, err := coreAudioName(device)
+		if err != nil {
+			sf.logger.Warnw("Failed to get device name", "device", device, "error", err)
+			continue
+		}
+
+		hasOutput := coreAudioDeviceHasScope(device, false)
+		hasInput := coreAudioDeviceHasScope(device, true)
+
+		if hasOutput {
+			*sessions = append(*sessions, newMasterSession(sf.sessionLogger, device, false,
+				name, fmt.Sprintf(deviceSessionFormat, name)))
+		}
+
+		// a device with both (i.e. a headset) goes by the same name for each, unlike windows devices
+		if hasInput {
+			if hasOutput {
+				name = fmt.Sprintf(inputDeviceSessionFormat, name)
+			}
+
+			*sessions = append(*sessions, newMasterSession(sf.sessionLogger, device, true,
+				name, fmt.Sprintf(deviceSessionFormat, name)))
+		}
+	}
+
+	return nil
+}
+
+func (sf *caSessionFinder) enumerateAndAddProcessSessions(sessions *[]Session) error {
+	processes, err := coreAudioProcesses()
+	if err != nil {
+		return fmt.Errorf("get audio process list: %w", err)
+	}
+
+	for _, process := range processes {
+
+		// every app that ever touched audio stays in the list, only the ones actually playing are of interest
+		if !coreAudioProcessPlaying(process) {
+			continue
+		}
+
+		name, err := coreAudioProcessName(process)
+		if err != nil {
+			sf.logger.Debugw("Failed to get audio process name", "process", process, "error", err)
+			continue
+		}
+
+		*sessions = append(*sessions, newCASession(sf.sessionLogger, name))
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build darwin && !cgo
+// +build darwin,!cgo
+
+package deej
+
+import (
+	"errors"
+
+	"go.uber.org/zap"
+)
+
+// CoreAudio is only reachable through cgo, so a macOS build without it can't control any audio
+var errCoreAudioUnavailable = errors.New("deej must be built with cgo to use CoreAudio")
+
+func newSessionFinder(logger *zap.SugaredLogger) (SessionFinder, error) {
+	return nil, errCoreAudioUnavailable
+}
@@ -3,6 +3,8 @@ package deej
 import (
 	"fmt"
 	"net"
+	"strconv"
+	"strings"
 
 	"github.com/jfreymuth/pulse/proto"
 	"go.uber.org/zap"
@@ -14,8 +16,17 @@ type paSessionFinder struct {
 
 	client *proto.Client
 	conn   net.Conn
+
+	defaultDeviceChangeConsumers []chan bool
 }
 
+// pulseaudio subscription constants, see pulse/def.h
+const (
+	paSubscriptionMaskServer    = 0x0080
+	paSubscriptionEventFacility = 0x000f
+	paSubscriptionEventServer   = 0x0007
+)
+
 func newSessionFinder(logger *zap.SugaredLogger) (SessionFinder, error) {
 	client, conn, err := proto.Connect("")
 	if err != nil {
@@ -41,6 +52,18 @@ func newSessionFinder(logger *zap.SugaredLogger) (SessionFinder, error) {
 		conn:          conn,
 	}
 
+	// the default sink and source are server properties, so a server change event is how we hear about them changing.
+	// this callback runs on the client's reader goroutine, which must never block
+	client.Callback = func(message interface{}) {
+		if event, ok := message.(*proto.SubscribeEvent); ok && event.Event&paSubscriptionEventFacility == paSubscriptionEventServer {
+			notifyDefaultDeviceChange(sf.defaultDeviceChangeConsumers)
+		}
+	}
+
+	if err := client.Request(&proto.Subscribe{Mask: paSubscriptionMaskServer}, nil); err != nil {
+		sf.logger.Warnw("Failed to subscribe to PulseAudio server changes", "error", err)
+	}
+
 	sf.logger.Debug("Created PA session finder instance")
 
 	return sf, nil
@@ -71,9 +94,79 @@ func (sf *paSessionFinder) GetAllSessions() ([]Session, error) {
 		return nil, fmt.Errorf("enumerate audio sessions: %w", err)
 	}
 
+	// every sink and source can also be addressed on its own, by its description
+	if err := sf.enumerateAndAddDeviceSessions(&sessions); err != nil {
+		sf.logger.Warnw("Failed to enumerate audio devices", "error", err)
+		return nil, fmt.Errorf("enumerate audio devices: %w", err)
+	}
+
 	return sessions, nil
 }
 
+// SubscribeToDefaultDeviceChanges returns a channel that receives a value whenever the default sink or source changes
+func (sf *paSessionFinder) SubscribeToDefaultDeviceChanges() chan bool {
+	ch := make(chan bool, 1)
+	sf.defaultDeviceChangeConsumers = append(sf.defaultDeviceChangeConsumers, ch)
+
+	return ch
+}
+
+// GetPlaybackDevices lists all sinks, using their description as a friendly name when they have one
+func (sf *paSessionFinder) GetPlaybackDevices() ([]AudioDevice, error) {
+	serverInfo := proto.GetServerInfoReply{}
+
+	if err := sf.client.Request(&proto.GetServerInfo{}, &serverInfo); err != nil {
+		sf.logger.Warnw("Failed to get server info", "error", err)
+		return nil, fmt.Errorf("get server info: %w", err)
+	}
+
+	reply := proto.GetSinkInfoListReply{}
+
+	if err := sf.client.Request(&proto.GetSinkInfoList{}, &reply); err != nil {
+		sf.logger.Warnw("Failed to get sink list", "error", err)
+		return nil, fmt.Errorf("get sink list: %w", err)
+	}
+
+	devices := make([]AudioDevice, 0, len(reply))
+
+	for _, info := range reply {
+		devices = append(devices, AudioDevice{
+			ID:      info.SinkName,
+			Name:    deviceDescription(info.Properties, info.SinkName),
+			Default: info.SinkName == serverInfo.DefaultSinkName,
+		})
+	}
+
+	return devices, nil
+}
+
+// SetDefaultPlaybackDevice makes the sink with the given name the default one
+func (sf *paSessionFinder) SetDefaultPlaybackDevice(id string) error {
+	devices, err := sf.GetPlaybackDevices()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, device := range devices {
+		if device.ID == id {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return errPlaybackDeviceNotFound
+	}
+
+	if err := sf.client.Request(&proto.SetDefaultSink{SinkName: id}, nil); err != nil {
+		sf.logger.Warnw("Failed to set default sink", "sinkName", id, "error", err)
+		return fmt.Errorf("set default sink: %w", err)
+	}
+
+	return nil
+}
+
 func (sf *paSessionFinder) Release() error {
 	if err := sf.conn.Close(); err != nil {
 		sf.logger.Warnw("Failed to close PulseAudio connection", "error", err)
@@ -97,7 +190,7 @@ func (sf *paSessionFinder) getMasterSinkSession() (Session, error) {
 	}
 
 	// create the master sink session
-	sink := newMasterSession(sf.sessionLogger, sf.client, reply.SinkIndex, reply.Channels, true)
+	sink := newMasterSession(sf.sessionLogger, sf.client, reply.SinkIndex, reply.Channels, true, masterSessionName, masterSessionName)
 
 	return sink, nil
 }
@@ -114,7 +207,7 @@ func (sf *paSessionFinder) getMasterSourceSession() (Session, error) {
 	}
 
 	// create the master source session
-	source := newMasterSession(sf.sessionLogger, sf.client, reply.SourceIndex, reply.Channels, false)
+	source := newMasterSession(sf.sessionLogger, sf.client, reply.SourceIndex, reply.Channels, false, inputSessionName, inputSessionName)
 
 	return source, nil
 }
@@ -128,9 +221,26 @@ func (sf *paSessionFinder) enumerateAndAddSessions(sessions *[]Session) error {
 		return fmt.Errorf("get sink input list: %w", err)
 	}
 
+	// only to say which device each session plays on, so going without is fine
+	sinkDescriptions := map[uint32]string{}
+	sinks := proto.GetSinkInfoListReply{}
+
+	if err := sf.client.Request(&proto.GetSinkInfoList{}, &sinks); err != nil {
+		sf.logger.Debugw("Failed to get sink list for sink input devices", "error", err)
+	}
+
+	for _, info := range sinks {
+		sinkDescriptions[info.SinkIndex] = deviceDescription(info.Properties, info.SinkName)
+	}
+
 	for _, info := range reply {
 		name, ok := info.Properties["application.process.binary"]
 
+		// some PipeWire clients (i.e. flatpaks) don't say which binary they are, but still name themselves
+		if !ok {
+			name, ok = info.Properties["application.name"]
+		}
+
 		if !ok {
 			sf.logger.Warnw("Failed to get sink input's process name",
 				"sinkInputIndex", info.SinkInputIndex)
@@ -138,8 +248,22 @@ func (sf *paSessionFinder) enumerateAndAddSessions(sessions *[]Session) error {
 			continue
 		}
 
+		// the application's own name for itself is friendlier than its binary, when it bothers to set one
+		var displayName string
+		if appName, ok := info.Properties["application.name"]; ok {
+			displayName = appName.String()
+		}
+
 		// create the deej session object
-		newSession := newPASession(sf.sessionLogger, sf.client, info.SinkInputIndex, info.Channels, name.String())
+		newSession := newPASession(sf.sessionLogger, sf.client, info.SinkInputIndex, info.Channels, name.String(), displayName)
+
+		// pulse has no expired state, sink inputs are just gone once their app stops using them
+		newSession.outputDevice = sinkDescriptions[info.SinkIndex]
+		newSession.inactive = info.Corked
+
+		if pid, ok := info.Properties["application.process.id"]; ok {
+			newSession.processID, _ = strconv.Atoi(pid.String())
+		}
 
 		// add it to our slice
 		*sessions = append(*sessions, newSession)
@@ -148,3 +272,51 @@ func (sf *paSessionFinder) enumerateAndAddSessions(sessions *[]Session) error {
 
 	return nil
 }
+
+func (sf *paSessionFinder) enumerateAndAddDeviceSessions(sessions *[]Session) error {
+	sinks := proto.GetSinkInfoListReply{}
+
+	if err := sf.client.Request(&proto.GetSinkInfoList{}, &sinks); err != nil {
+		sf.logger.Warnw("Failed to get sink list", "error", err)
+		return fmt.Errorf("get sink list: %w", err)
+	}
+
+	for _, info := range sinks {
+		description := deviceDescription(info.Properties, info.SinkName)
+
+		*sessions = append(*sessions, newMasterSession(sf.sessionLogger, sf.client, info.SinkIndex, info.Channels, true,
+			description, fmt.Sprintf(deviceSessionFormat, strings.ToLower(description))))
+	}
+
+	sources := proto.GetSourceInfoListReply{}
+
+	if err := sf.client.Request(&proto.GetSourceInfoList{}, &sources); err != nil {
+		sf.logger.Warnw("Failed to get source list", "error", err)
+		return fmt.Errorf("get source list: %w", err)
+	}
+
+	for _, info := range sources {
+
+		// every sink has a monitor source, which are what's playing rather than actual inputs
+		if info.MonitorSourceIndex != proto.Undefined {
+			continue
+		}
+
+		description := deviceDescription(info.Properties, info.SourceName)
+
+		*sessions = append(*sessions, newMasterSession(sf.sessionLogger, sf.client, info.SourceIndex, info.Channels, false,
+			description, fmt.Sprintf(deviceSessionFormat, strings.ToLower(description))))
+	}
+
+	return nil
+}
+
+// deviceDescription returns a sink or source's description (i.e. "Built-in Audio Analog Stereo"),
+// which is what desktop volume controls show it as, or its name if it doesn't have one
+func deviceDescription(properties proto.PropList, name string) string {
+	if description, ok := properties["device.description"]; ok && description.String() != "" {
+		return description.String()
+	}
+
+	return name
+}
@@ -0,0 +1,268 @@
+package deej
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// mockSessionFinder is an in-memory audio backend with a handful of made-up sessions, for working on the web UI
+// and API without real audio sessions (or on a platform without a backend). its sessions live as long as it does,
+// so volume and mute changes stick across session refreshes. run deej with DEEJ_MOCK_AUDIO set to use it
+type mockSessionFinder struct {
+	logger *zap.SugaredLogger
+
+	sessions []Session
+
+	lock          sync.Mutex
+	defaultDevice string
+}
+
+type mockSession struct {
+	baseSession
+
+	lock    sync.Mutex
+	volume  float32
+	muted   bool
+	balance float32
+
+	// set for sessions listed in DEEJ_MOCK_AUDIO_HANG, which take mockHangDuration to change, like a stuck OS call
+	hangs bool
+
+	// while set, changes wait for it to be closed no matter their context, see block
+	blocked chan struct{}
+}
+
+// long enough to outlast any session call timeout, short enough to see the session recover
+const mockHangDuration = 10 * time.Second
+
+// the focused window's process, as far as deej.current is concerned
+const mockFocusedProcess = "chrome.exe"
+
+var mockPlaybackDevices = []AudioDevice{
+	{ID: "speakers", Name: "Speakers (Mock Audio)"},
+	{ID: "headphones", Name: "Headphones (Mock Audio)"},
+}
+
+func newMockSessionFinder(logger *zap.SugaredLogger) *mockSessionFinder {
+	sessionLogger := logger.Named("sessions")
+
+	sf := &mockSessionFinder{
+		logger:        logger.Named("session_finder"),
+		defaultDevice: mockPlaybackDevices[0].ID,
+	}
+
+	sf.sessions = []Session{
+		newMockSession(sessionLogger, masterSessionName, "", 0.8),
+		newMockSession(sessionLogger, inputSessionName, "", 1),
+		newMockSession(sessionLogger, systemSessionName, "", 0.5),
+		newMockSession(sessionLogger, "spotify.exe", "Spotify", 0.6),
+		newMockSession(sessionLogger, "chrome.exe", "Google Chrome", 1),
+		newMockSession(sessionLogger, "discord.exe", "Discord", 0.7),
+		newMockSession(sessionLogger, "game.exe", "Some Game", 0.4),
+
+		// like the real thing, chrome plays from more than one process at a time
+		newMockSession(sessionLogger, "chrome.exe", "Google Chrome", 1),
+	}
+
+	hanging := make(map[string]bool)
+	for _, key := range strings.Split(os.Getenv(envMockAudioHang), ",") {
+		hanging[strings.ToLower(strings.TrimSpace(key))] = true
+	}
+
+	// apps get made-up process IDs and all play on the first device, and the game is paused
+	for idx, session := range sf.sessions {
+		mock := session.(*mockSession)
+		mock.hangs = hanging[mock.name]
+
+		if mock.master || mock.system {
+			continue
+		}
+
+		mock.processID = 4000 + idx*4
+		mock.outputDevice = mockPlaybackDevices[0].Name
+		mock.inactive = mock.name == "game.exe"
+	}
+
+	sf.logger.Debug("Created mock session finder instance")
+
+	return sf
+}
+
+func newMockSession(logger *zap.SugaredLogger, key string, displayName string, volume float32) *mockSession {
+	s := &mockSession{
+		volume: volume,
+	}
+
+	s.name = key
+	s.humanReadableDesc = key
+	s.displayName = displayName
+	s.master = key == masterSessionName || key == inputSessionName
+	s.system = key == systemSessionName
+
+	s.logger = logger.Named(key)
+	s.logger.Debugw(sessionCreationLogMessage, "session", s)
+
+	return s
+}
+
+func (sf *mockSessionFinder) GetAllSessions() ([]Session, error) {
+	return append([]Session{}, sf.sessions...), nil
+}
+
+func (sf *mockSessionFinder) Release() error {
+	sf.logger.Debug("Released mock session finder instance")
+
+	return nil
+}
+
+// GetCurrentWindowProcessNames always says the same app is focused
+func (sf *mockSessionFinder) GetCurrentWindowProcessNames() ([]string, error) {
+	return []string{mockFocusedProcess}, nil
+}
+
+// HasSystemSoundsSession is true, there's a made-up system sounds session too
+func (sf *mockSessionFinder) HasSystemSoundsSession() bool {
+	return true
+}
+
+// GetPlaybackDevices lists a couple of made-up playback devices
+func (sf *mockSessionFinder) GetPlaybackDevices() ([]AudioDevice, error) {
+	sf.lock.Lock()
+	defer sf.lock.Unlock()
+
+	devices := make([]AudioDevice, len(mockPlaybackDevices))
+
+	for idx, device := range mockPlaybackDevices {
+		device.Default = device.ID == sf.defaultDevice
+		devices[idx] = device
+	}
+
+	return devices, nil
+}
+
+// SetDefaultPlaybackDevice makes one of the made-up playback devices the default
+func (sf *mockSessionFinder) SetDefaultPlaybackDevice(id string) error {
+	sf.lock.Lock()
+	defer sf.lock.Unlock()
+
+	for _, device := range mockPlaybackDevices {
+		if device.ID == id {
+			sf.defaultDevice = id
+			return nil
+		}
+	}
+
+	return errPlaybackDeviceNotFound
+}
+
+func (s *mockSession) GetVolume() float32 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.volume
+}
+
+func (s *mockSession) SetVolume(ctx context.Context, v float32) error {
+	if err := s.hang(ctx); err != nil {
+		return fmt.Errorf("adjust session volume: %w", err)
+	}
+
+	s.lock.Lock()
+	s.volume = v
+	s.lock.Unlock()
+
+	s.logger.Debugw("Adjusting session volume", "to", fmt.Sprintf("%.2f", v))
+
+	return nil
+}
+
+func (s *mockSession) GetMute() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.muted
+}
+
+func (s *mockSession) SetMute(ctx context.Context, m bool) error {
+	if err := s.hang(ctx); err != nil {
+		return fmt.Errorf("adjust session mute state: %w", err)
+	}
+
+	s.lock.Lock()
+	s.muted = m
+	s.lock.Unlock()
+
+	s.logger.Debugw("Adjusting session mute state", "to", m)
+
+	return nil
+}
+
+// CanBalance is true for every mock session, there's no mono audio here
+func (s *mockSession) CanBalance() bool {
+	return true
+}
+
+func (s *mockSession) SetBalance(ctx context.Context, balance float32) error {
+	if err := s.hang(ctx); err != nil {
+		return fmt.Errorf("adjust session balance: %w", err)
+	}
+
+	s.lock.Lock()
+	s.balance = balance
+	s.lock.Unlock()
+
+	s.logger.Debugw("Adjusting session balance", "to", fmt.Sprintf("%.2f", balance))
+
+	return nil
+}
+
+// hang blocks a hanging session's changes without regard for their context, then checks it like a real session
+// would before going through with them
+func (s *mockSession) hang(ctx context.Context) error {
+	if s.hangs {
+		time.Sleep(mockHangDuration)
+	}
+
+	s.lock.Lock()
+	blocked := s.blocked
+	s.lock.Unlock()
+
+	if blocked != nil {
+		<-blocked
+	}
+
+	return ctx.Err()
+}
+
+// block has the session's changes hang until release is called, rather than for a set time
+func (s *mockSession) block() (release func()) {
+	blocked := make(chan struct{})
+
+	s.lock.Lock()
+	s.blocked = blocked
+	s.lock.Unlock()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			s.lock.Lock()
+			s.blocked = nil
+			s.lock.Unlock()
+
+			close(blocked)
+		})
+	}
+}
+
+func (s *mockSession) Release() {}
+
+func (s *mockSession) String() string {
+	return fmt.Sprintf(sessionStringFormat, s.humanReadableDesc, s.GetVolume())
+}
@@ -11,6 +11,8 @@ import (
 	ole "github.com/go-ole/go-ole"
 	wca "github.com/moutend/go-wca"
 	"go.uber.org/zap"
+
+	"github.com/omriharel/deej/pkg/deej/util"
 )
 
 type wcaSessionFinder struct {
@@ -24,6 +26,8 @@ type wcaSessionFinder struct {
 	mmNotificationClient    *wca.IMMNotificationClient
 	lastDefaultDeviceChange time.Time
 
+	defaultDeviceChangeConsumers []chan bool
+
 	// our master input and output sessions
 	masterOut *masterSession
 	masterIn  *masterSession
@@ -37,9 +41,6 @@ const (
 	// the notification client will call this multiple times in quick succession based on the
 	// default device's assigned media roles, so we need to filter out the extraneous calls
 	minDefaultDeviceChangeThreshold = 100 * time.Millisecond
-
-	// prefix for device sessions in logger
-	deviceSessionFormat = "device.%s"
 )
 
 func newSessionFinder(logger *zap.SugaredLogger) (SessionFinder, error) {
@@ -58,34 +59,8 @@ func (sf *wcaSessionFinder) GetAllSessions() ([]Session, error) {
 	sessions := []Session{}
 
 	// we must call this every time we're about to list devices, i think. could be wrong
-	if err := ole.CoInitializeEx(0, ole.COINIT_APARTMENTTHREADED); err != nil {
-
-		// if the error is "Incorrect function" that corresponds to 0x00000001,
-		// which represents E_FALSE in COM error handling. this is fine for this function,
-		// and just means that the call was redundant.
-		const eFalse = 1
-		oleError := &ole.OleError{}
-
-		if errors.As(err, &oleError) {
-			if oleError.Code() == eFalse {
-				sf.logger.Warn("CoInitializeEx failed with E_FALSE due to redundant invocation")
-			} else {
-				sf.logger.Warnw("Failed to call CoInitializeEx",
-					"isOleError", true,
-					"error", err,
-					"oleError", oleError)
-
-				return nil, fmt.Errorf("call CoInitializeEx: %w", err)
-			}
-		} else {
-			sf.logger.Warnw("Failed to call CoInitializeEx",
-				"isOleError", false,
-				"error", err,
-				"oleError", nil)
-
-			return nil, fmt.Errorf("call CoInitializeEx: %w", err)
-		}
-
+	if err := sf.initializeCOM(); err != nil {
+		return nil, err
 	}
 	defer ole.CoUninitialize()
 
@@ -159,6 +134,41 @@ func (sf *wcaSessionFinder) Release() error {
 	return nil
 }
 
+// initializeCOM initializes COM for the calling thread. callers must defer ole.CoUninitialize when it succeeds
+func (sf *wcaSessionFinder) initializeCOM() error {
+	if err := ole.CoInitializeEx(0, ole.COINIT_APARTMENTTHREADED); err != nil {
+
+		// if the error is "Incorrect function" that corresponds to 0x00000001,
+		// which represents E_FALSE in COM error handling. this is fine for this function,
+		// and just means that the call was redundant.
+		const eFalse = 1
+		oleError := &ole.OleError{}
+
+		if errors.As(err, &oleError) {
+			if oleError.Code() == eFalse {
+				sf.logger.Warn("CoInitializeEx failed with E_FALSE due to redundant invocation")
+			} else {
+				sf.logger.Warnw("Failed to call CoInitializeEx",
+					"isOleError", true,
+					"error", err,
+					"oleError", oleError)
+
+				return fmt.Errorf("call CoInitializeEx: %w", err)
+			}
+		} else {
+			sf.logger.Warnw("Failed to call CoInitializeEx",
+				"isOleError", false,
+				"error", err,
+				"oleError", nil)
+
+			return fmt.Errorf("call CoInitializeEx: %w", err)
+		}
+
+	}
+
+	return nil
+}
+
 func (sf *wcaSessionFinder) getDeviceEnumerator() error {
 
 	// get the IMMDeviceEnumerator (only once)
@@ -482,7 +492,8 @@ func (sf *wcaSessionFinder) enumerateAndAddProcessSessions(
 		simpleAudioVolume := (*wca.ISimpleAudioVolume)(unsafe.Pointer(dispatch))
 
 		// create the deej session object
-		newSession, err := newWCASession(sf.sessionLogger, audioSessionControl2, simpleAudioVolume, pid, sf.eventCtx)
+		newSession, err := newWCASession(sf.sessionLogger, audioSessionControl2, simpleAudioVolume, pid,
+			endpointFriendlyName, sf.eventCtx)
 		if err != nil {
 
 			// this could just mean this process is already closed by now, and the session will be cleaned up later by the OS
@@ -510,6 +521,145 @@ func (sf *wcaSessionFinder) enumerateAndAddProcessSessions(
 	return nil
 }
 
+// GetPlaybackDevices lists all active output devices by their friendly name, marking the default one
+func (sf *wcaSessionFinder) GetPlaybackDevices() ([]AudioDevice, error) {
+	if err := sf.initializeCOM(); err != nil {
+		return nil, err
+	}
+	defer ole.CoUninitialize()
+
+	if err := sf.getDeviceEnumerator(); err != nil {
+		sf.logger.Warnw("Failed to get device enumerator", "error", err)
+		return nil, fmt.Errorf("get device enumerator: %w", err)
+	}
+
+	return sf.enumeratePlaybackDevices()
+}
+
+// SetDefaultPlaybackDevice makes the output device with the given endpoint ID the default one, for all roles
+func (sf *wcaSessionFinder) SetDefaultPlaybackDevice(id string) error {
+	if err := sf.initializeCOM(); err != nil {
+		return err
+	}
+	defer ole.CoUninitialize()
+
+	if err := sf.getDeviceEnumerator(); err != nil {
+		sf.logger.Warnw("Failed to get device enumerator", "error", err)
+		return fmt.Errorf("get device enumerator: %w", err)
+	}
+
+	devices, err := sf.enumeratePlaybackDevices()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, device := range devices {
+		if device.ID == id {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return errPlaybackDeviceNotFound
+	}
+
+	if err := setDefaultEndpoint(id); err != nil {
+		sf.logger.Warnw("Failed to set default audio endpoint", "id", id, "error", err)
+		return fmt.Errorf("set default audio endpoint: %w", err)
+	}
+
+	return nil
+}
+
+// enumeratePlaybackDevices assumes COM is initialized and the device enumerator is ready
+func (sf *wcaSessionFinder) enumeratePlaybackDevices() ([]AudioDevice, error) {
+
+	// the default device is only a candidate for comparison, there might not be one if nothing is plugged in
+	var defaultID string
+	var defaultEndpoint *wca.IMMDevice
+
+	if err := sf.mmDeviceEnumerator.GetDefaultAudioEndpoint(wca.ERender, wca.EConsole, &defaultEndpoint); err == nil {
+		defaultID, _ = getEndpointID(defaultEndpoint)
+		defaultEndpoint.Release()
+	}
+
+	var deviceCollection *wca.IMMDeviceCollection
+
+	if err := sf.mmDeviceEnumerator.EnumAudioEndpoints(wca.ERender, wca.DEVICE_STATE_ACTIVE, &deviceCollection); err != nil {
+		sf.logger.Warnw("Failed to enumerate active output endpoints", "error", err)
+		return nil, fmt.Errorf("enumerate active output endpoints: %w", err)
+	}
+	defer deviceCollection.Release()
+
+	var deviceCount uint32
+
+	if err := deviceCollection.GetCount(&deviceCount); err != nil {
+		sf.logger.Warnw("Failed to get device count from device collection", "error", err)
+		return nil, fmt.Errorf("get device count from device collection: %w", err)
+	}
+
+	devices := make([]AudioDevice, 0, deviceCount)
+
+	for deviceIdx := uint32(0); deviceIdx < deviceCount; deviceIdx++ {
+		device, err := sf.getPlaybackDevice(deviceCollection, deviceIdx)
+		if err != nil {
+			return nil, err
+		}
+
+		device.Default = device.ID == defaultID
+		devices = append(devices, device)
+	}
+
+	return devices, nil
+}
+
+func (sf *wcaSessionFinder) getPlaybackDevice(deviceCollection *wca.IMMDeviceCollection, deviceIdx uint32) (AudioDevice, error) {
+	var endpoint *wca.IMMDevice
+
+	if err := deviceCollection.Item(deviceIdx, &endpoint); err != nil {
+		sf.logger.Warnw("Failed to get device from device collection",
+			"deviceIdx", deviceIdx,
+			"error", err)
+
+		return AudioDevice{}, fmt.Errorf("get device %d from device collection: %w", deviceIdx, err)
+	}
+	defer endpoint.Release()
+
+	id, err := getEndpointID(endpoint)
+	if err != nil {
+		sf.logger.Warnw("Failed to get endpoint ID for device",
+			"deviceIdx", deviceIdx,
+			"error", err)
+
+		return AudioDevice{}, fmt.Errorf("get device %d endpoint ID: %w", deviceIdx, err)
+	}
+
+	var propertyStore *wca.IPropertyStore
+
+	if err := endpoint.OpenPropertyStore(wca.STGM_READ, &propertyStore); err != nil {
+		sf.logger.Warnw("Failed to open property store for endpoint",
+			"deviceIdx", deviceIdx,
+			"error", err)
+
+		return AudioDevice{}, fmt.Errorf("open endpoint %d property store: %w", deviceIdx, err)
+	}
+	defer propertyStore.Release()
+
+	value := &wca.PROPVARIANT{}
+
+	if err := propertyStore.GetValue(&wca.PKEY_Device_FriendlyName, value); err != nil {
+		sf.logger.Warnw("Failed to get friendly name for device",
+			"deviceIdx", deviceIdx,
+			"error", err)
+
+		return AudioDevice{}, fmt.Errorf("get device %d friendly name: %w", deviceIdx, err)
+	}
+
+	return AudioDevice{ID: id, Name: value.String()}, nil
+}
+
 func (sf *wcaSessionFinder) defaultDeviceChangedCallback(
 	this *wca.IMMNotificationClient,
 	EDataFlow, eRole uint32,
@@ -534,8 +684,29 @@ func (sf *wcaSessionFinder) defaultDeviceChangedCallback(
 		sf.masterIn.markAsStale()
 	}
 
+	notifyDefaultDeviceChange(sf.defaultDeviceChangeConsumers)
+
 	return
 }
+
+// SubscribeToDefaultDeviceChanges returns a channel that receives a value whenever the default device changes
+func (sf *wcaSessionFinder) SubscribeToDefaultDeviceChanges() chan bool {
+	ch := make(chan bool, 1)
+	sf.defaultDeviceChangeConsumers = append(sf.defaultDeviceChangeConsumers, ch)
+
+	return ch
+}
+
+// GetCurrentWindowProcessNames returns the process names of the foreground window, and any of its child processes
+func (sf *wcaSessionFinder) GetCurrentWindowProcessNames() ([]string, error) {
+	return util.GetCurrentWindowProcessNames()
+}
+
+// HasSystemSoundsSession is always true, the mixer has one whether or not it's playing right now
+func (sf *wcaSessionFinder) HasSystemSoundsSession() bool {
+	return true
+}
+
 func (sf *wcaSessionFinder) noopCallback() (hResult uintptr) {
 	return
 }
@@ -1,6 +1,7 @@
 package deej
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -23,6 +24,9 @@ type paSession struct {
 
 	sinkInputIndex    uint32
 	sinkInputChannels byte
+
+	// pulse only has per-channel volumes, so the balance goes into every volume change
+	balance float32
 }
 
 type masterSession struct {
@@ -33,6 +37,8 @@ type masterSession struct {
 	streamIndex    uint32
 	streamChannels byte
 	isOutput       bool
+
+	balance float32
 }
 
 func newPASession(
@@ -41,6 +47,7 @@ func newPASession(
 	sinkInputIndex uint32,
 	sinkInputChannels byte,
 	processName string,
+	displayName string,
 ) *paSession {
 
 	s := &paSession{
@@ -52,6 +59,7 @@ func newPASession(
 	s.processName = processName
 	s.name = processName
 	s.humanReadableDesc = processName
+	s.displayName = displayName
 
 	// use a self-identifying session name e.g. deej.sessions.chrome
 	s.logger = logger.Named(s.Key())
@@ -66,6 +74,8 @@ func newMasterSession(
 	streamIndex uint32,
 	streamChannels byte,
 	isOutput bool,
+	key string,
+	loggerKey string,
 ) *masterSession {
 
 	s := &masterSession{
@@ -75,15 +85,7 @@ func newMasterSession(
 		isOutput:       isOutput,
 	}
 
-	var key string
-
-	if isOutput {
-		key = masterSessionName
-	} else {
-		key = inputSessionName
-	}
-
-	s.logger = logger.Named(key)
+	s.logger = logger.Named(loggerKey)
 	s.master = true
 	s.name = key
 	s.humanReadableDesc = key
@@ -108,8 +110,12 @@ func (s *paSession) GetVolume() float32 {
 	return level
 }
 
-func (s *paSession) SetVolume(v float32) error {
-	volumes := createChannelVolumes(s.sinkInputChannels, v)
+func (s *paSession) SetVolume(ctx context.Context, v float32) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("adjust session volume: %w", err)
+	}
+
+	volumes := createChannelVolumes(s.sinkInputChannels, v, s.balance)
 	request := proto.SetSinkInputVolume{
 		SinkInputIndex: s.sinkInputIndex,
 		ChannelVolumes: volumes,
@@ -125,6 +131,59 @@ func (s *paSession) SetVolume(v float32) error {
 	return nil
 }
 
+func (s *paSession) GetMute() bool {
+	request := proto.GetSinkInputInfo{
+		SinkInputIndex: s.sinkInputIndex,
+	}
+	reply := proto.GetSinkInputInfoReply{}
+
+	if err := s.client.Request(&request, &reply); err != nil {
+		s.logger.Warnw("Failed to get session mute state", "error", err)
+	}
+
+	return reply.Muted
+}
+
+func (s *paSession) SetMute(ctx context.Context, m bool) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("adjust session mute state: %w", err)
+	}
+
+	request := proto.SetSinkInputMute{
+		SinkInputIndex: s.sinkInputIndex,
+		Mute:           m,
+	}
+
+	if err := s.client.Request(&request, nil); err != nil {
+		s.logger.Warnw("Failed to set session mute state", "error", err)
+		return fmt.Errorf("adjust session mute state: %w", err)
+	}
+
+	s.logger.Debugw("Adjusting session mute state", "to", m)
+
+	return nil
+}
+
+// CanBalance is only true for stereo streams, which is what nearly every app plays
+func (s *paSession) CanBalance() bool {
+	return s.sinkInputChannels == 2
+}
+
+func (s *paSession) SetBalance(ctx context.Context, balance float32) error {
+	if !s.CanBalance() {
+		return errBalanceUnsupported
+	}
+
+	s.balance = balance
+	if err := s.SetVolume(ctx, s.GetVolume()); err != nil {
+		return fmt.Errorf("adjust session balance: %w", err)
+	}
+
+	s.logger.Debugw("Adjusting session balance", "to", fmt.Sprintf("%.2f", balance))
+
+	return nil
+}
+
 func (s *paSession) Release() {
 	s.logger.Debug("Releasing audio session")
 }
@@ -165,10 +224,14 @@ func (s *masterSession) GetVolume() float32 {
 	return level
 }
 
-func (s *masterSession) SetVolume(v float32) error {
+func (s *masterSession) SetVolume(ctx context.Context, v float32) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("adjust session volume: %w", err)
+	}
+
 	var request proto.RequestArgs
 
-	volumes := createChannelVolumes(s.streamChannels, v)
+	volumes := createChannelVolumes(s.streamChannels, v, s.balance)
 
 	if s.isOutput {
 		request = &proto.SetSinkVolume{
@@ -195,6 +258,86 @@ func (s *masterSession) SetVolume(v float32) error {
 	return nil
 }
 
+func (s *masterSession) GetMute() bool {
+	if s.isOutput {
+		request := proto.GetSinkInfo{
+			SinkIndex: s.streamIndex,
+		}
+		reply := proto.GetSinkInfoReply{}
+
+		if err := s.client.Request(&request, &reply); err != nil {
+			s.logger.Warnw("Failed to get session mute state", "error", err)
+			return false
+		}
+
+		return reply.Mute
+	}
+
+	request := proto.GetSourceInfo{
+		SourceIndex: s.streamIndex,
+	}
+	reply := proto.GetSourceInfoReply{}
+
+	if err := s.client.Request(&request, &reply); err != nil {
+		s.logger.Warnw("Failed to get session mute state", "error", err)
+		return false
+	}
+
+	return reply.Mute
+}
+
+func (s *masterSession) SetMute(ctx context.Context, m bool) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("adjust session mute state: %w", err)
+	}
+
+	var request proto.RequestArgs
+
+	if s.isOutput {
+		request = &proto.SetSinkMute{
+			SinkIndex: s.streamIndex,
+			Mute:      m,
+		}
+	} else {
+		request = &proto.SetSourceMute{
+			SourceIndex: s.streamIndex,
+			Mute:        m,
+		}
+	}
+
+	if err := s.client.Request(request, nil); err != nil {
+		s.logger.Warnw("Failed to set session mute state",
+			"error", err,
+			"mute", m)
+
+		return fmt.Errorf("adjust session mute state: %w", err)
+	}
+
+	s.logger.Debugw("Adjusting session mute state", "to", m)
+
+	return nil
+}
+
+// CanBalance is only true for stereo sinks and sources
+func (s *masterSession) CanBalance() bool {
+	return s.streamChannels == 2
+}
+
+func (s *masterSession) SetBalance(ctx context.Context, balance float32) error {
+	if !s.CanBalance() {
+		return errBalanceUnsupported
+	}
+
+	s.balance = balance
+	if err := s.SetVolume(ctx, s.GetVolume()); err != nil {
+		return fmt.Errorf("adjust session balance: %w", err)
+	}
+
+	s.logger.Debugw("Adjusting session balance", "to", fmt.Sprintf("%.2f", balance))
+
+	return nil
+}
+
 func (s *masterSession) Release() {
 	s.logger.Debug("Releasing audio session")
 }
@@ -203,22 +346,34 @@ func (s *masterSession) String() string {
 	return fmt.Sprintf(sessionStringFormat, s.humanReadableDesc, s.GetVolume())
 }
 
-func createChannelVolumes(channels byte, volume float32) []uint32 {
+// createChannelVolumes spreads a volume over a stream's channels. a balance only applies to stereo streams,
+// whose channels are left and right in that order
+func createChannelVolumes(channels byte, volume float32, balance float32) []uint32 {
 	volumes := make([]uint32, channels)
 
 	for i := range volumes {
 		volumes[i] = uint32(volume * maxVolume)
 	}
 
+	if channels == 2 && balance != 0 {
+		left, right := balanceChannelFactors(balance)
+
+		volumes[0] = uint32(volume * left * maxVolume)
+		volumes[1] = uint32(volume * right * maxVolume)
+	}
+
 	return volumes
 }
 
+// parseChannelVolumes returns the loudest channel's volume, which is the stream's volume whatever its balance
 func parseChannelVolumes(volumes []uint32) float32 {
 	var level uint32
 
 	for _, volume := range volumes {
-		level += volume
+		if volume > level {
+			level = volume
+		}
 	}
 
-	return float32(level) / float32(len(volumes)) / float32(maxVolume)
+	return float32(level) / float32(maxVolume)
 }
@@ -1,13 +1,14 @@
 package deej
 
 import (
+	"encoding/base64"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/omriharel/deej/pkg/deej/util"
 	"github.com/thoas/go-funk"
 	"go.uber.org/zap"
 )
@@ -19,10 +20,105 @@ type sessionMap struct {
 	m    map[string][]Session
 	lock sync.Locker
 
+	// last known volume and mute state per session key, as last applied by deej (or read when acquired)
+	states map[string]sessionState
+
+	// session keys that deej muted because their slider hit zero, and should be unmuted once it comes back up.
+	// only ever touched while handling slider move events (and volume commands), which happens on a single goroutine
+	mutedAtZero map[string]bool
+
+	// when each session key was last adjusted by a physical slider, touched on the same goroutine
+	lastSliderMove map[string]time.Time
+
+	// where each slider is on its way to its last reported position, keyed by slider index. same goroutine as well
+	smoothers map[int]*sliderSmoother
+
+	// the slider in each group (by name) that was moved last, which is what last_touched groups follow. same goroutine
+	lastGroupSliderMove map[string]int
+
+	// remote volume changes are handled on the slider move goroutine too, so they can't race a physical move
+	targetVolumeCommands chan targetVolumeCommand
+
+	// each session key's state from before deej first changed it, kept across refreshes (see volume_restore.go)
+	snapshots map[string]sessionState
+
+	// restoring them on exit happens on the slider move goroutine as well, which stops once it's done
+	restoreRequests chan chan bool
+
+	// and so does resetting every mapped target's volume, see volume_reset.go
+	resetRequests chan chan VolumeResetSummary
+
+	// whether slider moves are ignored, and the slider move goroutine's cue to catch up once they aren't (see pause.go)
+	paused         bool
+	pauseLock      sync.Mutex
+	resumeRequests chan bool
+
+	// session keys that appeared since apply_volume_on_launch last set their volume, and a nudge for the slider
+	// move goroutine to do so. refreshes can happen on that goroutine too, so it's never waited on (see launch_volume.go)
+	launchedKeys   map[string]bool
+	launchLock     sync.Mutex
+	launchedSignal chan bool
+
+	// the slider position each session key was last set from, and the keys whose volume was since changed from
+	// outside of deej. positions are only touched on the slider move goroutine (see external_volume.go)
+	appliedPositions map[string]float32
+	externalVolumes  map[string]ExternalVolumeChange
+	externalLock     sync.Mutex
+
+	// when a slider last set each resolved target's volume, and the volumes held back since for being too soon
+	// after it. both only touched on the slider move goroutine (see apply_throttle.go)
+	lastApply      map[string]time.Time
+	pendingApplies map[string]pendingApply
+
+	// the session keys applyBalances last panned, so they can be re-centered once their balance is removed
+	balancedKeys map[string]bool
+	balanceLock  sync.Mutex
+
+	// compiled pattern targets, see target_pattern.go
+	patterns    map[string]*regexp.Regexp
+	patternLock sync.Mutex
+
+	// sessions with a call that timed out and hasn't finished since, and how many calls timed out so far
+	// (see session_timeout.go). the count is only ever touched atomically
+	pendingCalls    map[Session]bool
+	pendingCallLock sync.Mutex
+	sessionTimeouts uint64
+
+	// sessions and their volumes are only ever reached through the backend. the finder behind it is still
+	// asked for what the backend doesn't cover: playback devices and default device changes
+	backend       SessionBackend
 	sessionFinder SessionFinder
 
 	lastSessionRefresh time.Time
 	unmappedSessions   []Session
+
+	// why the last refresh couldn't get any sessions from the session finder, nil if it could. guarded by lock
+	lastRefreshErr error
+
+	// the session_names the current sessions were keyed by. guarded by lock
+	sessionNames SessionNameSettings
+
+	// what deej.current last resolved to, counting only targets that actually had sessions
+	lastCurrentWindowTargets []string
+
+	// the session keys seen in the last refresh, used to tell which sessions came and went
+	lastSessionKeys        map[string]bool
+	sessionChangeConsumers []chan SessionChangeEvent
+
+	volumeChangeConsumers []chan VolumeChangeEvent
+}
+
+// SessionChangeEvent describes which session keys appeared or disappeared during a session refresh
+type SessionChangeEvent struct {
+	Added   []string
+	Removed []string
+}
+
+// VolumeChangeEvent describes the new state of all sessions sharing a key, whenever deej changes it (or first sees it)
+type VolumeChangeEvent struct {
+	Key    string
+	Volume float32
+	Muted  bool
 }
 
 const (
@@ -45,27 +141,57 @@ const (
 	// key "process_refresh_frequency", but exposing this type of implementation detail seems wrong now
 	minTimeBetweenSessionRefreshes = time.Second * 5
 
-	// determines whether the map should be refreshed when a slider moves.
-	// this is a bit greedy but allows us to ensure sessions are always re-acquired, which is
-	// especially important for process groups (because you can have one ongoing session
-	// always preventing lookup of other processes bound to its slider, which forces the user
-	// to manually refresh sessions). a cleaner way to do this down the line is by registering to notifications
-	// whenever a new session is added, but that's too hard to justify for how easy this solution is
-	maxTimeBetweenSessionRefreshes = time.Second * 45
+	// slider values below this count as zero for the purpose of muting at zero. leaving zero again requires
+	// a move larger than the slider's noise threshold, so this can't flap back and forth on a jittery slider
+	muteAtZeroEpsilon = 0.005
 )
 
-// this matches friendly device names (on Windows), e.g. "Headphones (Realtek Audio)"
-var deviceSessionKeyPattern = regexp.MustCompile(`^.+ \(.+\)$`)
+// targetVolumeCommand is a volume change for a (possibly special) target that didn't come from a slider
+type targetVolumeCommand struct {
+	target  string
+	volume  float32
+	lockout time.Duration
+}
+
+// sessionState represents the volume and mute state of all sessions sharing a key
+type sessionState struct {
+	volume float32
+	muted  bool
+}
 
 func newSessionMap(deej *Deej, logger *zap.SugaredLogger, sessionFinder SessionFinder) (*sessionMap, error) {
 	logger = logger.Named("sessions")
 
 	m := &sessionMap{
-		deej:          deej,
-		logger:        logger,
-		m:             make(map[string][]Session),
-		lock:          &sync.Mutex{},
-		sessionFinder: sessionFinder,
+		deej:           deej,
+		logger:         logger,
+		m:              make(map[string][]Session),
+		states:         make(map[string]sessionState),
+		mutedAtZero:    make(map[string]bool),
+		lastSliderMove: make(map[string]time.Time),
+		smoothers:      make(map[int]*sliderSmoother),
+
+		lastGroupSliderMove:  make(map[string]int),
+		targetVolumeCommands: make(chan targetVolumeCommand),
+		snapshots:            make(map[string]sessionState),
+		restoreRequests:      make(chan chan bool),
+		resetRequests:        make(chan chan VolumeResetSummary),
+		resumeRequests:       make(chan bool),
+		appliedPositions:     make(map[string]float32),
+		externalVolumes:      make(map[string]ExternalVolumeChange),
+		lastApply:            make(map[string]time.Time),
+		pendingApplies:       make(map[string]pendingApply),
+		launchedKeys:         make(map[string]bool),
+		launchedSignal:       make(chan bool, 1),
+		patterns:             make(map[string]*regexp.Regexp),
+		pendingCalls:         make(map[Session]bool),
+		lock:                 &sync.Mutex{},
+		backend:              newFinderSessionBackend(sessionFinder),
+		sessionFinder:        sessionFinder,
+
+		lastSessionKeys:        make(map[string]bool),
+		sessionChangeConsumers: []chan SessionChangeEvent{},
+		volumeChangeConsumers:  []chan VolumeChangeEvent{},
 	}
 
 	logger.Debug("Created session map instance")
@@ -81,12 +207,14 @@ func (m *sessionMap) initialize() error {
 
 	m.setupOnConfigReload()
 	m.setupOnSliderMove()
+	m.setupOnDefaultDeviceChange()
+	m.setupOnButtonPress()
 
 	return nil
 }
 
 func (m *sessionMap) release() error {
-	if err := m.sessionFinder.Release(); err != nil {
+	if err := m.backend.Release(); err != nil {
 		m.logger.Warnw("Failed to release session finder during session map release", "error", err)
 		return fmt.Errorf("release session finder during release: %w", err)
 	}
@@ -100,50 +228,249 @@ func (m *sessionMap) getAndAddSessions() error {
 
 	// mark that we're refreshing before anything else
 	m.lastSessionRefresh = time.Now()
-	m.unmappedSessions = nil
 
-	sessions, err := m.sessionFinder.GetAllSessions()
+	sessions, err := m.backend.ListSessions()
+
+	m.lock.Lock()
+	m.lastRefreshErr = err
+	m.sessionNames = m.deej.config.SessionNames
+	m.lock.Unlock()
+
 	if err != nil {
 		m.logger.Warnw("Failed to get sessions from session finder", "error", err)
 		return fmt.Errorf("get sessions from SessionFinder: %w", err)
 	}
 
 	for _, session := range sessions {
+		m.normalizeSession(session)
 		m.add(session)
 
-		if !m.sessionMapped(session) {
-			m.logger.Debugw("Tracking unmapped session", "session", session)
-			m.unmappedSessions = append(m.unmappedSessions, session)
+		// remember the state we found each session in, so it can be reported without re-querying the OS
+		if _, ok := m.getState(session.Key()); !ok {
+			m.setState(session.Key(), sessionState{volume: m.backend.GetVolume(session), muted: m.backend.GetMute(session)})
 		}
 	}
 
+	m.recomputeUnmappedSessions()
+
+	// new sessions start out centered
+	m.applyBalances()
+
 	m.logger.Infow("Got all audio sessions successfully", "sessionMap", m)
 
+	m.notifySessionChanges()
+
 	return nil
 }
 
+// SubscribeToSessionChanges returns an unbuffered channel that receives a SessionChangeEvent
+// whenever a session refresh finds that sessions were added or removed
+func (m *sessionMap) SubscribeToSessionChanges() chan SessionChangeEvent {
+	ch := make(chan SessionChangeEvent)
+	m.sessionChangeConsumers = append(m.sessionChangeConsumers, ch)
+
+	return ch
+}
+
+// SubscribeToVolumeChanges returns an unbuffered channel that receives a VolumeChangeEvent
+// whenever the known volume or mute state of a session key changes
+func (m *sessionMap) SubscribeToVolumeChanges() chan VolumeChangeEvent {
+	ch := make(chan VolumeChangeEvent)
+	m.volumeChangeConsumers = append(m.volumeChangeConsumers, ch)
+
+	return ch
+}
+
+func (m *sessionMap) notifySessionChanges() {
+	m.lock.Lock()
+
+	event := SessionChangeEvent{}
+	currentKeys := make(map[string]bool, len(m.m))
+
+	// on the first refresh every session is new, but none of them were just launched
+	firstRefresh := len(m.lastSessionKeys) == 0
+
+	for key := range m.m {
+		currentKeys[key] = true
+
+		if !m.lastSessionKeys[key] {
+			event.Added = append(event.Added, key)
+		}
+	}
+
+	for key := range m.lastSessionKeys {
+		if !currentKeys[key] {
+			event.Removed = append(event.Removed, key)
+		}
+	}
+
+	m.lastSessionKeys = currentKeys
+	m.lock.Unlock()
+
+	if len(event.Added) == 0 && len(event.Removed) == 0 {
+		return
+	}
+
+	if !firstRefresh {
+		m.queueLaunchVolumes(event.Added)
+	}
+
+	m.logger.Debugw("Sessions changed", "added", event.Added, "removed", event.Removed)
+
+	for _, consumer := range m.sessionChangeConsumers {
+		consumer <- event
+	}
+}
+
 func (m *sessionMap) setupOnConfigReload() {
 	configReloadedChannel := m.deej.config.SubscribeToChanges()
 
 	go func() {
+
+		// sessions are re-acquired every session_refresh_interval, so apps that were started since show up
+		// (and ones that were closed go away). a cleaner way to do this down the line is by registering to
+		// notifications whenever a new session is added, but go-wca's binding for that isn't usable as-is
+		refreshTimer := time.NewTimer(m.untilNextRefresh())
+
 		for {
 			select {
 			case <-configReloadedChannel:
 				m.logger.Info("Detected config reload, attempting to re-acquire all audio sessions")
-				m.refreshSessions(false)
+
+				// sessions are only keyed anew when they're re-acquired, so new session_names can't wait for it
+				m.lock.Lock()
+				renamed := m.sessionNames != m.deej.config.SessionNames
+				m.lock.Unlock()
+
+				m.refreshSessions(renamed)
+
+				// the refresh could've been skipped due to its cooldown, but the slider mapping did change
+				m.recomputeUnmappedSessions()
+
+				// and so could the balances
+				m.applyBalances()
+
+				// so could the interval
+				resetTimer(refreshTimer, m.untilNextRefresh())
+
+			case <-refreshTimer.C:
+
+				// a refresh for any other reason in the meantime pushes the next one back
+				if m.untilNextRefresh() <= 0 {
+					m.logger.Debug("Session refresh interval elapsed, refreshing")
+					m.refreshSessions(true)
+				}
+
+				refreshTimer.Reset(m.untilNextRefresh())
 			}
 		}
 	}()
 }
 
+// untilNextRefresh returns how long until the sessions are due to be re-acquired, which is 0 or less if they're due
+func (m *sessionMap) untilNextRefresh() time.Duration {
+	return time.Until(m.lastSessionRefresh.Add(m.deej.config.SessionRefreshInterval))
+}
+
+// resetTimer re-arms a timer that may or may not have fired (and been received from) already
+func resetTimer(timer *time.Timer, duration time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+
+	timer.Reset(duration)
+}
+
 func (m *sessionMap) setupOnSliderMove() {
 	sliderEventsChannel := m.deej.serial.SubscribeToSliderMoveEvents()
 
 	go func() {
+
+		// only set while a smoothed slider is ramping. it's never reset by other events,
+		// so a slider that keeps moving can't hold the ramp back
+		var smoothingStep <-chan time.Time
+
+		// likewise, only set while volumes are being held back by volume_apply_max_rate
+		var pendingApply <-chan time.Time
+
+		// reading volumes back happens here too, so it can't race a slider setting them
+		externalVolumeCheck := time.NewTimer(m.deej.config.ExternalVolume.Interval)
+
 		for {
 			select {
 			case event := <-sliderEventsChannel:
 				m.handleSliderMoveEvent(event)
+			case command := <-m.targetVolumeCommands:
+				m.handleTargetVolumeCommand(command)
+			case <-smoothingStep:
+				smoothingStep = nil
+				m.stepSmoothing()
+			case <-m.launchedSignal:
+				m.applyLaunchVolumes()
+			case <-m.resumeRequests:
+				m.applyCurrentSliderPositions()
+			case done := <-m.resetRequests:
+				done <- m.resetVolumes()
+			case <-pendingApply:
+				pendingApply = nil
+				m.applyPendingVolumes()
+			case <-externalVolumeCheck.C:
+				m.checkExternalVolumes()
+				externalVolumeCheck.Reset(m.deej.config.ExternalVolume.Interval)
+			case done := <-m.restoreRequests:
+				m.applyVolumeSnapshots()
+				close(done)
+
+				// deej is exiting, and anything applied from here on would undo the restore
+				return
+			}
+
+			if smoothingStep == nil && m.smoothingActive() {
+				smoothingStep = time.After(smoothingStepInterval)
+			}
+
+			if pendingApply == nil {
+				if due, ok := m.untilNextPendingApply(); ok {
+					pendingApply = time.After(due)
+				}
+			}
+		}
+	}()
+}
+
+func (m *sessionMap) setupOnButtonPress() {
+	buttonEventsChannel := m.deej.serial.SubscribeToButtonPressEvents()
+
+	go func() {
+		for {
+			select {
+			case event := <-buttonEventsChannel:
+				m.handleButtonPressEvent(event)
+			}
+		}
+	}()
+}
+
+func (m *sessionMap) setupOnDefaultDeviceChange() {
+	notifier, ok := m.sessionFinder.(defaultDeviceChangeNotifier)
+	if !ok {
+		return
+	}
+
+	defaultDeviceChangedChannel := notifier.SubscribeToDefaultDeviceChanges()
+
+	go func() {
+		for {
+			select {
+			case <-defaultDeviceChangedChannel:
+				m.logger.Info("Detected default audio device change, re-acquiring all audio sessions")
+
+				// performance: the master and mic sessions we hold belong to the previous default devices,
+				// so they're of no use anymore. this only happens when the user changes their devices
+				m.refreshSessions(true)
 			}
 		}
 	}()
@@ -167,96 +494,195 @@ func (m *sessionMap) refreshSessions(force bool) {
 	}
 }
 
+// recomputeUnmappedSessions works out which sessions aren't mapped to any slider.
+// this has to happen whenever either the sessions themselves or the slider mapping change
+func (m *sessionMap) recomputeUnmappedSessions() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	unmappedSessions := []Session{}
+
+	for _, sessions := range m.m {
+		for _, session := range sessions {
+			if !m.sessionMapped(session) {
+				m.logger.Debugw("Tracking unmapped session", "session", session)
+				unmappedSessions = append(unmappedSessions, session)
+			}
+		}
+	}
+
+	m.unmappedSessions = unmappedSessions
+}
+
 // returns true if a session is not currently mapped to any slider, false otherwise
 // special sessions (master, system, mic) and device-specific sessions always count as mapped,
 // even when absent from the config. this makes sense for every current feature that uses "unmapped sessions"
 func (m *sessionMap) sessionMapped(session Session) bool {
 
-	// count master/system/mic as mapped
-	if funk.ContainsString([]string{masterSessionName, systemSessionName, inputSessionName}, session.Key()) {
-		return true
-	}
-
-	// count device sessions as mapped
-	if deviceSessionKeyPattern.MatchString(session.Key()) {
+	// count master/system/mic and device sessions as mapped
+	if sessionTargetableByName(session) {
 		return true
 	}
 
 	matchFound := false
 
 	// look through the actual mappings
-	m.deej.config.SliderMapping.iterate(func(sliderIdx int, targets []string) {
-		for _, target := range targets {
+	m.deej.config.sliderMapping().iterate(func(sliderIdx int, targets []string) {
+		matchFound = matchFound || m.targetsMatchSession(targets, session)
+	})
 
-			// ignore special transforms
-			if m.targetHasSpecialTransform(target) {
-				continue
-			}
+	// and the slider groups, whose targets are just as mapped
+	for _, group := range m.deej.config.SliderGroups {
+		matchFound = matchFound || m.targetsMatchSession(group.Targets, session)
+	}
 
-			// safe to assume this has a single element because we made sure there's no special transform
-			target = m.resolveTarget(target)[0]
+	return matchFound
+}
 
-			if target == session.Key() {
-				matchFound = true
-				return
+// targetsMatchSession returns true if any of the given (unresolved) targets maps the session
+func (m *sessionMap) targetsMatchSession(targets []string, session Session) bool {
+	for _, target := range targets {
+
+		// ignore special transforms
+		if m.targetHasSpecialTransform(target) {
+			continue
+		}
+
+		// a session matching a pattern is mapped, even if precedence has another slider control it
+		if isTargetPattern(target) {
+			if pattern := m.getTargetPattern(target); pattern != nil && pattern.MatchString(session.Key()) {
+				return true
 			}
+
+			continue
 		}
-	})
 
-	return matchFound
+		// this is called with the lock held, so it can't resolve the target like everything else does. it doesn't
+		// have to either: special and device sessions never get here, and every other one is an app
+		name := strings.ToLower(targetName(target))
+
+		if name == session.Key() || m.deej.config.SessionNames.normalize(name) == session.Key() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sessionTargetableByName returns true for special (master, system, mic) and device sessions,
+// which can only be targeted by their exact name
+func sessionTargetableByName(session Session) bool {
+	if funk.ContainsString([]string{masterSessionName, systemSessionName, inputSessionName}, session.Key()) {
+		return true
+	}
+
+	// devices are master sessions of their own. their names don't look any different from a process's on linux.
+	// this doesn't go by the platform's session type, since a macOS build without cgo has none
+	named, ok := session.(namedDeviceSession)
+	if !ok {
+		return false
+	}
+
+	_, device := named.deviceName()
+
+	return device
 }
 
 func (m *sessionMap) handleSliderMoveEvent(event SliderMoveEvent) {
 
-	// first of all, ensure our session map isn't moldy
-	if m.lastSessionRefresh.Add(maxTimeBetweenSessionRefreshes).Before(time.Now()) {
-		m.logger.Debug("Stale session map detected on slider move, refreshing")
-		m.refreshSessions(true)
+	// the move is still picked up by everything else, it just doesn't change any volumes
+	if m.Paused() {
+		return
 	}
 
-	// get the targets mapped to this slider from the config
-	targets, ok := m.deej.config.SliderMapping.get(event.SliderID)
+	smoothing := m.deej.config.SliderSmoothing(event.SliderID)
 
-	// if slider not found in config, silently ignore
+	smoother, ok := m.smoothers[event.SliderID]
 	if !ok {
+		smoother = &sliderSmoother{}
+		m.smoothers[event.SliderID] = smoother
+	}
+
+	smoother.target = event.PercentValue
+
+	// this is the actual move, smoothing steps that follow it don't count as touching the slider again
+	for _, group := range m.sliderGroups(event.SliderID) {
+		m.lastGroupSliderMove[group.Name] = event.SliderID
+	}
+
+	// the first position a slider reports is applied right away, there's nothing to ramp from yet.
+	// otherwise, smoothed sliders get there one step at a time (see stepSmoothing)
+	if !ok || smoothing.Type == smoothingNone {
+		smoother.position = event.PercentValue
+		m.applySliderPosition(event.SliderID, event.PercentValue)
 		return
 	}
 
-	targetFound := false
-	adjustmentFailed := false
+	// it was moved back to where the ramp had already gotten to
+	if !smoother.ramping() {
+		smoother.lastStep = time.Time{}
+		return
+	}
 
-	// for each possible target for this slider...
-	for _, target := range targets {
+	// a slider that was already ramping keeps its pace, one that wasn't starts counting from now
+	if smoother.lastStep.IsZero() {
+		smoother.lastStep = time.Now()
+	}
+}
 
-		// resolve the target name by cleaning it up and applying any special transformations.
-		// depending on the transformation applied, this can result in more than one target name
-		resolvedTargets := m.resolveTarget(target)
+// stepSmoothing moves every smoothed slider that hasn't reached its final position yet a bit closer to it
+func (m *sessionMap) stepSmoothing() {
+	now := time.Now()
 
-		// for each resolved target...
-		for _, resolvedTarget := range resolvedTargets {
+	for sliderID, smoother := range m.smoothers {
+		if !smoother.ramping() {
+			continue
+		}
 
-			// check the map for matching sessions
-			sessions, ok := m.get(resolvedTarget)
+		smoother.position = m.deej.config.SliderSmoothing(sliderID).step(
+			smoother.position,
+			smoother.target,
+			now.Sub(smoother.lastStep))
 
-			// no sessions matching this target - move on
-			if !ok {
-				continue
-			}
+		smoother.lastStep = now
 
-			targetFound = true
+		if !smoother.ramping() {
+			smoother.lastStep = time.Time{}
+		}
 
-			// iterate all matching sessions and adjust the volume of each one
-			for _, session := range sessions {
-				if session.GetVolume() != event.PercentValue {
-					if err := session.SetVolume(event.PercentValue); err != nil {
-						m.logger.Warnw("Failed to set target session volume", "error", err)
-						adjustmentFailed = true
-					}
-				}
-			}
+		m.applySliderPosition(sliderID, smoother.position)
+	}
+}
+
+// smoothingActive returns whether any slider is still ramping towards its final position
+func (m *sessionMap) smoothingActive() bool {
+	for _, smoother := range m.smoothers {
+		if smoother.ramping() {
+			return true
 		}
 	}
 
+	return false
+}
+
+// applySliderPosition sets the volume of every target mapped to a slider, according to the slider's position
+func (m *sessionMap) applySliderPosition(sliderID int, position float32) {
+
+	// first of all, ensure our session map isn't moldy. the periodic refresh normally takes care of this,
+	// but an app that was just started shouldn't have to wait for it if someone's moving its slider
+	if m.untilNextRefresh() <= 0 {
+		m.logger.Debug("Stale session map detected on slider move, refreshing")
+		m.refreshSessions(true)
+	}
+
+	// if slider not found in config, silently ignore
+	if !m.sliderMapped(sliderID) {
+		return
+	}
+
+	// a slider can reach the same session through more than one of its targets, but should only adjust it once
+	targetFound, adjustmentFailed := m.applySliderTargets(sliderID, position, make(map[string]bool))
+
 	// if we still haven't found a target or the volume adjustment failed, maybe look for the target again.
 	// processes could've opened since the last time this slider moved.
 	// if they haven't, the cooldown will take care to not spam it up
@@ -271,81 +697,616 @@ func (m *sessionMap) handleSliderMoveEvent(event SliderMoveEvent) {
 	}
 }
 
-func (m *sessionMap) targetHasSpecialTransform(target string) bool {
-	return strings.HasPrefix(target, specialTargetTransformPrefix)
-}
+// sliderMapped returns whether a slider has any targets, either of its own or through a group
+func (m *sessionMap) sliderMapped(sliderID int) bool {
+	_, ok := m.deej.config.sliderMapping().get(sliderID)
 
-func (m *sessionMap) resolveTarget(target string) []string {
+	return ok || len(m.sliderGroups(sliderID)) > 0
+}
 
-	// start by ignoring the case
-	target = strings.ToLower(target)
+// applySliderTargets sets the volume of a slider's targets (and its groups'), skipping any resolved target
+// that's already in adjustedTargets. it returns whether any target had sessions, and whether adjusting any failed
+func (m *sessionMap) applySliderTargets(sliderID int, position float32, adjustedTargets map[string]bool) (bool, bool) {
 
-	// look for any special targets first, by examining the prefix
-	if m.targetHasSpecialTransform(target) {
-		return m.applyTargetTransform(strings.TrimPrefix(target, specialTargetTransformPrefix))
-	}
+	// get the targets mapped to this slider from the config, and any groups it's in
+	targets, _ := m.deej.config.sliderMapping().get(sliderID)
+	groups := m.sliderGroups(sliderID)
 
-	return []string{target}
-}
+	// a session that's reached from elsewhere too is only adjusted by whichever controls it, see target_precedence.go
+	controllers := m.resolveControllers()
 
-func (m *sessionMap) applyTargetTransform(specialTargetName string) []string {
+	targetFound := false
+	adjustmentFailed := false
 
-	// select the transformation based on its name
-	switch specialTargetName {
+	// the slider's position isn't necessarily the volume it stands for
+	volume := m.deej.config.SliderVolumeCurve(sliderID).apply(position)
 
-	// get current active window
-	case specialTargetCurrentWindow:
-		currentWindowProcessNames, err := util.GetCurrentWindowProcessNames()
+	// for each possible target for this slider...
+	for _, target := range targets {
 
-		// silently ignore errors here, as this is on deej's "hot path" (and it could just mean the user's running linux)
-		if err != nil {
-			return nil
+		// targets that are also in one of the slider's groups are left to the group
+		if sliderGroupsHaveTarget(groups, target) {
+			continue
 		}
 
-		// we could have gotten a non-lowercase names from that, so let's ensure we return ones that are lowercase
-		for targetIdx, target := range currentWindowProcessNames {
-			currentWindowProcessNames[targetIdx] = strings.ToLower(target)
-		}
+		// the target was already resolved (with any special transformations applied) along with every other one.
+		// depending on the transformation, this can be more than one session key
+		resolvedTargets := controllers.sliderKeys(sliderID, target)
 
-		// remove dupes
-		return funk.UniqString(currentWindowProcessNames)
+		// weighted targets get their share of the volume, before any limit is applied to it
+		_, weight, _ := splitTargetWeight(target)
 
-	// get currently unmapped sessions
-	case specialTargetAllUnmapped:
-		targetKeys := make([]string, len(m.unmappedSessions))
-		for sessionIdx, session := range m.unmappedSessions {
-			targetKeys[sessionIdx] = session.Key()
-		}
+		found, failed := m.applyResolvedTargets(resolvedTargets, weighted(volume, weight), position, adjustedTargets)
+		targetFound = targetFound || found
+		adjustmentFailed = adjustmentFailed || failed
+	}
 
-		return targetKeys
+	for _, group := range groups {
+		found, failed := m.applySliderGroup(group, controllers, adjustedTargets)
+		targetFound = targetFound || found
+		adjustmentFailed = adjustmentFailed || failed
 	}
 
-	return nil
+	return targetFound, adjustmentFailed
 }
 
-func (m *sessionMap) add(value Session) {
-	m.lock.Lock()
-	defer m.lock.Unlock()
-
-	key := value.Key()
+// applySliderGroup sets the volume of a group's targets, combined from the positions of all its sliders.
+// sliders that haven't reported a position yet are left out, and if none have, nothing changes
+func (m *sessionMap) applySliderGroup(
+	group SliderGroup,
+	controllers sessionControllers,
+	adjustedTargets map[string]bool,
+) (bool, bool) {
+	positions := make(map[int]float32, len(group.Sliders))
+
+	for _, sliderIdx := range group.Sliders {
+		if smoother, ok := m.smoothers[sliderIdx]; ok {
+			positions[sliderIdx] = smoother.position
+		}
+	}
 
-	existing, ok := m.m[key]
+	position, ok := group.combine(positions, m.lastGroupSliderMove[group.Name])
 	if !ok {
-		m.m[key] = []Session{value}
-	} else {
-		m.m[key] = append(existing, value)
+		return true, false
 	}
-}
 
-func (m *sessionMap) get(key string) ([]Session, bool) {
-	m.lock.Lock()
-	defer m.lock.Unlock()
+	// the group has no curve of its own, it goes by its first slider's
+	volume := m.deej.config.SliderVolumeCurve(group.Sliders[0]).apply(position)
 
-	value, ok := m.m[key]
-	return value, ok
-}
+	targetFound := false
+	adjustmentFailed := false
 
-func (m *sessionMap) clear() {
+	for _, target := range group.Targets {
+		_, weight, _ := splitTargetWeight(target)
+
+		found, failed := m.applyResolvedTargets(controllers.groupKeys(group.Name, target), weighted(volume, weight), position,
+			adjustedTargets)
+		targetFound = targetFound || found
+		adjustmentFailed = adjustmentFailed || failed
+	}
+
+	return targetFound, adjustmentFailed
+}
+
+// applyResolvedTargets sets the volume of every resolved target that wasn't already adjusted for the same move
+func (m *sessionMap) applyResolvedTargets(
+	resolvedTargets []string,
+	volume float32,
+	position float32,
+	adjustedTargets map[string]bool,
+) (bool, bool) {
+	targetFound := false
+	adjustmentFailed := false
+
+	for _, resolvedTarget := range resolvedTargets {
+		if adjustedTargets[resolvedTarget] {
+			continue
+		}
+
+		adjustedTargets[resolvedTarget] = true
+
+		// a volume that was changed from outside of deej can stay that way until the slider moves far enough
+		if m.externalVolumeHeld(resolvedTarget, position) {
+			targetFound = true
+			continue
+		}
+
+		m.lastSliderMove[resolvedTarget] = time.Now()
+		m.appliedPositions[resolvedTarget] = position
+
+		// a limited target gets the slider's whole travel stretched over its range. mute at zero still goes
+		// by the slider's position, so with it enabled, the bottom of the travel mutes rather than sits at min
+		targetVolume := volume
+		if limit, ok := m.deej.config.volumeLimit(resolvedTarget); ok {
+			targetVolume = limit.scale(volume)
+		}
+
+		// too soon after the last time, this volume gets applied once it's been long enough (unless another replaces it)
+		if m.throttleApply(resolvedTarget, targetVolume, position) {
+			_, found := m.get(resolvedTarget)
+			targetFound = targetFound || found
+			continue
+		}
+
+		found, failed := m.applyVolume(resolvedTarget, targetVolume, position)
+		targetFound = targetFound || found
+		adjustmentFailed = adjustmentFailed || failed
+	}
+
+	return targetFound, adjustmentFailed
+}
+
+// sliderGroups returns the groups the given slider is part of
+func (m *sessionMap) sliderGroups(sliderID int) []SliderGroup {
+	groups := []SliderGroup{}
+
+	for _, group := range m.deej.config.SliderGroups {
+		if group.has(sliderID) {
+			groups = append(groups, group)
+		}
+	}
+
+	return groups
+}
+
+func sliderGroupsHaveTarget(groups []SliderGroup, target string) bool {
+	for _, group := range groups {
+		if group.hasTarget(target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// handleTargetVolumeCommand applies a volume change that didn't come from a slider, unless a slider
+// adjusted the same sessions within the command's lockout - the user's hand on the hardware always wins
+func (m *sessionMap) handleTargetVolumeCommand(command targetVolumeCommand) {
+	targetFound := false
+	adjustmentFailed := false
+
+	for _, resolvedTarget := range m.resolveTarget(command.target) {
+		if time.Since(m.lastSliderMove[resolvedTarget]) < command.lockout {
+			m.logger.Debugw("Ignoring volume command for recently moved target", "target", resolvedTarget)
+
+			// the sessions are there, they're just not ours to change right now
+			targetFound = true
+			continue
+		}
+
+		// this already is the volume that was asked for, so a limit only keeps it within range
+		volume := command.volume
+		if limit, ok := m.deej.config.volumeLimit(resolvedTarget); ok {
+			volume = limit.clamp(volume)
+		}
+
+		// there's no slider position behind this volume, so mute at zero goes by the requested volume itself
+		found, failed := m.applyVolume(resolvedTarget, volume, command.volume)
+		targetFound = targetFound || found
+		adjustmentFailed = adjustmentFailed || failed
+	}
+
+	if !targetFound {
+		m.refreshSessions(false)
+	} else if adjustmentFailed {
+
+		// performance: same as with slider moves, this only happens when a SetVolume call errored
+		m.refreshSessions(true)
+	}
+}
+
+// applyVolume sets the volume of all sessions under a resolved target, muting them at zero if enabled.
+// position is the slider position the volume came from, which is what mute at zero goes by.
+// it returns whether the target had any sessions, and whether adjusting any of them failed
+func (m *sessionMap) applyVolume(resolvedTarget string, volume float32, position float32) (bool, bool) {
+
+	// check the map for matching sessions
+	sessions, ok := m.get(resolvedTarget)
+
+	// no sessions matching this target - move on
+	if !ok {
+		return false, false
+	}
+
+	adjustmentFailed := false
+
+	// iterate all matching sessions and adjust the volume of each one
+	for _, session := range sessions {
+
+		// there's nothing to do for these, and they'd only throw off the target's state
+		if !session.Controllable() {
+			continue
+		}
+
+		m.snapshotSession(resolvedTarget, session)
+
+		if m.backend.GetVolume(session) != volume {
+			if err := m.setSessionVolume(session, volume); err != nil {
+				if !sessionCallAbandoned(err) {
+					m.logger.Warnw("Failed to set target session volume", "error", err)
+					adjustmentFailed = true
+				}
+
+				continue
+			}
+		}
+
+		state, _ := m.getState(resolvedTarget)
+		state.volume = volume
+
+		if m.deej.config.MuteAtZero {
+			if err := m.applyMuteAtZero(resolvedTarget, session, position); err != nil {
+				if !sessionCallAbandoned(err) {
+					m.logger.Warnw("Failed to set target session mute state", "error", err)
+					adjustmentFailed = true
+				}
+
+				continue
+			}
+
+			state.muted = m.backend.GetMute(session)
+		}
+
+		m.setState(resolvedTarget, state)
+	}
+
+	// every session under this key had its chance to be unmuted by now
+	if position >= muteAtZeroEpsilon {
+		delete(m.mutedAtZero, resolvedTarget)
+	}
+
+	// whatever it was changed to from outside of deej, it's what deej set it to now
+	m.forgetExternalVolume(resolvedTarget)
+
+	return true, adjustmentFailed
+}
+
+// SetTargetVolume queues a volume change for a (possibly special) target, to be applied just like a slider move.
+// it's ignored for any sessions a slider adjusted within the given lockout
+func (m *sessionMap) SetTargetVolume(target string, volume float32, lockout time.Duration) {
+	m.targetVolumeCommands <- targetVolumeCommand{
+		target:  target,
+		volume:  volume,
+		lockout: lockout,
+	}
+}
+
+// handleButtonPressEvent toggles the mute state of every target mapped to the pressed button. a long press
+// resets them to the long press volume instead, unmuted
+func (m *sessionMap) handleButtonPressEvent(event ButtonPressEvent) {
+	targets, ok := m.deej.config.ButtonMapping.get(event.ButtonID)
+
+	// if button not found in config, silently ignore
+	if !ok {
+		return
+	}
+
+	for _, target := range targets {
+		if event.LongPress {
+			m.resetTargetVolume(target)
+			continue
+		}
+
+		found, err := m.ToggleTargetMute(target)
+		if err != nil {
+			m.logger.Warnw("Failed to toggle target mute state", "target", target, "error", err)
+			continue
+		}
+
+		// processes could've opened since the last refresh, the cooldown will keep this from spamming
+		if !found {
+			m.refreshSessions(false)
+		}
+	}
+}
+
+// resetTargetVolume sets a target to the long press volume, and unmutes it
+func (m *sessionMap) resetTargetVolume(target string) {
+	m.SetTargetVolume(target, m.deej.config.Buttons.LongPressVolume, 0)
+
+	found, err := m.SetTargetMute(target, false)
+	if err != nil {
+		m.logger.Warnw("Failed to unmute target", "target", target, "error", err)
+		return
+	}
+
+	if !found {
+		m.refreshSessions(false)
+	}
+}
+
+// ToggleTargetMute flips the mute state of a (possibly special) target, based on the state of its first session.
+// it returns false if the target doesn't currently have any sessions
+func (m *sessionMap) ToggleTargetMute(target string) (bool, error) {
+	for _, resolvedTarget := range m.resolveTarget(target) {
+		sessions, ok := m.get(resolvedTarget)
+		if !ok || len(sessions) == 0 {
+			continue
+		}
+
+		// every session of the target ends up in the same state, even if they didn't start out that way
+		return m.SetTargetMute(target, !m.backend.GetMute(sessions[0]))
+	}
+
+	return false, nil
+}
+
+// SetTargetMute mutes or unmutes all sessions that a (possibly special) target resolves to.
+// it returns false if the target doesn't currently have any sessions
+func (m *sessionMap) SetTargetMute(target string, muted bool) (bool, error) {
+	found := false
+
+	for _, resolvedTarget := range m.resolveTarget(target) {
+		sessions, ok := m.get(resolvedTarget)
+		if !ok {
+			continue
+		}
+
+		found = true
+
+		for _, session := range sessions {
+			if !session.Controllable() {
+				continue
+			}
+
+			m.snapshotSession(resolvedTarget, session)
+
+			if err := m.setSessionMute(session, muted); err != nil {
+				if sessionCallAbandoned(err) {
+					return true, fmt.Errorf("set mute state for %s: %w", resolvedTarget, err)
+				}
+
+				m.logger.Warnw("Failed to set target session mute state", "target", resolvedTarget, "error", err)
+
+				// performance: this mostly fails for a stale master session, and will keep failing until we refresh
+				m.refreshSessions(true)
+				return true, fmt.Errorf("set mute state for %s: %w", resolvedTarget, err)
+			}
+		}
+
+		state, _ := m.getState(resolvedTarget)
+		state.muted = muted
+		m.setState(resolvedTarget, state)
+	}
+
+	return found, nil
+}
+
+// GetPlaybackDevices lists the active playback devices, marking the current default one
+func (m *sessionMap) GetPlaybackDevices() ([]AudioDevice, error) {
+	manager, ok := m.sessionFinder.(playbackDeviceManager)
+	if !ok {
+		return nil, errPlaybackDevicesUnsupported
+	}
+
+	return manager.GetPlaybackDevices()
+}
+
+// SetDefaultPlaybackDevice makes the playback device with the given ID the OS default,
+// and re-acquires all sessions so that master follows it right away
+func (m *sessionMap) SetDefaultPlaybackDevice(id string) error {
+	manager, ok := m.sessionFinder.(playbackDeviceManager)
+	if !ok {
+		return errPlaybackDevicesUnsupported
+	}
+
+	if err := manager.SetDefaultPlaybackDevice(id); err != nil {
+		return err
+	}
+
+	m.logger.Infow("Changed default playback device", "id", id)
+
+	// performance: the OS will tell us about the change too, but not necessarily before the next slider move.
+	// this only happens when the user explicitly switches devices
+	m.refreshSessions(true)
+
+	return nil
+}
+
+// applyMuteAtZero mutes a session when its slider is pulled all the way down, and unmutes it when it comes back up.
+// sessions that were muted by something other than deej are never unmuted
+func (m *sessionMap) applyMuteAtZero(target string, session Session, sliderValue float32) error {
+	if sliderValue < muteAtZeroEpsilon {
+		if !m.backend.GetMute(session) {
+			if err := m.setSessionMute(session, true); err != nil {
+				return fmt.Errorf("mute session: %w", err)
+			}
+
+			m.mutedAtZero[target] = true
+		}
+
+		return nil
+	}
+
+	if m.mutedAtZero[target] && m.backend.GetMute(session) {
+		if err := m.setSessionMute(session, false); err != nil {
+			return fmt.Errorf("unmute session: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (m *sessionMap) targetHasSpecialTransform(target string) bool {
+	return strings.HasPrefix(target, specialTargetTransformPrefix)
+}
+
+func (m *sessionMap) resolveTarget(target string) []string {
+
+	// a target's weight only matters for its volume, see target_weight.go
+	target = targetName(target)
+
+	// patterns match case-insensitively on their own, and lowercasing a regex could change its meaning
+	if isTargetPattern(target) {
+		return m.matchTargetPattern(target)
+	}
+
+	// start by ignoring the case
+	target = strings.ToLower(target)
+
+	// look for any special targets first, by examining the prefix
+	if m.targetHasSpecialTransform(target) {
+		return m.applyTargetTransform(strings.TrimPrefix(target, specialTargetTransformPrefix))
+	}
+
+	// master, mic and system are whatever the backend says they are
+	switch target {
+	case masterSessionName, inputSessionName, systemSessionName:
+		keys, err := m.backend.ResolveSpecialTarget(target)
+		if err != nil {
+			return nil
+		}
+
+		return keys
+	}
+
+	// an app's name can be spelled in more than one way, see session_names.go
+	return []string{m.normalizeTarget(target)}
+}
+
+func (m *sessionMap) applyTargetTransform(specialTargetName string) []string {
+
+	// select the transformation based on its name
+	switch specialTargetName {
+
+	// get current active window
+	case specialTargetCurrentWindow:
+		if !m.backend.SpecialTargetSupported(specialTargetTransformPrefix + specialTargetCurrentWindow) {
+			return nil
+		}
+
+		currentWindowProcessNames, err := m.backend.ResolveSpecialTarget(specialTargetTransformPrefix + specialTargetCurrentWindow)
+
+		// silently ignore errors here, as this is on deej's "hot path"
+		if err != nil {
+			return nil
+		}
+
+		// we could have gotten a non-lowercase names from that, so let's ensure we return ones that are lowercase
+		// (and otherwise keyed like their sessions are)
+		for targetIdx, target := range currentWindowProcessNames {
+			currentWindowProcessNames[targetIdx] = m.deej.config.SessionNames.normalize(target)
+		}
+
+		// remove dupes
+		return m.resolveCurrentWindowTargets(funk.UniqString(currentWindowProcessNames))
+
+	// get currently unmapped sessions
+	case specialTargetAllUnmapped:
+		m.lock.Lock()
+		defer m.lock.Unlock()
+
+		targetKeys := make([]string, len(m.unmappedSessions))
+		for sessionIdx, session := range m.unmappedSessions {
+			targetKeys[sessionIdx] = session.Key()
+		}
+
+		// a process can have more than one session, but they all share a key
+		return funk.UniqString(targetKeys)
+	}
+
+	return nil
+}
+
+// resolveCurrentWindowTargets decides what deej.current controls, given the current window's process names.
+// when none of them has an audio session, it either controls nothing or sticks with the last window that had one
+func (m *sessionMap) resolveCurrentWindowTargets(processNames []string) []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	withSessions := []string{}
+	for _, processName := range processNames {
+		if _, ok := m.m[processName]; ok {
+			withSessions = append(withSessions, processName)
+		}
+	}
+
+	if len(withSessions) > 0 {
+		m.lastCurrentWindowTargets = withSessions
+		return withSessions
+	}
+
+	if m.deej.config.CurrentWindowFallback == currentWindowFallbackLast {
+		return m.lastCurrentWindowTargets
+	}
+
+	return processNames
+}
+
+// CurrentWindowTargets returns the session keys deej.current would control right now
+func (m *sessionMap) CurrentWindowTargets() []string {
+	targets := m.resolveTarget(specialTargetTransformPrefix + specialTargetCurrentWindow)
+
+	if targets == nil {
+		return []string{}
+	}
+
+	return targets
+}
+
+func (m *sessionMap) add(value Session) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	key := value.Key()
+
+	existing, ok := m.m[key]
+	if !ok {
+		m.m[key] = []Session{value}
+	} else {
+		m.m[key] = append(existing, value)
+	}
+}
+
+func (m *sessionMap) get(key string) ([]Session, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	value, ok := m.m[key]
+	return value, ok
+}
+
+func (m *sessionMap) getState(key string) (sessionState, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	state, ok := m.states[key]
+	return state, ok
+}
+
+func (m *sessionMap) setState(key string, state sessionState) {
+	m.lock.Lock()
+
+	previous, ok := m.states[key]
+	m.states[key] = state
+
+	m.lock.Unlock()
+
+	if ok && previous == state {
+		return
+	}
+
+	event := VolumeChangeEvent{Key: key, Volume: state.volume, Muted: state.muted}
+
+	for _, consumer := range m.volumeChangeConsumers {
+		consumer <- event
+	}
+}
+
+// getTargetState returns the last known state of the sessions matching a (possibly special) target.
+// targets that resolve to more than one session key report the state of the first one found,
+// since deej applies the same value to all of them anyway
+func (m *sessionMap) getTargetState(target string) (sessionState, bool) {
+	for _, resolvedTarget := range m.resolveTarget(target) {
+		if state, ok := m.getState(resolvedTarget); ok {
+			return state, true
+		}
+	}
+
+	return sessionState{}, false
+}
+
+func (m *sessionMap) clear() {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
@@ -357,8 +1318,12 @@ func (m *sessionMap) clear() {
 		}
 
 		delete(m.m, key)
+		delete(m.states, key)
 	}
 
+	// these were all just released
+	m.unmappedSessions = nil
+
 	m.logger.Debug("Session map cleared")
 }
 
@@ -380,10 +1345,62 @@ type SessionInfo struct {
 	Key         string `json:"key"`
 	SessionType string `json:"type"`
 	DisplayName string `json:"displayName"`
+
+	// base64-encoded PNG, if the session has one
+	Icon string `json:"icon,omitempty"`
+
+	// true for sessions that aren't mapped to any slider, which are the ones deej.unmapped controls
+	Unmapped bool `json:"unmapped"`
+
+	// false for sessions the platform lists but doesn't let deej change, i.e. apps on macOS
+	Controllable bool `json:"controllable"`
+
+	// whether the session can be panned with a balance, which needs per-channel volumes
+	BalanceSupported bool `json:"balanceSupported"`
+
+	// a key stands for every session of an app, which is what sliders control - so an app playing from several
+	// processes (i.e. a browser) is listed once, with one instance per session. its state is the most active of
+	// its instances'. special targets have neither
+	State     string            `json:"state,omitempty"`
+	Instances []SessionInstance `json:"instances,omitempty"`
+
+	// the one slider, group or special target that controls it, if any does (see target_precedence.go)
+	Controller *SessionController `json:"controller,omitempty"`
+}
+
+// SessionInstance is one of the sessions behind a key
+type SessionInstance struct {
+
+	// 0 (and left out) for sessions that aren't a process of their own, like master, or when it isn't known
+	PID int `json:"pid,omitempty"`
+
+	// the output device the session plays on, or the device itself for master, mic and device sessions.
+	// left out when the platform doesn't say (i.e. which device master is on macOS)
+	Device string `json:"device,omitempty"`
+
+	// active, inactive (not playing anything right now) or expired
+	State string `json:"state"`
+}
+
+// RefreshStatus returns how many sessions deej currently has, and why the last session refresh failed (if it did)
+func (m *sessionMap) RefreshStatus() (int, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	count := 0
+	for _, sessions := range m.m {
+		count += len(sessions)
+	}
+
+	return count, m.lastRefreshErr
 }
 
 // GetAllSessionKeys returns all current audio sessions for the web UI
 func (m *sessionMap) GetAllSessionKeys() []SessionInfo {
+
+	// resolving targets takes the lock too
+	controllers := m.resolveControllers()
+
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
@@ -400,26 +1417,174 @@ func (m *sessionMap) GetAllSessionKeys() []SessionInfo {
 	}
 
 	for _, special := range specialSessions {
+
+		// system sounds are only a session of their own on windows
+		if special.key == systemSessionName && !m.hasSystemSounds() {
+			continue
+		}
+
+		info := SessionInfo{
+			Key:          special.key,
+			SessionType:  "system",
+			DisplayName:  special.displayName,
+			Controllable: m.keyControllable(special.key),
+
+			BalanceSupported: m.keyCanBalance(special.key),
+		}
+
+		info.State, info.Instances = sessionInstances(m.m[special.key])
+		info.Controller = controllers.get(special.key)
+
+		// until a system sound plays there's no session behind it, but it can still be mapped ahead of time
+		if special.key == systemSessionName && len(info.Instances) == 0 {
+			info.State = sessionStateInactive
+		}
+
+		sessions = append(sessions, info)
+	}
+
+	// special targets aren't sessions of their own, but they're picked just like sessions are
+	sessions = append(sessions, SessionInfo{
+		Key:          specialTargetTransformPrefix + specialTargetAllUnmapped,
+		SessionType:  "special",
+		DisplayName:  "All Unmapped Apps",
+		Controllable: true,
+	})
+
+	// finding the focused window is only supported on windows
+	if m.backend.SpecialTargetSupported(specialTargetTransformPrefix + specialTargetCurrentWindow) {
 		sessions = append(sessions, SessionInfo{
-			Key:         special.key,
-			SessionType: "system",
-			DisplayName: special.displayName,
+			Key:          specialTargetTransformPrefix + specialTargetCurrentWindow,
+			SessionType:  "special",
+			DisplayName:  "Focused App",
+			Controllable: true,
 		})
 	}
 
+	unmappedKeys := make(map[string]bool, len(m.unmappedSessions))
+	for _, session := range m.unmappedSessions {
+		unmappedKeys[session.Key()] = true
+	}
+
 	// Add process sessions from the session map
-	for key := range m.m {
+	for key, keySessions := range m.m {
 		// Skip special sessions we already added
 		if key == masterSessionName || key == systemSessionName || key == inputSessionName {
 			continue
 		}
 
-		sessions = append(sessions, SessionInfo{
-			Key:         key,
-			SessionType: "process",
-			DisplayName: key,
-		})
+		info := SessionInfo{
+			Key:          key,
+			SessionType:  "process",
+			DisplayName:  key,
+			Unmapped:     unmappedKeys[key],
+			Controllable: m.keyControllable(key),
+
+			BalanceSupported: m.keyCanBalance(key),
+		}
+
+		info.State, info.Instances = sessionInstances(keySessions)
+		info.Controller = controllers.get(key)
+
+		// all sessions under a key belong to the same executable, so the first one speaks for them
+		if len(keySessions) > 0 {
+			if displayName := keySessions[0].DisplayName(); displayName != "" {
+				info.DisplayName = displayName
+			}
+
+			if icon := keySessions[0].Icon(); len(icon) > 0 {
+				info.Icon = base64.StdEncoding.EncodeToString(icon)
+			}
+		}
+
+		sessions = append(sessions, info)
 	}
 
 	return sessions
 }
+
+// hasSystemSounds tells whether the backend has a system sounds session, even if it hasn't shown up yet
+func (m *sessionMap) hasSystemSounds() bool {
+	if m.backend.SpecialTargetSupported(systemSessionName) {
+		return true
+	}
+
+	_, ok := m.m[systemSessionName]
+	return ok
+}
+
+// sessionInstances describes every session under a key, sorted by PID, along with the most active of their states
+func sessionInstances(sessions []Session) (string, []SessionInstance) {
+	if len(sessions) == 0 {
+		return "", nil
+	}
+
+	state := sessionStateExpired
+	instances := make([]SessionInstance, 0, len(sessions))
+
+	for _, session := range sessions {
+		instance := SessionInstance{
+			PID:    session.PID(),
+			Device: session.Device(),
+			State:  session.State(),
+		}
+
+		if instance.State == sessionStateActive ||
+			(instance.State == sessionStateInactive && state == sessionStateExpired) {
+			state = instance.State
+		}
+
+		instances = append(instances, instance)
+	}
+
+	sort.SliceStable(instances, func(i, j int) bool {
+		return instances[i].PID < instances[j].PID
+	})
+
+	return state, instances
+}
+
+// keyControllable returns whether any session under a key can be changed. a key without sessions (i.e. mic
+// while there's no input device) isn't known not to be, so it counts as controllable. callers hold the lock
+func (m *sessionMap) keyControllable(key string) bool {
+	sessions, ok := m.m[key]
+	if !ok {
+		return true
+	}
+
+	for _, session := range sessions {
+		if session.Controllable() {
+			return true
+		}
+	}
+
+	return len(sessions) == 0
+}
+
+// UnmatchedTargets returns the targets that don't currently match any session or special target, i.e. typos
+// or apps that aren't running yet. patterns count as matching if any live session matches them
+func (m *sessionMap) UnmatchedTargets(targets []string) []string {
+	known := make(map[string]bool)
+	for _, info := range m.GetAllSessionKeys() {
+		known[info.Key] = true
+	}
+
+	unmatched := []string{}
+
+	for _, target := range targets {
+		if isTargetPattern(target) {
+			if len(m.matchTargetPattern(target)) == 0 {
+				unmatched = append(unmatched, target)
+			}
+
+			continue
+		}
+
+		name := strings.ToLower(targetName(target))
+		if !known[name] && !known[m.normalizeTarget(name)] {
+			unmatched = append(unmatched, target)
+		}
+	}
+
+	return unmatched
+}
@@ -0,0 +1,228 @@
+package deej
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// newTestDeej runs deej on the mock audio backend in a temporary directory, with the given config.yaml. the
+// config is loaded and the sessions are found, but nothing is started
+func newTestDeej(t *testing.T, logger *zap.SugaredLogger, configYAML string) *Deej {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("get working directory: %v", err)
+	}
+
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("change to temporary directory: %v", err)
+	}
+
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	if err := ioutil.WriteFile(userConfigFilepath, []byte(configYAML), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	d, err := newDeej(logger, false, newMockSessionFinder(logger))
+	if err != nil {
+		t.Fatalf("create deej: %v", err)
+	}
+
+	if err := d.config.Load(); err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	// queued edits are written in the temporary directory, before going back to the one the test started in
+	t.Cleanup(func() { d.config.FlushPendingWrites() })
+
+	if err := d.sessions.getAndAddSessions(); err != nil {
+		t.Fatalf("get sessions: %v", err)
+	}
+
+	return d
+}
+
+// mockVolume returns the volume of the first mock session under a key
+func mockVolume(t *testing.T, d *Deej, key string) float32 {
+	t.Helper()
+
+	sessions, ok := d.sessions.get(key)
+	if !ok || len(sessions) == 0 {
+		t.Fatalf("no mock session %q", key)
+	}
+
+	return sessions[0].GetVolume()
+}
+
+// recordingBackend passes everything on to the real backend, noting which sessions had their volume set
+type recordingBackend struct {
+	SessionBackend
+
+	lock       sync.Mutex
+	volumeSets map[string]float32
+}
+
+func (b *recordingBackend) SetVolume(ctx context.Context, session Session, v float32) error {
+	b.lock.Lock()
+	b.volumeSets[session.Key()] = v
+	b.lock.Unlock()
+
+	return b.SessionBackend.SetVolume(ctx, session, v)
+}
+
+func TestResolveTarget(t *testing.T) {
+	d := newTestDeej(t, zap.NewNop().Sugar(), `
+com_port: x
+slider_mapping:
+  0: master
+  1: spotify.exe
+  2: [chrome.exe, discord.exe]
+`)
+
+	tests := []struct {
+		target string
+		want   []string
+	}{
+		{"master", []string{"master"}},
+		{"MIC", []string{"mic"}},
+		{"system", []string{"system"}},
+		{"Spotify.EXE", []string{"spotify.exe"}},
+		{"spotify.exe@0.5", []string{"spotify.exe"}},
+		{"deej.current", []string{mockFocusedProcess}},
+		{"deej.unmapped", []string{"game.exe"}},
+		{"deej.nonsense", nil},
+	}
+
+	for _, test := range tests {
+		got := d.sessions.resolveTarget(test.target)
+		sort.Strings(got)
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("resolveTarget(%q) = %v, want %v", test.target, got, test.want)
+		}
+	}
+}
+
+func TestSpecialTargetsSupported(t *testing.T) {
+	d := newTestDeej(t, zap.NewNop().Sugar(), "com_port: x\n")
+
+	supported := make(map[string]bool)
+	for _, target := range d.sessions.SpecialTargets() {
+		supported[target.Key] = target.Supported
+	}
+
+	tests := []struct {
+		target string
+		want   bool
+	}{
+		{"master", true},
+		{"mic", true},
+		{"system", true},
+		{"deej.unmapped", true},
+		{"deej.current", true},
+	}
+
+	for _, test := range tests {
+		if got, ok := supported[test.target]; !ok || got != test.want {
+			t.Errorf("special target %q supported = %v (listed: %v), want %v", test.target, got, ok, test.want)
+		}
+	}
+}
+
+func TestSliderMoveSetsVolumeThroughBackend(t *testing.T) {
+	d := newTestDeej(t, zap.NewNop().Sugar(), `
+com_port: x
+slider_mapping:
+  0: spotify.exe
+  1: [chrome.exe, deej.current]
+  2: deej.unmapped
+  3: not-running.exe
+`)
+
+	backend := &recordingBackend{SessionBackend: d.sessions.backend, volumeSets: make(map[string]float32)}
+	d.sessions.backend = backend
+
+	tests := []struct {
+		sliderID int
+		position float32
+		want     map[string]float32
+	}{
+		{0, 0.25, map[string]float32{"spotify.exe": 0.25}},
+
+		// chrome is reached twice, but only set once
+		{1, 0.5, map[string]float32{"chrome.exe": 0.5}},
+		{2, 0.75, map[string]float32{"discord.exe": 0.75, "game.exe": 0.75}},
+		{3, 0.3, map[string]float32{}},
+	}
+
+	for _, test := range tests {
+		backend.volumeSets = make(map[string]float32)
+
+		d.sessions.handleSliderMoveEvent(SliderMoveEvent{SliderID: test.sliderID, PercentValue: test.position})
+
+		if !reflect.DeepEqual(backend.volumeSets, test.want) {
+			t.Errorf("moving slider %d to %.2f set %v, want %v", test.sliderID, test.position, backend.volumeSets, test.want)
+		}
+
+		for key, volume := range test.want {
+			if got := mockVolume(t, d, key); got != volume {
+				t.Errorf("after moving slider %d, %s is at %.2f, want %.2f", test.sliderID, key, got, volume)
+			}
+		}
+	}
+}
+
+func TestSmoothedSliderRamps(t *testing.T) {
+	d := newTestDeej(t, zap.NewNop().Sugar(), `
+com_port: x
+slider_mapping:
+  0: spotify.exe
+slider_settings:
+  0:
+    smoothing: slew
+    smoothing_rate: 4
+`)
+
+	// the first position is applied as-is, there's nothing to ramp from
+	d.sessions.handleSliderMoveEvent(SliderMoveEvent{SliderID: 0, PercentValue: 0.2})
+
+	tests := []struct {
+		name    string
+		move    float32
+		elapsed time.Duration
+		want    float32
+	}{
+		{"move waits for a step", 1, 0, 0.2},
+		{"a step goes part of the way", -1, 50 * time.Millisecond, 0.4},
+		{"the next step goes further", -1, 100 * time.Millisecond, 0.8},
+		{"the last step arrives", -1, 100 * time.Millisecond, 1},
+		{"moving back ramps down", 0.9, 10 * time.Millisecond, 0.96},
+	}
+
+	for _, test := range tests {
+		if test.move >= 0 {
+			d.sessions.handleSliderMoveEvent(SliderMoveEvent{SliderID: 0, PercentValue: test.move})
+		}
+
+		// as if that long had passed since the last step
+		if test.elapsed > 0 {
+			d.sessions.smoothers[0].lastStep = time.Now().Add(-test.elapsed)
+			d.sessions.stepSmoothing()
+		}
+
+		if got := mockVolume(t, d, "spotify.exe"); math.Abs(float64(got-test.want)) > 0.011 {
+			t.Errorf("%s: spotify.exe is at %.2f, want %.2f", test.name, got, test.want)
+		}
+	}
+}
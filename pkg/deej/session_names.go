@@ -0,0 +1,90 @@
+package deej
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// some platforms (or apps) name their sessions by a full path, or with different casing from one run to the next,
+// so session keys are normalized before anything goes by them. they're always lowercase, and with session_names
+// set, apps' sessions can also lose their path (ending up named after their executable) and ".exe". slider
+// targets naming an app go through the same steps, so "C:\Program Files\Google\Chrome.EXE", "Chrome.exe" and
+// "chrome.exe" all map the same app. master, system, mic, deej.* and device names are left alone, and so are
+// patterns - they match against the keys as they are after normalization
+
+// SessionNameSettings decides how apps' session names become keys
+type SessionNameSettings struct {
+	StripPath      bool
+	StripExtension bool
+}
+
+const sessionNameExtension = ".exe"
+
+// normalize returns an app's name as it's keyed by
+func (s SessionNameSettings) normalize(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	if s.StripPath {
+
+		// a name that ends in a separator would be left with nothing, so it stays as it is
+		if idx := strings.LastIndexAny(name, `/\`); idx >= 0 && idx < len(name)-1 {
+			name = name[idx+1:]
+		}
+	}
+
+	if s.StripExtension && len(name) > len(sessionNameExtension) {
+		name = strings.TrimSuffix(name, sessionNameExtension)
+	}
+
+	return name
+}
+
+func sessionNameSettingsFromConfig(userConfig *viper.Viper) SessionNameSettings {
+	return SessionNameSettings{
+		StripPath:      userConfig.GetBool(configKeySessionStripPath),
+		StripExtension: userConfig.GetBool(configKeySessionStripExt),
+	}
+}
+
+// nameNormalizer is implemented by every session through baseSession
+type nameNormalizer interface {
+	normalizeName(settings SessionNameSettings)
+}
+
+// normalizeName sets the key the session goes by from its name. the name itself is kept as it is, so the
+// settings can change later on
+func (s *baseSession) normalizeName(settings SessionNameSettings) {
+	s.normalizedName = settings.normalize(s.name)
+}
+
+// normalizeSession applies the configured normalization to an app's session, leaving every other session alone
+func (m *sessionMap) normalizeSession(session Session) {
+	if sessionTargetableByName(session) {
+		return
+	}
+
+	if normalizer, ok := session.(nameNormalizer); ok {
+		normalizer.normalizeName(m.deej.config.SessionNames)
+	}
+}
+
+// normalizeTarget returns a lowercase target that names an app (not a pattern or a special target) in the same
+// form that app's session is keyed by
+func (m *sessionMap) normalizeTarget(target string) string {
+	switch target {
+	case masterSessionName, systemSessionName, inputSessionName:
+		return target
+	}
+
+	if m.targetHasSpecialTransform(target) {
+		return target
+	}
+
+	// devices are named by the user just like apps are, but only need lowercasing
+	if sessions, ok := m.get(target); ok && len(sessions) > 0 && sessionTargetableByName(sessions[0]) {
+		return target
+	}
+
+	return m.deej.config.SessionNames.normalize(target)
+}
@@ -0,0 +1,99 @@
+package deej
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestSessionNameNormalize(t *testing.T) {
+	none := SessionNameSettings{}
+	stripPath := SessionNameSettings{StripPath: true}
+	stripExtension := SessionNameSettings{StripExtension: true}
+	both := SessionNameSettings{StripPath: true, StripExtension: true}
+
+	tests := []struct {
+		settings SessionNameSettings
+		name     string
+		want     string
+	}{
+		{none, " Chrome.EXE ", "chrome.exe"},
+		{none, `C:\Program Files\Google\Chrome.exe`, `c:\program files\google\chrome.exe`},
+
+		{stripPath, `C:\Program Files\Google\Chrome.exe`, "chrome.exe"},
+		{stripPath, "/usr/lib/firefox/firefox", "firefox"},
+		{stripPath, "chrome.exe", "chrome.exe"},
+		{stripPath, `C:\Games\`, `c:\games\`},
+
+		{stripExtension, "Chrome.EXE", "chrome"},
+		{stripExtension, "chrome", "chrome"},
+		{stripExtension, "chrome.exe.exe", "chrome.exe"},
+		{stripExtension, ".exe", ".exe"},
+		{stripExtension, `C:\Google\Chrome.exe`, `c:\google\chrome`},
+
+		{both, `C:\Program Files\Google\Chrome.EXE`, "chrome"},
+		{both, "/opt/game/game.exe", "game"},
+		{both, `C:\Games\.exe`, ".exe"},
+	}
+
+	for _, test := range tests {
+		if got := test.settings.normalize(test.name); got != test.want {
+			t.Errorf("%+v normalize(%q) = %q, want %q", test.settings, test.name, got, test.want)
+		}
+	}
+}
+
+func TestNormalizedSessionsAndTargets(t *testing.T) {
+	d := newTestDeej(t, zap.NewNop().Sugar(), `
+com_port: x
+session_names:
+  strip_extension: true
+slider_mapping:
+  0: Spotify.EXE
+  1: [master, chrome]
+`)
+
+	keys := []string{}
+	for _, session := range d.sessions.GetAllSessionKeys() {
+		if session.SessionType != "special" {
+			keys = append(keys, session.Key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	if want := []string{"chrome", "discord", "game", "master", "mic", "spotify", "system"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("sessions are keyed %v, want %v", keys, want)
+	}
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"spotify.exe", "spotify"},
+		{"spotify", "spotify"},
+		{"master", "master"},
+		{"mic", "mic"},
+		{"deej.current", "deej.current"},
+		{"deej.unmapped", "deej.unmapped"},
+	}
+
+	for _, test := range tests {
+		if got := d.sessions.normalizeTarget(test.target); got != test.want {
+			t.Errorf("normalizeTarget(%q) = %q, want %q", test.target, got, test.want)
+		}
+	}
+
+	// a target goes by the same name as the session it maps, however it's written
+	d.sessions.handleSliderMoveEvent(SliderMoveEvent{SliderID: 0, PercentValue: 0.25})
+	d.sessions.handleSliderMoveEvent(SliderMoveEvent{SliderID: 1, PercentValue: 0.5})
+
+	volumes := map[string]float32{"spotify": 0.25, "master": 0.5, "chrome": 0.5, "discord": 0.7}
+	for key, want := range volumes {
+		if got := mockVolume(t, d, key); got != want {
+			t.Errorf("%s is at %.2f, want %.2f", key, got, want)
+		}
+	}
+}
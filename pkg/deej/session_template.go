@@ -0,0 +1,51 @@
+package deej
+
+import (
+	"sort"
+)
+
+// starting out, it's easiest to map whatever's playing right now. GET /api/sessions/template suggests a mapping
+// that does just that: master on slider 0, then every app that's playing something, one per slider in order of
+// their names, for as many sliders as there are. apps that don't fit are listed separately. nothing is saved, the
+// suggestion is in the same shape as GET /api/sliders so it can be edited and then PUT there
+
+const (
+
+	// what a template is made for before the board has said how many sliders it has
+	defaultTemplateSliders = 5
+
+	// so a typo in the query can't ask for an absurdly large mapping
+	maxTemplateSliders = 64
+)
+
+// MappingTemplate returns a suggested mapping for the given number of sliders, and the active apps that were left
+// out of it for lack of sliders
+func (m *sessionMap) MappingTemplate(sliders int) (map[int][]string, []string) {
+	m.lock.Lock()
+
+	keys := []string{}
+	for key, sessions := range m.m {
+		if len(sessions) == 0 || sessionTargetableByName(sessions[0]) || !m.keyControllable(key) {
+			continue
+		}
+
+		if state, _ := sessionInstances(sessions); state != sessionStateActive {
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+
+	m.lock.Unlock()
+
+	sort.Strings(keys)
+
+	mapping := map[int][]string{0: {masterSessionName}}
+
+	for sliderID := 1; sliderID < sliders && len(keys) > 0; sliderID++ {
+		mapping[sliderID] = []string{keys[0]}
+		keys = keys[1:]
+	}
+
+	return mapping, keys
+}
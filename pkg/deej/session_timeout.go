@@ -0,0 +1,143 @@
+package deej
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// changing a session's volume, mute state or balance means asking the OS, which is usually quick - but a busy
+// audio service or a hung app's session can take seconds to answer. rather than holding up every other slider
+// behind it, each of these calls gets session_call_timeout seconds to finish. one that doesn't is abandoned: it's
+// logged, counted (GET /api/status reports it as sessionTimeouts) and left to finish in the background, and deej
+// moves on to the next session. until it does finish, deej doesn't call into that session again, so a session
+// that's stuck for good costs one goroutine rather than one per slider move. sessions check their context before
+// every request they make to the OS, so an abandoned call doesn't go on to apply a stale change once it's unstuck
+
+const (
+
+	// in seconds
+	defaultSessionCallTimeout = 1.0
+	minSessionCallTimeout     = 0.05
+)
+
+var (
+	errSessionCallTimedOut = errors.New("session call timed out")
+	errSessionCallPending  = errors.New("previous session call still pending")
+)
+
+// sessionCallAbandoned returns whether a session call's error is deej giving up on it, rather than the session
+// failing. these don't call for a session refresh, since the session is most likely still there
+func sessionCallAbandoned(err error) bool {
+	return errors.Is(err, errSessionCallTimedOut) || errors.Is(err, errSessionCallPending)
+}
+
+// callSession runs a call to a session with the configured timeout, and gives up on it once that's up
+func (m *sessionMap) callSession(session Session, operation string, call func(ctx context.Context) error) error {
+	m.pendingCallLock.Lock()
+	pending := m.pendingCalls[session]
+	m.pendingCallLock.Unlock()
+
+	if pending {
+		if m.deej.Verbose() {
+			m.logger.Debugw("Skipping session call, the previous one hasn't finished",
+				"session", session.Key(),
+				"operation", operation)
+		}
+
+		return errSessionCallPending
+	}
+
+	timeout := m.deej.config.SessionCallTimeout
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+
+	// buffered, so an abandoned call can still finish without anyone waiting for it
+	done := make(chan error, 1)
+
+	go func() {
+		defer cancel()
+		done <- call(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	// the call could've finished right as the deadline passed
+	select {
+	case err := <-done:
+		return err
+	default:
+	}
+
+	atomic.AddUint64(&m.sessionTimeouts, 1)
+
+	m.logger.Warnw("Session call timed out, moving on without it",
+		"session", session.Key(),
+		"operation", operation,
+		"timeout", timeout)
+
+	m.pendingCallLock.Lock()
+	m.pendingCalls[session] = true
+	m.pendingCallLock.Unlock()
+
+	go func() {
+		err := <-done
+
+		m.pendingCallLock.Lock()
+		delete(m.pendingCalls, session)
+		m.pendingCallLock.Unlock()
+
+		m.logger.Debugw("Abandoned session call finished", "session", session.Key(), "operation", operation, "error", err)
+	}()
+
+	return errSessionCallTimedOut
+}
+
+// setSessionVolume sets a session's volume within the configured timeout
+func (m *sessionMap) setSessionVolume(session Session, volume float32) error {
+	return m.callSession(session, "volume", func(ctx context.Context) error {
+		return m.backend.SetVolume(ctx, session, volume)
+	})
+}
+
+// setSessionMute sets a session's mute state within the configured timeout
+func (m *sessionMap) setSessionMute(session Session, muted bool) error {
+	return m.callSession(session, "mute", func(ctx context.Context) error {
+		return m.backend.SetMute(ctx, session, muted)
+	})
+}
+
+// setSessionBalance sets a session's balance within the configured timeout
+func (m *sessionMap) setSessionBalance(session Session, balanced balancedSession, balance float32) error {
+	return m.callSession(session, "balance", func(ctx context.Context) error {
+		return balanced.SetBalance(ctx, balance)
+	})
+}
+
+// SessionTimeouts returns how many session calls were abandoned for taking too long, since deej started
+func (m *sessionMap) SessionTimeouts() uint64 {
+	return atomic.LoadUint64(&m.sessionTimeouts)
+}
+
+func sessionCallTimeoutFromConfig(
+	userConfig *viper.Viper,
+	warnInvalidValue func(message string, key string, keysAndValues ...interface{}),
+) time.Duration {
+	timeout := userConfig.GetFloat64(configKeySessionTimeout)
+	if timeout < minSessionCallTimeout {
+		warnInvalidValue("Session call timeout is too short, using the minimum instead",
+			configKeySessionTimeout,
+			"invalidValue", timeout,
+			"minimumValue", minSessionCallTimeout)
+
+		timeout = minSessionCallTimeout
+	}
+
+	return time.Duration(timeout * float64(time.Second))
+}
@@ -0,0 +1,96 @@
+package deej
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestStuckSessionTimesOut(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+
+	d := newTestDeej(t, zap.New(core).Sugar(), `
+com_port: x
+session_call_timeout: 0.05
+slider_mapping:
+  0: discord.exe
+  1: spotify.exe
+`)
+
+	sessions, _ := d.sessions.get("discord.exe")
+	release := sessions[0].(*mockSession).block()
+	defer release()
+
+	tests := []struct {
+		name     string
+		sliderID int
+		position float32
+
+		// whether the stuck session is let go of before the move
+		release bool
+
+		wantTimeouts uint64
+		wantDiscord  float32
+		wantSpotify  float32
+	}{
+		{"stuck session times out", 0, 0.2, false, 1, 0.7, 0.6},
+		{"other sessions still move", 1, 0.3, false, 1, 0.7, 0.3},
+		{"stuck session isn't called again", 0, 0.4, false, 1, 0.7, 0.3},
+
+		// the abandoned change isn't applied late, but the next one is
+		{"unstuck session moves again", 0, 0.5, true, 1, 0.5, 0.3},
+	}
+
+	for _, test := range tests {
+		if test.release {
+			release()
+			waitForPendingCalls(t, d.sessions)
+		}
+
+		d.sessions.handleSliderMoveEvent(SliderMoveEvent{SliderID: test.sliderID, PercentValue: test.position})
+
+		if got := d.sessions.SessionTimeouts(); got != test.wantTimeouts {
+			t.Errorf("%s: %d session calls timed out, want %d", test.name, got, test.wantTimeouts)
+		}
+
+		if got := mockVolume(t, d, "discord.exe"); got != test.wantDiscord {
+			t.Errorf("%s: discord.exe is at %.2f, want %.2f", test.name, got, test.wantDiscord)
+		}
+
+		if got := mockVolume(t, d, "spotify.exe"); got != test.wantSpotify {
+			t.Errorf("%s: spotify.exe is at %.2f, want %.2f", test.name, got, test.wantSpotify)
+		}
+	}
+
+	timedOut := logs.FilterMessage("Session call timed out, moving on without it").All()
+	if len(timedOut) != 1 {
+		t.Fatalf("timeout logged %d times, want once", len(timedOut))
+	}
+
+	if session := timedOut[0].ContextMap()["session"]; session != "discord.exe" {
+		t.Errorf("timeout logged for %v, want discord.exe", session)
+	}
+}
+
+// waitForPendingCalls waits for every abandoned session call to finish
+func waitForPendingCalls(t *testing.T, m *sessionMap) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+
+	for time.Now().Before(deadline) {
+		m.pendingCallLock.Lock()
+		pending := len(m.pendingCalls)
+		m.pendingCallLock.Unlock()
+
+		if pending == 0 {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatal("abandoned session calls didn't finish")
+}
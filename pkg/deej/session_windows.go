@@ -1,14 +1,18 @@
 package deej
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
+	"unsafe"
 
 	ole "github.com/go-ole/go-ole"
 	ps "github.com/mitchellh/go-ps"
 	wca "github.com/moutend/go-wca"
 	"go.uber.org/zap"
+
+	"github.com/omriharel/deej/pkg/deej/util"
 )
 
 var errNoSuchProcess = errors.New("No such process")
@@ -23,6 +27,9 @@ type wcaSession struct {
 	control *wca.IAudioSessionControl2
 	volume  *wca.ISimpleAudioVolume
 
+	// nil if the session doesn't have one, in which case it can't be balanced
+	channelVolume *iChannelAudioVolume
+
 	eventCtx *ole.GUID
 }
 
@@ -34,6 +41,9 @@ type masterSession struct {
 	eventCtx *ole.GUID
 
 	stale bool // when set to true, we should refresh sessions on the next call to SetVolume
+
+	// endpoint channel volumes aren't separate from the master volume, so the balance goes into every volume change
+	balance float32
 }
 
 func newWCASession(
@@ -41,6 +51,7 @@ func newWCASession(
 	control *wca.IAudioSessionControl2,
 	volume *wca.ISimpleAudioVolume,
 	pid uint32,
+	device string,
 	eventCtx *ole.GUID,
 ) (*wcaSession, error) {
 
@@ -51,6 +62,9 @@ func newWCASession(
 		eventCtx: eventCtx,
 	}
 
+	s.processID = int(pid)
+	s.outputDevice = device
+
 	// special treatment for system sounds session
 	if pid == 0 {
 		s.system = true
@@ -77,10 +91,27 @@ func newWCASession(
 		s.processName = process.Executable()
 		s.name = s.processName
 		s.humanReadableDesc = fmt.Sprintf("%s (pid %d)", s.processName, s.pid)
+
+		// not having a friendly name or icon is no reason to skip the session, so just note it
+		metadata, err := util.GetProcessMetadata(pid)
+		if err != nil {
+			logger.Debugw("Failed to get process metadata", "pid", pid, "error", err)
+		}
+
+		s.displayName = metadata.DisplayName
+		s.icon = metadata.Icon
 	}
 
 	// use a self-identifying session name e.g. deej.sessions.chrome
 	s.logger = logger.Named(strings.TrimSuffix(s.Key(), ".exe"))
+
+	// not being able to balance the session is no reason to skip it either
+	if dispatch, err := control.QueryInterface(iidChannelAudioVolume); err == nil {
+		s.channelVolume = (*iChannelAudioVolume)(unsafe.Pointer(dispatch))
+	} else {
+		s.logger.Debugw("Failed to query session's IChannelAudioVolume", "error", err)
+	}
+
 	s.logger.Debugw(sessionCreationLogMessage, "session", s)
 
 	return s, nil
@@ -103,6 +134,7 @@ func newMasterSession(
 	s.master = true
 	s.name = key
 	s.humanReadableDesc = key
+	s.displayName = key
 
 	s.logger.Debugw(sessionCreationLogMessage, "session", s)
 
@@ -119,7 +151,11 @@ func (s *wcaSession) GetVolume() float32 {
 	return level
 }
 
-func (s *wcaSession) SetVolume(v float32) error {
+func (s *wcaSession) SetVolume(ctx context.Context, v float32) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("adjust session volume: %w", err)
+	}
+
 	if err := s.volume.SetMasterVolume(v, s.eventCtx); err != nil {
 		s.logger.Warnw("Failed to set session volume", "error", err)
 		return fmt.Errorf("adjust session volume: %w", err)
@@ -143,9 +179,91 @@ func (s *wcaSession) SetVolume(v float32) error {
 	return nil
 }
 
+// State asks windows for the session's current state, since apps start and stop playing all the time
+func (s *wcaSession) State() string {
+	var state uint32
+
+	if err := s.control.GetState(&state); err != nil {
+		s.logger.Debugw("Failed to get session state", "error", err)
+		return s.baseSession.State()
+	}
+
+	switch state {
+	case wca.AudioSessionStateInactive:
+		return sessionStateInactive
+	case wca.AudioSessionStateExpired:
+		return sessionStateExpired
+	}
+
+	return sessionStateActive
+}
+
+func (s *wcaSession) GetMute() bool {
+	var mute bool
+
+	if err := s.volume.GetMute(&mute); err != nil {
+		s.logger.Warnw("Failed to get session mute state", "error", err)
+	}
+
+	return mute
+}
+
+func (s *wcaSession) SetMute(ctx context.Context, m bool) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("adjust session mute state: %w", err)
+	}
+
+	if err := s.volume.SetMute(m, s.eventCtx); err != nil {
+		s.logger.Warnw("Failed to set session mute state", "error", err)
+		return fmt.Errorf("adjust session mute state: %w", err)
+	}
+
+	s.logger.Debugw("Adjusting session mute state", "to", m)
+
+	return nil
+}
+
+// CanBalance is only true for stereo sessions, which is what nearly every app plays
+func (s *wcaSession) CanBalance() bool {
+	if s.channelVolume == nil {
+		return false
+	}
+
+	count, err := s.channelVolume.getChannelCount()
+	return err == nil && count == 2
+}
+
+// SetBalance only changes the session's channel volumes, which windows applies on top of its volume
+func (s *wcaSession) SetBalance(ctx context.Context, balance float32) error {
+	if !s.CanBalance() {
+		return errBalanceUnsupported
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("adjust session balance: %w", err)
+	}
+
+	left, right := balanceChannelFactors(balance)
+
+	for channel, level := range []float32{left, right} {
+		if err := s.channelVolume.setChannelVolume(uint32(channel), level, s.eventCtx); err != nil {
+			s.logger.Warnw("Failed to set session balance", "error", err)
+			return fmt.Errorf("adjust session balance: %w", err)
+		}
+	}
+
+	s.logger.Debugw("Adjusting session balance", "to", fmt.Sprintf("%.2f", balance))
+
+	return nil
+}
+
 func (s *wcaSession) Release() {
 	s.logger.Debug("Releasing audio session")
 
+	if s.channelVolume != nil {
+		s.channelVolume.Release()
+	}
+
 	s.volume.Release()
 	s.control.Release()
 }
@@ -164,12 +282,16 @@ func (s *masterSession) GetVolume() float32 {
 	return level
 }
 
-func (s *masterSession) SetVolume(v float32) error {
+func (s *masterSession) SetVolume(ctx context.Context, v float32) error {
 	if s.stale {
 		s.logger.Warnw("Session expired because default device has changed, triggering session refresh")
 		return errRefreshSessions
 	}
 
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("adjust session volume: %w", err)
+	}
+
 	if err := s.volume.SetMasterVolumeLevelScalar(v, s.eventCtx); err != nil {
 		s.logger.Warnw("Failed to set session volume",
 			"error", err,
@@ -178,11 +300,103 @@ func (s *masterSession) SetVolume(v float32) error {
 		return fmt.Errorf("adjust session volume: %w", err)
 	}
 
+	// windows keeps the channels' proportions itself, but not once they've both been at 0
+	if s.balance != 0 {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("adjust session volume: %w", err)
+		}
+
+		if err := s.setChannelVolumes(v); err != nil {
+			return fmt.Errorf("adjust session volume: %w", err)
+		}
+	}
+
 	s.logger.Debugw("Adjusting session volume", "to", fmt.Sprintf("%.2f", v))
 
 	return nil
 }
 
+func (s *masterSession) GetMute() bool {
+	var mute bool
+
+	if err := s.volume.GetMute(&mute); err != nil {
+		s.logger.Warnw("Failed to get session mute state", "error", err)
+	}
+
+	return mute
+}
+
+func (s *masterSession) SetMute(ctx context.Context, m bool) error {
+	if s.stale {
+		s.logger.Warnw("Session expired because default device has changed, triggering session refresh")
+		return errRefreshSessions
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("adjust session mute state: %w", err)
+	}
+
+	if err := s.volume.SetMute(m, s.eventCtx); err != nil {
+		s.logger.Warnw("Failed to set session mute state",
+			"error", err,
+			"mute", m)
+
+		return fmt.Errorf("adjust session mute state: %w", err)
+	}
+
+	s.logger.Debugw("Adjusting session mute state", "to", m)
+
+	return nil
+}
+
+// CanBalance is only true for stereo devices
+func (s *masterSession) CanBalance() bool {
+	var count uint32
+
+	return s.volume.GetChannelCount(&count) == nil && count == 2
+}
+
+func (s *masterSession) SetBalance(ctx context.Context, balance float32) error {
+	if s.stale {
+		s.logger.Warnw("Session expired because default device has changed, triggering session refresh")
+		return errRefreshSessions
+	}
+
+	if !s.CanBalance() {
+		return errBalanceUnsupported
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("adjust session balance: %w", err)
+	}
+
+	s.balance = balance
+	if err := s.setChannelVolumes(s.GetVolume()); err != nil {
+		return fmt.Errorf("adjust session balance: %w", err)
+	}
+
+	s.logger.Debugw("Adjusting session balance", "to", fmt.Sprintf("%.2f", balance))
+
+	return nil
+}
+
+// setChannelVolumes spreads a volume over the left and right channels according to the balance
+func (s *masterSession) setChannelVolumes(v float32) error {
+	left, right := balanceChannelFactors(s.balance)
+
+	for channel, factor := range []float32{left, right} {
+		if err := s.volume.SetChannelVolumeLevelScalar(uint32(channel), v*factor, s.eventCtx); err != nil {
+			s.logger.Warnw("Failed to set session channel volume",
+				"error", err,
+				"channel", channel)
+
+			return fmt.Errorf("set channel %d volume: %w", channel, err)
+		}
+	}
+
+	return nil
+}
+
 func (s *masterSession) Release() {
 	s.logger.Debug("Releasing audio session")
 
@@ -0,0 +1,336 @@
+package deej
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// SliderAction runs a command whenever its slider moves past a threshold, i.e. to launch OBS when the top
+// slider goes over 90%:
+//
+//	slider_actions:
+//	  enabled: true
+//	  actions:
+//	    - slider: 0
+//	      threshold: 0.9
+//	      command: C:\Program Files\obs-studio\bin\64bit\obs64.exe
+//
+// once it fired, the slider has to go back past the threshold by the hysteresis before it can fire again,
+// so a slider resting right at the threshold doesn't run the command over and over
+type SliderAction struct {
+	Slider    int     `json:"slider" mapstructure:"slider"`
+	Threshold float64 `json:"threshold" mapstructure:"threshold"`
+
+	// "above" (the default) fires when the slider goes over the threshold, "below" when it goes under it
+	Direction  string  `json:"direction" mapstructure:"direction"`
+	Hysteresis float64 `json:"hysteresis" mapstructure:"hysteresis"`
+
+	// run as-is, without a shell. the process is killed once it's been running for the timeout (in seconds)
+	Command string   `json:"command" mapstructure:"command"`
+	Args    []string `json:"args" mapstructure:"args"`
+	Timeout float64  `json:"timeout" mapstructure:"timeout"`
+}
+
+// SliderActionSettings holds everything under slider_actions. running local commands is a lot more than
+// moving volumes, so nothing runs unless it's explicitly enabled, and the API can only replace the actions
+// when that's explicitly allowed too
+type SliderActionSettings struct {
+	Enabled      bool
+	AllowAPIEdit bool
+	Actions      []SliderAction
+}
+
+// SliderActionRunner watches slider values and runs the configured actions' commands
+type SliderActionRunner struct {
+	deej   *Deej
+	logger *zap.SugaredLogger
+
+	lock sync.Mutex
+
+	// the actions the states were made for, and one state for each of them
+	actions []SliderAction
+	states  []*sliderActionState
+}
+
+type sliderActionState struct {
+
+	// whether the slider is back on the near side of the threshold, so the action can fire again.
+	// the first value only tells us where the slider starts out
+	seen  bool
+	armed bool
+
+	// the command is never started again while it's still running
+	running bool
+}
+
+const (
+	sliderActionDirectionAbove = "above"
+	sliderActionDirectionBelow = "below"
+
+	defaultSliderActionHysteresis = 0.05
+	defaultSliderActionTimeout    = 30
+
+	// longer output is cut off in the logs
+	sliderActionMaxLoggedOutput = 2000
+)
+
+var errInvalidSliderAction = errors.New("invalid slider action")
+
+// NewSliderActionRunner creates a SliderActionRunner. it consumes slider values right away,
+// and ignores them for as long as actions aren't enabled
+func NewSliderActionRunner(deej *Deej, logger *zap.SugaredLogger) *SliderActionRunner {
+	logger = logger.Named("slider_actions")
+
+	r := &SliderActionRunner{
+		deej:   deej,
+		logger: logger,
+	}
+
+	go r.consumeSliderValues(deej.serial.SubscribeToSliderValues())
+
+	logger.Debug("Created slider action runner instance")
+
+	return r
+}
+
+func (r *SliderActionRunner) consumeSliderValues(valuesChannel chan []float32) {
+	for values := range valuesChannel {
+		r.handleSliderValues(values)
+	}
+}
+
+func (r *SliderActionRunner) handleSliderValues(values []float32) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	settings := r.deej.config.SliderActions
+
+	// changed actions start over from wherever their sliders are. so do actions that were just enabled,
+	// which would otherwise go by where the sliders were back when they were disabled
+	if !settings.Enabled {
+		r.actions = nil
+		r.states = nil
+
+		return
+	}
+
+	if !reflect.DeepEqual(settings.Actions, r.actions) {
+		r.actions = settings.Actions
+		r.states = make([]*sliderActionState, len(settings.Actions))
+
+		for idx := range r.states {
+			r.states[idx] = &sliderActionState{}
+		}
+	}
+
+	for idx, action := range r.actions {
+		if action.Slider >= len(values) {
+			continue
+		}
+
+		state := r.states[idx]
+		value := float64(values[action.Slider])
+
+		past := value >= action.Threshold
+		backAgain := value <= action.Threshold-action.Hysteresis
+
+		if action.Direction == sliderActionDirectionBelow {
+			past = value <= action.Threshold
+			backAgain = value >= action.Threshold+action.Hysteresis
+		}
+
+		if !state.seen {
+			state.seen = true
+			state.armed = !past
+
+			continue
+		}
+
+		if backAgain {
+			state.armed = true
+			continue
+		}
+
+		if !past || !state.armed {
+			continue
+		}
+
+		state.armed = false
+
+		if state.running {
+			r.logger.Infow("Slider action's command is still running, not starting it again",
+				"slider", action.Slider,
+				"command", action.Command)
+
+			continue
+		}
+
+		state.running = true
+		go r.run(action, state, value)
+	}
+}
+
+// run runs an action's command to completion (or its timeout), and logs what it printed
+func (r *SliderActionRunner) run(action SliderAction, state *sliderActionState, value float64) {
+	defer func() {
+		r.lock.Lock()
+		state.running = false
+		r.lock.Unlock()
+	}()
+
+	timeout := time.Duration(action.Timeout * float64(time.Second))
+	logger := r.logger.With("slider", action.Slider, "value", value, "command", action.Command, "args", action.Args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	logger.Infow("Running slider action")
+
+	started := time.Now()
+	output, err := exec.CommandContext(ctx, action.Command, action.Args...).CombinedOutput()
+
+	logger = logger.With("duration", time.Since(started).Truncate(time.Millisecond), "output", loggableOutput(output))
+
+	if ctx.Err() == context.DeadlineExceeded {
+		logger.Warnw("Slider action timed out and was killed", "timeout", timeout)
+		return
+	}
+
+	if err != nil {
+		logger.Warnw("Slider action failed", "error", err)
+		return
+	}
+
+	logger.Info("Slider action finished")
+}
+
+func loggableOutput(output []byte) string {
+	trimmed := strings.TrimSpace(string(output))
+
+	if len(trimmed) > sliderActionMaxLoggedOutput {
+		return trimmed[:sliderActionMaxLoggedOutput] + "... (cut off)"
+	}
+
+	return trimmed
+}
+
+func (action *SliderAction) validate() error {
+	action.Command = strings.TrimSpace(action.Command)
+	action.Direction = strings.ToLower(strings.TrimSpace(action.Direction))
+
+	// leaving these out means the defaults
+	if action.Direction == "" {
+		action.Direction = sliderActionDirectionAbove
+	}
+
+	if action.Hysteresis == 0 {
+		action.Hysteresis = defaultSliderActionHysteresis
+	}
+
+	if action.Timeout == 0 {
+		action.Timeout = defaultSliderActionTimeout
+	}
+
+	if action.Args == nil {
+		action.Args = []string{}
+	}
+
+	if action.Slider < 0 {
+		return fmt.Errorf("%w: slider must be a non-negative slider index, got %d", errInvalidSliderAction, action.Slider)
+	}
+
+	// at either end, the slider could never be on the near side
+	if action.Threshold <= 0 || action.Threshold >= 1 {
+		return fmt.Errorf("%w: threshold must be between 0 and 1, got %v", errInvalidSliderAction, action.Threshold)
+	}
+
+	if action.Direction != sliderActionDirectionAbove && action.Direction != sliderActionDirectionBelow {
+		return fmt.Errorf("%w: direction must be %q or %q, got %q", errInvalidSliderAction,
+			sliderActionDirectionAbove, sliderActionDirectionBelow, action.Direction)
+	}
+
+	// the slider has to be able to get back past the threshold by this much, or it'd only ever fire once
+	rearmValue := action.Threshold - action.Hysteresis
+	if action.Direction == sliderActionDirectionBelow {
+		rearmValue = action.Threshold + action.Hysteresis
+	}
+
+	if action.Hysteresis < 0 || rearmValue < 0 || rearmValue > 1 {
+		return fmt.Errorf("%w: hysteresis must be positive, and leave room between the threshold and the end of the slider, got %v",
+			errInvalidSliderAction, action.Hysteresis)
+	}
+
+	if action.Command == "" {
+		return fmt.Errorf("%w: command can't be empty", errInvalidSliderAction)
+	}
+
+	if action.Timeout < 0 {
+		return fmt.Errorf("%w: timeout must be positive, got %v", errInvalidSliderAction, action.Timeout)
+	}
+
+	return nil
+}
+
+func sliderActionsFromConfig(
+	userConfig *viper.Viper,
+	warnInvalidValue func(message string, key string, keysAndValues ...interface{}),
+) []SliderAction {
+	var rawActions []SliderAction
+
+	if err := userConfig.UnmarshalKey(configKeySliderActions, &rawActions); err != nil {
+		warnInvalidValue("Invalid slider actions specified, ignoring all of them",
+			configKeySliderActions,
+			"error", err)
+
+		return []SliderAction{}
+	}
+
+	actions := make([]SliderAction, 0, len(rawActions))
+
+	for idx, action := range rawActions {
+		if err := action.validate(); err != nil {
+			warnInvalidValue("Invalid slider action specified, ignoring it",
+				configKeySliderActions,
+				"index", idx,
+				"error", err)
+
+			continue
+		}
+
+		actions = append(actions, action)
+	}
+
+	return actions
+}
+
+// sliderActionsToConfigValue returns the whole slider_actions section in the shape it's written to config.yaml in
+func sliderActionsToConfigValue(settings SliderActionSettings) map[string]interface{} {
+	actions := make([]map[string]interface{}, len(settings.Actions))
+
+	for idx, action := range settings.Actions {
+		actions[idx] = map[string]interface{}{
+			"slider":     action.Slider,
+			"threshold":  action.Threshold,
+			"direction":  action.Direction,
+			"hysteresis": action.Hysteresis,
+			"command":    action.Command,
+			"args":       action.Args,
+			"timeout":    action.Timeout,
+		}
+	}
+
+	return map[string]interface{}{
+		"enabled":        settings.Enabled,
+		"allow_api_edit": settings.AllowAPIEdit,
+		"actions":        actions,
+	}
+}
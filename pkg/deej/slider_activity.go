@@ -0,0 +1,50 @@
+package deej
+
+import (
+	"time"
+)
+
+// the UI lights up whichever slider is being moved. a slider counts as active for a moment after its value last
+// changed by more than its noise gate (or was moved by another input, i.e. OSC), so jitter never lights it up.
+// the times themselves are kept by SerialIO, next to the values, and set wherever a move is detected
+
+// long enough to bridge the pauses while a slider is being adjusted
+const sliderActiveDuration = 750 * time.Millisecond
+
+// SliderActivity is when a slider was last moved. lastMoved is nil for sliders that haven't moved since deej started
+type SliderActivity struct {
+	LastMoved *time.Time `json:"lastMoved"`
+	Active    bool       `json:"active"`
+}
+
+// markSliderMoved records a slider move. must be called with the slider lock held
+func (sio *SerialIO) markSliderMoved(sliderIdx int, now time.Time) {
+	for len(sio.lastSliderMoves) <= sliderIdx {
+		sio.lastSliderMoves = append(sio.lastSliderMoves, time.Time{})
+	}
+
+	sio.lastSliderMoves[sliderIdx] = now
+}
+
+// SliderActivity returns when every slider was last moved, by index. it's as long as the slider values are
+func (sio *SerialIO) SliderActivity() []SliderActivity {
+	sio.sliderLock.Lock()
+	defer sio.sliderLock.Unlock()
+
+	now := time.Now()
+	activity := make([]SliderActivity, len(sio.currentSliderPercentValues))
+
+	for idx := range activity {
+		if idx >= len(sio.lastSliderMoves) || sio.lastSliderMoves[idx].IsZero() {
+			continue
+		}
+
+		lastMoved := sio.lastSliderMoves[idx]
+		activity[idx] = SliderActivity{
+			LastMoved: &lastMoved,
+			Active:    now.Sub(lastMoved) < sliderActiveDuration,
+		}
+	}
+
+	return activity
+}
@@ -0,0 +1,76 @@
+package deej
+
+import (
+	"sort"
+)
+
+// a slider can be pinned at a fixed value, i.e. while its pot is broken and waiting for a replacement, or to keep
+// its targets at a constant level no matter what. a slider with a fixed_value under its slider_settings is moved
+// there as soon as deej starts (and on every config reload, like the board's sliders are), and whatever the board,
+// OSC or MIDI report for it is ignored until the fixed value is cleared - at which point the board's position takes
+// over again with its next line. the value is the slider's position, so the slider's volume curve still applies.
+// PUT /api/sliders/{id}/fixed sets it and DELETE clears it, and GET /api/status lists fixed sliders under
+// fixedSliders so the UI can grey them out
+
+func validFixedValue(value float64) bool {
+	return value >= 0 && value <= 1
+}
+
+// SliderFixedValue returns the value a slider is held at, and false if it isn't fixed
+func (cc *CanonicalConfig) SliderFixedValue(sliderIdx int) (float32, bool) {
+	if settings, ok := cc.SliderSettings[sliderIdx]; ok && settings.FixedValue != nil {
+		return float32(*settings.FixedValue), true
+	}
+
+	return 0, false
+}
+
+// FixedSliders returns the value of every fixed slider, by index
+func (cc *CanonicalConfig) FixedSliders() map[int]float32 {
+	fixed := make(map[int]float32)
+
+	for sliderIdx := range cc.SliderSettings {
+		if value, ok := cc.SliderFixedValue(sliderIdx); ok {
+			fixed[sliderIdx] = value
+		}
+	}
+
+	return fixed
+}
+
+// applyFixedValues moves every fixed slider to its value, the same way a physical move would. every one of them
+// gets a move event, whether its value changed or not, so its targets are set again after sessions were re-acquired
+func (sio *SerialIO) applyFixedValues() {
+	fixed := sio.deej.config.FixedSliders()
+	if len(fixed) == 0 {
+		return
+	}
+
+	sliderIDs := make([]int, 0, len(fixed))
+	for sliderIdx := range fixed {
+		sliderIDs = append(sliderIDs, sliderIdx)
+	}
+
+	sort.Ints(sliderIDs)
+
+	moveEvents := make([]SliderMoveEvent, 0, len(sliderIDs))
+
+	sio.sliderLock.Lock()
+
+	for _, sliderIdx := range sliderIDs {
+		sio.growSliderValues(sliderIdx + 1)
+		sio.currentSliderPercentValues[sliderIdx] = fixed[sliderIdx]
+
+		moveEvents = append(moveEvents, SliderMoveEvent{
+			SliderID:     sliderIdx,
+			PercentValue: fixed[sliderIdx],
+		})
+	}
+
+	values := sio.sliderValues()
+	sio.sliderLock.Unlock()
+
+	sio.logger.Debugw("Applying fixed slider values", "sliders", fixed)
+
+	sio.deliverSliderMoves(moveEvents, values)
+}
@@ -0,0 +1,232 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// SliderGroup has several sliders jointly control the same targets, which get a volume combined from all of them:
+//
+//	slider_groups:
+//	  - name: music
+//	    sliders: [3, 4]
+//	    combiner: coarse_fine
+//	    targets: spotify.exe
+//
+// a grouped slider still controls its own slider_mapping targets on its own. a target that's both in a group
+// and in one of its sliders' own mapping is left to the group, so the two can't fight over it
+type SliderGroup struct {
+	Name     string   `json:"name" mapstructure:"name"`
+	Sliders  []int    `json:"sliders" mapstructure:"sliders"`
+	Combiner string   `json:"combiner" mapstructure:"combiner"`
+	Targets  []string `json:"targets" mapstructure:"targets"`
+
+	// only used by coarse_fine: how much of the full range the fine slider covers, end to end
+	FineRange float64 `json:"fineRange,omitempty" mapstructure:"fine_range"`
+}
+
+const (
+
+	// the group follows whichever of its sliders was moved last
+	sliderGroupLastTouched = "last_touched"
+
+	// the group sits at the average of its sliders' positions
+	sliderGroupAverage = "average"
+
+	// the first slider sets the volume and the second nudges it, with its center being no change
+	sliderGroupCoarseFine = "coarse_fine"
+
+	// the fine slider moves the volume by up to 5% either way
+	defaultSliderGroupFineRange = 0.1
+)
+
+var errInvalidSliderGroup = errors.New("invalid slider group")
+
+func (group *SliderGroup) validate() error {
+	if group.Name == "" {
+		return fmt.Errorf("%w: name can't be empty", errInvalidSliderGroup)
+	}
+
+	if group.Combiner == "" {
+		group.Combiner = sliderGroupLastTouched
+	}
+
+	group.Combiner = strings.ToLower(group.Combiner)
+
+	switch group.Combiner {
+	case sliderGroupLastTouched, sliderGroupAverage:
+		group.FineRange = 0
+
+		if len(group.Sliders) < 2 {
+			return fmt.Errorf("%w: %s needs at least 2 sliders, got %d", errInvalidSliderGroup, group.Combiner, len(group.Sliders))
+		}
+
+	case sliderGroupCoarseFine:
+		if group.FineRange == 0 {
+			group.FineRange = defaultSliderGroupFineRange
+		}
+
+		if group.FineRange < 0 || group.FineRange > 1 {
+			return fmt.Errorf("%w: fine_range must be between 0 and 1, got %v", errInvalidSliderGroup, group.FineRange)
+		}
+
+		if len(group.Sliders) != 2 {
+			return fmt.Errorf("%w: %s needs exactly 2 sliders (coarse, then fine), got %d",
+				errInvalidSliderGroup, group.Combiner, len(group.Sliders))
+		}
+
+	default:
+		return fmt.Errorf("%w: unknown combiner %q (supported: %s, %s, %s)",
+			errInvalidSliderGroup, group.Combiner, sliderGroupLastTouched, sliderGroupAverage, sliderGroupCoarseFine)
+	}
+
+	seen := make(map[int]bool, len(group.Sliders))
+
+	for _, sliderIdx := range group.Sliders {
+		if sliderIdx < 0 {
+			return fmt.Errorf("%w: sliders must be non-negative slider indices, got %d", errInvalidSliderGroup, sliderIdx)
+		}
+
+		if seen[sliderIdx] {
+			return fmt.Errorf("%w: slider %d is listed more than once", errInvalidSliderGroup, sliderIdx)
+		}
+
+		seen[sliderIdx] = true
+	}
+
+	if len(group.Targets) == 0 {
+		return fmt.Errorf("%w: targets can't be empty", errInvalidSliderGroup)
+	}
+
+	return nil
+}
+
+// has returns whether the given slider is part of the group
+func (group SliderGroup) has(sliderIdx int) bool {
+	for _, member := range group.Sliders {
+		if member == sliderIdx {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasTarget returns whether the group controls the given (unresolved) target
+func (group SliderGroup) hasTarget(target string) bool {
+	for _, groupTarget := range group.Targets {
+		if strings.EqualFold(targetName(groupTarget), targetName(target)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// combine returns the group's position, given the positions of its sliders and which of them moved last.
+// positions only has the sliders that reported one so far, so it returns false if there's nothing to go by yet
+func (group SliderGroup) combine(positions map[int]float32, lastMoved int) (float32, bool) {
+	switch group.Combiner {
+	case sliderGroupAverage:
+		var sum float32
+		count := 0
+
+		for _, sliderIdx := range group.Sliders {
+			if position, ok := positions[sliderIdx]; ok {
+				sum += position
+				count++
+			}
+		}
+
+		if count == 0 {
+			return 0, false
+		}
+
+		return sum / float32(count), true
+
+	case sliderGroupCoarseFine:
+		coarse, ok := positions[group.Sliders[0]]
+		if !ok {
+			return 0, false
+		}
+
+		// an untouched fine slider doesn't change anything
+		fine, ok := positions[group.Sliders[1]]
+		if !ok {
+			fine = 0.5
+		}
+
+		// clamped, so 0 and 1 stay reachable with the fine slider anywhere
+		combined := float64(coarse) + (float64(fine)-0.5)*group.FineRange
+
+		return float32(math.Max(0, math.Min(1, combined))), true
+
+	default:
+		position, ok := positions[lastMoved]
+		return position, ok
+	}
+}
+
+func sliderGroupsFromConfig(
+	userConfig *viper.Viper,
+	sliderMapping *sliderMap,
+	warnInvalidValue func(message string, key string, keysAndValues ...interface{}),
+) []SliderGroup {
+	var rawGroups []SliderGroup
+
+	if err := userConfig.UnmarshalKey(configKeySliderGroups, &rawGroups); err != nil {
+		warnInvalidValue("Invalid slider groups specified, ignoring all of them",
+			configKeySliderGroups,
+			"error", err)
+
+		return []SliderGroup{}
+	}
+
+	groups := make([]SliderGroup, 0, len(rawGroups))
+	names := make(map[string]bool, len(rawGroups))
+
+	for idx, group := range rawGroups {
+		if err := group.validate(); err != nil {
+			warnInvalidValue("Invalid slider group specified, ignoring it",
+				configKeySliderGroups,
+				"index", idx,
+				"error", err)
+
+			continue
+		}
+
+		if names[strings.ToLower(group.Name)] {
+			warnInvalidValue("Duplicate slider group name specified, ignoring it",
+				configKeySliderGroups,
+				"index", idx,
+				"name", group.Name)
+
+			continue
+		}
+
+		names[strings.ToLower(group.Name)] = true
+
+		// not invalid as such, but likely a leftover from before the sliders were grouped
+		for _, sliderIdx := range group.Sliders {
+			targets, _ := sliderMapping.get(sliderIdx)
+
+			for _, target := range targets {
+				if group.hasTarget(target) {
+					warnInvalidValue("Grouped slider is also mapped to one of its group's targets, the group takes precedence",
+						configKeySliderGroups,
+						"group", group.Name,
+						"sliderIdx", sliderIdx,
+						"target", target)
+				}
+			}
+		}
+
+		groups = append(groups, group)
+	}
+
+	return groups
+}
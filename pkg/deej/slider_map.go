@@ -1,13 +1,18 @@
 package deej
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/thoas/go-funk"
 )
 
+var errSliderNotMapped = errors.New("slider is not mapped")
+
 type sliderMap struct {
 	m    map[int][]string
 	lock sync.Locker
@@ -25,7 +30,10 @@ func sliderMapFromConfigs(userMapping map[string][]string, internalMapping map[s
 
 	// copy targets from user config, ignoring empty values
 	for sliderIdxString, targets := range userMapping {
-		sliderIdx, _ := strconv.Atoi(sliderIdxString)
+		sliderIdx, err := strconv.Atoi(sliderIdxString)
+		if err != nil {
+			continue
+		}
 
 		resultMap.set(sliderIdx, funk.FilterString(targets, func(s string) bool {
 			return s != ""
@@ -34,7 +42,10 @@ func sliderMapFromConfigs(userMapping map[string][]string, internalMapping map[s
 
 	// add targets from internal configs, ignoring duplicate or empty values
 	for sliderIdxString, targets := range internalMapping {
-		sliderIdx, _ := strconv.Atoi(sliderIdxString)
+		sliderIdx, err := strconv.Atoi(sliderIdxString)
+		if err != nil {
+			continue
+		}
 
 		existingTargets, ok := resultMap.get(sliderIdx)
 		if !ok {
@@ -52,6 +63,41 @@ func sliderMapFromConfigs(userMapping map[string][]string, internalMapping map[s
 	return resultMap
 }
 
+// warnInvalidSliderIndices warns about the indices of a mapping that no slider (or button) could ever have.
+// ones that aren't whole numbers are left out of the mapping, negative ones are kept so they can be removed
+func warnInvalidSliderIndices(
+	mapping map[string][]string,
+	key string,
+	warnInvalidValue func(message string, key string, keysAndValues ...interface{}),
+) {
+	for sliderIdxString := range mapping {
+		sliderIdx, err := strconv.Atoi(sliderIdxString)
+		if err != nil {
+			warnInvalidValue("Invalid index specified, it must be a whole number - ignoring it",
+				key,
+				"invalidValue", sliderIdxString)
+
+			continue
+		}
+
+		if sliderIdx < 0 {
+			warnInvalidValue("Negative index specified, it won't ever be moved",
+				key,
+				"invalidValue", sliderIdx)
+		}
+	}
+}
+
+func sliderMapFromRaw(mapping map[int][]string) *sliderMap {
+	resultMap := newSliderMap()
+
+	for key, value := range mapping {
+		resultMap.set(key, value)
+	}
+
+	return resultMap
+}
+
 func (m *sliderMap) iterate(f func(int, []string)) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
@@ -76,6 +122,22 @@ func (m *sliderMap) set(key int, value []string) {
 	m.m[key] = value
 }
 
+// raw returns a deep copy of the mapping, safe to hand out
+func (m *sliderMap) raw() map[int][]string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	result := make(map[int][]string, len(m.m))
+
+	for key, value := range m.m {
+		valueCopy := make([]string, len(value))
+		copy(valueCopy, value)
+		result[key] = valueCopy
+	}
+
+	return result
+}
+
 func (m *sliderMap) String() string {
 	m.lock.Lock()
 	defer m.lock.Unlock()
@@ -90,3 +152,66 @@ func (m *sliderMap) String() string {
 
 	return fmt.Sprintf("<%d sliders mapped to %d targets>", sliderCount, targetCount)
 }
+
+// targetIdentity returns what makes two mapped targets the same one: their name without any weight, which is
+// case-insensitive for everything but regular expressions
+func targetIdentity(target string) string {
+	name := strings.TrimSpace(targetName(target))
+
+	if isRegexTarget(name) {
+		return name
+	}
+
+	return strings.ToLower(name)
+}
+
+// dedupeTargets returns a slider's targets with any repeats of the same target removed, keeping the first one
+// (and its weight). the removed ones are returned as well, as they were given
+func dedupeTargets(targets []string) ([]string, []string) {
+	deduped := make([]string, 0, len(targets))
+	duplicates := []string{}
+	seen := make(map[string]bool, len(targets))
+
+	for _, target := range targets {
+		identity := targetIdentity(target)
+
+		if seen[identity] {
+			duplicates = append(duplicates, target)
+			continue
+		}
+
+		seen[identity] = true
+		deduped = append(deduped, target)
+	}
+
+	return deduped, duplicates
+}
+
+// otherSlidersMapping returns every slider but the given one that maps each of its targets, sorted.
+// targets no other slider maps are left out
+func otherSlidersMapping(mapping map[int][]string, sliderIdx int) map[string][]int {
+	result := make(map[string][]int)
+
+	identities := make(map[string]string)
+	for _, target := range mapping[sliderIdx] {
+		identities[targetIdentity(target)] = target
+	}
+
+	for otherIdx, targets := range mapping {
+		if otherIdx == sliderIdx {
+			continue
+		}
+
+		for _, target := range targets {
+			if own, ok := identities[targetIdentity(target)]; ok {
+				result[own] = append(result[own], otherIdx)
+			}
+		}
+	}
+
+	for _, sliders := range result {
+		sort.Ints(sliders)
+	}
+
+	return result
+}
@@ -0,0 +1,197 @@
+package deej
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// with persist_slider_positions enabled, deej keeps the last value of every slider in a file next to config.yaml,
+// and moves the sliders back to them on startup - so the levels are there again after a reboot, before the board
+// sends anything. the board's first line still moves every slider to where it actually is, so the saved values
+// only last until then (or for as long as the board isn't connected)
+
+const (
+
+	// kept next to config.yaml, just like the self-signed certificate
+	sliderPositionsFilepath = "slider-positions.json"
+
+	// how often the values are saved while they change. they're saved once more on exit
+	sliderPositionsSaveInterval = 5 * time.Second
+)
+
+// SliderPositionStore saves slider values to disk, and restores them on startup
+type SliderPositionStore struct {
+	deej   *Deej
+	logger *zap.SugaredLogger
+
+	lock sync.Mutex
+
+	// what's in the file, so it's only written when something changed
+	saved map[int]float32
+
+	stopChannel chan bool
+}
+
+type sliderPositionsFile struct {
+	SavedAt time.Time          `json:"savedAt"`
+	Sliders map[string]float32 `json:"sliders"`
+}
+
+// NewSliderPositionStore creates a SliderPositionStore
+func NewSliderPositionStore(deej *Deej, logger *zap.SugaredLogger) *SliderPositionStore {
+	logger = logger.Named("slider_positions")
+
+	store := &SliderPositionStore{
+		deej:        deej,
+		logger:      logger,
+		saved:       map[int]float32{},
+		stopChannel: make(chan bool),
+	}
+
+	logger.Debug("Created slider position store instance")
+
+	return store
+}
+
+// Restore moves every slider to its saved value, if enabled. it has to run before the first connection to the
+// board is made, so the board's first line comes after (and overrides) the saved values
+func (store *SliderPositionStore) Restore() {
+	if !store.deej.config.PersistSliderPositions {
+		return
+	}
+
+	positions, err := readSliderPositions()
+	if errors.Is(err, os.ErrNotExist) {
+		store.logger.Debugw("No saved slider positions yet", "path", sliderPositionsFilepath)
+		return
+	}
+
+	if err != nil {
+		store.logger.Warnw("Failed to read saved slider positions, ignoring them", "path", sliderPositionsFilepath, "error", err)
+		return
+	}
+
+	store.lock.Lock()
+	store.saved = positions
+	store.lock.Unlock()
+
+	for sliderIdx, value := range positions {
+		store.deej.serial.SetSliderValue(sliderIdx, value)
+	}
+
+	store.logger.Infow("Restored saved slider positions", "sliders", len(positions))
+}
+
+// Start saves the slider values in the background every once in a while, for as long as it's enabled
+func (store *SliderPositionStore) Start() {
+	go func() {
+		ticker := time.NewTicker(sliderPositionsSaveInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				store.save()
+			case <-store.stopChannel:
+				return
+			}
+		}
+	}()
+}
+
+// Stop saves the slider values one last time
+func (store *SliderPositionStore) Stop() {
+	select {
+	case store.stopChannel <- true:
+	default:
+	}
+
+	store.save()
+}
+
+func (store *SliderPositionStore) save() {
+	if !store.deej.config.PersistSliderPositions {
+		return
+	}
+
+	positions := store.deej.serial.KnownSliderValues()
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	if reflect.DeepEqual(positions, store.saved) {
+		return
+	}
+
+	if err := writeSliderPositions(positions); err != nil {
+		store.logger.Warnw("Failed to save slider positions", "path", sliderPositionsFilepath, "error", err)
+		return
+	}
+
+	store.saved = positions
+}
+
+func readSliderPositions() (map[int]float32, error) {
+	data, err := os.ReadFile(sliderPositionsFilepath)
+	if err != nil {
+		return nil, fmt.Errorf("read slider positions: %w", err)
+	}
+
+	var file sliderPositionsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse slider positions: %w", err)
+	}
+
+	positions := make(map[int]float32, len(file.Sliders))
+
+	for key, value := range file.Sliders {
+		sliderIdx, err := strconv.Atoi(key)
+		if err != nil || sliderIdx < 0 {
+			return nil, fmt.Errorf("invalid slider index %q", key)
+		}
+
+		if value < 0 || value > 1 {
+			return nil, fmt.Errorf("invalid value %v for slider %d", value, sliderIdx)
+		}
+
+		positions[sliderIdx] = value
+	}
+
+	return positions, nil
+}
+
+func writeSliderPositions(positions map[int]float32) error {
+	file := sliderPositionsFile{
+		SavedAt: time.Now(),
+		Sliders: make(map[string]float32, len(positions)),
+	}
+
+	for sliderIdx, value := range positions {
+		file.Sliders[strconv.Itoa(sliderIdx)] = value
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal slider positions: %w", err)
+	}
+
+	// same as the config, a crash halfway through writing shouldn't leave a broken file behind
+	tmpPath := sliderPositionsFilepath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("write temp slider positions: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, sliderPositionsFilepath); err != nil {
+		return fmt.Errorf("rename slider positions: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,275 @@
+package deej
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/viper"
+)
+
+// SliderSettings holds per-slider overrides of the global slider behavior.
+// fields left unset fall back to their global counterparts
+type SliderSettings struct {
+	Invert         *bool        `json:"invert,omitempty"`
+	NoiseThreshold *float64     `json:"noiseThreshold,omitempty" schema:"minimum=0,exclusiveMaximum=1"`
+	VolumeCurve    *VolumeCurve `json:"volumeCurve,omitempty"`
+	Smoothing      *Smoothing   `json:"smoothing,omitempty"`
+	Calibration    *Calibration `json:"calibration,omitempty"`
+	DeadZone       *float64     `json:"deadZone,omitempty" schema:"minimum=0,exclusiveMaximum=maxDeadZone"`
+
+	// holds the slider there regardless of its input, see slider_fixed_value.go
+	FixedValue *float64 `json:"fixedValue,omitempty" schema:"minimum=0,maximum=1"`
+
+	// only shown in the UI (i.e. "by the keyboard"), it doesn't change what the slider does
+	Label *string `json:"label,omitempty" schema:"maxLength=maxSliderLabelLength"`
+}
+
+const (
+	configKeySliderSettingInvert         = "invert"
+	configKeySliderSettingNoiseThreshold = "noise_threshold"
+	configKeySliderSettingVolumeCurve    = "volume_curve"
+	configKeySliderSettingCurveExponent  = "volume_curve_exponent"
+	configKeySliderSettingSmoothing      = "smoothing"
+	configKeySliderSettingSmoothingRate  = "smoothing_rate"
+	configKeySliderSettingSmoothingTime  = "smoothing_time"
+	configKeySliderSettingCalibrationMin = "calibration_min"
+	configKeySliderSettingCalibrationMax = "calibration_max"
+	configKeySliderSettingDeadZone       = "dead_zone"
+	configKeySliderSettingFixedValue     = "fixed_value"
+	configKeySliderSettingLabel          = "label"
+
+	// in characters. a label is a few words, not a description
+	maxSliderLabelLength = 32
+)
+
+var errInvalidSliderLabel = fmt.Errorf("labels can be at most %d characters long", maxSliderLabelLength)
+
+// normalizeSliderLabel trims a label, and returns an error if it's too long. an empty label means no label
+func normalizeSliderLabel(label string) (string, error) {
+	label = strings.TrimSpace(label)
+
+	if utf8.RuneCountInString(label) > maxSliderLabelLength {
+		return "", errInvalidSliderLabel
+	}
+
+	return label, nil
+}
+
+// noise thresholds are a fraction of the full slider range, and a threshold of 1 or more would never let it move
+func validNoiseThreshold(threshold float64) bool {
+	return threshold >= 0 && threshold < 1
+}
+
+// invalid settings are reported through warnInvalidValue and left unset
+func sliderSettingsFromConfig(
+	userConfig *viper.Viper,
+	warnInvalidValue func(message string, key string, keysAndValues ...interface{}),
+) map[int]SliderSettings {
+	result := make(map[int]SliderSettings)
+
+	for sliderIdxString := range userConfig.GetStringMap(configKeySliderSettings) {
+		sliderIdx, err := strconv.Atoi(sliderIdxString)
+		if err != nil || sliderIdx < 0 {
+			continue
+		}
+
+		settings := SliderSettings{}
+		keyPrefix := fmt.Sprintf("%s.%s.", configKeySliderSettings, sliderIdxString)
+
+		if userConfig.IsSet(keyPrefix + configKeySliderSettingInvert) {
+			invert := userConfig.GetBool(keyPrefix + configKeySliderSettingInvert)
+			settings.Invert = &invert
+		}
+
+		if userConfig.IsSet(keyPrefix + configKeySliderSettingNoiseThreshold) {
+			noiseThreshold := userConfig.GetFloat64(keyPrefix + configKeySliderSettingNoiseThreshold)
+
+			if validNoiseThreshold(noiseThreshold) {
+				settings.NoiseThreshold = &noiseThreshold
+			} else {
+				warnInvalidValue("Invalid slider noise threshold specified, using global value instead",
+					keyPrefix+configKeySliderSettingNoiseThreshold,
+					"invalidValue", noiseThreshold)
+			}
+		}
+
+		if userConfig.IsSet(keyPrefix + configKeySliderSettingVolumeCurve) {
+			volumeCurve := VolumeCurve{
+				Type:     userConfig.GetString(keyPrefix + configKeySliderSettingVolumeCurve),
+				Exponent: userConfig.GetFloat64(keyPrefix + configKeySliderSettingCurveExponent),
+			}
+
+			if err := volumeCurve.validate(); err == nil {
+				settings.VolumeCurve = &volumeCurve
+			} else {
+				warnInvalidValue("Invalid slider volume curve specified, using global value instead",
+					keyPrefix+configKeySliderSettingVolumeCurve,
+					"error", err)
+			}
+		}
+
+		if userConfig.IsSet(keyPrefix + configKeySliderSettingSmoothing) {
+			smoothing := Smoothing{
+				Type: userConfig.GetString(keyPrefix + configKeySliderSettingSmoothing),
+				Rate: userConfig.GetFloat64(keyPrefix + configKeySliderSettingSmoothingRate),
+				Time: userConfig.GetFloat64(keyPrefix + configKeySliderSettingSmoothingTime),
+			}
+
+			if err := smoothing.validate(); err == nil {
+				settings.Smoothing = &smoothing
+			} else {
+				warnInvalidValue("Invalid slider smoothing specified, not smoothing this slider",
+					keyPrefix+configKeySliderSettingSmoothing,
+					"error", err)
+			}
+		}
+
+		// either end can be left out, which leaves it where the board's range ends
+		if userConfig.IsSet(keyPrefix+configKeySliderSettingCalibrationMin) ||
+			userConfig.IsSet(keyPrefix+configKeySliderSettingCalibrationMax) {
+
+			calibration := defaultCalibration
+
+			if userConfig.IsSet(keyPrefix + configKeySliderSettingCalibrationMin) {
+				calibration.Min = userConfig.GetInt(keyPrefix + configKeySliderSettingCalibrationMin)
+			}
+
+			if userConfig.IsSet(keyPrefix + configKeySliderSettingCalibrationMax) {
+				calibration.Max = userConfig.GetInt(keyPrefix + configKeySliderSettingCalibrationMax)
+			}
+
+			if err := calibration.validate(); err == nil {
+				settings.Calibration = &calibration
+			} else {
+				warnInvalidValue("Invalid slider calibration specified, using the full range instead",
+					keyPrefix+configKeySliderSettingCalibrationMin,
+					"error", err)
+			}
+		}
+
+		if userConfig.IsSet(keyPrefix + configKeySliderSettingDeadZone) {
+			deadZone := userConfig.GetFloat64(keyPrefix + configKeySliderSettingDeadZone)
+
+			if validDeadZone(deadZone) {
+				settings.DeadZone = &deadZone
+			} else {
+				warnInvalidValue("Invalid slider dead zone specified, it must be at least 0 and less than 0.5 - not using one",
+					keyPrefix+configKeySliderSettingDeadZone,
+					"invalidValue", deadZone)
+			}
+		}
+
+		if userConfig.IsSet(keyPrefix + configKeySliderSettingFixedValue) {
+			fixedValue := userConfig.GetFloat64(keyPrefix + configKeySliderSettingFixedValue)
+
+			if validFixedValue(fixedValue) {
+				settings.FixedValue = &fixedValue
+			} else {
+				warnInvalidValue("Invalid slider fixed value specified, it must be between 0 and 1 - not fixing the slider",
+					keyPrefix+configKeySliderSettingFixedValue,
+					"invalidValue", fixedValue)
+			}
+		}
+
+		if userConfig.IsSet(keyPrefix + configKeySliderSettingLabel) {
+			label, err := normalizeSliderLabel(userConfig.GetString(keyPrefix + configKeySliderSettingLabel))
+
+			if err != nil {
+				warnInvalidValue("Invalid slider label specified, not using it",
+					keyPrefix+configKeySliderSettingLabel,
+					"error", err)
+			} else if label != "" {
+				settings.Label = &label
+			}
+		}
+
+		result[sliderIdx] = settings
+	}
+
+	return result
+}
+
+// copySliderSettings returns a copy of every slider's settings. the values they point to are shared, since an edit
+// replaces a setting rather than changing what it points to
+func copySliderSettings(settings map[int]SliderSettings) map[int]SliderSettings {
+	result := make(map[int]SliderSettings, len(settings))
+
+	for sliderIdx, sliderSettings := range settings {
+		result[sliderIdx] = sliderSettings
+	}
+
+	return result
+}
+
+// sliderSettingsToConfigValue returns the settings of all sliders in the shape they're written to config.yaml in
+func sliderSettingsToConfigValue(settings map[int]SliderSettings) map[string]interface{} {
+	value := make(map[string]interface{})
+
+	for sliderIdx, ss := range settings {
+
+		// sliders that don't override anything are left out entirely
+		if sliderValue := ss.toConfigValue(); sliderValue != nil {
+			value[strconv.Itoa(sliderIdx)] = sliderValue
+		}
+	}
+
+	return value
+}
+
+// toConfigValue returns the settings in the shape they're written to config.yaml in,
+// or nil if nothing is overridden
+func (ss SliderSettings) toConfigValue() map[string]interface{} {
+	value := make(map[string]interface{})
+
+	if ss.Invert != nil {
+		value[configKeySliderSettingInvert] = *ss.Invert
+	}
+
+	if ss.NoiseThreshold != nil {
+		value[configKeySliderSettingNoiseThreshold] = *ss.NoiseThreshold
+	}
+
+	if ss.VolumeCurve != nil {
+		value[configKeySliderSettingVolumeCurve] = ss.VolumeCurve.Type
+
+		if ss.VolumeCurve.Type == volumeCurvePower {
+			value[configKeySliderSettingCurveExponent] = ss.VolumeCurve.Exponent
+		}
+	}
+
+	if ss.Smoothing != nil {
+		value[configKeySliderSettingSmoothing] = ss.Smoothing.Type
+
+		switch ss.Smoothing.Type {
+		case smoothingSlew:
+			value[configKeySliderSettingSmoothingRate] = ss.Smoothing.Rate
+		case smoothingEMA:
+			value[configKeySliderSettingSmoothingTime] = ss.Smoothing.Time
+		}
+	}
+
+	if ss.Calibration != nil {
+		value[configKeySliderSettingCalibrationMin] = ss.Calibration.Min
+		value[configKeySliderSettingCalibrationMax] = ss.Calibration.Max
+	}
+
+	if ss.DeadZone != nil {
+		value[configKeySliderSettingDeadZone] = *ss.DeadZone
+	}
+
+	if ss.FixedValue != nil {
+		value[configKeySliderSettingFixedValue] = *ss.FixedValue
+	}
+
+	if ss.Label != nil {
+		value[configKeySliderSettingLabel] = *ss.Label
+	}
+
+	if len(value) == 0 {
+		return nil
+	}
+
+	return value
+}
@@ -0,0 +1,108 @@
+package deej
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// sliderSettingsFromYAML parses slider_settings out of a config, along with the keys it warned about
+func sliderSettingsFromYAML(t *testing.T, configYAML string) (map[int]SliderSettings, []string) {
+	t.Helper()
+
+	userConfig := viper.New()
+	userConfig.SetConfigType("yaml")
+
+	if err := userConfig.ReadConfig(strings.NewReader(configYAML)); err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+
+	warned := []string{}
+	settings := sliderSettingsFromConfig(userConfig, func(message string, key string, keysAndValues ...interface{}) {
+		warned = append(warned, key)
+	})
+
+	return settings, warned
+}
+
+func TestSliderNoiseThreshold(t *testing.T) {
+	settings, warned := sliderSettingsFromYAML(t, `
+slider_settings:
+  0:
+    noise_threshold: 0.1
+  1:
+    noise_threshold: 1
+  2:
+    noise_threshold: -0.2
+  3:
+    noise_threshold: 0
+`)
+
+	if want := 2; len(warned) != want {
+		t.Errorf("warned about %v, want %d invalid thresholds", warned, want)
+	}
+
+	tests := []struct {
+		name                string
+		sliderIdx           int
+		globalThreshold     float64
+		noiseReductionLevel string
+		want                float64
+	}{
+		{"slider's own", 0, 0.05, "high", 0.1},
+		{"slider's own of 0", 3, 0.05, "high", 0},
+		{"global over an invalid one", 1, 0.05, "high", 0.05},
+		{"level over an invalid one", 2, 0, "high", 0.035},
+		{"global", 5, 0.2, "low", 0.2},
+		{"level", 5, 0, "low", 0.015},
+		{"default level", 5, 0, "", 0.025},
+	}
+
+	for _, test := range tests {
+		cc := &CanonicalConfig{
+			SliderSettings:      settings,
+			NoiseThreshold:      test.globalThreshold,
+			NoiseReductionLevel: test.noiseReductionLevel,
+		}
+
+		if got := cc.SliderNoiseThreshold(test.sliderIdx); got != test.want {
+			t.Errorf("%s: SliderNoiseThreshold(%d) = %v, want %v", test.name, test.sliderIdx, got, test.want)
+		}
+	}
+}
+
+func TestSliderVolumeCurve(t *testing.T) {
+	settings, warned := sliderSettingsFromYAML(t, `
+slider_settings:
+  0:
+    volume_curve: power
+    volume_curve_exponent: 3
+  1:
+    volume_curve: Logarithmic
+  2:
+    volume_curve: cubic
+`)
+
+	if len(warned) != 1 || warned[0] != "slider_settings.2.volume_curve" {
+		t.Errorf("warned about %v, want only slider 2's curve", warned)
+	}
+
+	cc := &CanonicalConfig{SliderSettings: settings, VolumeCurve: VolumeCurve{Type: volumeCurvePower, Exponent: 2}}
+
+	tests := []struct {
+		sliderIdx int
+		want      VolumeCurve
+	}{
+		{0, VolumeCurve{Type: volumeCurvePower, Exponent: 3}},
+		{1, VolumeCurve{Type: volumeCurveLogarithmic}},
+		{2, VolumeCurve{Type: volumeCurvePower, Exponent: 2}},
+		{3, VolumeCurve{Type: volumeCurvePower, Exponent: 2}},
+	}
+
+	for _, test := range tests {
+		if got := cc.SliderVolumeCurve(test.sliderIdx); got != test.want {
+			t.Errorf("SliderVolumeCurve(%d) = %+v, want %+v", test.sliderIdx, got, test.want)
+		}
+	}
+}
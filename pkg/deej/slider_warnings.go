@@ -0,0 +1,155 @@
+package deej
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// mapped sliders that aren't on any board do nothing when they're edited, which is easy to miss. their mappings
+// are kept as they are (the board might just not be plugged in, or have fewer sliders than it's about to), deej
+// only warns about them
+
+// OrphanedSliders returns the mapped slider indices that none of the boards send, in order. it's empty until every
+// connected board has reported its sliders, and with no boards connected, since there's nothing to compare against.
+// a board that's configured but not connected is assumed to have every slider it has room for.
+// sliders that are moved over MIDI don't have to be on a board at all, so they're never orphaned
+func (sio *SerialIO) OrphanedSliders(mapping map[int][]string) []int {
+	connections := sio.currentConnections()
+
+	sio.sliderLock.Lock()
+	defer sio.sliderLock.Unlock()
+
+	type sliderRange struct{ start, end int }
+	ranges := []sliderRange{}
+
+	anyConnected := false
+
+	for _, connection := range connections {
+
+		// a board that isn't plugged in could have any of the sliders it has room for once it is
+		if !connection.isConnected() {
+			end := connection.indexLimit
+			if end == 0 {
+				end = math.MaxInt32
+			}
+
+			ranges = append(ranges, sliderRange{start: connection.source.IndexOffset, end: end})
+			continue
+		}
+
+		// and so could a board that was only just connected
+		if connection.lastKnownNumSliders == 0 {
+			return []int{}
+		}
+
+		anyConnected = true
+		ranges = append(ranges, sliderRange{
+			start: connection.source.IndexOffset,
+			end:   connection.source.IndexOffset + connection.lastKnownNumSliders,
+		})
+	}
+
+	orphaned := []int{}
+
+	if !anyConnected {
+		return orphaned
+	}
+
+	midi := sio.deej.config.MIDI.Enabled
+
+	for sliderIdx, targets := range mapping {
+		if len(targets) == 0 {
+			continue
+		}
+
+		if _, ok := sio.deej.config.MIDIMapping[sliderIdx]; ok && midi {
+			continue
+		}
+
+		onBoard := false
+
+		for _, r := range ranges {
+			if sliderIdx >= r.start && sliderIdx < r.end {
+				onBoard = true
+				break
+			}
+		}
+
+		if !onBoard {
+			orphaned = append(orphaned, sliderIdx)
+		}
+	}
+
+	sort.Ints(orphaned)
+
+	return orphaned
+}
+
+// SliderWarnings returns human-readable warnings about the slider mapping not matching the boards, for the API
+func (sio *SerialIO) SliderWarnings() []string {
+	orphaned := sio.OrphanedSliders(sio.deej.config.GetSliderMappingRaw())
+	if len(orphaned) == 0 {
+		return []string{}
+	}
+
+	return []string{orphanedSlidersMessage(orphaned)}
+}
+
+// warnOrphanedSliders logs the orphaned sliders whenever they change, so each mismatch is only logged once
+func (sio *SerialIO) warnOrphanedSliders() {
+	orphaned := sio.OrphanedSliders(sio.deej.config.GetSliderMappingRaw())
+	description := fmt.Sprint(orphaned)
+
+	sio.sliderLock.Lock()
+	changed := description != sio.loggedOrphanedSliders
+	sio.loggedOrphanedSliders = description
+	sio.sliderLock.Unlock()
+
+	if changed && len(orphaned) > 0 {
+		sio.logger.Warnw("Some mapped sliders aren't on the board, moving them does nothing until they are",
+			"sliders", orphaned,
+			"hardwareSliderCount", sio.HardwareSliderCount())
+	}
+}
+
+// reasons a mapped slider is listed as invalid by GET /api/sliders
+const (
+	invalidSliderNegative   = "negative"
+	invalidSliderNotOnBoard = "notOnBoard"
+)
+
+// InvalidSliders returns why each mapped slider that can't be moved can't be: negative indices never can, and
+// orphaned sliders can't until they're on a board
+func (sio *SerialIO) InvalidSliders(mapping map[int][]string) map[int]string {
+	invalid := make(map[int]string)
+
+	for _, sliderIdx := range sio.OrphanedSliders(mapping) {
+		invalid[sliderIdx] = invalidSliderNotOnBoard
+	}
+
+	// these are flagged even with nothing to compare against, since no board sends them
+	for sliderIdx := range mapping {
+		if sliderIdx < 0 {
+			invalid[sliderIdx] = invalidSliderNegative
+		}
+	}
+
+	return invalid
+}
+
+func orphanedSlidersMessage(orphaned []int) string {
+	indices := make([]string, len(orphaned))
+	for idx, sliderIdx := range orphaned {
+		indices[idx] = strconv.Itoa(sliderIdx)
+	}
+
+	if len(orphaned) == 1 {
+		return fmt.Sprintf("Slider %s is mapped, but isn't on the board - moving it does nothing until it is", indices[0])
+	}
+
+	return fmt.Sprintf("Sliders %s are mapped, but aren't on the board - moving them does nothing until they are",
+		strings.Join(indices, ", "))
+}
@@ -0,0 +1,120 @@
+package deej
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	smoothingNone = "none"
+
+	// moves at most a fixed amount per second, so the ramp is linear
+	smoothingSlew = "slew"
+
+	// an exponential moving average, which starts out fast and eases into the final value
+	smoothingEMA = "ema"
+
+	// end to end in a quarter of a second
+	defaultSmoothingRate = 4.0
+
+	// in seconds, the time it takes to cover about 63% of the remaining way
+	defaultSmoothingTime = 0.05
+
+	// an average never quite gets there on its own. this is well below the 0.01 steps slider values come in,
+	// so snapping to the final value from here is inaudible, and is what makes true 0 and 100 reachable
+	smoothingSnapDistance = 0.002
+
+	// how often a slider that's still on its way to its final value is stepped
+	smoothingStepInterval = 10 * time.Millisecond
+)
+
+// Smoothing limits how fast the volume set by a slider can change, so yanking it doesn't cause audible zipper noise
+type Smoothing struct {
+	Type string `json:"type" schema:"enum=smoothings"`
+
+	// only used by slew: the most the slider's position can change per second, as a fraction of its full range
+	Rate float64 `json:"rate,omitempty" schema:"minimum=0"`
+
+	// only used by ema: the average's time constant, in seconds
+	Time float64 `json:"time,omitempty" schema:"minimum=0"`
+}
+
+// validate makes sure the smoothing can be applied, filling in the default parameter for its type
+func (s *Smoothing) validate() error {
+	s.Type = strings.ToLower(s.Type)
+
+	switch s.Type {
+	case smoothingNone:
+		s.Rate, s.Time = 0, 0
+
+	case smoothingSlew:
+		s.Time = 0
+
+		if s.Rate == 0 {
+			s.Rate = defaultSmoothingRate
+		}
+
+		if s.Rate < 0 {
+			return fmt.Errorf("slew rate must be positive, got %v", s.Rate)
+		}
+
+	case smoothingEMA:
+		s.Rate = 0
+
+		if s.Time == 0 {
+			s.Time = defaultSmoothingTime
+		}
+
+		if s.Time < 0 {
+			return fmt.Errorf("smoothing time must be positive, got %v", s.Time)
+		}
+
+	default:
+		return fmt.Errorf("unknown smoothing type %q (supported: %s, %s, %s)",
+			s.Type, smoothingNone, smoothingSlew, smoothingEMA)
+	}
+
+	return nil
+}
+
+// step moves a slider's position from current towards target, as far as the given time allows.
+// it always returns exactly target once it's there, and never overshoots it
+func (s Smoothing) step(current float32, target float32, elapsed time.Duration) float32 {
+	distance := float64(target - current)
+
+	switch s.Type {
+	case smoothingSlew:
+		maxDistance := s.Rate * elapsed.Seconds()
+
+		if math.Abs(distance) <= maxDistance {
+			return target
+		}
+
+		return current + float32(math.Copysign(maxDistance, distance))
+
+	case smoothingEMA:
+		remaining := distance * math.Exp(-elapsed.Seconds()/s.Time)
+
+		if math.Abs(remaining) < smoothingSnapDistance {
+			return target
+		}
+
+		return target - float32(remaining)
+
+	default:
+		return target
+	}
+}
+
+// sliderSmoother tracks where a slider's position is on its way to where it was last moved to
+type sliderSmoother struct {
+	position float32
+	target   float32
+	lastStep time.Time
+}
+
+func (ss *sliderSmoother) ramping() bool {
+	return ss.position != ss.target
+}
@@ -0,0 +1,79 @@
+package deej
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSmoothingStep(t *testing.T) {
+	slew := Smoothing{Type: smoothingSlew, Rate: 4}
+	ema := Smoothing{Type: smoothingEMA, Time: 0.05}
+
+	tests := []struct {
+		name      string
+		smoothing Smoothing
+		current   float32
+		target    float32
+		elapsed   time.Duration
+		want      float32
+	}{
+		{"none jumps", Smoothing{Type: smoothingNone}, 0, 1, time.Millisecond, 1},
+
+		{"slew up", slew, 0, 1, 100 * time.Millisecond, 0.4},
+		{"slew down", slew, 1, 0, 100 * time.Millisecond, 0.6},
+		{"slew arrives", slew, 0.9, 1, 100 * time.Millisecond, 1},
+		{"slew doesn't overshoot", slew, 0, 1, time.Second, 1},
+
+		// one time constant covers 1 - 1/e of the way
+		{"ema up", ema, 0, 1, 50 * time.Millisecond, float32(1 - math.Exp(-1))},
+		{"ema down", ema, 1, 0, 50 * time.Millisecond, float32(math.Exp(-1))},
+		{"ema snaps once close", ema, 0.999, 1, 10 * time.Millisecond, 1},
+		{"ema snaps to zero", ema, 0.001, 0, 10 * time.Millisecond, 0},
+	}
+
+	for _, test := range tests {
+		got := test.smoothing.step(test.current, test.target, test.elapsed)
+
+		if math.Abs(float64(got-test.want)) > 0.0001 {
+			t.Errorf("%s: step(%v, %v, %v) = %v, want %v", test.name, test.current, test.target, test.elapsed, got, test.want)
+		}
+
+		// it never goes past where it's headed
+		if (test.target-test.current)*(test.target-got) < 0 {
+			t.Errorf("%s: stepped past %v to %v", test.name, test.target, got)
+		}
+	}
+}
+
+func TestSmoothingValidate(t *testing.T) {
+	tests := []struct {
+		smoothing Smoothing
+		want      Smoothing
+		wantErr   bool
+	}{
+		{Smoothing{Type: "None", Rate: 3, Time: 1}, Smoothing{Type: smoothingNone}, false},
+		{Smoothing{Type: "slew"}, Smoothing{Type: smoothingSlew, Rate: defaultSmoothingRate}, false},
+		{Smoothing{Type: "slew", Rate: 2, Time: 1}, Smoothing{Type: smoothingSlew, Rate: 2}, false},
+		{Smoothing{Type: "EMA"}, Smoothing{Type: smoothingEMA, Time: defaultSmoothingTime}, false},
+		{Smoothing{Type: "ema", Rate: 2, Time: 0.2}, Smoothing{Type: smoothingEMA, Time: 0.2}, false},
+
+		{Smoothing{Type: "slew", Rate: -1}, Smoothing{}, true},
+		{Smoothing{Type: "ema", Time: -1}, Smoothing{}, true},
+		{Smoothing{Type: "spline"}, Smoothing{}, true},
+	}
+
+	for _, test := range tests {
+		smoothing := test.smoothing
+		err := smoothing.validate()
+
+		if (err != nil) != test.wantErr {
+			t.Errorf("%+v.validate() returned %v, want an error: %v", test.smoothing, err, test.wantErr)
+			continue
+		}
+
+		if err == nil && smoothing != test.want {
+			t.Errorf("%+v.validate() made it %+v, want %+v", test.smoothing, smoothing, test.want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package deej
+
+import "sort"
+
+// SpecialTarget is a target that isn't an app: master, mic and system, deej's own deej.* targets, and every
+// device by name. which of them work depends on the platform and its audio backend, so the web UI asks for them
+// rather than assuming
+type SpecialTarget struct {
+	Key         string `json:"key"`
+	DisplayName string `json:"displayName"`
+	Description string `json:"description"`
+
+	// "special", or "device" for a specific device's volume
+	Kind string `json:"kind"`
+
+	// false where the backend can't do it, i.e. system sounds outside of windows
+	Supported bool `json:"supported"`
+
+	// a supported target with nothing behind it right now, i.e. system sounds before any system sound played.
+	// it can still be mapped, and its slider takes over with its next move once there is
+	Inactive bool `json:"inactive,omitempty"`
+}
+
+const (
+	specialTargetKindSpecial = "special"
+	specialTargetKindDevice  = "device"
+)
+
+// every session gets this through baseSession, but only a device's own session has a name to give
+type namedDeviceSession interface {
+	deviceName() (string, bool)
+}
+
+// SpecialTargets returns every special target deej knows about, along with the devices the backend currently has
+func (m *sessionMap) SpecialTargets() []SpecialTarget {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	// master, mic and system are sessions the backend provides (or doesn't), i.e. there's no mic without
+	// an input device
+	sessionBacked := func(key string) bool {
+		sessions, ok := m.m[key]
+		return ok && len(sessions) > 0 && m.keyControllable(key)
+	}
+
+	focusSupported := m.backend.SpecialTargetSupported(specialTargetTransformPrefix + specialTargetCurrentWindow)
+
+	targets := []SpecialTarget{
+		{
+			Key:         masterSessionName,
+			DisplayName: "Master Volume",
+			Description: "The default playback device's volume",
+			Supported:   sessionBacked(masterSessionName),
+		},
+		{
+			Key:         inputSessionName,
+			DisplayName: "Microphone",
+			Description: "The default recording device's input level",
+			Supported:   sessionBacked(inputSessionName),
+		},
+		{
+			Key:         systemSessionName,
+			DisplayName: "System Sounds",
+			Description: "The system sounds volume in the Windows mixer",
+			Supported:   m.hasSystemSounds() && m.keyControllable(systemSessionName),
+			Inactive:    m.hasSystemSounds() && len(m.m[systemSessionName]) == 0,
+		},
+		{
+			Key:         specialTargetTransformPrefix + specialTargetAllUnmapped,
+			DisplayName: "All Unmapped Apps",
+			Description: "Every app that isn't mapped to any slider",
+			Supported:   true,
+		},
+		{
+			Key:         specialTargetTransformPrefix + specialTargetCurrentWindow,
+			DisplayName: "Focused App",
+			Description: "Whichever app is in focus",
+			Supported:   focusSupported,
+		},
+	}
+
+	for idx := range targets {
+		targets[idx].Kind = specialTargetKindSpecial
+	}
+
+	devices := []SpecialTarget{}
+
+	for key, sessions := range m.m {
+		if len(sessions) == 0 {
+			continue
+		}
+
+		device, ok := sessions[0].(namedDeviceSession)
+		if !ok {
+			continue
+		}
+
+		name, ok := device.deviceName()
+		if !ok {
+			continue
+		}
+
+		displayName := sessions[0].DisplayName()
+		if displayName == "" {
+			displayName = name
+		}
+
+		devices = append(devices, SpecialTarget{
+			Key:         key,
+			DisplayName: displayName,
+			Description: "This device's volume, whether or not it's the default",
+			Kind:        specialTargetKindDevice,
+			Supported:   m.keyControllable(key),
+		})
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Key < devices[j].Key
+	})
+
+	return append(targets, devices...)
+}
@@ -0,0 +1,113 @@
+package deej
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// slider targets can also be patterns, which are matched against the keys of all live sessions:
+//   - wildcards, i.e. "chrome*.exe", where * matches any run of characters and ? matches a single one
+//   - regular expressions between slashes, i.e. "/^spotify/i". matching is always case-insensitive, just like
+//     other targets are, so the "i" flag is accepted but doesn't change anything
+//
+// special (master, system, mic) and device sessions can only be targeted by name, never by a pattern.
+// a session that matches targets on more than one slider is only ever controlled by one of them:
+// a target naming it exactly always wins, and otherwise it's the matching slider with the lowest index
+// (see target_precedence.go for how that fits in with groups and special targets)
+
+// isTargetPattern returns true if a target should be matched as a pattern, rather than taken as a session key
+func isTargetPattern(target string) bool {
+	target = targetName(target)
+
+	if strings.HasPrefix(strings.ToLower(target), specialTargetTransformPrefix) {
+		return false
+	}
+
+	return isRegexTarget(target) || strings.ContainsAny(target, "*?")
+}
+
+func isRegexTarget(target string) bool {
+	if !strings.HasPrefix(target, "/") {
+		return false
+	}
+
+	lastSlash := strings.LastIndex(target, "/")
+	if lastSlash == 0 {
+		return false
+	}
+
+	// the only flag we know is i
+	return strings.Trim(target[lastSlash+1:], "i") == ""
+}
+
+func compileTargetPattern(target string) (*regexp.Regexp, error) {
+	if isRegexTarget(target) {
+		return regexp.Compile("(?i)" + target[1:strings.LastIndex(target, "/")])
+	}
+
+	// wildcards become an anchored regex, with everything other than the wildcards themselves taken literally
+	var expr strings.Builder
+	expr.WriteString("(?i)^")
+
+	for _, char := range target {
+		switch char {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+
+	expr.WriteString("$")
+
+	return regexp.Compile(expr.String())
+}
+
+// getTargetPattern returns a pattern target's compiled form, or nil if it doesn't compile
+func (m *sessionMap) getTargetPattern(target string) *regexp.Regexp {
+	target = targetName(target)
+
+	m.patternLock.Lock()
+	defer m.patternLock.Unlock()
+
+	if pattern, ok := m.patterns[target]; ok {
+		return pattern
+	}
+
+	// invalid patterns are remembered as nil, so we only warn about each one once
+	pattern, err := compileTargetPattern(target)
+	if err != nil {
+		m.logger.Warnw("Invalid slider target pattern, ignoring it", "target", target, "error", err)
+		pattern = nil
+	}
+
+	m.patterns[target] = pattern
+
+	return pattern
+}
+
+// matchTargetPattern returns the keys of all live sessions that match a pattern target, regardless of precedence
+func (m *sessionMap) matchTargetPattern(target string) []string {
+	pattern := m.getTargetPattern(target)
+	if pattern == nil {
+		return nil
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	matches := []string{}
+
+	for key, sessions := range m.m {
+		if len(sessions) > 0 && !sessionTargetableByName(sessions[0]) && pattern.MatchString(key) {
+			matches = append(matches, key)
+		}
+	}
+
+	sort.Strings(matches)
+
+	return matches
+}
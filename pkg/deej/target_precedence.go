@@ -0,0 +1,231 @@
+package deej
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// a session can be reached by more than one slider at once, i.e. an app with a slider of its own that's also the
+// focused one on a deej.current slider. rather than whichever slider moved last winning, every session is only
+// ever controlled by one source, picked by target_precedence. by default that's:
+//   - mapping: a slider's own targets. one naming the session exactly beats a pattern matching it, and otherwise
+//     the lowest slider index wins
+//   - group: a slider group's targets, the first group (in config order) that has it
+//   - unmapped: deej.unmapped, wherever it's mapped
+//   - current: deej.current, wherever it's mapped
+//
+// deej.unmapped and deej.current go by their own place in the order, whether a slider or a group has them. a
+// target that's both on a slider and in one of that slider's groups is left to the group, same as always
+
+const (
+	targetSourceMapping  = "mapping"
+	targetSourceGroup    = "group"
+	targetSourceUnmapped = "unmapped"
+	targetSourceCurrent  = "current"
+)
+
+var defaultTargetPrecedence = []string{
+	targetSourceMapping,
+	targetSourceGroup,
+	targetSourceUnmapped,
+	targetSourceCurrent,
+}
+
+// SessionController is the single source that controls a session's volume
+type SessionController struct {
+
+	// mapping, group, unmapped or current
+	Source string `json:"source"`
+
+	// the slider or group the session is reached through, whichever it is
+	SliderID *int   `json:"sliderId,omitempty"`
+	Group    string `json:"group,omitempty"`
+
+	// the target that reaches it, as it's mapped
+	Target string `json:"target"`
+}
+
+// sessionControllers holds the controller of every session key that any slider or group reaches right now
+type sessionControllers map[string]SessionController
+
+// get returns a key's controller, nil if nothing controls it
+func (c sessionControllers) get(key string) *SessionController {
+	controller, ok := c[key]
+	if !ok {
+		return nil
+	}
+
+	return &controller
+}
+
+// sliderKeys returns the keys a slider controls through one of its own targets
+func (c sessionControllers) sliderKeys(sliderID int, target string) []string {
+	return c.keys(func(controller SessionController) bool {
+		return controller.SliderID != nil && *controller.SliderID == sliderID && controller.Target == target
+	})
+}
+
+// groupKeys returns the keys a group controls through one of its targets
+func (c sessionControllers) groupKeys(group string, target string) []string {
+	return c.keys(func(controller SessionController) bool {
+		return controller.SliderID == nil && controller.Group == group && controller.Target == target
+	})
+}
+
+func (c sessionControllers) keys(matches func(SessionController) bool) []string {
+	keys := []string{}
+	for key, controller := range c {
+		if matches(controller) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// targetSource returns which source in the order of precedence a mapped target belongs to
+func targetSource(target string, grouped bool) string {
+	switch strings.ToLower(targetName(target)) {
+	case specialTargetTransformPrefix + specialTargetAllUnmapped:
+		return targetSourceUnmapped
+	case specialTargetTransformPrefix + specialTargetCurrentWindow:
+		return targetSourceCurrent
+	}
+
+	if grouped {
+		return targetSourceGroup
+	}
+
+	return targetSourceMapping
+}
+
+// resolveControllers decides what controls each session key, by resolving every mapped target once. it takes
+// the session map's lock itself (through resolveTarget), so it must be called without holding it
+func (m *sessionMap) resolveControllers() sessionControllers {
+	type candidate struct {
+		controller SessionController
+		rank       int
+	}
+
+	// two ranks for each source, so a slider's patterns fit in right after its exact names
+	ranks := make(map[string]int, len(m.deej.config.TargetPrecedence))
+	for idx, source := range m.deej.config.TargetPrecedence {
+		ranks[source] = idx * 2
+	}
+
+	candidates := []candidate{}
+
+	mapping := m.deej.config.sliderMapping()
+
+	sliderIDs := []int{}
+	mapping.iterate(func(sliderID int, _ []string) {
+		sliderIDs = append(sliderIDs, sliderID)
+	})
+
+	sort.Ints(sliderIDs)
+
+	for _, sliderID := range sliderIDs {
+		sliderID := sliderID
+		targets, _ := mapping.get(sliderID)
+		groups := m.sliderGroups(sliderID)
+
+		for _, target := range targets {
+			if sliderGroupsHaveTarget(groups, target) {
+				continue
+			}
+
+			rank := ranks[targetSource(target, false)]
+
+			// a pattern only gets what no slider names exactly, so it ranks right after them
+			if isTargetPattern(target) {
+				rank++
+			}
+
+			candidates = append(candidates, candidate{
+				controller: SessionController{Source: targetSource(target, false), SliderID: &sliderID, Target: target},
+				rank:       rank,
+			})
+		}
+	}
+
+	for _, group := range m.deej.config.SliderGroups {
+		for _, target := range group.Targets {
+			candidates = append(candidates, candidate{
+				controller: SessionController{Source: targetSource(target, true), Group: group.Name, Target: target},
+				rank:       ranks[targetSource(target, true)],
+			})
+		}
+	}
+
+	// stable, so sliders keep their index order (and groups their config order) within the same rank
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].rank < candidates[j].rank
+	})
+
+	// deej.current asks the OS every time it's resolved, and should resolve the same way for everyone
+	resolved := make(map[string][]string)
+	controllers := make(sessionControllers)
+
+	for _, candidate := range candidates {
+		name := targetName(candidate.controller.Target)
+
+		keys, ok := resolved[name]
+		if !ok {
+			keys = m.resolveTarget(name)
+			resolved[name] = keys
+		}
+
+		for _, key := range keys {
+			if _, claimed := controllers[key]; !claimed {
+				controllers[key] = candidate.controller
+			}
+		}
+	}
+
+	return controllers
+}
+
+func targetPrecedenceFromConfig(
+	userConfig *viper.Viper,
+	warnInvalidValue func(message string, key string, keysAndValues ...interface{}),
+) []string {
+	configured := userConfig.GetStringSlice(configKeyTargetPrecedence)
+
+	precedence := []string{}
+	seen := make(map[string]bool)
+
+	for _, source := range configured {
+		source = strings.ToLower(strings.TrimSpace(source))
+
+		known := false
+		for _, candidate := range defaultTargetPrecedence {
+			known = known || candidate == source
+		}
+
+		if !known || seen[source] {
+			warnInvalidValue("Invalid target precedence specified, using default value",
+				configKeyTargetPrecedence,
+				"invalidValue", configured,
+				"allowedValues", defaultTargetPrecedence,
+				"defaultValue", defaultTargetPrecedence)
+
+			return defaultTargetPrecedence
+		}
+
+		seen[source] = true
+		precedence = append(precedence, source)
+	}
+
+	// whatever's left out goes last, in its usual order
+	for _, source := range defaultTargetPrecedence {
+		if !seen[source] {
+			precedence = append(precedence, source)
+		}
+	}
+
+	return precedence
+}
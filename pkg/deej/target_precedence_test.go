@@ -0,0 +1,136 @@
+package deej
+
+import (
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+const targetPrecedenceTestConfig = `
+com_port: x
+slider_mapping:
+  0: deej.current
+  1: chrome.exe
+  2: g*.exe
+  3: [spotify.exe, game.exe]
+  4: Spotify.exe
+slider_groups:
+  - name: voice
+    sliders: [5, 6]
+    targets: [discord.exe, game.exe]
+`
+
+func TestResolveControllers(t *testing.T) {
+	slider := func(sliderID int, target string) SessionController {
+		source := targetSourceMapping
+		if target == "deej.current" {
+			source = targetSourceCurrent
+		}
+
+		return SessionController{Source: source, SliderID: &sliderID, Target: target}
+	}
+
+	group := func(target string) SessionController {
+		return SessionController{Source: targetSourceGroup, Group: "voice", Target: target}
+	}
+
+	tests := []struct {
+		name       string
+		precedence string
+		want       map[string]SessionController
+	}{
+		// exact names beat patterns, and the lowest slider index wins between exact names
+		{"default", "", map[string]SessionController{
+			"chrome.exe":  slider(1, "chrome.exe"),
+			"spotify.exe": slider(3, "spotify.exe"),
+			"game.exe":    slider(3, "game.exe"),
+			"discord.exe": group("discord.exe"),
+		}},
+		{"current first", "target_precedence: [current, group]\n", map[string]SessionController{
+			"chrome.exe":  slider(0, "deej.current"),
+			"spotify.exe": slider(3, "spotify.exe"),
+			"game.exe":    group("game.exe"),
+			"discord.exe": group("discord.exe"),
+		}},
+		{"groups first", "target_precedence: [group]\n", map[string]SessionController{
+			"chrome.exe":  slider(1, "chrome.exe"),
+			"spotify.exe": slider(3, "spotify.exe"),
+			"game.exe":    group("game.exe"),
+			"discord.exe": group("discord.exe"),
+		}},
+	}
+
+	for _, test := range tests {
+		d := newTestDeej(t, zap.NewNop().Sugar(), targetPrecedenceTestConfig+test.precedence)
+		controllers := d.sessions.resolveControllers()
+
+		for key, want := range test.want {
+			got := controllers.get(key)
+			if got == nil || !reflect.DeepEqual(*got, want) {
+				t.Errorf("%s: %s is controlled by %+v, want %+v", test.name, key, got, want)
+			}
+		}
+
+		if got := controllers.get("master"); got != nil {
+			t.Errorf("%s: master is controlled by %+v, but nothing maps it", test.name, got)
+		}
+	}
+}
+
+func TestSliderOnlySetsSessionsItControls(t *testing.T) {
+	d := newTestDeej(t, zap.NewNop().Sugar(), targetPrecedenceTestConfig)
+
+	backend := &recordingBackend{SessionBackend: d.sessions.backend, volumeSets: make(map[string]float32)}
+	d.sessions.backend = backend
+
+	tests := []struct {
+		sliderID int
+		want     map[string]float32
+	}{
+		{0, map[string]float32{}},
+		{1, map[string]float32{"chrome.exe": 0.5}},
+		{2, map[string]float32{}},
+		{3, map[string]float32{"spotify.exe": 0.5, "game.exe": 0.5}},
+		{4, map[string]float32{}},
+	}
+
+	for _, test := range tests {
+		backend.volumeSets = make(map[string]float32)
+
+		d.sessions.handleSliderMoveEvent(SliderMoveEvent{SliderID: test.sliderID, PercentValue: 0.5})
+
+		if !reflect.DeepEqual(backend.volumeSets, test.want) {
+			t.Errorf("moving slider %d set %v, want %v", test.sliderID, backend.volumeSets, test.want)
+		}
+	}
+}
+
+func TestTargetPrecedenceFromConfig(t *testing.T) {
+	tests := []struct {
+		configYAML string
+		want       []string
+		wantWarned bool
+	}{
+		{"", defaultTargetPrecedence, false},
+		{"target_precedence: [current, unmapped, group, mapping]\n", []string{"current", "unmapped", "group", "mapping"}, false},
+		{"target_precedence: [Group]\n", []string{"group", "mapping", "unmapped", "current"}, false},
+		{"target_precedence: [current, mapping]\n", []string{"current", "mapping", "group", "unmapped"}, false},
+		{"target_precedence: [mapping, nonsense]\n", defaultTargetPrecedence, true},
+		{"target_precedence: [group, group]\n", defaultTargetPrecedence, true},
+	}
+
+	for _, test := range tests {
+		warned := false
+
+		precedence := targetPrecedenceFromConfig(userConfigFromYAML(t, test.configYAML),
+			func(message string, key string, keysAndValues ...interface{}) {
+				warned = true
+			})
+
+		if !reflect.DeepEqual(precedence, test.want) || warned != test.wantWarned {
+			t.Errorf("config %q gave %v (warned: %v), want %v (warned: %v)",
+				test.configYAML, precedence, warned, test.want, test.wantWarned)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package deej
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// a slider's targets can each take a share of its volume, by following them with @ and a weight:
+//
+//	slider_mapping:
+//	  1:
+//	    - discord.exe
+//	    - spotify.exe@0.6
+//
+// moves discord along with the slider and spotify at 60% of it. the weighted volume is capped at 100%, so weights
+// above 1 make a target reach full volume before the slider does. targets without a weight get the slider's volume
+// as-is. weights are kept as part of the target everywhere (the config, the API, profiles), and only split off
+// where a target is matched against sessions
+
+const targetWeightSeparator = "@"
+
+// splitTargetWeight returns a mapped target without its weight, and the weight (1 if it doesn't have one).
+// anything after the last @ that isn't a number is part of the target's name. ok is false for weights
+// that are numbers but can't be used, which are then taken as 1
+func splitTargetWeight(target string) (name string, weight float32, ok bool) {
+	separatorIdx := strings.LastIndex(target, targetWeightSeparator)
+	if separatorIdx <= 0 {
+		return target, 1, true
+	}
+
+	rawWeight := strings.TrimSpace(target[separatorIdx+len(targetWeightSeparator):])
+
+	parsed, err := strconv.ParseFloat(rawWeight, 32)
+	if err != nil {
+		return target, 1, true
+	}
+
+	name = strings.TrimSpace(target[:separatorIdx])
+
+	if parsed < 0 || math.IsNaN(parsed) || math.IsInf(parsed, 0) {
+		return name, 1, false
+	}
+
+	return name, float32(parsed), true
+}
+
+// targetName returns a mapped target without its weight
+func targetName(target string) string {
+	name, _, _ := splitTargetWeight(target)
+	return name
+}
+
+// weighted returns a volume after applying a target's weight, capped at full volume
+func weighted(volume float32, weight float32) float32 {
+	if product := volume * weight; product < 1 {
+		return product
+	}
+
+	return 1
+}
+
+// sliderTargetWeights returns the weight of every weighted target in a mapping, by slider and then by target
+// as it's mapped. targets without a weight are left out
+func sliderTargetWeights(mapping map[int][]string) map[string]map[string]float32 {
+	weights := make(map[string]map[string]float32)
+
+	for sliderIdx, targets := range mapping {
+		for _, target := range targets {
+			name, weight, _ := splitTargetWeight(target)
+			if name == target {
+				continue
+			}
+
+			key := strconv.Itoa(sliderIdx)
+
+			if weights[key] == nil {
+				weights[key] = make(map[string]float32)
+			}
+
+			weights[key][target] = weight
+		}
+	}
+
+	return weights
+}
+
+// invalidTargetWeight returns the first of the targets whose weight can't be used, if there is one
+func invalidTargetWeight(targets []string) (string, bool) {
+	for _, target := range targets {
+		if _, _, ok := splitTargetWeight(target); !ok {
+			return target, true
+		}
+	}
+
+	return "", false
+}
+
+// warnInvalidTargetWeights warns about weights that are taken as 1 because they can't be used
+func warnInvalidTargetWeights(
+	mapping *sliderMap,
+	key string,
+	warnInvalidValue func(message string, key string, keysAndValues ...interface{}),
+) {
+	mapping.iterate(func(sliderIdx int, targets []string) {
+		for _, target := range targets {
+			if _, _, ok := splitTargetWeight(target); !ok {
+				warnInvalidValue("Invalid target weight specified, it must be a non-negative number - using 1 instead",
+					key,
+					"sliderIdx", sliderIdx,
+					"target", target)
+			}
+		}
+	})
+}
@@ -22,6 +22,25 @@ func (d *Deej) initializeTray(onDone func()) {
 		openWebUI := systray.AddMenuItem("Open configuration UI", "Open web browser to configure sliders")
 		openWebUI.SetIcon(icon.EditConfig)
 
+		// the title changes along with the server's state, which it's told about below
+		toggleWebUI := systray.AddMenuItem("Stop configuration UI", "")
+		serverStateChanges := d.server.SubscribeToStateChanges()
+
+		updateWebUIItems := func() {
+			if d.server.IsRunning() {
+				toggleWebUI.SetTitle("Stop configuration UI")
+				toggleWebUI.SetTooltip("Stop serving the configuration UI, without quitting deej")
+				openWebUI.Enable()
+			} else {
+				toggleWebUI.SetTitle("Start configuration UI")
+				toggleWebUI.SetTooltip("Serve the configuration UI again")
+				openWebUI.Disable()
+			}
+		}
+
+		// the server only starts once onDone runs, which tells us about it
+		updateWebUIItems()
+
 		editConfig := systray.AddMenuItem("Edit configuration file", "Open config file with notepad")
 		editConfig.SetIcon(icon.EditConfig)
 
@@ -42,6 +61,19 @@ func (d *Deej) initializeTray(onDone func()) {
 			for {
 				select {
 
+				// the server started or stopped
+				case <-serverStateChanges:
+					updateWebUIItems()
+
+				// start or stop the web UI
+				case <-toggleWebUI.ClickedCh:
+					logger.Info("Toggle web UI menu item clicked")
+
+					if _, err := d.server.Toggle(); err != nil {
+						logger.Warnw("Failed to start web server", "error", err)
+						d.notifier.Notify("Can't start the configuration UI!", err.Error())
+					}
+
 				// quit
 				case <-quit.ClickedCh:
 					logger.Info("Quit menu item clicked, stopping")
@@ -57,6 +89,8 @@ func (d *Deej) initializeTray(onDone func()) {
 						var cmd *exec.Cmd
 						if util.Linux() {
 							cmd = exec.Command("xdg-open", url)
+						} else if util.MacOS() {
+							cmd = exec.Command("open", url)
 						} else {
 							cmd = exec.Command("cmd", "/C", "start", url)
 						}
@@ -73,6 +107,10 @@ func (d *Deej) initializeTray(onDone func()) {
 					editor := "notepad.exe"
 					if util.Linux() {
 						editor = "gedit"
+					} else if util.MacOS() {
+
+						// the default text editor, whichever that is
+						editor = "open -t"
 					}
 
 					if err := util.OpenExternal(logger, editor, userConfigFilepath); err != nil {
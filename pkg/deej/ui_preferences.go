@@ -0,0 +1,215 @@
+package deej
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// the web UI keeps a few preferences of its own (i.e. its theme) in config.yaml, under ui_preferences, so they
+// follow the user to whichever browser they open it in. they're a plain key -> value store, but only for the keys
+// listed here, each checked by its own rule. GET /api/preferences reads them (with defaults for anything that isn't
+// set), and PUT /api/preferences only changes the keys it's given - a null value puts one back to its default.
+// none of them have any effect on deej itself
+
+// uiPreference is a key the UI may store, by its name in the API
+type uiPreference struct {
+	name      string
+	configKey string
+
+	defaultValue interface{}
+
+	// normalize returns the value as it's stored, or an error saying what's wrong with it
+	normalize func(value interface{}) (interface{}, error)
+}
+
+var uiPreferences = []uiPreference{
+
+	// "system" follows the OS's dark or light mode
+	{
+		name:         "theme",
+		configKey:    "theme",
+		defaultValue: "system",
+		normalize:    uiPreferenceOneOf("system", "light", "dark"),
+	},
+
+	// in seconds, how often the UI refreshes the list of sessions
+	{
+		name:         "pollInterval",
+		configKey:    "poll_interval",
+		defaultValue: 10.0,
+		normalize:    uiPreferenceBetween(1, 300),
+	},
+
+	// how slider positions are shown, "percent" (i.e. 42%) or "decimal" (0.42)
+	{
+		name:         "units",
+		configKey:    "units",
+		defaultValue: "percent",
+		normalize:    uiPreferenceOneOf("percent", "decimal"),
+	},
+}
+
+func uiPreferenceOneOf(allowed ...string) func(value interface{}) (interface{}, error) {
+	return func(value interface{}) (interface{}, error) {
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be one of %s", strings.Join(allowed, ", "))
+		}
+
+		str = strings.ToLower(strings.TrimSpace(str))
+		for _, candidate := range allowed {
+			if str == candidate {
+				return str, nil
+			}
+		}
+
+		return nil, fmt.Errorf("must be one of %s", strings.Join(allowed, ", "))
+	}
+}
+
+func uiPreferenceBetween(minValue float64, maxValue float64) func(value interface{}) (interface{}, error) {
+	return func(value interface{}) (interface{}, error) {
+		var number float64
+
+		// JSON numbers are always float64, but YAML ones can be ints
+		switch typed := value.(type) {
+		case float64:
+			number = typed
+		case int:
+			number = float64(typed)
+		default:
+			return nil, fmt.Errorf("must be a number between %g and %g", minValue, maxValue)
+		}
+
+		if number < minValue || number > maxValue {
+			return nil, fmt.Errorf("must be a number between %g and %g", minValue, maxValue)
+		}
+
+		return number, nil
+	}
+}
+
+// findUIPreference looks a preference up by its name in the API
+func findUIPreference(name string) (uiPreference, bool) {
+	for _, preference := range uiPreferences {
+		if preference.name == name {
+			return preference, true
+		}
+	}
+
+	return uiPreference{}, false
+}
+
+// defaultUIPreferences returns every preference at its default value
+func defaultUIPreferences() map[string]interface{} {
+	result := make(map[string]interface{})
+	for _, preference := range uiPreferences {
+		result[preference.name] = preference.defaultValue
+	}
+
+	return result
+}
+
+// mergeUIPreferences applies a partial update (as it came with a PUT) on top of the current preferences. it checks
+// every key before changing anything, so a bad update never partially applies
+func mergeUIPreferences(current map[string]interface{}, update map[string]interface{}) (map[string]interface{}, error) {
+	names := []string{}
+	for name := range update {
+		names = append(names, name)
+	}
+
+	// so the same bad update always gets the same error
+	sort.Strings(names)
+
+	result := make(map[string]interface{})
+	for name, value := range current {
+		result[name] = value
+	}
+
+	for _, name := range names {
+		preference, ok := findUIPreference(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown preference %q", name)
+		}
+
+		if update[name] == nil {
+			result[name] = preference.defaultValue
+			continue
+		}
+
+		value, err := preference.normalize(update[name])
+		if err != nil {
+			return nil, fmt.Errorf("%s %w", name, err)
+		}
+
+		result[name] = value
+	}
+
+	return result, nil
+}
+
+func uiPreferencesFromConfig(
+	userConfig *viper.Viper,
+	warnInvalidValue func(message string, key string, keysAndValues ...interface{}),
+) map[string]interface{} {
+	result := defaultUIPreferences()
+	section := userConfig.GetStringMap(configKeyUIPreferences)
+
+	for _, preference := range uiPreferences {
+		value, ok := section[preference.configKey]
+		if !ok || value == nil {
+			continue
+		}
+
+		normalized, err := preference.normalize(value)
+		if err != nil {
+			warnInvalidValue("Invalid UI preference specified, using default value",
+				configKeyUIPreferences+"."+preference.configKey,
+				"invalidValue", value,
+				"reason", err.Error(),
+				"defaultValue", preference.defaultValue)
+
+			continue
+		}
+
+		result[preference.name] = normalized
+	}
+
+	for configKey := range section {
+		known := false
+		for _, preference := range uiPreferences {
+			known = known || preference.configKey == configKey
+		}
+
+		if !known {
+			warnInvalidValue("Unknown UI preference specified, ignoring it",
+				configKeyUIPreferences+"."+configKey)
+		}
+	}
+
+	return result
+}
+
+// uiPreferencesToConfigValue returns the preferences in the shape they're written to config.yaml in. ones at their
+// default value are left out, and nil means none of them are set
+func uiPreferencesToConfigValue(preferences map[string]interface{}) interface{} {
+	configValue := make(map[string]interface{})
+
+	for _, preference := range uiPreferences {
+		value, ok := preferences[preference.name]
+		if !ok || value == preference.defaultValue {
+			continue
+		}
+
+		configValue[preference.configKey] = value
+	}
+
+	if len(configValue) == 0 {
+		return nil
+	}
+
+	return configValue
+}
@@ -0,0 +1,223 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/lxn/win"
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modKernel32 = windows.NewLazySystemDLL("kernel32.dll")
+	modVersion  = windows.NewLazySystemDLL("version.dll")
+
+	procQueryFullProcessImageName = modKernel32.NewProc("QueryFullProcessImageNameW")
+	procGetFileVersionInfoSize    = modVersion.NewProc("GetFileVersionInfoSizeW")
+	procGetFileVersionInfo        = modVersion.NewProc("GetFileVersionInfoW")
+	procVerQueryValue             = modVersion.NewProc("VerQueryValueW")
+
+	// keyed by executable path. failed lookups are cached too, so we don't keep retrying them
+	processMetadataCache     = map[string]ProcessMetadata{}
+	processMetadataCacheLock sync.Mutex
+)
+
+func getProcessMetadata(pid uint32) (ProcessMetadata, error) {
+	executablePath, err := getProcessExecutablePath(pid)
+	if err != nil {
+		return ProcessMetadata{}, fmt.Errorf("get executable path for pid %d: %w", pid, err)
+	}
+
+	processMetadataCacheLock.Lock()
+	defer processMetadataCacheLock.Unlock()
+
+	if metadata, ok := processMetadataCache[executablePath]; ok {
+		return metadata, nil
+	}
+
+	metadata := ProcessMetadata{
+		DisplayName: getExecutableDescription(executablePath),
+		Icon:        getExecutableIcon(executablePath),
+	}
+
+	processMetadataCache[executablePath] = metadata
+
+	return metadata, nil
+}
+
+func getProcessExecutablePath(pid uint32) (string, error) {
+	handle, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return "", fmt.Errorf("open process: %w", err)
+	}
+	defer windows.CloseHandle(handle)
+
+	buf := make([]uint16, windows.MAX_PATH)
+	size := uint32(len(buf))
+
+	if ret, _, err := procQueryFullProcessImageName.Call(
+		uintptr(handle),
+		0,
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(unsafe.Pointer(&size)),
+	); ret == 0 {
+		return "", fmt.Errorf("query full process image name: %w", err)
+	}
+
+	return windows.UTF16ToString(buf[:size]), nil
+}
+
+// getExecutableDescription reads the executable's version resource, preferring its
+// file description (what task manager shows) over its product name
+func getExecutableDescription(executablePath string) string {
+	pathPtr, err := syscall.UTF16PtrFromString(executablePath)
+	if err != nil {
+		return ""
+	}
+
+	size, _, _ := procGetFileVersionInfoSize.Call(uintptr(unsafe.Pointer(pathPtr)), 0)
+	if size == 0 {
+		return ""
+	}
+
+	versionInfo := make([]byte, size)
+	if ret, _, _ := procGetFileVersionInfo.Call(
+		uintptr(unsafe.Pointer(pathPtr)),
+		0,
+		size,
+		uintptr(unsafe.Pointer(&versionInfo[0])),
+	); ret == 0 {
+		return ""
+	}
+
+	// string values live under a language and codepage specific block, so find out which one first
+	translationPtr, translationLen := queryVersionValue(versionInfo, `\VarFileInfo\Translation`)
+	if translationLen < 4 {
+		return ""
+	}
+
+	translation := (*[2]uint16)(translationPtr)
+	language, codepage := translation[0], translation[1]
+
+	for _, field := range []string{"FileDescription", "ProductName"} {
+		valuePtr, valueLen := queryVersionValue(versionInfo, fmt.Sprintf(`\StringFileInfo\%04x%04x\%s`, language, codepage, field))
+		if valueLen == 0 {
+			continue
+		}
+
+		// string values are UTF-16 pointing somewhere inside versionInfo, and their length is in characters
+		chars := (*[1 << 16]uint16)(valuePtr)[:valueLen:valueLen]
+		if name := windows.UTF16ToString(chars); name != "" {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// queryVersionValue returns a pointer into versionInfo and the value's length, which is 0 if it doesn't exist
+func queryVersionValue(versionInfo []byte, subBlock string) (unsafe.Pointer, uint32) {
+	subBlockPtr, err := syscall.UTF16PtrFromString(subBlock)
+	if err != nil {
+		return nil, 0
+	}
+
+	var (
+		valuePtr unsafe.Pointer
+		valueLen uint32
+	)
+
+	if ret, _, _ := procVerQueryValue.Call(
+		uintptr(unsafe.Pointer(&versionInfo[0])),
+		uintptr(unsafe.Pointer(subBlockPtr)),
+		uintptr(unsafe.Pointer(&valuePtr)),
+		uintptr(unsafe.Pointer(&valueLen)),
+	); ret == 0 || valuePtr == nil {
+		return nil, 0
+	}
+
+	return valuePtr, valueLen
+}
+
+// getExecutableIcon extracts the executable's main icon and encodes it as a PNG
+func getExecutableIcon(executablePath string) []byte {
+	pathPtr, err := syscall.UTF16PtrFromString(executablePath)
+	if err != nil {
+		return nil
+	}
+
+	// ExtractIcon returns 1 when the file isn't an executable, and 0 when it has no icons
+	hIcon := win.ExtractIcon(0, pathPtr, 0)
+	if hIcon <= 1 {
+		return nil
+	}
+	defer win.DestroyIcon(hIcon)
+
+	var iconInfo win.ICONINFO
+	if !win.GetIconInfo(hIcon, &iconInfo) {
+		return nil
+	}
+	defer win.DeleteObject(win.HGDIOBJ(iconInfo.HbmColor))
+	defer win.DeleteObject(win.HGDIOBJ(iconInfo.HbmMask))
+
+	var bitmap win.BITMAP
+	if win.GetObject(win.HGDIOBJ(iconInfo.HbmColor), unsafe.Sizeof(bitmap), unsafe.Pointer(&bitmap)) == 0 {
+		return nil
+	}
+
+	width, height := int(bitmap.BmWidth), int(bitmap.BmHeight)
+
+	bitmapInfo := win.BITMAPINFO{}
+	bitmapInfo.BmiHeader.BiSize = uint32(unsafe.Sizeof(bitmapInfo.BmiHeader))
+	bitmapInfo.BmiHeader.BiWidth = int32(width)
+	bitmapInfo.BmiHeader.BiHeight = -int32(height) // negative height means top-down rows
+	bitmapInfo.BmiHeader.BiPlanes = 1
+	bitmapInfo.BmiHeader.BiBitCount = 32
+	bitmapInfo.BmiHeader.BiCompression = win.BI_RGB
+
+	pixels := make([]byte, width*height*4)
+
+	hdc := win.CreateCompatibleDC(0)
+	defer win.DeleteDC(hdc)
+
+	if win.GetDIBits(hdc, iconInfo.HbmColor, 0, uint32(height), &pixels[0], &bitmapInfo, win.DIB_RGB_COLORS) == 0 {
+		return nil
+	}
+
+	// older icons have no alpha channel at all, in which case they should be fully opaque
+	hasAlpha := false
+	for i := 3; i < len(pixels); i += 4 {
+		if pixels[i] != 0 {
+			hasAlpha = true
+			break
+		}
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			offset := (y*width + x) * 4
+
+			alpha := pixels[offset+3]
+			if !hasAlpha {
+				alpha = 0xff
+			}
+
+			// the bitmap's pixels are in BGRA order
+			img.SetNRGBA(x, y, color.NRGBA{R: pixels[offset+2], G: pixels[offset+1], B: pixels[offset], A: alpha})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil
+	}
+
+	return buf.Bytes()
+}
@@ -36,6 +36,11 @@ func Linux() bool {
 	return runtime.GOOS == "linux"
 }
 
+// MacOS returns true if we're running on macOS
+func MacOS() bool {
+	return runtime.GOOS == "darwin"
+}
+
 // SetupCloseHandler creates a 'listener' on a new goroutine which will notify the
 // program if it receives an interrupt from the OS
 func SetupCloseHandler() chan os.Signal {
@@ -52,12 +57,33 @@ func GetCurrentWindowProcessNames() ([]string, error) {
 	return getCurrentWindowProcessNames()
 }
 
+// ProcessMetadata holds human-friendly information about a running process
+type ProcessMetadata struct {
+	DisplayName string
+
+	// PNG-encoded, nil when the process has no icon we can get to
+	Icon []byte
+}
+
+// GetProcessMetadata returns a friendly name and icon for the process with the given PID, where available.
+// Results are cached per executable, since looking these up is relatively expensive.
+// This is currently only implemented for Windows
+func GetProcessMetadata(pid uint32) (ProcessMetadata, error) {
+	return getProcessMetadata(pid)
+}
+
+// ListSerialPorts returns the names of all serial ports that currently exist on this machine,
+// such as "COM3" on Windows, "/dev/ttyUSB0" on Linux or "/dev/cu.usbmodem1101" on macOS
+func ListSerialPorts() ([]string, error) {
+	return listSerialPorts()
+}
+
 // OpenExternal spawns a detached window with the provided command and argument
 func OpenExternal(logger *zap.SugaredLogger, cmd string, arg string) error {
 
-	// use cmd for windows, bash for linux
+	// use cmd for windows, bash for linux and macos
 	execCommandArgs := []string{"cmd.exe", "/C", "start", "/b", cmd, arg}
-	if Linux() {
+	if Linux() || MacOS() {
 		execCommandArgs = []string{"/bin/bash", "-c", fmt.Sprintf("%s %s", cmd, arg)}
 	}
 
@@ -83,6 +109,11 @@ func NormalizeScalar(v float32) float32 {
 
 // SignificantlyDifferent returns true if there's a significant enough volume difference between two given values
 func SignificantlyDifferent(old float32, new float32, noiseReductionLevel string) bool {
+	return SignificantlyDifferentByThreshold(old, new, NoiseReductionThreshold(noiseReductionLevel))
+}
+
+// NoiseReductionThreshold returns the smallest volume difference considered significant for a noise reduction level
+func NoiseReductionThreshold(noiseReductionLevel string) float64 {
 
 	const (
 		noiseReductionHigh = "high"
@@ -92,22 +123,19 @@ func SignificantlyDifferent(old float32, new float32, noiseReductionLevel string
 	// this threshold is solely responsible for dealing with hardware interference when
 	// sliders are producing noisy values. this value should be a median value between two
 	// round percent values. for instance, 0.025 means volume can move at 3% increments
-	var significantDifferenceThreshold float64
-
-	// choose our noise reduction level based on the config-provided value
 	switch noiseReductionLevel {
 	case noiseReductionHigh:
-		significantDifferenceThreshold = 0.035
-		break
+		return 0.035
 	case noiseReductionLow:
-		significantDifferenceThreshold = 0.015
-		break
+		return 0.015
 	default:
-		significantDifferenceThreshold = 0.025
-		break
+		return 0.025
 	}
+}
 
-	if math.Abs(float64(old-new)) >= significantDifferenceThreshold {
+// SignificantlyDifferentByThreshold returns true if two given volume values are at least threshold apart
+func SignificantlyDifferentByThreshold(old float32, new float32, threshold float64) bool {
+	if math.Abs(float64(old-new)) >= threshold {
 		return true
 	}
 
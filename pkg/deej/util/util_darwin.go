@@ -0,0 +1,34 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// arduino boards show up under one of these, depending on their usb-to-serial chip. the tty.* twins of these
+// block on open until carrier detect, which boards never raise
+var serialPortPatterns = []string{"/dev/cu.usbmodem*", "/dev/cu.usbserial*", "/dev/cu.wchusbserial*"}
+
+func getCurrentWindowProcessNames() ([]string, error) {
+	return nil, errors.New("Not implemented")
+}
+
+func getProcessMetadata(pid uint32) (ProcessMetadata, error) {
+	return ProcessMetadata{}, errors.New("Not implemented")
+}
+
+func listSerialPorts() ([]string, error) {
+	ports := []string{}
+
+	for _, pattern := range serialPortPatterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("glob serial ports (%s): %w", pattern, err)
+		}
+
+		ports = append(ports, matches...)
+	}
+
+	return ports, nil
+}
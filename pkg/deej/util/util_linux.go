@@ -2,8 +2,32 @@ package util
 
 import (
 	"errors"
+	"fmt"
+	"path/filepath"
 )
 
+// arduino boards show up under one of these, depending on their usb-to-serial chip
+var serialPortPatterns = []string{"/dev/ttyUSB*", "/dev/ttyACM*"}
+
 func getCurrentWindowProcessNames() ([]string, error) {
 	return nil, errors.New("Not implemented")
 }
+
+func getProcessMetadata(pid uint32) (ProcessMetadata, error) {
+	return ProcessMetadata{}, errors.New("Not implemented")
+}
+
+func listSerialPorts() ([]string, error) {
+	ports := []string{}
+
+	for _, pattern := range serialPortPatterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("glob serial ports (%s): %w", pattern, err)
+		}
+
+		ports = append(ports, matches...)
+	}
+
+	return ports, nil
+}
@@ -0,0 +1,54 @@
+package util
+
+import "testing"
+
+func TestNoiseReductionThreshold(t *testing.T) {
+	tests := []struct {
+		level string
+		want  float64
+	}{
+		{"high", 0.035},
+		{"low", 0.015},
+		{"default", 0.025},
+		{"", 0.025},
+		{"nonsense", 0.025},
+	}
+
+	for _, test := range tests {
+		if got := NoiseReductionThreshold(test.level); got != test.want {
+			t.Errorf("NoiseReductionThreshold(%q) = %v, want %v", test.level, got, test.want)
+		}
+	}
+}
+
+func TestSignificantlyDifferentByThreshold(t *testing.T) {
+	tests := []struct {
+		old       float32
+		new       float32
+		threshold float64
+		want      bool
+	}{
+		{0.5, 0.5, 0.025, false},
+		{0.5, 0.51, 0.025, false},
+		{0.5, 0.49, 0.025, false},
+		{0.5, 0.53, 0.025, true},
+		{0.5, 0.47, 0.025, true},
+
+		// a bigger threshold swallows bigger moves
+		{0.5, 0.53, 0.05, false},
+		{0.5, 0.6, 0.05, true},
+
+		// either end is always reached, however small the move there
+		{0.99, 1, 0.025, true},
+		{0.01, 0, 0.025, true},
+		{1, 1, 0.025, false},
+		{0, 0, 0.025, false},
+	}
+
+	for _, test := range tests {
+		if got := SignificantlyDifferentByThreshold(test.old, test.new, test.threshold); got != test.want {
+			t.Errorf("SignificantlyDifferentByThreshold(%v, %v, %v) = %v, want %v",
+				test.old, test.new, test.threshold, got, test.want)
+		}
+	}
+}
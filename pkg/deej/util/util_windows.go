@@ -8,10 +8,14 @@ import (
 
 	"github.com/lxn/win"
 	"github.com/mitchellh/go-ps"
+	"golang.org/x/sys/windows/registry"
 )
 
 const (
 	getCurrentWindowInternalCooldown = time.Millisecond * 350
+
+	// windows keeps a live list of all serial devices here, mapping driver names to port names
+	serialPortsRegistryPath = `HARDWARE\DEVICEMAP\SERIALCOMM`
 )
 
 var (
@@ -94,3 +98,35 @@ func getCurrentWindowProcessNames() ([]string, error) {
 	lastGetCurrentWindowResult = result
 	return result, nil
 }
+
+func listSerialPorts() ([]string, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, serialPortsRegistryPath, registry.QUERY_VALUE)
+	if err != nil {
+
+		// this key doesn't exist at all when there are no serial devices connected
+		if err == registry.ErrNotExist {
+			return []string{}, nil
+		}
+
+		return nil, fmt.Errorf("open serial ports registry key: %w", err)
+	}
+	defer key.Close()
+
+	valueNames, err := key.ReadValueNames(0)
+	if err != nil {
+		return nil, fmt.Errorf("read serial ports registry value names: %w", err)
+	}
+
+	ports := []string{}
+
+	for _, valueName := range valueNames {
+		port, _, err := key.GetStringValue(valueName)
+		if err != nil {
+			continue
+		}
+
+		ports = append(ports, port)
+	}
+
+	return ports, nil
+}
@@ -0,0 +1,78 @@
+package deej
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/omriharel/deej/pkg/deej/util"
+)
+
+const (
+	volumeCurveLinear      = "linear"
+	volumeCurveLogarithmic = "logarithmic"
+	volumeCurvePower       = "power"
+
+	defaultVolumeCurve         = volumeCurveLinear
+	defaultVolumeCurveExponent = 2.0
+)
+
+// VolumeCurve determines how a slider's position translates to the volume of its targets
+type VolumeCurve struct {
+	Type string `json:"type" schema:"enum=volumeCurves"`
+
+	// only used by the power curve
+	Exponent float64 `json:"exponent,omitempty" schema:"minimum=0"`
+}
+
+// validate makes sure the curve can be applied, filling in the default exponent for power curves that don't have one
+func (vc *VolumeCurve) validate() error {
+	vc.Type = strings.ToLower(vc.Type)
+
+	if !validVolumeCurveType(vc.Type) {
+		return fmt.Errorf("unknown volume curve type %q (supported: %s, %s, %s)",
+			vc.Type, volumeCurveLinear, volumeCurveLogarithmic, volumeCurvePower)
+	}
+
+	if vc.Type != volumeCurvePower {
+		vc.Exponent = 0
+		return nil
+	}
+
+	if vc.Exponent == 0 {
+		vc.Exponent = defaultVolumeCurveExponent
+	}
+
+	if vc.Exponent < 0 {
+		return fmt.Errorf("volume curve exponent must be positive, got %v", vc.Exponent)
+	}
+
+	return nil
+}
+
+func validVolumeCurveType(curveType string) bool {
+	switch curveType {
+	case volumeCurveLinear, volumeCurveLogarithmic, volumeCurvePower:
+		return true
+	default:
+		return false
+	}
+}
+
+// apply maps a slider value between 0.0 and 1.0 to a volume between 0.0 and 1.0.
+// every curve keeps both ends in place, they only differ in how they get from one to the other
+func (vc VolumeCurve) apply(value float32) float32 {
+	switch vc.Type {
+
+	// perceived loudness is logarithmic, so an exponential taper makes the slider feel linear to the ear.
+	// this is scaled to make sure 0.0 and 1.0 are still silence and full volume respectively
+	case volumeCurveLogarithmic:
+		return util.NormalizeScalar(float32((math.Pow(10, float64(value)) - 1) / 9))
+
+	case volumeCurvePower:
+		return util.NormalizeScalar(float32(math.Pow(float64(value), vc.Exponent)))
+
+	default:
+		return value
+	}
+}
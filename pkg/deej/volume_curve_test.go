@@ -0,0 +1,66 @@
+package deej
+
+import "testing"
+
+func TestVolumeCurveApply(t *testing.T) {
+	tests := []struct {
+		curve VolumeCurve
+		value float32
+		want  float32
+	}{
+		{VolumeCurve{Type: volumeCurveLinear}, 0, 0},
+		{VolumeCurve{Type: volumeCurveLinear}, 0.5, 0.5},
+		{VolumeCurve{Type: volumeCurveLinear}, 1, 1},
+
+		// (10^0.5 - 1) / 9 is just over 0.24
+		{VolumeCurve{Type: volumeCurveLogarithmic}, 0, 0},
+		{VolumeCurve{Type: volumeCurveLogarithmic}, 0.5, 0.24},
+		{VolumeCurve{Type: volumeCurveLogarithmic}, 1, 1},
+
+		{VolumeCurve{Type: volumeCurvePower, Exponent: 2}, 0, 0},
+		{VolumeCurve{Type: volumeCurvePower, Exponent: 2}, 0.5, 0.25},
+		{VolumeCurve{Type: volumeCurvePower, Exponent: 2}, 1, 1},
+		{VolumeCurve{Type: volumeCurvePower, Exponent: 3}, 0.5, 0.12},
+		{VolumeCurve{Type: volumeCurvePower, Exponent: 0.5}, 0.25, 0.5},
+	}
+
+	for _, test := range tests {
+		if got := test.curve.apply(test.value); got != test.want {
+			t.Errorf("%+v.apply(%v) = %v, want %v", test.curve, test.value, got, test.want)
+		}
+	}
+}
+
+func TestVolumeCurveValidate(t *testing.T) {
+	tests := []struct {
+		curve   VolumeCurve
+		want    VolumeCurve
+		wantErr bool
+	}{
+		{VolumeCurve{Type: "linear"}, VolumeCurve{Type: volumeCurveLinear}, false},
+		{VolumeCurve{Type: "Logarithmic"}, VolumeCurve{Type: volumeCurveLogarithmic}, false},
+
+		// only power curves have an exponent, and they get a default one
+		{VolumeCurve{Type: "linear", Exponent: 3}, VolumeCurve{Type: volumeCurveLinear}, false},
+		{VolumeCurve{Type: "power"}, VolumeCurve{Type: volumeCurvePower, Exponent: defaultVolumeCurveExponent}, false},
+		{VolumeCurve{Type: "power", Exponent: 1.5}, VolumeCurve{Type: volumeCurvePower, Exponent: 1.5}, false},
+
+		{VolumeCurve{Type: "power", Exponent: -1}, VolumeCurve{}, true},
+		{VolumeCurve{Type: "cubic"}, VolumeCurve{}, true},
+		{VolumeCurve{Type: ""}, VolumeCurve{}, true},
+	}
+
+	for _, test := range tests {
+		curve := test.curve
+		err := curve.validate()
+
+		if (err != nil) != test.wantErr {
+			t.Errorf("%+v.validate() returned %v, want an error: %v", test.curve, err, test.wantErr)
+			continue
+		}
+
+		if err == nil && curve != test.want {
+			t.Errorf("%+v.validate() made it %+v, want %+v", test.curve, curve, test.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// VolumeLimit keeps a target's volume within a range, no matter what its slider (or anything else) asks for:
+//
+//	volume_limits:
+//	  - target: master
+//	    min: 0.1
+//	  - target: spotify.exe
+//	    max: 0.8
+//
+// a slider's full travel is stretched over the range after its volume curve, so it stays just as fine-grained.
+// volumes set over MQTT are clamped to it instead, since they already are a volume
+type VolumeLimit struct {
+	Target string  `json:"target" mapstructure:"target" schema:"required,minLength=1,examples=targets"`
+	Min    float64 `json:"min" mapstructure:"min" schema:"minimum=0,maximum=1"`
+	Max    float64 `json:"max" mapstructure:"max" schema:"minimum=0,maximum=1"`
+}
+
+var errInvalidVolumeLimit = errors.New("invalid volume limit")
+
+func (limit *VolumeLimit) validate() error {
+	limit.Target = strings.ToLower(strings.TrimSpace(limit.Target))
+
+	if limit.Target == "" {
+		return fmt.Errorf("%w: target can't be empty", errInvalidVolumeLimit)
+	}
+
+	// leaving it out means no upper limit
+	if limit.Max == 0 {
+		limit.Max = 1
+	}
+
+	if limit.Min < 0 || limit.Max > 1 || limit.Min >= limit.Max {
+		return fmt.Errorf("%w: min and max must be between 0 and 1 with min below max, got %v and %v",
+			errInvalidVolumeLimit, limit.Min, limit.Max)
+	}
+
+	return nil
+}
+
+// scale maps a volume from the full 0.0 - 1.0 into the limited range
+func (limit VolumeLimit) scale(volume float32) float32 {
+	return float32(limit.Min + float64(volume)*(limit.Max-limit.Min))
+}
+
+// clamp moves a volume that's outside the limited range to its nearest end
+func (limit VolumeLimit) clamp(volume float32) float32 {
+	if float64(volume) < limit.Min {
+		return float32(limit.Min)
+	}
+
+	if float64(volume) > limit.Max {
+		return float32(limit.Max)
+	}
+
+	return volume
+}
+
+// volumeLimit returns the limit for a resolved target, if it has one
+func (cc *CanonicalConfig) volumeLimit(resolvedTarget string) (VolumeLimit, bool) {
+	for _, limit := range cc.VolumeLimits {
+		if limit.Target == resolvedTarget || cc.SessionNames.normalize(limit.Target) == resolvedTarget {
+			return limit, true
+		}
+	}
+
+	return VolumeLimit{}, false
+}
+
+func volumeLimitsFromConfig(
+	userConfig *viper.Viper,
+	warnInvalidValue func(message string, key string, keysAndValues ...interface{}),
+) []VolumeLimit {
+	var rawLimits []VolumeLimit
+
+	if err := userConfig.UnmarshalKey(configKeyVolumeLimits, &rawLimits); err != nil {
+		warnInvalidValue("Invalid volume limits specified, ignoring all of them",
+			configKeyVolumeLimits,
+			"error", err)
+
+		return []VolumeLimit{}
+	}
+
+	limits := make([]VolumeLimit, 0, len(rawLimits))
+	targets := make(map[string]bool, len(rawLimits))
+
+	for idx, limit := range rawLimits {
+		if err := limit.validate(); err != nil {
+			warnInvalidValue("Invalid volume limit specified, ignoring it",
+				configKeyVolumeLimits,
+				"index", idx,
+				"error", err)
+
+			continue
+		}
+
+		if targets[limit.Target] {
+			warnInvalidValue("Duplicate volume limit target specified, ignoring it",
+				configKeyVolumeLimits,
+				"index", idx,
+				"target", limit.Target)
+
+			continue
+		}
+
+		targets[limit.Target] = true
+		limits = append(limits, limit)
+	}
+
+	return limits
+}
+
+// volumeLimitsToConfigValue returns the limits in the shape they're written to config.yaml in
+func volumeLimitsToConfigValue(limits []VolumeLimit) []map[string]interface{} {
+	value := make([]map[string]interface{}, len(limits))
+
+	for idx, limit := range limits {
+		value[idx] = map[string]interface{}{
+			"target": limit.Target,
+			"min":    limit.Min,
+			"max":    limit.Max,
+		}
+	}
+
+	return value
+}
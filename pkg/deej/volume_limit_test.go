@@ -0,0 +1,189 @@
+package deej
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// userConfigFromYAML reads a config the way deej reads config.yaml
+func userConfigFromYAML(t *testing.T, configYAML string) *viper.Viper {
+	t.Helper()
+
+	userConfig := viper.New()
+	userConfig.SetConfigType("yaml")
+
+	if err := userConfig.ReadConfig(strings.NewReader(configYAML)); err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+
+	return userConfig
+}
+
+func TestVolumeLimitValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   VolumeLimit
+		want    VolumeLimit
+		wantErr bool
+	}{
+		{"min only", VolumeLimit{Target: "Master", Min: 0.1}, VolumeLimit{Target: "master", Min: 0.1, Max: 1}, false},
+		{"max only", VolumeLimit{Target: " spotify.exe ", Max: 0.8}, VolumeLimit{Target: "spotify.exe", Max: 0.8}, false},
+		{"both", VolumeLimit{Target: "mic", Min: 0.2, Max: 0.6}, VolumeLimit{Target: "mic", Min: 0.2, Max: 0.6}, false},
+		{"full range", VolumeLimit{Target: "mic", Max: 1}, VolumeLimit{Target: "mic", Max: 1}, false},
+
+		{"no target", VolumeLimit{Target: "  ", Min: 0.1}, VolumeLimit{}, true},
+		{"min below 0", VolumeLimit{Target: "mic", Min: -0.1}, VolumeLimit{}, true},
+		{"max above 1", VolumeLimit{Target: "mic", Max: 1.5}, VolumeLimit{}, true},
+		{"min at max", VolumeLimit{Target: "mic", Min: 0.5, Max: 0.5}, VolumeLimit{}, true},
+		{"min above max", VolumeLimit{Target: "mic", Min: 0.7, Max: 0.3}, VolumeLimit{}, true},
+		{"min of 1 without max", VolumeLimit{Target: "mic", Min: 1}, VolumeLimit{}, true},
+	}
+
+	for _, test := range tests {
+		limit := test.limit
+		err := limit.validate()
+
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: validate() returned %v, want an error: %v", test.name, err, test.wantErr)
+			continue
+		}
+
+		if err != nil {
+			if !errors.Is(err, errInvalidVolumeLimit) {
+				t.Errorf("%s: validate() returned %v, want an invalid volume limit", test.name, err)
+			}
+
+			continue
+		}
+
+		if limit != test.want {
+			t.Errorf("%s: validate() left %+v, want %+v", test.name, limit, test.want)
+		}
+	}
+}
+
+func TestVolumeLimitScaleAndClamp(t *testing.T) {
+	limit := VolumeLimit{Target: "master", Min: 0.2, Max: 0.6}
+
+	tests := []struct {
+		volume    float32
+		wantScale float32
+		wantClamp float32
+	}{
+		{0, 0.2, 0.2},
+		{0.1, 0.24, 0.2},
+		{0.5, 0.4, 0.5},
+		{0.6, 0.44, 0.6},
+		{1, 0.6, 0.6},
+	}
+
+	for _, test := range tests {
+		if got := limit.scale(test.volume); !floatsClose(got, test.wantScale) {
+			t.Errorf("scale(%v) = %v, want %v", test.volume, got, test.wantScale)
+		}
+
+		if got := limit.clamp(test.volume); !floatsClose(got, test.wantClamp) {
+			t.Errorf("clamp(%v) = %v, want %v", test.volume, got, test.wantClamp)
+		}
+	}
+}
+
+func TestVolumeLimitsFromConfig(t *testing.T) {
+	userConfig := userConfigFromYAML(t, `
+volume_limits:
+  - target: Master
+    min: 0.1
+  - target: spotify.exe
+    max: 0.8
+  - target: master
+    max: 0.5
+  - target: ""
+    min: 0.1
+  - target: discord.exe
+    min: 0.9
+    max: 0.2
+`)
+
+	warnings := 0
+	limits := volumeLimitsFromConfig(userConfig, func(message string, key string, keysAndValues ...interface{}) {
+		warnings++
+	})
+
+	want := []VolumeLimit{
+		{Target: "master", Min: 0.1, Max: 1},
+		{Target: "spotify.exe", Max: 0.8},
+	}
+
+	if !reflect.DeepEqual(limits, want) {
+		t.Errorf("volumeLimitsFromConfig() = %+v, want %+v", limits, want)
+	}
+
+	// the duplicate, the empty target and the backwards range
+	if warnings != 3 {
+		t.Errorf("warned %d times, want 3", warnings)
+	}
+
+	cc := &CanonicalConfig{VolumeLimits: limits, SessionNames: SessionNameSettings{StripExtension: true}}
+
+	tests := []struct {
+		resolvedTarget string
+		want           VolumeLimit
+		wantOK         bool
+	}{
+		{"master", want[0], true},
+		{"spotify.exe", want[1], true},
+		{"spotify", want[1], true},
+		{"discord.exe", VolumeLimit{}, false},
+	}
+
+	for _, test := range tests {
+		if got, ok := cc.volumeLimit(test.resolvedTarget); got != test.want || ok != test.wantOK {
+			t.Errorf("volumeLimit(%q) = %+v, %v, want %+v, %v", test.resolvedTarget, got, ok, test.want, test.wantOK)
+		}
+	}
+}
+
+func floatsClose(a, b float32) bool {
+	return a-b < 0.0001 && b-a < 0.0001
+}
+
+func TestLimitedSliderStretchesOverRange(t *testing.T) {
+	d := newTestDeej(t, zap.NewNop().Sugar(), `
+com_port: x
+slider_mapping:
+  0: spotify.exe
+  1: discord.exe
+volume_limits:
+  - target: spotify.exe
+    min: 0.2
+    max: 0.6
+`)
+
+	tests := []struct {
+		position    float32
+		wantSpotify float32
+		wantDiscord float32
+	}{
+		{0, 0.2, 0},
+		{0.5, 0.4, 0.5},
+		{1, 0.6, 1},
+	}
+
+	for _, test := range tests {
+		d.sessions.handleSliderMoveEvent(SliderMoveEvent{SliderID: 0, PercentValue: test.position})
+		d.sessions.handleSliderMoveEvent(SliderMoveEvent{SliderID: 1, PercentValue: test.position})
+
+		if got := mockVolume(t, d, "spotify.exe"); !floatsClose(got, test.wantSpotify) {
+			t.Errorf("at %.2f, spotify.exe is at %.2f, want %.2f", test.position, got, test.wantSpotify)
+		}
+
+		if got := mockVolume(t, d, "discord.exe"); !floatsClose(got, test.wantDiscord) {
+			t.Errorf("at %.2f, discord.exe is at %.2f, want %.2f", test.position, got, test.wantDiscord)
+		}
+	}
+}
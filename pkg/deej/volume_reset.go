@@ -0,0 +1,200 @@
+package deej
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// after experimenting with volumes, POST /api/reset puts every target that's mapped to a slider (directly or through
+// a group) back to reset.volume, or the volume of its own under reset.targets, and unmutes it - wherever the sliders
+// are. it's a one-off: the next time a slider moves, it sets its targets' volumes like always.
+//
+// a reset isn't a slider move, so it still happens while slider moves are paused. unpausing applies every slider's
+// current position though, like it always does, which undoes the reset. with mute_at_zero, a target whose slider is
+// at the bottom is unmuted all the same, and stays that way until the slider moves again. a target's volume limit
+// still applies to the volume it's reset to
+
+const defaultResetVolume = 1.0
+
+// ResetSettings holds the volumes POST /api/reset sets targets to
+type ResetSettings struct {
+	Volume float32
+
+	// keyed by lowercase target, only for targets with a volume of their own
+	Targets map[string]float32
+}
+
+// TargetReset is a resolved target that was reset, and the volume it was reset to
+type TargetReset struct {
+	Target string  `json:"target"`
+	Volume float32 `json:"volume"`
+
+	// whether any of its sessions were muted before
+	Unmuted bool `json:"unmuted"`
+}
+
+// VolumeResetSummary is what a reset did
+type VolumeResetSummary struct {
+	Reset []TargetReset `json:"reset"`
+
+	// mapped targets that don't have any sessions right now, and ones that had a session fail to change
+	Missing []string `json:"missing"`
+	Failed  []string `json:"failed"`
+
+	// slider moves are paused, so unpausing will undo the reset
+	Paused bool `json:"paused"`
+}
+
+// ResetVolumes resets every mapped target, on the slider move goroutine, and returns what it did
+func (m *sessionMap) ResetVolumes() VolumeResetSummary {
+	done := make(chan VolumeResetSummary, 1)
+	m.resetRequests <- done
+
+	return <-done
+}
+
+// mappedResolvedTargets returns the session keys every target mapped to a slider (or a slider group) resolves to
+func (m *sessionMap) mappedResolvedTargets() []string {
+	targets := []string{}
+
+	m.deej.config.sliderMapping().iterate(func(_ int, sliderTargets []string) {
+		targets = append(targets, sliderTargets...)
+	})
+
+	for _, group := range m.deej.config.SliderGroups {
+		targets = append(targets, group.Targets...)
+	}
+
+	seen := make(map[string]bool)
+	resolvedTargets := []string{}
+
+	for _, target := range targets {
+		for _, resolvedTarget := range m.resolveTarget(target) {
+			if !seen[resolvedTarget] {
+				seen[resolvedTarget] = true
+				resolvedTargets = append(resolvedTargets, resolvedTarget)
+			}
+		}
+	}
+
+	sort.Strings(resolvedTargets)
+
+	return resolvedTargets
+}
+
+// resetVolumes resets every mapped target. must only be called from the slider move goroutine
+func (m *sessionMap) resetVolumes() VolumeResetSummary {
+	summary := VolumeResetSummary{
+		Reset:   []TargetReset{},
+		Missing: []string{},
+		Failed:  []string{},
+		Paused:  m.Paused(),
+	}
+
+	adjustmentFailed := false
+
+	for _, resolvedTarget := range m.mappedResolvedTargets() {
+		sessions, ok := m.get(resolvedTarget)
+		if !ok {
+			summary.Missing = append(summary.Missing, resolvedTarget)
+			continue
+		}
+
+		wasMuted := false
+		for _, session := range sessions {
+			wasMuted = wasMuted || (session.Controllable() && m.backend.GetMute(session))
+		}
+
+		volume := m.deej.config.resetVolume(resolvedTarget)
+		if limit, ok := m.deej.config.volumeLimit(resolvedTarget); ok {
+			volume = limit.clamp(volume)
+		}
+
+		// a volume held back from before would otherwise land right after the reset
+		delete(m.pendingApplies, resolvedTarget)
+
+		// the target is unmuted either way, so mute at zero is kept out of it by going as if the slider was up
+		_, failed := m.applyVolume(resolvedTarget, volume, 1)
+		if failed {
+			adjustmentFailed = true
+			summary.Failed = append(summary.Failed, resolvedTarget)
+
+			continue
+		}
+
+		// this refreshes sessions on its own if it fails
+		if _, err := m.SetTargetMute(resolvedTarget, false); err != nil {
+			m.logger.Warnw("Failed to unmute target while resetting", "target", resolvedTarget, "error", err)
+			summary.Failed = append(summary.Failed, resolvedTarget)
+
+			continue
+		}
+
+		summary.Reset = append(summary.Reset, TargetReset{Target: resolvedTarget, Volume: volume, Unmuted: wasMuted})
+	}
+
+	m.logger.Infow("Reset mapped targets' volumes",
+		"reset", len(summary.Reset),
+		"missing", summary.Missing,
+		"failed", summary.Failed)
+
+	if len(summary.Missing) > 0 {
+		m.refreshSessions(false)
+	} else if adjustmentFailed {
+
+		// performance: same as with slider moves, this only happens when a SetVolume call errored
+		m.refreshSessions(true)
+	}
+
+	return summary
+}
+
+// resetVolume returns the volume a resolved target is reset to
+func (cc *CanonicalConfig) resetVolume(resolvedTarget string) float32 {
+	for target, volume := range cc.Reset.Targets {
+		if target == resolvedTarget || cc.SessionNames.normalize(target) == resolvedTarget {
+			return volume
+		}
+	}
+
+	return cc.Reset.Volume
+}
+
+func resetSettingsFromConfig(
+	userConfig *viper.Viper,
+	warnInvalidValue func(message string, key string, keysAndValues ...interface{}),
+) ResetSettings {
+	volume := userConfig.GetFloat64(configKeyResetVolume)
+	if volume < 0 || volume > 1 {
+		warnInvalidValue("Invalid reset volume specified, it must be between 0 and 1 - using default value",
+			configKeyResetVolume,
+			"invalidValue", volume,
+			"defaultValue", defaultResetVolume)
+
+		volume = defaultResetVolume
+	}
+
+	settings := ResetSettings{
+		Volume:  float32(volume),
+		Targets: make(map[string]float32),
+	}
+
+	for target, rawVolume := range userConfig.GetStringMapString(configKeyResetTargets) {
+		targetVolume, err := strconv.ParseFloat(rawVolume, 32)
+		if err != nil || targetVolume < 0 || targetVolume > 1 {
+			warnInvalidValue("Invalid reset volume specified (must be between 0 and 1), ignoring",
+				configKeyResetTargets,
+				"target", target,
+				"invalidValue", rawVolume)
+
+			continue
+		}
+
+		settings.Targets[strings.ToLower(target)] = float32(targetVolume)
+	}
+
+	return settings
+}
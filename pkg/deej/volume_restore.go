@@ -0,0 +1,92 @@
+package deej
+
+import (
+	"time"
+)
+
+// how long restoring volumes on exit may take. the OS audio APIs can hang (i.e. on a device that's going away),
+// and that shouldn't keep deej from exiting
+const volumeRestoreTimeout = 3 * time.Second
+
+// snapshotSession remembers the state a session key was in before deej first changed it, so it can be restored
+// on exit. later calls for the same key keep the original snapshot, even across session refreshes
+func (m *sessionMap) snapshotSession(key string, session Session) {
+	m.lock.Lock()
+	_, ok := m.snapshots[key]
+	m.lock.Unlock()
+
+	if ok {
+		return
+	}
+
+	// read outside of the lock, since asking the OS can take a moment
+	state := sessionState{volume: m.backend.GetVolume(session), muted: m.backend.GetMute(session)}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.snapshots[key]; !ok {
+		m.snapshots[key] = state
+	}
+}
+
+// restoreVolumes puts every session deej changed back the way it found it, unless the user would rather keep
+// deej's levels. it runs on the slider move goroutine so nothing can move a slider after it, and gives up
+// after the given timeout
+func (m *sessionMap) restoreVolumes(timeout time.Duration) {
+	if !m.deej.config.RestoreVolumesOnExit {
+		return
+	}
+
+	done := make(chan bool)
+	deadline := time.After(timeout)
+
+	select {
+	case m.restoreRequests <- done:
+	case <-deadline:
+		m.logger.Warnw("Timed out waiting to restore volumes", "timeout", timeout)
+		return
+	}
+
+	select {
+	case <-done:
+	case <-deadline:
+		m.logger.Warnw("Timed out restoring volumes", "timeout", timeout)
+	}
+}
+
+// applyVolumeSnapshots must only be called from the slider move goroutine
+func (m *sessionMap) applyVolumeSnapshots() {
+	m.lock.Lock()
+	snapshots := make(map[string]sessionState, len(m.snapshots))
+	for key, state := range m.snapshots {
+		snapshots[key] = state
+	}
+	m.lock.Unlock()
+
+	restored := 0
+
+	for key, state := range snapshots {
+
+		// an app that was closed since has nothing left to restore
+		sessions, ok := m.get(key)
+		if !ok {
+			continue
+		}
+
+		for _, session := range sessions {
+			if err := m.setSessionVolume(session, state.volume); err != nil {
+				m.logger.Warnw("Failed to restore session volume", "key", key, "error", err)
+				continue
+			}
+
+			if err := m.setSessionMute(session, state.muted); err != nil {
+				m.logger.Warnw("Failed to restore session mute state", "key", key, "error", err)
+			}
+		}
+
+		restored++
+	}
+
+	m.logger.Infow("Restored volumes from before deej took over", "targets", restored)
+}
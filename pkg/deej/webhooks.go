@@ -0,0 +1,292 @@
+package deej
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// WebhookRule POSTs to a URL whenever a slider crosses a threshold, in either direction:
+//
+//	webhooks:
+//	  - slider: 1
+//	    threshold: 0.1
+//	    url: http://127.0.0.1:8080/mic-muted
+//
+// it's comparable, so every rule can keep its own state until the rules change
+type WebhookRule struct {
+	Slider    int     `json:"slider" mapstructure:"slider"`
+	Threshold float64 `json:"threshold" mapstructure:"threshold"`
+	URL       string  `json:"url" mapstructure:"url"`
+}
+
+// WebhookNotifier watches slider values and delivers webhooks for the configured rules
+type WebhookNotifier struct {
+	deej   *Deej
+	logger *zap.SugaredLogger
+	client *http.Client
+
+	lock   sync.Mutex
+	states map[WebhookRule]*webhookRuleState
+}
+
+type webhookRuleState struct {
+
+	// the side of the threshold that was last reported (or first seen)
+	above bool
+
+	// the slider's latest value, and a pending crossing, which is called off if the slider goes back before it settles
+	value   float32
+	pending *time.Timer
+}
+
+type webhookPayload struct {
+	Slider    int     `json:"slider"`
+	Threshold float64 `json:"threshold"`
+	Value     float32 `json:"value"`
+
+	// "above" or "below", the side of the threshold the slider is on now
+	Direction string `json:"direction"`
+
+	Time time.Time `json:"time"`
+}
+
+const (
+	webhookDirectionAbove = "above"
+	webhookDirectionBelow = "below"
+
+	// a crossing only fires once the slider has stayed on the other side for this long,
+	// so a slider resting right at the threshold doesn't flap
+	webhookDebounce = 250 * time.Millisecond
+
+	// deliveries happen in the background, these only bound how long each one can take
+	webhookTimeout    = 5 * time.Second
+	webhookRetryDelay = time.Second
+)
+
+var errInvalidWebhookRule = errors.New("invalid webhook rule")
+
+// NewWebhookNotifier creates a WebhookNotifier. it consumes slider values right away, even with no rules configured
+func NewWebhookNotifier(deej *Deej, logger *zap.SugaredLogger) *WebhookNotifier {
+	logger = logger.Named("webhooks")
+
+	n := &WebhookNotifier{
+		deej:   deej,
+		logger: logger,
+		client: &http.Client{Timeout: webhookTimeout},
+		states: make(map[WebhookRule]*webhookRuleState),
+	}
+
+	go n.consumeSliderValues(deej.serial.SubscribeToSliderValues())
+
+	logger.Debug("Created webhook notifier instance")
+
+	return n
+}
+
+func (n *WebhookNotifier) consumeSliderValues(valuesChannel chan []float32) {
+	for values := range valuesChannel {
+		n.handleSliderValues(values)
+	}
+}
+
+func (n *WebhookNotifier) handleSliderValues(values []float32) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	rules := n.deej.config.Webhooks
+	current := make(map[WebhookRule]bool, len(rules))
+
+	for _, rule := range rules {
+		current[rule] = true
+
+		if rule.Slider >= len(values) {
+			continue
+		}
+
+		value := values[rule.Slider]
+		above := float64(value) >= rule.Threshold
+
+		// the first value only tells us where the slider starts out
+		state, ok := n.states[rule]
+		if !ok {
+			n.states[rule] = &webhookRuleState{above: above, value: value}
+			continue
+		}
+
+		state.value = value
+
+		if above == state.above {
+
+			// it went back before the crossing settled
+			if state.pending != nil {
+				state.pending.Stop()
+				state.pending = nil
+			}
+
+			continue
+		}
+
+		if state.pending == nil {
+			rule, state := rule, state
+
+			var timer *time.Timer
+			timer = time.AfterFunc(webhookDebounce, func() { n.fire(rule, state, timer) })
+			state.pending = timer
+		}
+	}
+
+	// forget rules that were removed or changed, along with any crossing they still had pending
+	for rule, state := range n.states {
+		if !current[rule] {
+			if state.pending != nil {
+				state.pending.Stop()
+			}
+
+			delete(n.states, rule)
+		}
+	}
+}
+
+// fire reports a crossing that settled, unless it was called off in the meantime
+func (n *WebhookNotifier) fire(rule WebhookRule, state *webhookRuleState, timer *time.Timer) {
+	n.lock.Lock()
+
+	if n.states[rule] != state || state.pending != timer {
+		n.lock.Unlock()
+		return
+	}
+
+	state.pending = nil
+	state.above = !state.above
+
+	payload := webhookPayload{
+		Slider:    rule.Slider,
+		Threshold: rule.Threshold,
+		Value:     state.value,
+		Direction: webhookDirectionBelow,
+		Time:      time.Now(),
+	}
+
+	if state.above {
+		payload.Direction = webhookDirectionAbove
+	}
+
+	n.lock.Unlock()
+
+	go n.deliver(rule.URL, payload)
+}
+
+// deliver POSTs the payload, retrying once if the first attempt fails
+func (n *WebhookNotifier) deliver(hookURL string, payload webhookPayload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		n.logger.Warnw("Failed to marshal webhook payload", "error", err)
+		return
+	}
+
+	logger := n.logger.With("url", hookURL, "slider", payload.Slider, "direction", payload.Direction)
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		err = n.post(hookURL, body)
+		if err == nil {
+			logger.Debug("Delivered webhook")
+			return
+		}
+
+		logger.Debugw("Webhook delivery attempt failed", "attempt", attempt, "error", err)
+
+		if attempt == 1 {
+			<-time.After(webhookRetryDelay)
+		}
+	}
+
+	logger.Warnw("Failed to deliver webhook", "error", err)
+}
+
+func (n *WebhookNotifier) post(hookURL string, body []byte) error {
+	response, err := n.client.Post(hookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", response.Status)
+	}
+
+	return nil
+}
+
+func (rule WebhookRule) validate() error {
+	if rule.Slider < 0 {
+		return fmt.Errorf("%w: slider must be a non-negative slider index, got %d", errInvalidWebhookRule, rule.Slider)
+	}
+
+	// at either end, the slider could never be on the other side
+	if rule.Threshold <= 0 || rule.Threshold >= 1 {
+		return fmt.Errorf("%w: threshold must be between 0 and 1, got %v", errInvalidWebhookRule, rule.Threshold)
+	}
+
+	parsed, err := url.Parse(rule.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: url must be an http:// or https:// URL, got %q", errInvalidWebhookRule, rule.URL)
+	}
+
+	return nil
+}
+
+func webhookRulesFromConfig(
+	userConfig *viper.Viper,
+	warnInvalidValue func(message string, key string, keysAndValues ...interface{}),
+) []WebhookRule {
+	var rawRules []WebhookRule
+
+	if err := userConfig.UnmarshalKey(configKeyWebhooks, &rawRules); err != nil {
+		warnInvalidValue("Invalid webhooks specified, ignoring all of them",
+			configKeyWebhooks,
+			"error", err)
+
+		return []WebhookRule{}
+	}
+
+	rules := make([]WebhookRule, 0, len(rawRules))
+
+	for idx, rule := range rawRules {
+		if err := rule.validate(); err != nil {
+			warnInvalidValue("Invalid webhook specified, ignoring it",
+				configKeyWebhooks,
+				"index", idx,
+				"error", err)
+
+			continue
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+// webhookRulesToConfigValue returns the rules in the shape they're written to config.yaml in
+func webhookRulesToConfigValue(rules []WebhookRule) []map[string]interface{} {
+	value := make([]map[string]interface{}, len(rules))
+
+	for idx, rule := range rules {
+		value[idx] = map[string]interface{}{
+			"slider":    rule.Slider,
+			"threshold": rule.Threshold,
+			"url":       rule.URL,
+		}
+	}
+
+	return value
+}